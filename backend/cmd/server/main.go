@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strings"
+	"time"
+	// База поясов IANA внутри бинарника: часовые пояса пользователей работают и в образе без tzdata.
+	_ "time/tzdata"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
+	"github.com/spozitivom/taskmanager/internal/blob"
 	appdb "github.com/spozitivom/taskmanager/internal/db"
 	"github.com/spozitivom/taskmanager/internal/handlers"
+	"github.com/spozitivom/taskmanager/internal/mail"
 	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/oidc"
 	"github.com/spozitivom/taskmanager/internal/services"
 	"github.com/spozitivom/taskmanager/internal/storage"
 )
@@ -24,32 +32,188 @@ func main() {
 	// Подключаемся к БД и инициализируем слои приложения.
 	db := appdb.Connect()
 
+	// Файлы (аватары, вложения задач) — в локальном каталоге или S3-совместимом хранилище, см. BLOB_BACKEND.
+	blobStore, err := blob.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Ошибка настройки хранилища файлов: %v", err)
+	}
+
 	taskStorage := storage.NewTaskStorage(db)
 	userStorage := storage.NewUserStorage(db)
 	projectStorage := storage.NewProjectStorage(db)
-	taskService := services.NewTaskService(taskStorage)
+	sessionStorage := storage.NewSessionStorage(db)
+	accessTokenStorage := storage.NewAccessTokenStorage(db)
+	passwordResetStorage := storage.NewPasswordResetStorage(db)
+	emailVerificationStorage := storage.NewEmailVerificationStorage(db)
+	recoveryCodeStorage := storage.NewRecoveryCodeStorage(db)
+	loginFailureStorage := storage.NewLoginFailureStorage(db)
+	oidcStorage := storage.NewOIDCStorage(db)
+	adminStorage := storage.NewAdminStorage(db)
+	securityEventStorage := storage.NewSecurityEventStorage(db)
+	dataExportStorage := storage.NewDataExportStorage(db)
+	attachmentStorage := storage.NewAttachmentStorage(db)
+	labelStorage := storage.NewLabelStorage(db)
+	savedViewStorage := storage.NewSavedViewStorage(db)
+	statsStorage := storage.NewStatsStorage(db)
+	timeEntryStorage := storage.NewTimeEntryStorage(db)
+	milestoneStorage := storage.NewMilestoneStorage(db)
+	projectTemplateStorage := storage.NewProjectTemplateStorage(db)
+	mailer := mail.NewOutboxSender(db)
+	// Тарифы (лимиты проектов, задач, участников и файлов) можно переопределить через QUOTA_PLANS.
+	services.SetQuotaPlans(services.QuotaPlansFromEnv())
+	attachmentQuotas := services.AttachmentQuotasFromEnv()
+	taskService := services.NewTaskService(taskStorage, projectStorage, userStorage)
 	projectService := services.NewProjectService(projectStorage, taskStorage, userStorage)
 	userService := services.NewUserService(db, userStorage, projectStorage, taskStorage)
+	sessionService := services.NewSessionService(sessionStorage, userStorage)
+	accessTokenService := services.NewAccessTokenService(accessTokenStorage, userStorage)
+	passwordResetService := services.NewPasswordResetService(passwordResetStorage, userStorage, sessionService, mailer)
+	verificationService := services.NewEmailVerificationService(emailVerificationStorage, userStorage, mailer, services.VerificationPolicyFromEnv())
+	twoFactorService := services.NewTwoFactorService(userStorage, recoveryCodeStorage)
+	loginThrottleService := services.NewLoginThrottleService(loginFailureStorage, services.DefaultLoginThrottleConfig())
+	securityEventService := services.NewSecurityEventService(securityEventStorage)
+	dataExportService := services.NewDataExportService(db, dataExportStorage, userStorage, mailer)
+	avatarService := services.NewAvatarService(db, blobStore, userStorage)
+	labelService := services.NewLabelService(labelStorage, taskStorage, projectStorage)
+	savedViewService := services.NewSavedViewService(savedViewStorage, taskStorage, projectStorage, userStorage, labelService)
+	statsService := services.NewStatsService(statsStorage, projectStorage, userStorage)
+	calendarService := services.NewCalendarService(taskStorage, projectStorage, userStorage)
+	timeEntryService := services.NewTimeEntryService(timeEntryStorage, taskStorage, projectStorage)
+	milestoneService := services.NewMilestoneService(milestoneStorage, taskStorage, projectStorage, userStorage)
+	projectTemplateService := services.NewProjectTemplateService(projectTemplateStorage, projectStorage, taskStorage, userStorage)
+	attachmentService := services.NewAttachmentService(attachmentStorage, taskStorage, projectStorage, userStorage, blobStore, attachmentQuotas)
+	quotaService := services.NewQuotaService(projectStorage, taskStorage, userStorage, attachmentStorage, attachmentQuotas)
+
+	// SSO через OpenID Connect включается, если заданы OIDC_ISSUER, OIDC_CLIENT_ID и OIDC_REDIRECT_URL.
+	var oidcProvider *oidc.Provider
+	oidcConfig, oidcEnabled := oidc.ConfigFromEnv()
+	if oidcEnabled {
+		oidcProvider = oidc.NewProvider(oidcConfig, nil)
+	}
+	oidcService := services.NewOIDCService(oidcProvider, oidcConfig.Issuer, oidcStorage, userStorage, services.OIDCSettingsFromEnv())
 	taskHandler := handlers.NewTaskHandler(taskService, projectService)
+	taskHandler.Labels = labelService
+	labelHandler := handlers.NewLabelHandler(labelService)
+	savedViewHandler := handlers.NewSavedViewHandler(savedViewService)
+	statsHandler := handlers.NewStatsHandler(statsService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	timeEntryHandler := handlers.NewTimeEntryHandler(timeEntryService)
+	milestoneHandler := handlers.NewMilestoneHandler(milestoneService)
+	projectTemplateHandler := handlers.NewProjectTemplateHandler(projectTemplateService)
 	projectHandler := handlers.NewProjectHandler(projectService)
-	userHandler := handlers.NewUserHandler(userService)
+	userHandler := handlers.NewUserHandler(userService, verificationService)
+	userHandler.Security = securityEventService
+	userHandler.Avatars = avatarService
+	avatarHandler := handlers.NewAvatarHandler(avatarService)
+	avatarHandler.Security = securityEventService
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	quotaHandler := handlers.NewQuotaHandler(quotaService)
+	accessTokenHandler := handlers.NewAccessTokenHandler(accessTokenService)
+	accessTokenHandler.Security = securityEventService
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	twoFactorHandler.Security = securityEventService
+	adminService := services.NewAdminService(adminStorage, userStorage, accessTokenStorage, sessionService, passwordResetService, loginThrottleService)
+	adminHandler := handlers.NewAdminHandler(adminService, securityEventService)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService)
+	dataExportHandler.Security = securityEventService
+
+	authHandler := &handlers.AuthHandler{
+		DB:            db,
+		Sessions:      sessionService,
+		PasswordReset: passwordResetService,
+		Verification:  verificationService,
+		TwoFactor:     twoFactorService,
+		Throttle:      loginThrottleService,
+		OIDC:          oidcService,
+		Users:         userService,
+		Security:      securityEventService,
+	}
+
+	// Access-токены проверяются по серверным сессиям, чтобы logout срабатывал сразу.
+	middleware.SetSessionValidator(sessionService)
+	middleware.SetTokenAuthenticator(accessTokenService)
+	middleware.SetCapabilityChecker(verificationService)
+	middleware.SetAccountStatusChecker(userService)
 
-	authHandler := &handlers.AuthHandler{DB: db}
+	// Письма копятся в outbox и доставляются фоном (SMTP, если настроен, иначе в лог).
+	var transport mail.Sender
+	if smtpSender := mail.NewSMTPSenderFromEnv(); smtpSender != nil {
+		transport = smtpSender
+	} else {
+		logSender := mail.NewLogSenderFromEnv()
+		if logSender.LogBodies {
+			log.Println("⚠️ MAIL_LOG_BODIES=true: письма со ссылками для входа попадают в лог, не используйте в проде")
+		}
+		transport = logSender
+	}
+	go mail.NewDispatcher(db, transport).Run(15*time.Second, nil)
+	// Аватары, сохранённые раньше data URL в таблице users, переносим в хранилище файлов.
+	go func() {
+		migrated, dropped, err := avatarService.MigrateDataURLs(context.Background())
+		if err != nil {
+			log.Printf("avatar migration: %v", err)
+		}
+		if migrated+dropped > 0 {
+			log.Printf("avatar migration: moved %d, dropped %d", migrated, dropped)
+		}
+	}()
+	// Выгрузки данных пользователей собираются фоном.
+	go dataExportService.Run(30*time.Second, nil)
+
+	// Старые неудачные попытки входа, брошенные SSO-входы, просроченные выгрузки и
+	// файлы окончательно удалённых задач чистим раз в час.
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := loginThrottleService.Purge(); err != nil {
+				log.Printf("login failures purge: %v", err)
+			}
+			if err := oidcService.Purge(); err != nil {
+				log.Printf("oidc requests purge: %v", err)
+			}
+			if err := dataExportService.Purge(); err != nil {
+				log.Printf("data exports purge: %v", err)
+			}
+			if _, err := attachmentService.PurgeOrphans(context.Background()); err != nil {
+				log.Printf("attachments purge: %v", err)
+			}
+		}
+	}()
 
 	// Готовим Gin.
 	router := gin.Default()
+	// Ограничение попыток входа опирается на ClientIP: за прокси укажите его адреса.
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		if err := router.SetTrustedProxies(strings.Split(proxies, ",")); err != nil {
+			log.Fatalf("❌ Некорректный TRUSTED_PROXIES: %v", err)
+		}
+	} else if err := router.SetTrustedProxies(nil); err != nil {
+		log.Fatalf("❌ Ошибка настройки прокси: %v", err)
+	}
 	router.Use(cors.Default())
 	router.Use(middleware.Recover())
 	router.Use(middleware.ForceUTF8())
 
-	// Публичные эндпоинты авторизации.
-	router.POST("/api/auth/register", authHandler.Register)
-	router.POST("/api/auth/login", authHandler.Login)
+	// Эндпоинты авторизации и сессий.
+	authHandler.RegisterRoutes(router)
 
 	// Защищённые маршруты.
 	taskHandler.RegisterRoutes(router)
+	attachmentHandler.RegisterRoutes(router)
+	labelHandler.RegisterRoutes(router)
+	savedViewHandler.RegisterRoutes(router)
+	statsHandler.RegisterRoutes(router)
+	calendarHandler.RegisterRoutes(router)
+	timeEntryHandler.RegisterRoutes(router)
+	milestoneHandler.RegisterRoutes(router)
+	projectTemplateHandler.RegisterRoutes(router)
 	projectHandler.RegisterRoutes(router)
 	userHandler.RegisterRoutes(router)
+	quotaHandler.RegisterRoutes(router)
+	accessTokenHandler.RegisterRoutes(router)
+	twoFactorHandler.RegisterRoutes(router)
+	adminHandler.RegisterRoutes(router)
+	dataExportHandler.RegisterRoutes(router)
+	avatarHandler.RegisterRoutes(router)
 
 	// Запускаем сервер.
 	port := os.Getenv("PORT")
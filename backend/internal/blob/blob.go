@@ -0,0 +1,68 @@
+// Package blob — хранилище двоичных объектов (аватары, вложения) с бэкендами
+// на локальной файловой системе и в S3-совместимом сервисе.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Object — метаданные сохранённого объекта.
+type Object struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Store — минимальный интерфейс хранилища. Ключи — пути через «/» без ведущего слэша.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get возвращает содержимое объекта; вызывающий закрывает reader. ErrNotFound — объекта нет.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete удаляет объект; отсутствие объекта ошибкой не считается.
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv создаёт хранилище по BLOB_BACKEND: local (по умолчанию, каталог BLOB_DIR)
+// или s3 (S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY).
+func FromEnv() (Store, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(os.Getenv("BLOB_BACKEND"))); backend {
+	case "", "local":
+		dir := strings.TrimSpace(os.Getenv("BLOB_DIR"))
+		if dir == "" {
+			dir = "data/blobs"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  strings.TrimSpace(os.Getenv("S3_ENDPOINT")),
+			Region:    strings.TrimSpace(os.Getenv("S3_REGION")),
+			Bucket:    strings.TrimSpace(os.Getenv("S3_BUCKET")),
+			AccessKey: strings.TrimSpace(os.Getenv("S3_ACCESS_KEY")),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown BLOB_BACKEND %q", backend)
+	}
+}
+
+// validKey не пускает пустые ключи, абсолютные пути и выход за пределы хранилища.
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
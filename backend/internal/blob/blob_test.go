@@ -0,0 +1,58 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/blob"
+	"github.com/spozitivom/taskmanager/internal/blob/blobtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores_RoundTrip(t *testing.T) {
+	local, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	minio := blobtest.NewS3Server("avatars")
+	t.Cleanup(minio.Close)
+	s3, err := blob.NewS3Store(minio.Config("avatars"), nil)
+	require.NoError(t, err)
+
+	for name, store := range map[string]blob.Store{"local": local, "s3": s3} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "users/1/avatar 64.png"
+			require.NoError(t, store.Put(ctx, key, strings.NewReader("png-bytes"), 9, "image/png"))
+
+			rc, obj, err := store.Get(ctx, key)
+			require.NoError(t, err)
+			data, err := io.ReadAll(rc)
+			require.NoError(t, rc.Close())
+			require.NoError(t, err)
+			require.Equal(t, "png-bytes", string(data))
+			require.EqualValues(t, 9, obj.Size)
+
+			require.NoError(t, store.Delete(ctx, key))
+			require.NoError(t, store.Delete(ctx, key))
+			_, _, err = store.Get(ctx, key)
+			require.ErrorIs(t, err, blob.ErrNotFound)
+
+			require.Error(t, store.Put(ctx, "../escape", strings.NewReader("x"), 1, ""))
+		})
+	}
+}
+
+func TestS3Store_RejectsWrongCredentials(t *testing.T) {
+	minio := blobtest.NewS3Server("avatars")
+	t.Cleanup(minio.Close)
+	cfg := minio.Config("avatars")
+	cfg.SecretKey = "wrong"
+	store, err := blob.NewS3Store(cfg, nil)
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "k", strings.NewReader("x"), 1, "")
+	require.ErrorContains(t, err, "SignatureDoesNotMatch")
+	require.Zero(t, minio.Len())
+}
@@ -0,0 +1,125 @@
+// Package blobtest — заглушка S3-совместимого сервера (в духе MinIO) для тестов.
+package blobtest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/blob"
+)
+
+const (
+	AccessKey = "minio-test"
+	SecretKey = "minio-test-secret"
+	Region    = "us-east-1"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+// S3Server хранит объекты в памяти и проверяет подпись SigV4 каждого запроса.
+// Поддерживаются PUT, GET и DELETE объектов с адресацией path-style.
+type S3Server struct {
+	server  *httptest.Server
+	buckets map[string]struct{}
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+// NewS3Server запускает заглушку с указанными бакетами.
+func NewS3Server(buckets ...string) *S3Server {
+	s := &S3Server{buckets: map[string]struct{}{}, objects: map[string]object{}}
+	for _, b := range buckets {
+		s.buckets[b] = struct{}{}
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *S3Server) URL() string { return s.server.URL }
+
+func (s *S3Server) Close() { s.server.Close() }
+
+// Config возвращает настройки клиента для бакета этой заглушки.
+func (s *S3Server) Config(bucket string) blob.S3Config {
+	return blob.S3Config{Endpoint: s.server.URL, Region: Region, Bucket: bucket, AccessKey: AccessKey, SecretKey: SecretKey}
+}
+
+// Len возвращает число хранимых объектов.
+func (s *S3Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+func (s *S3Server) handle(w http.ResponseWriter, r *http.Request) {
+	if err := blob.VerifyV4(r, AccessKey, SecretKey, Region, "s3"); err != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if _, ok := s.buckets[bucket]; !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", bucket)
+		return
+	}
+	if key == "" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "bucket operations are not supported")
+		return
+	}
+	id := bucket + "/" + key
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		if blob.PayloadHash(data) != r.Header.Get("X-Amz-Content-Sha256") {
+			writeError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash mismatch")
+			return
+		}
+		s.mu.Lock()
+		s.objects[id] = object{data: data, contentType: r.Header.Get("Content-Type"), modified: time.Now().UTC()}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		s.mu.Lock()
+		obj, ok := s.objects[id]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", key)
+			return
+		}
+		if obj.contentType != "" {
+			w.Header().Set("Content-Type", obj.contentType)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modified.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(obj.data)
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, id)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?><Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
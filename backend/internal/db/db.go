@@ -39,16 +39,58 @@ func Connect() *gorm.DB {
 		log.Fatalf("DB connect error: %v", err)
 	}
 
+	// Колонка email_verified_at появляется впервые — существующие аккаунты
+	// считаем подтверждёнными, чтобы не заблокировать их политикой верификации.
+	backfillVerified := db.Migrator().HasTable(&models.User{}) &&
+		!db.Migrator().HasColumn(&models.User{}, "email_verified_at")
+
+	// completed_at появляется впервые — для уже завершённых задач берём время
+	// последнего изменения, это лучшая доступная оценка.
+	backfillCompleted := db.Migrator().HasTable(&models.Task{}) &&
+		!db.Migrator().HasColumn(&models.Task{}, "completed_at")
+
 	// миграции схемы
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Task{},
 		&models.Project{},
 		&models.ProjectMember{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PersonalAccessToken{},
+		&models.PasswordResetToken{},
+		&models.OutboxEmail{},
+		&models.EmailVerificationToken{},
+		&models.RecoveryCode{},
+		&models.LoginFailure{},
+		&models.OIDCIdentity{},
+		&models.OIDCAuthRequest{},
+		&models.AdminAuditEntry{},
+		&models.SecurityEvent{},
+		&models.DataExport{},
+		&models.Attachment{},
+		&models.Label{},
+		&models.TaskLabel{},
+		&models.SavedView{},
+		&models.TimeEntry{},
+		&models.Milestone{},
+		&models.ProjectTemplate{},
+		&models.ProjectTemplateShare{},
 	); err != nil {
 		log.Fatalf("AutoMigrate error: %v", err)
 	}
 	migrateDeadlineColumn(db)
+	migrateDisabledColumns(db)
+	if backfillVerified {
+		if err := db.Exec("UPDATE users SET email_verified_at = created_at WHERE email <> '' AND email_verified_at IS NULL").Error; err != nil {
+			log.Printf("failed to mark existing users as verified: %v", err)
+		}
+	}
+	if backfillCompleted {
+		if err := db.Exec("UPDATE tasks SET completed_at = updated_at WHERE status = ? AND completed_at IS NULL", models.StatusCompleted).Error; err != nil {
+			log.Printf("failed to backfill completed_at: %v", err)
+		}
+	}
 
 	// пул соединений
 	sqlDB, _ := db.DB()
@@ -59,6 +101,23 @@ func Connect() *gorm.DB {
 	return db
 }
 
+// migrateDisabledColumns переносит флаг disabled_at в статус suspended и удаляет старые колонки.
+func migrateDisabledColumns(db *gorm.DB) {
+	if db == nil || !db.Migrator().HasColumn(&models.User{}, "disabled_at") {
+		return
+	}
+	if err := db.Exec(`UPDATE users SET status = ?, suspended_at = disabled_at, status_reason = COALESCE(disabled_reason, '')
+		WHERE disabled_at IS NOT NULL`, models.AccountStatusSuspended).Error; err != nil {
+		log.Printf("failed to migrate disabled accounts: %v", err)
+		return
+	}
+	for _, column := range []string{"disabled_at", "disabled_reason"} {
+		if err := db.Migrator().DropColumn(&models.User{}, column); err != nil {
+			log.Printf("failed to drop legacy %s column: %v", column, err)
+		}
+	}
+}
+
 func migrateDeadlineColumn(db *gorm.DB) {
 	if db == nil {
 		return
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// AccessTokenHandler — управление персональными токенами доступа.
+type AccessTokenHandler struct {
+	Service  *services.AccessTokenService
+	Security *services.SecurityEventService
+}
+
+func NewAccessTokenHandler(s *services.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{Service: s}
+}
+
+// Токены создаются и отзываются только из интерактивной сессии, не другим токеном.
+func (h *AccessTokenHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth(), middleware.RequireSession())
+	{
+		api.GET("/tokens", h.ListTokens)
+		api.POST("/tokens", middleware.RequireCapability(services.CapabilityAccessTokens), h.CreateToken)
+		api.DELETE("/tokens/:id", h.RevokeToken)
+	}
+}
+
+func (h *AccessTokenHandler) ListTokens(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	tokens, err := h.Service.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// POST /api/tokens
+// Принимает: { "name": "CI", "scopes": ["tasks:read"], "expires_at": "2026-01-01T00:00:00Z" }.
+// Открытое значение токена возвращается только в этом ответе.
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload services.AccessTokenInput
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	created, err := h.Service.Create(userID, payload)
+	if err != nil {
+		if errors.Is(err, services.ErrAccessTokenLimit) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventTokenCreate, models.SecurityOutcomeSuccess, map[string]any{
+		"token_id": created.ID,
+		"name":     created.Name,
+		"scopes":   created.Scopes,
+	})
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *AccessTokenHandler) RevokeToken(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.Revoke(userID, id); err != nil {
+		if errors.Is(err, services.ErrAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventTokenRevoke, models.SecurityOutcomeSuccess, map[string]any{"token_id": id})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// AdminHandler — эндпоинты, доступные только администраторам.
+type AdminHandler struct {
+	Service  *services.AdminService
+	Security *services.SecurityEventService
+}
+
+func NewAdminHandler(s *services.AdminService, security *services.SecurityEventService) *AdminHandler {
+	return &AdminHandler{Service: s, Security: security}
+}
+
+// Админка доступна только из интерактивной сессии администратора, не по персональному токену.
+func (h *AdminHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/admin", middleware.Auth(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+	{
+		api.GET("/users", h.ListUsers)
+		api.GET("/users/:id", h.GetUser)
+		api.PATCH("/users/:id", h.UpdateUser)
+		api.POST("/users/:id/force-password-reset", h.ForcePasswordReset)
+		api.POST("/users/:id/suspend", h.SuspendUser)
+		api.POST("/users/:id/reactivate", h.ReactivateUser)
+		api.GET("/audit", h.AuditLog)
+		api.POST("/login-lockouts/unlock", h.UnlockLogin)
+		api.GET("/security-events", h.SecurityEvents)
+		api.GET("/security-events/export", h.ExportSecurityEvents)
+	}
+}
+
+// GET /api/admin/users?q=ann&limit=50&offset=0
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, offset := pageFromQuery(c)
+	list, err := h.Service.ListUsers(c.Query("q"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	user, err := h.Service.GetUser(id)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// PATCH /api/admin/users/:id
+// Принимает: { "max_projects": 100, "role": "admin", "plan": "pro" } — любое из полей.
+func (h *AdminHandler) UpdateUser(c *gin.Context) {
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var patch services.AdminUserPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	user, err := h.Service.UpdateUser(actorID, id, patch)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.ForcePasswordReset(actorID, id); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/admin/users/:id/suspend
+// Принимает: { "reason": "..." } (необязательно).
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+	user, err := h.Service.Suspend(actorID, id, payload.Reason)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	user, err := h.Service.Reactivate(actorID, id)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// GET /api/admin/audit?user_id=5&limit=50&offset=0
+func (h *AdminHandler) AuditLog(c *gin.Context) {
+	var targetID uint
+	if raw := c.Query("user_id"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		targetID = uint(n)
+	}
+	limit, offset := pageFromQuery(c)
+	entries, err := h.Service.AuditLog(targetID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// POST /api/admin/login-lockouts/unlock
+// Принимает: { "identifier": "user@example.com", "ip": "203.0.113.7" } — хотя бы одно поле.
+func (h *AdminHandler) UnlockLogin(c *gin.Context) {
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Identifier string `json:"identifier"`
+		IP         string `json:"ip"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if strings.TrimSpace(payload.Identifier) == "" && strings.TrimSpace(payload.IP) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "identifier or ip is required"})
+		return
+	}
+	removed, err := h.Service.UnlockLogin(actorID, payload.Identifier, payload.IP)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cleared_failures": removed})
+}
+
+// GET /api/admin/security-events?user_id=&type=&outcome=&ip=&from=&to=&limit=&offset=
+// from/to — RFC 3339 или YYYY-MM-DD; to не включается.
+func (h *AdminHandler) SecurityEvents(c *gin.Context) {
+	filter, ok := securityEventFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = pageFromQuery(c)
+	events, err := h.Security.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// GET /api/admin/security-events/export?format=csv|json и те же фильтры.
+// Выгружает до services.SecurityEventsExportLimit событий файлом.
+func (h *AdminHandler) ExportSecurityEvents(c *gin.Context) {
+	filter, ok := securityEventFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	events, err := h.Security.Export(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("security-events-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, events)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"id", "created_at", "user_id", "actor_id", "event_type", "outcome", "ip", "user_agent", "details"})
+	for _, e := range events {
+		details := ""
+		if len(e.Details) > 0 {
+			raw, _ := e.Details.MarshalJSON()
+			details = string(raw)
+		}
+		_ = w.Write([]string{
+			strconv.FormatUint(uint64(e.ID), 10),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			optionalID(e.UserID),
+			optionalID(e.ActorID),
+			e.EventType,
+			e.Outcome,
+			e.IP,
+			e.UserAgent,
+			details,
+		})
+	}
+	w.Flush()
+}
+
+func securityEventFilterFromQuery(c *gin.Context) (services.SecurityEventFilter, bool) {
+	filter := services.SecurityEventFilter{
+		EventType: strings.TrimSpace(c.Query("type")),
+		Outcome:   strings.TrimSpace(c.Query("outcome")),
+		IP:        strings.TrimSpace(c.Query("ip")),
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return filter, false
+		}
+		filter.UserID = uint(n)
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := c.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + bound.name + ": use RFC 3339 or YYYY-MM-DD"})
+			return filter, false
+		}
+		*bound.dst = &t
+	}
+	return filter, true
+}
+
+func parseTimeParam(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+func optionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func respondAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAdminUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAdminInvalidRole), errors.Is(err, services.ErrAdminInvalidLimit),
+		errors.Is(err, services.ErrAdminLimitAbovePlan), errors.Is(err, services.ErrUnknownPlan):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAdminSelfAction),
+		errors.Is(err, services.ErrAdminLastAdmin),
+		errors.Is(err, services.ErrAdminAlreadySuspended),
+		errors.Is(err, services.ErrAdminAlreadyActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// pageFromQuery читает limit/offset; некорректные значения заменяются значениями по умолчанию.
+func pageFromQuery(c *gin.Context) (int, int) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	return limit, offset
+}
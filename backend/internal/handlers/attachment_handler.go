@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// AttachmentHandler — вложения задач: загрузка, список, скачивание и удаление.
+type AttachmentHandler struct {
+	Service *services.AttachmentService
+}
+
+func NewAttachmentHandler(s *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{Service: s}
+}
+
+func (h *AttachmentHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth())
+	{
+		read := middleware.RequireScope(models.ScopeTasksRead)
+		write := middleware.RequireScope(models.ScopeTasksWrite)
+
+		api.GET("/tasks/:id/attachments", read, h.List)
+		api.POST("/tasks/:id/attachments", write, h.Upload)
+		api.GET("/attachments/:id", read, h.Download)
+		api.DELETE("/attachments/:id", write, h.Delete)
+	}
+}
+
+func (h *AttachmentHandler) List(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	taskID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	attachments, err := h.Service.List(userID, taskID)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, attachments)
+}
+
+// POST /api/tasks/:id/attachments — multipart-поле file. Код 201, тело — вложение.
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	taskID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if limit := h.Service.MaxFileBytes(); limit > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			respondAttachmentError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.Service.Upload(c.Request.Context(), userID, taskID, header.Filename, file, header.Size)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GET /api/attachments/:id — файл всегда отдаётся на скачивание, а не открывается
+// в браузере: тип взят из содержимого, активный контент отдаётся как текст.
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	download, err := h.Service.Open(c.Request.Context(), userID, id)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	defer download.Body.Close()
+
+	attachment := download.Attachment
+	etag := `"` + attachment.SHA256 + `"`
+	c.Header("Cache-Control", "private, max-age=0, must-revalidate")
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.DataFromReader(http.StatusOK, attachment.Size, download.ContentType, download.Body, map[string]string{
+		"Content-Disposition": disposition,
+	})
+}
+
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.Delete(c.Request.Context(), userID, id); err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondAttachmentError(c *gin.Context, err error) {
+	if respondQuotaError(c, err) {
+		return
+	}
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound), errors.Is(err, services.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.As(err, &maxBytes):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+	case errors.Is(err, services.ErrAttachmentEmpty), errors.Is(err, io.ErrUnexpectedEOF):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
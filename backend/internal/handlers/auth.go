@@ -1,18 +1,52 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/services"
 	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	Sessions      *services.SessionService
+	PasswordReset *services.PasswordResetService
+	Verification  *services.EmailVerificationService
+	TwoFactor     *services.TwoFactorService
+	Throttle      *services.LoginThrottleService
+	OIDC          *services.OIDCService // nil или без провайдера — SSO выключен
+	Users         *services.UserService
+	Security      *services.SecurityEventService
+}
+
+func (h *AuthHandler) RegisterRoutes(r *gin.Engine) {
+	// Публичные эндпоинты авторизации.
+	r.POST("/api/auth/register", h.Register)
+	r.POST("/api/auth/login", h.Login)
+	r.POST("/api/auth/login/2fa", h.LoginTwoFactor)
+	r.POST("/api/auth/reactivate", h.Reactivate)
+	r.POST("/api/auth/refresh", h.Refresh)
+	r.POST("/api/auth/password/forgot", h.ForgotPassword)
+	r.POST("/api/auth/password/reset", h.ResetPassword)
+	r.POST("/api/auth/verify-email", h.VerifyEmail)
+	r.GET("/api/auth/oidc/authorize", h.OIDCAuthorize)
+	r.POST("/api/auth/oidc/callback", h.OIDCCallback)
+
+	api := r.Group("/api/auth", middleware.Auth(), middleware.RequireSession())
+	{
+		api.POST("/logout", h.Logout)
+		api.POST("/logout-all", h.LogoutAll)
+		api.GET("/sessions", h.ListSessions)
+		api.DELETE("/sessions/:id", h.RevokeSession)
+		api.POST("/verify-email/resend", h.ResendVerification)
+	}
 }
 
 // POST /api/auth/register
@@ -28,7 +62,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	email := strings.ToLower(strings.TrimSpace(req.Email))
+	email, err := services.NormalizeEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	username := strings.ToLower(strings.TrimSpace(req.Username))
 	pass := strings.TrimSpace(req.Password)
 
@@ -83,12 +121,73 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "user registered"})
+	// Письмо с подтверждением; сбой отправки не отменяет регистрацию — можно запросить повторно.
+	verificationSent := false
+	if user.Email != "" {
+		if err := h.Verification.Send(&user); err != nil {
+			log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+		} else {
+			verificationSent = true
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":           "user registered",
+		"verification_sent": verificationSent,
+	})
 }
 
 // POST /api/auth/login
 // Принимает: { "email": "...", "username": "...", "password": "..." } — любой из идентификаторов.
 func (h *AuthHandler) Login(c *gin.Context) {
+	user, ok := h.checkCredentials(c)
+	if !ok {
+		return
+	}
+	h.completeLogin(c, user, "password", false)
+}
+
+// POST /api/auth/reactivate
+// Принимает то же, что и /api/auth/login. Возвращает аккаунт, отключённый самим
+// пользователем, и сразу выполняет вход.
+func (h *AuthHandler) Reactivate(c *gin.Context) {
+	user, ok := h.checkCredentials(c)
+	if !ok {
+		return
+	}
+	// С включённой 2FA одного пароля мало: статус меняется только после второго шага,
+	// поэтому признак восстановления уходит в токен второго шага.
+	if user.TwoFactorEnabled && user.Status == models.AccountStatusDeactivated {
+		h.completeLogin(c, user, "password", true)
+		return
+	}
+	if !h.reactivate(c, user) {
+		return
+	}
+	h.completeLogin(c, user, "password", false)
+}
+
+// reactivate возвращает деактивированный аккаунт в работу. При отказе ответ уже отправлен.
+func (h *AuthHandler) reactivate(c *gin.Context, user *models.User) bool {
+	wasDeactivated := user.Status == models.AccountStatusDeactivated
+	if err := h.Users.Reactivate(user); err != nil {
+		if errors.Is(err, services.ErrAccountSuspended) {
+			recordSecurityEvent(c, h.Security, user.ID, models.SecurityEventAccountReactivate, models.SecurityOutcomeFailure, map[string]any{"reason": err.Error()})
+			respondAccountStatus(c, err, user)
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reactivate account"})
+		return false
+	}
+	if wasDeactivated {
+		recordSecurityEvent(c, h.Security, user.ID, models.SecurityEventAccountReactivate, models.SecurityOutcomeSuccess, nil)
+	}
+	return true
+}
+
+// checkCredentials проверяет идентификатор и пароль с учётом ограничения попыток.
+// При отказе ответ уже отправлен.
+func (h *AuthHandler) checkCredentials(c *gin.Context) (*models.User, bool) {
 	var req struct {
 		Email    string `json:"email"`
 		Username string `json:"username"`
@@ -96,7 +195,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
-		return
+		return nil, false
 	}
 	ident := strings.ToLower(strings.TrimSpace(req.Email))
 	if ident == "" {
@@ -104,7 +203,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 	if ident == "" || strings.TrimSpace(req.Password) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "identifier and password are required"})
-		return
+		return nil, false
+	}
+
+	// Защита от перебора: до проверки пароля смотрим на недавние неудачи
+	ip := c.ClientIP()
+	if err := h.Throttle.Check(ident, ip); err != nil {
+		h.recordLoginEvent(c, 0, models.SecurityOutcomeFailure, map[string]any{"identifier": ident, "reason": "throttled"})
+		respondThrottled(c, err)
+		return nil, false
 	}
 
 	// Поиск по email ИЛИ username (case-insensitive — мы нормализовали в lower)
@@ -112,29 +219,430 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	q := h.DB.Where("email = ? OR username = ?", ident, ident).First(&user)
 	if q.Error != nil {
 		if q.Error == gorm.ErrRecordNotFound {
+			h.recordLoginFailure(ident, ip)
+			h.recordLoginEvent(c, 0, models.SecurityOutcomeFailure, map[string]any{"identifier": ident, "reason": "unknown_user"})
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
-			return
+			return nil, false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query user"})
-		return
+		return nil, false
 	}
 
 	// Проверка пароля
 	if err := services.CheckPassword(user.Password, req.Password); err != nil {
+		h.recordLoginFailure(ident, ip)
+		h.recordLoginEvent(c, user.ID, models.SecurityOutcomeFailure, map[string]any{"identifier": ident, "reason": "invalid_password"})
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return nil, false
+	}
+	if err := h.Throttle.RecordSuccess(ident); err != nil {
+		log.Printf("failed to reset login failures: %v", err)
+	}
+	return &user, true
+}
+
+// completeLogin — общая часть входа по паролю и через SSO: статус аккаунта,
+// политика подтверждения email и второй фактор, затем выдача сессии.
+// method — способ входа для журнала безопасности (password, oidc).
+// reactivate — вход начат восстановлением аккаунта с 2FA: деактивированный статус
+// допустим, его снимет LoginTwoFactor после проверки кода.
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User, method string, reactivate bool) {
+	if err := services.AccountStatusError(user); err != nil && !(reactivate && errors.Is(err, services.ErrAccountDeactivated)) {
+		h.recordLoginEvent(c, user.ID, models.SecurityOutcomeFailure, map[string]any{"method": method, "reason": "account_" + user.Status})
+		respondAccountStatus(c, err, user)
+		return
+	}
+
+	// Политика для неподтверждённого email
+	if err := h.Verification.Require(user, services.CapabilityLogin); err != nil {
+		h.recordLoginEvent(c, user.ID, models.SecurityOutcomeFailure, map[string]any{"method": method, "reason": "email_unverified"})
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// С включённой 2FA выдаём только токен второго шага
+	if user.TwoFactorEnabled {
+		challenge, err := services.GenerateChallengeToken(services.LoginChallenge{UserID: user.ID, Reactivate: reactivate})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int64(services.ChallengeTokenTTL().Seconds()),
+		})
+		return
+	}
+
+	h.startSession(c, user, method)
+}
+
+// POST /api/auth/login/2fa
+// Принимает: { "challenge_token": "...", "code": "123456" } или { "challenge_token": "...", "recovery_code": "..." }.
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.ChallengeToken == "" || (strings.TrimSpace(req.Code) == "" && strings.TrimSpace(req.RecoveryCode) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token and code are required"})
+		return
+	}
+
+	challenge, err := services.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	userID := challenge.UserID
+	// Коды 2FA ограничиваются так же, как пароли: ключ — пользователь из challenge.
+	throttleKey := services.TwoFactorThrottleKey(userID)
+	ip := c.ClientIP()
+	if err := h.Throttle.Check(throttleKey, ip); err != nil {
+		h.recordLoginEvent(c, userID, models.SecurityOutcomeFailure, map[string]any{"method": "two_factor", "reason": "throttled"})
+		respondThrottled(c, err)
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": services.ErrInvalidChallenge.Error()})
+		return
+	}
+	if err := h.TwoFactor.Verify(&user, req.Code, req.RecoveryCode); err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) || errors.Is(err, services.ErrTwoFactorNotEnabled) {
+			h.recordLoginFailure(throttleKey, ip)
+			h.recordLoginEvent(c, user.ID, models.SecurityOutcomeFailure, map[string]any{"method": "two_factor", "reason": "invalid_code"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": services.ErrInvalidTwoFactorCode.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+	if err := h.Throttle.RecordSuccess(throttleKey); err != nil {
+		log.Printf("failed to reset login failures: %v", err)
+	}
+	// Восстановление по паролю применяется только после верного второго фактора.
+	if challenge.Reactivate && !h.reactivate(c, &user) {
+		return
+	}
+	// Аккаунт могли заблокировать, пока пользователь вводил код.
+	if err := services.AccountStatusError(&user); err != nil {
+		h.recordLoginEvent(c, user.ID, models.SecurityOutcomeFailure, map[string]any{"method": "two_factor", "reason": "account_" + user.Status})
+		respondAccountStatus(c, err, &user)
+		return
+	}
+
+	method := "two_factor"
+	if strings.TrimSpace(req.Code) == "" {
+		method = "recovery_code"
+	}
+	h.startSession(c, &user, method)
+}
+
+// GET /api/auth/oidc/authorize
+// Возвращает ссылку на страницу входа провайдера; фронтенд переходит по ней.
+func (h *AuthHandler) OIDCAuthorize(c *gin.Context) {
+	if !h.OIDC.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrOIDCDisabled.Error()})
+		return
+	}
+	authURL, state, err := h.OIDC.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("oidc authorize: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+		return
+	}
+	// Хэш state в cookie привязывает вход к этому браузеру: чужой code+state,
+	// подсунутый жертве, callback не примет (login CSRF).
+	setOIDCStateCookie(c, services.HashOpaqueToken(state), int(services.OIDCRequestTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// POST /api/auth/oidc/callback
+// Принимает: { "code": "...", "state": "..." } — параметры, с которыми провайдер вернул пользователя.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	var req struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if !h.OIDC.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrOIDCDisabled.Error()})
+		return
+	}
+	stateHash, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	if stateHash == "" || subtle.ConstantTimeCompare([]byte(stateHash), []byte(services.HashOpaqueToken(req.State))) != 1 {
+		h.recordLoginEvent(c, 0, models.SecurityOutcomeFailure, map[string]any{"method": "oidc", "reason": "state does not match this browser"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-on failed, please try again"})
+		return
+	}
+
+	user, err := h.OIDC.Complete(c.Request.Context(), req.State, req.Code)
+	if err != nil {
+		h.recordLoginEvent(c, 0, models.SecurityOutcomeFailure, map[string]any{"method": "oidc", "reason": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrOIDCInvalidState), errors.Is(err, services.ErrOIDCProvider):
+			log.Printf("oidc callback: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-on failed, please try again"})
+		case errors.Is(err, services.ErrOIDCEmailNotVerified), errors.Is(err, services.ErrOIDCNoAccount):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrOIDCAccountUnverified):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("oidc callback: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to complete sign-on"})
+		}
 		return
 	}
 
-	// JWT
-	token, err := services.GenerateJWT(user.ID, user.Role)
+	h.completeLogin(c, user, "oidc", false)
+}
+
+// oidcStateCookie хранит хэш state начатого входа через провайдера.
+const oidcStateCookie = "oidc_state"
+
+// setOIDCStateCookie ставит (maxAge > 0) или стирает (maxAge < 0) cookie со state.
+// Cookie видна только эндпоинтам SSO и недоступна скриптам страницы.
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, "/api/auth/oidc", "", secure, true)
+}
+
+func (h *AuthHandler) recordLoginFailure(identifier, ip string) {
+	if err := h.Throttle.RecordFailure(identifier, ip); err != nil {
+		log.Printf("failed to record login failure: %v", err)
+	}
+}
+
+func (h *AuthHandler) recordLoginEvent(c *gin.Context, userID uint, outcome string, details map[string]any) {
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventLogin, outcome, details)
+}
+
+// respondAccountStatus отвечает 403 для неактивного аккаунта. Отключённому самим
+// пользователем аккаунту подсказываем, как его вернуть.
+func respondAccountStatus(c *gin.Context, err error, user *models.User) {
+	body := gin.H{"error": err.Error(), "status": user.Status}
+	if errors.Is(err, services.ErrAccountDeactivated) {
+		body["reactivate_url"] = "/api/auth/reactivate"
+	}
+	c.JSON(http.StatusForbidden, body)
+}
+
+// respondThrottled отвечает 429 с Retry-After, если попытка отклонена ограничителем.
+func respondThrottled(c *gin.Context, err error) {
+	if !respondQuotaError(c, err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check login attempts"})
+	}
+}
+
+// startSession открывает сессию и отвечает парой токенов — общий финал обоих шагов входа.
+func (h *AuthHandler) startSession(c *gin.Context, user *models.User, method string) {
+	pair, err := h.Sessions.Start(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
+	h.recordLoginEvent(c, user.ID, models.SecurityOutcomeSuccess, map[string]any{"method": method})
 
 	c.JSON(http.StatusOK, gin.H{
-		"token":  token,
-		"userID": user.ID,
-		"role":   user.Role,
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+		"userID":        user.ID,
+		"role":          user.Role,
 	})
 }
+
+// POST /api/auth/refresh
+// Принимает: { "refresh_token": "..." } — возвращает новую пару токенов, старый refresh становится недействительным.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.RefreshToken) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	pair, err := h.Sessions.Refresh(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRefreshToken),
+			errors.Is(err, services.ErrRefreshTokenReused),
+			errors.Is(err, services.ErrSessionRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAccountSuspended), errors.Is(err, services.ErrAccountDeactivated):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, pair)
+}
+
+// POST /api/auth/logout — завершает текущую сессию.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	sessionID, ok := sessionIDFromContext(c)
+	if !ok {
+		return
+	}
+	if err := h.Sessions.Revoke(userID, sessionID); err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventLogout, models.SecurityOutcomeSuccess, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/logout-all — завершает все сессии пользователя, включая текущую.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	if err := h.Sessions.RevokeAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventLogout, models.SecurityOutcomeSuccess, map[string]any{"all_sessions": true})
+	c.Status(http.StatusNoContent)
+}
+
+// GET /api/auth/sessions — активные сессии пользователя.
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	currentID, _ := c.Get("sessionID")
+	current, _ := currentID.(uint)
+	sessions, err := h.Sessions.List(userID, current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// DELETE /api/auth/sessions/:id — завершает выбранную сессию.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	sessionID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Sessions.Revoke(userID, sessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/password/forgot
+// Принимает: { "email": "..." }. Ответ одинаковый независимо от того, существует ли аккаунт.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+	if err := h.PasswordReset.RequestReset(req.Email); err != nil {
+		log.Printf("password reset request failed: %v", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset link has been sent"})
+}
+
+// POST /api/auth/password/reset
+// Принимает: { "token": "...", "new_password": "...", "confirm_password": "..." }.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token   string `json:"token"`
+		New     string `json:"new_password"`
+		Confirm string `json:"confirm_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if strings.TrimSpace(req.Token) == "" || req.New == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and new password are required"})
+		return
+	}
+	if req.Confirm != "" && req.New != req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password confirmation does not match"})
+		return
+	}
+	if err := h.PasswordReset.ResetPassword(req.Token, req.New); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/verify-email
+// Принимает: { "token": "..." } из ссылки в письме.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Token) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	if err := h.Verification.Confirm(req.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/verify-email/resend — повторно отправляет письмо текущему пользователю.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	if err := h.Verification.Resend(userID); err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrEmailAlreadyVerified), errors.Is(err, services.ErrInvalidEmail):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send verification email"})
+		}
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,533 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/mail"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/oidc"
+	"github.com/spozitivom/taskmanager/internal/oidc/oidctest"
+	"github.com/spozitivom/taskmanager/internal/services"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type authTestEnv struct {
+	router    *gin.Engine
+	db        *gorm.DB
+	auth      *AuthHandler
+	twoFactor *services.TwoFactorService
+}
+
+func newAuthTestEnv(t *testing.T) authTestEnv {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "insecure-test-secret")
+
+	dsn := fmt.Sprintf("file:auth-tests-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.Project{},
+		&models.ProjectMember{},
+		&models.Task{},
+		&models.PersonalAccessToken{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.EmailVerificationToken{},
+		&models.RecoveryCode{},
+		&models.LoginFailure{},
+		&models.OIDCIdentity{},
+		&models.OIDCAuthRequest{},
+		&models.AdminAuditEntry{},
+		&models.SecurityEvent{},
+		&models.DataExport{},
+		&models.Attachment{},
+		&models.Label{},
+		&models.TaskLabel{},
+		&models.SavedView{},
+		&models.TimeEntry{},
+		&models.Milestone{},
+		&models.ProjectTemplate{},
+		&models.ProjectTemplateShare{},
+		&models.OutboxEmail{},
+	))
+
+	users := storage.NewUserStorage(db)
+	outbox := mail.NewOutboxSender(db)
+	sessions := services.NewSessionService(storage.NewSessionStorage(db), users)
+	twoFactor := services.NewTwoFactorService(users, storage.NewRecoveryCodeStorage(db))
+	auth := &AuthHandler{
+		DB:            db,
+		Sessions:      sessions,
+		PasswordReset: services.NewPasswordResetService(storage.NewPasswordResetStorage(db), users, sessions, outbox),
+		Verification:  services.NewEmailVerificationService(storage.NewEmailVerificationStorage(db), users, outbox, services.NewVerificationPolicy(services.CapabilityLogin)),
+		TwoFactor:     twoFactor,
+		Throttle:      services.NewLoginThrottleService(storage.NewLoginFailureStorage(db), services.DefaultLoginThrottleConfig()),
+		Security:      services.NewSecurityEventService(storage.NewSecurityEventStorage(db)),
+	}
+
+	router := gin.New()
+	auth.RegisterRoutes(router)
+	return authTestEnv{router: router, db: db, auth: auth, twoFactor: twoFactor}
+}
+
+func TestAuthHandler_RegisterLoginRefresh(t *testing.T) {
+	env := newAuthTestEnv(t)
+
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/register", map[string]any{
+		"email": "new@example.com", "password": "secret1",
+	}, http.StatusCreated, nil)
+
+	var outbox []models.OutboxEmail
+	require.NoError(t, env.db.Find(&outbox).Error)
+	require.Len(t, outbox, 1)
+	require.Equal(t, "verify_email", outbox[0].Kind)
+
+	var login map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+		"email": "new@example.com", "password": "secret1",
+	}, http.StatusOK, &login)
+	require.NotEmpty(t, login["token"])
+	require.NotEmpty(t, login["refresh_token"])
+
+	var refreshed map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/refresh", map[string]any{
+		"refresh_token": login["refresh_token"],
+	}, http.StatusOK, &refreshed)
+	require.NotEqual(t, login["refresh_token"], refreshed["refresh_token"])
+
+	doAuthorizedJSON(t, env.router, refreshed["token"].(string), http.MethodPost, "/api/auth/logout", nil, http.StatusNoContent, nil)
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/refresh", map[string]any{
+		"refresh_token": refreshed["refresh_token"],
+	}, http.StatusUnauthorized, nil)
+}
+
+func TestAuthHandler_RegisterRejectsInvalidEmail(t *testing.T) {
+	env := newAuthTestEnv(t)
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/register", map[string]any{
+		"email": "not-an-email", "password": "secret1",
+	}, http.StatusBadRequest, nil)
+}
+
+func TestAuthHandler_LoginWithTwoFactor(t *testing.T) {
+	env := newAuthTestEnv(t)
+	hash, err := services.HashPassword("secret1")
+	require.NoError(t, err)
+	user := models.User{Email: "mfa@example.com", Username: "mfa", Password: hash, Role: "user"}
+	require.NoError(t, env.db.Create(&user).Error)
+
+	enrollment, err := env.twoFactor.BeginEnrollment(user.ID)
+	require.NoError(t, err)
+	code, err := services.TOTPCode(enrollment.Secret, time.Now().Add(-30*time.Second).Unix()/30)
+	require.NoError(t, err)
+	recovery, err := env.twoFactor.ConfirmEnrollment(user.ID, code)
+	require.NoError(t, err)
+
+	var first map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+		"email": "mfa@example.com", "password": "secret1",
+	}, http.StatusOK, &first)
+	require.Equal(t, true, first["two_factor_required"])
+	require.Nil(t, first["token"])
+	challenge := first["challenge_token"].(string)
+
+	// Токен второго шага не даёт доступа к защищённым маршрутам.
+	doAuthorizedJSON(t, env.router, challenge, http.MethodGet, "/api/auth/sessions", nil, http.StatusUnauthorized, nil)
+
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login/2fa", map[string]any{
+		"challenge_token": challenge, "code": "000000",
+	}, http.StatusUnauthorized, nil)
+
+	var second map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login/2fa", map[string]any{
+		"challenge_token": challenge, "recovery_code": recovery[0],
+	}, http.StatusOK, &second)
+	require.NotEmpty(t, second["token"])
+	require.NotEmpty(t, second["refresh_token"])
+}
+
+func TestAuthHandler_LoginThrottled(t *testing.T) {
+	env := newAuthTestEnv(t)
+	hash, err := services.HashPassword("secret1")
+	require.NoError(t, err)
+	require.NoError(t, env.db.Create(&models.User{Email: "victim@example.com", Username: "victim", Password: hash, Role: "user"}).Error)
+
+	for i := 0; i < 3; i++ {
+		doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+			"email": "victim@example.com", "password": "wrong",
+		}, http.StatusUnauthorized, nil)
+	}
+
+	// Даже верный пароль отклоняется, пока не истекла задержка.
+	var resp map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+		"email": "victim@example.com", "password": "secret1",
+	}, http.StatusTooManyRequests, &resp)
+	require.Equal(t, false, resp["locked"])
+	require.EqualValues(t, 1, resp["retry_after"])
+	require.Equal(t, services.QuotaLoginAttempts, resp["quota"])
+}
+
+func TestAuthHandler_VerificationResendRateQuota(t *testing.T) {
+	env := newAuthTestEnv(t)
+	user := models.User{Email: "slow@example.com", Username: "slow", Password: "x", Role: "user"}
+	require.NoError(t, env.db.Create(&user).Error)
+	pair, err := env.auth.Sessions.Start(&user, "", "")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		doAuthorizedJSON(t, env.router, pair.AccessToken, http.MethodPost, "/api/auth/verify-email/resend", nil, http.StatusAccepted, nil)
+	}
+
+	// Квота частоты — 429 с Retry-After, а не 409, как у квот объёма.
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/verify-email/resend", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	require.Equalf(t, http.StatusTooManyRequests, w.Code, "body=%s", w.Body.String())
+	require.Equal(t, "3600", w.Header().Get("Retry-After"))
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, services.QuotaVerificationEmails, resp["quota"])
+	require.EqualValues(t, 3, resp["limit"])
+	require.EqualValues(t, 3, resp["used"])
+}
+
+func TestAuthHandler_OIDCLogin(t *testing.T) {
+	env := newAuthTestEnv(t)
+	provider := oidctest.NewProvider("taskmanager", "")
+	defer provider.Close()
+	client := oidc.NewProvider(oidc.Config{
+		Issuer:      provider.Issuer(),
+		ClientID:    "taskmanager",
+		RedirectURL: "http://localhost:5173/sso/callback",
+	}, provider.Server.Client())
+	env.auth.OIDC = services.NewOIDCService(client, provider.Issuer(), storage.NewOIDCStorage(env.db), storage.NewUserStorage(env.db),
+		services.OIDCSettings{AutoProvision: true})
+
+	// authorize возвращает ссылку и ставит браузеру HttpOnly cookie с хэшем state.
+	begin := func(identity oidctest.Identity) (string, string, *http.Cookie) {
+		w := httptest.NewRecorder()
+		env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/authorize", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var start map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &start))
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.True(t, cookies[0].HttpOnly)
+		require.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		code, state, err := provider.Authorize(start["authorization_url"], identity)
+		require.NoError(t, err)
+		return code, state, cookies[0]
+	}
+	callback := func(code, state string, cookie *http.Cookie, wantStatus int, out any) {
+		raw, err := json.Marshal(map[string]string{"code": code, "state": state})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/oidc/callback", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		env.router.ServeHTTP(w, req)
+		require.Equalf(t, wantStatus, w.Code, "body=%s", w.Body.String())
+		if out != nil {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), out))
+		}
+	}
+
+	code, state, cookie := begin(oidctest.Identity{Subject: "sso-1", Email: "sso@example.com", EmailVerified: true})
+
+	// Чужой code+state (вход злоумышленника) в браузере жертвы не принимается:
+	// ни без cookie, ни с cookie другого входа.
+	attackerCode, attackerState, _ := begin(oidctest.Identity{Subject: "sso-2", Email: "attacker@example.com", EmailVerified: true})
+	callback(attackerCode, attackerState, nil, http.StatusUnauthorized, nil)
+	callback(attackerCode, attackerState, cookie, http.StatusUnauthorized, nil)
+
+	var login map[string]any
+	callback(code, state, cookie, http.StatusOK, &login)
+	require.NotEmpty(t, login["token"])
+	require.NotEmpty(t, login["refresh_token"])
+
+	// Повтор того же callback отклоняется.
+	callback(code, state, cookie, http.StatusUnauthorized, nil)
+}
+
+func TestAuthHandler_OIDCDisabled(t *testing.T) {
+	env := newAuthTestEnv(t)
+	doAuthorizedJSON(t, env.router, "", http.MethodGet, "/api/auth/oidc/authorize", nil, http.StatusNotFound, nil)
+}
+
+func TestAdminRoutes_RequireAdminRole(t *testing.T) {
+	env := newAuthTestEnv(t)
+	users := storage.NewUserStorage(env.db)
+	sessions := services.NewSessionService(storage.NewSessionStorage(env.db), users)
+	admin := NewAdminHandler(services.NewAdminService(storage.NewAdminStorage(env.db), users, storage.NewAccessTokenStorage(env.db),
+		sessions, env.auth.PasswordReset, env.auth.Throttle), env.auth.Security)
+	admin.RegisterRoutes(env.router)
+	middleware.SetSessionValidator(sessions)
+	t.Cleanup(func() { middleware.SetSessionValidator(nil) })
+
+	member := models.User{Email: "member@example.com", Username: "member", Password: "x", Role: models.RoleUser}
+	root := models.User{Email: "root@example.com", Username: "root", Password: "x", Role: models.RoleAdmin}
+	require.NoError(t, env.db.Create(&member).Error)
+	require.NoError(t, env.db.Create(&root).Error)
+
+	memberPair, err := sessions.Start(&member, "", "")
+	require.NoError(t, err)
+	doAuthorizedJSON(t, env.router, memberPair.AccessToken, http.MethodGet, "/api/admin/users", nil, http.StatusForbidden, nil)
+
+	rootPair, err := sessions.Start(&root, "", "")
+	require.NoError(t, err)
+	var list map[string]any
+	doAuthorizedJSON(t, env.router, rootPair.AccessToken, http.MethodGet, "/api/admin/users?q=member", nil, http.StatusOK, &list)
+	require.EqualValues(t, 1, list["total"])
+
+	path := fmt.Sprintf("/api/admin/users/%d/suspend", member.ID)
+	doAuthorizedJSON(t, env.router, rootPair.AccessToken, http.MethodPost, path, map[string]any{"reason": "spam"}, http.StatusOK, nil)
+	doAuthorizedJSON(t, env.router, memberPair.AccessToken, http.MethodGet, "/api/auth/sessions", nil, http.StatusUnauthorized, nil)
+}
+
+func TestAuthHandler_DeactivateAndReactivate(t *testing.T) {
+	env := newAuthTestEnv(t)
+	users := storage.NewUserStorage(env.db)
+	projects := storage.NewProjectStorage(env.db)
+	userService := services.NewUserService(env.db, users, projects, storage.NewTaskStorage(env.db))
+	env.auth.Users = userService
+	NewUserHandler(userService, env.auth.Verification).RegisterRoutes(env.router)
+	NewProjectHandler(services.NewProjectService(projects, storage.NewTaskStorage(env.db), users)).RegisterRoutes(env.router)
+	middleware.SetSessionValidator(env.auth.Sessions)
+	middleware.SetAccountStatusChecker(userService)
+	t.Cleanup(func() {
+		middleware.SetSessionValidator(nil)
+		middleware.SetAccountStatusChecker(nil)
+	})
+
+	hash, err := services.HashPassword("secret1")
+	require.NoError(t, err)
+	owner := models.User{Email: "owner@example.com", Username: "owner", Password: hash, Role: "user", MaxProjects: 5}
+	viewer := models.User{Email: "viewer@example.com", Username: "viewer", Password: hash, Role: "user"}
+	require.NoError(t, env.db.Create(&owner).Error)
+	require.NoError(t, env.db.Create(&viewer).Error)
+
+	ownerPair, err := env.auth.Sessions.Start(&owner, "", "")
+	require.NoError(t, err)
+	viewerPair, err := env.auth.Sessions.Start(&viewer, "", "")
+	require.NoError(t, err)
+
+	var project models.Project
+	doAuthorizedJSON(t, env.router, ownerPair.AccessToken, http.MethodPost, "/api/projects", map[string]any{"title": "Shared"}, http.StatusCreated, &project)
+	membersPath := fmt.Sprintf("/api/projects/%d/members", project.ID)
+	doAuthorizedJSON(t, env.router, ownerPair.AccessToken, http.MethodPost, membersPath, map[string]any{"email": "viewer@example.com"}, http.StatusCreated, nil)
+
+	doAuthorizedJSON(t, env.router, ownerPair.AccessToken, http.MethodPost, "/api/user/deactivate", map[string]any{
+		"password": "secret1", "reason": "vacation",
+	}, http.StatusNoContent, nil)
+
+	// Уже выданный токен больше не работает, вход по паролю отклоняется с подсказкой.
+	doAuthorizedJSON(t, env.router, ownerPair.AccessToken, http.MethodGet, "/api/user/profile", nil, http.StatusUnauthorized, nil)
+	var denied map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+		"email": "owner@example.com", "password": "secret1",
+	}, http.StatusForbidden, &denied)
+	require.Equal(t, models.AccountStatusDeactivated, denied["status"])
+
+	// Участник по-прежнему видит общий проект.
+	doAuthorizedJSON(t, env.router, viewerPair.AccessToken, http.MethodGet, fmt.Sprintf("/api/projects/%d", project.ID), nil, http.StatusOK, nil)
+
+	var login map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/reactivate", map[string]any{
+		"email": "owner@example.com", "password": "secret1",
+	}, http.StatusOK, &login)
+	doAuthorizedJSON(t, env.router, login["token"].(string), http.MethodGet, "/api/user/profile", nil, http.StatusOK, nil)
+}
+
+func TestAuthHandler_ReactivateRequiresSecondFactor(t *testing.T) {
+	env := newAuthTestEnv(t)
+	users := storage.NewUserStorage(env.db)
+	userService := services.NewUserService(env.db, users, storage.NewProjectStorage(env.db), storage.NewTaskStorage(env.db))
+	env.auth.Users = userService
+
+	hash, err := services.HashPassword("secret1")
+	require.NoError(t, err)
+	user := models.User{Email: "mfa@example.com", Username: "mfa", Password: hash, Role: "user"}
+	require.NoError(t, env.db.Create(&user).Error)
+	enrollment, err := env.twoFactor.BeginEnrollment(user.ID)
+	require.NoError(t, err)
+	code, err := services.TOTPCode(enrollment.Secret, time.Now().Add(-30*time.Second).Unix()/30)
+	require.NoError(t, err)
+	recovery, err := env.twoFactor.ConfirmEnrollment(user.ID, code)
+	require.NoError(t, err)
+	require.NoError(t, env.db.Model(&models.User{}).Where("id = ?", user.ID).Update("status", models.AccountStatusDeactivated).Error)
+
+	// Одного пароля мало: аккаунт остаётся деактивированным до второго шага.
+	var first map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/reactivate", map[string]any{
+		"email": "mfa@example.com", "password": "secret1",
+	}, http.StatusOK, &first)
+	require.Equal(t, true, first["two_factor_required"])
+	require.Nil(t, first["token"])
+	reloaded, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountStatusDeactivated, reloaded.Status)
+
+	// Обычный вход не даёт обойти деактивацию через второй шаг.
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+		"email": "mfa@example.com", "password": "secret1",
+	}, http.StatusForbidden, nil)
+
+	challenge := first["challenge_token"].(string)
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login/2fa", map[string]any{
+		"challenge_token": challenge, "code": "000000",
+	}, http.StatusUnauthorized, nil)
+	reloaded, err = users.GetByID(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountStatusDeactivated, reloaded.Status)
+
+	var second map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login/2fa", map[string]any{
+		"challenge_token": challenge, "recovery_code": recovery[0],
+	}, http.StatusOK, &second)
+	require.NotEmpty(t, second["token"])
+	reloaded, err = users.GetByID(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountStatusActive, reloaded.Status)
+}
+
+func TestSecurityEvents_RecordedAndQueried(t *testing.T) {
+	env := newAuthTestEnv(t)
+	users := storage.NewUserStorage(env.db)
+	userService := services.NewUserService(env.db, users, storage.NewProjectStorage(env.db), storage.NewTaskStorage(env.db))
+	userHandler := NewUserHandler(userService, env.auth.Verification)
+	userHandler.Security = env.auth.Security
+	userHandler.RegisterRoutes(env.router)
+	NewAdminHandler(services.NewAdminService(storage.NewAdminStorage(env.db), users, storage.NewAccessTokenStorage(env.db),
+		env.auth.Sessions, env.auth.PasswordReset, env.auth.Throttle), env.auth.Security).RegisterRoutes(env.router)
+	middleware.SetSessionValidator(env.auth.Sessions)
+	t.Cleanup(func() { middleware.SetSessionValidator(nil) })
+
+	hash, err := services.HashPassword("secret1")
+	require.NoError(t, err)
+	member := models.User{Email: "member@example.com", Username: "member", Password: hash, Role: models.RoleUser}
+	root := models.User{Email: "root@example.com", Username: "root", Password: hash, Role: models.RoleAdmin}
+	require.NoError(t, env.db.Create(&member).Error)
+	require.NoError(t, env.db.Create(&root).Error)
+
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+		"email": "member@example.com", "password": "wrong",
+	}, http.StatusUnauthorized, nil)
+	var login map[string]any
+	doAuthorizedJSON(t, env.router, "", http.MethodPost, "/api/auth/login", map[string]any{
+		"email": "member@example.com", "password": "secret1",
+	}, http.StatusOK, &login)
+	token := login["token"].(string)
+	doAuthorizedJSON(t, env.router, token, http.MethodPatch, "/api/user/password", map[string]any{
+		"current_password": "secret1", "new_password": "secret2", "confirm_password": "secret2",
+	}, http.StatusNoContent, nil)
+
+	// Пользователь видит свои события от новых к старым.
+	var own []models.SecurityEvent
+	doAuthorizedJSON(t, env.router, token, http.MethodGet, "/api/user/security-events", nil, http.StatusOK, &own)
+	require.Len(t, own, 3)
+	require.Equal(t, models.SecurityEventPasswordChange, own[0].EventType)
+	require.Equal(t, models.SecurityEventLogin, own[1].EventType)
+	require.Equal(t, models.SecurityOutcomeSuccess, own[1].Outcome)
+	require.Equal(t, models.SecurityOutcomeFailure, own[2].Outcome)
+	require.Equal(t, "invalid_password", own[2].Details["reason"])
+
+	// Обычному пользователю чужой журнал недоступен.
+	doAuthorizedJSON(t, env.router, token, http.MethodGet, "/api/admin/security-events", nil, http.StatusForbidden, nil)
+
+	rootPair, err := env.auth.Sessions.Start(&root, "", "")
+	require.NoError(t, err)
+	var failures []models.SecurityEvent
+	doAuthorizedJSON(t, env.router, rootPair.AccessToken, http.MethodGet, "/api/admin/security-events?type=login&outcome=failure", nil, http.StatusOK, &failures)
+	require.Len(t, failures, 1)
+	require.Equal(t, member.ID, *failures[0].UserID)
+	doAuthorizedJSON(t, env.router, rootPair.AccessToken, http.MethodGet, "/api/admin/security-events?from=yesterday", nil, http.StatusBadRequest, nil)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/admin/security-events/export?format=csv&user_id=%d", member.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+rootPair.AccessToken)
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	require.Equal(t, "event_type", rows[0][4])
+}
+
+func TestDataExport_RequestLinkAndDownload(t *testing.T) {
+	env := newAuthTestEnv(t)
+	users := storage.NewUserStorage(env.db)
+	exports := services.NewDataExportService(env.db, storage.NewDataExportStorage(env.db), users, mail.NewOutboxSender(env.db))
+	NewDataExportHandler(exports).RegisterRoutes(env.router)
+
+	user := models.User{Email: "takeout@example.com", Username: "takeout", Password: "x", Role: models.RoleUser}
+	require.NoError(t, env.db.Create(&user).Error)
+	pair, err := env.auth.Sessions.Start(&user, "", "")
+	require.NoError(t, err)
+
+	var export models.DataExport
+	doAuthorizedJSON(t, env.router, pair.AccessToken, http.MethodPost, "/api/user/exports", nil, http.StatusAccepted, &export)
+	linkPath := fmt.Sprintf("/api/user/exports/%d/link", export.ID)
+	doAuthorizedJSON(t, env.router, pair.AccessToken, http.MethodPost, linkPath, nil, http.StatusConflict, nil)
+
+	_, err = exports.ProcessPending(1)
+	require.NoError(t, err)
+	var link services.DataExportLink
+	doAuthorizedJSON(t, env.router, pair.AccessToken, http.MethodPost, linkPath, nil, http.StatusOK, &link)
+
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link.URL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, services.DataExportDownloadPath+"?token=bogus", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDataExport_LinkDiesWithAccount(t *testing.T) {
+	env := newAuthTestEnv(t)
+	users := storage.NewUserStorage(env.db)
+	exports := services.NewDataExportService(env.db, storage.NewDataExportStorage(env.db), users, mail.NewOutboxSender(env.db))
+	NewDataExportHandler(exports).RegisterRoutes(env.router)
+	NewUserHandler(services.NewUserService(env.db, users, storage.NewProjectStorage(env.db), storage.NewTaskStorage(env.db)), env.auth.Verification).RegisterRoutes(env.router)
+
+	user := models.User{Email: "leaving@example.com", Username: "leaving", Password: "x", Role: models.RoleUser}
+	require.NoError(t, env.db.Create(&user).Error)
+	pair, err := env.auth.Sessions.Start(&user, "", "")
+	require.NoError(t, err)
+
+	var export models.DataExport
+	doAuthorizedJSON(t, env.router, pair.AccessToken, http.MethodPost, "/api/user/exports", nil, http.StatusAccepted, &export)
+	_, err = exports.ProcessPending(1)
+	require.NoError(t, err)
+	var link services.DataExportLink
+	doAuthorizedJSON(t, env.router, pair.AccessToken, http.MethodPost, fmt.Sprintf("/api/user/exports/%d/link", export.ID), nil, http.StatusOK, &link)
+
+	doAuthorizedJSON(t, env.router, pair.AccessToken, http.MethodDelete, "/api/user", map[string]any{"confirm": "DELETE"}, http.StatusNoContent, nil)
+
+	// Архив удалён вместе с аккаунтом: ссылка из письма больше ничего не отдаёт.
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link.URL, nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var count int64
+	require.NoError(t, env.db.Model(&models.DataExport{}).Where("user_id = ?", user.ID).Count(&count).Error)
+	require.Zero(t, count)
+}
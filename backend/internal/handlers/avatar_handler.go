@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// AvatarHandler — загрузка аватаров и их раздача.
+type AvatarHandler struct {
+	Service  *services.AvatarService
+	Security *services.SecurityEventService
+}
+
+func NewAvatarHandler(s *services.AvatarService) *AvatarHandler {
+	return &AvatarHandler{Service: s}
+}
+
+// Раздача публичная: <img> не передаёт токен, а адрес содержит версию, которую не угадать.
+func (h *AvatarHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET(services.AvatarPathPrefix+"/:userId/:version", h.Serve)
+
+	api := r.Group("/api/user", middleware.Auth(), middleware.RequireScope(models.ScopeUserWrite))
+	{
+		api.POST("/avatar", h.Upload)
+		api.DELETE("/avatar", h.Remove)
+	}
+}
+
+// POST /api/user/avatar — multipart-поле file (JPEG, PNG или GIF).
+func (h *AvatarHandler) Upload(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxAvatarUploadBytes+1<<20)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > services.MaxAvatarUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAvatarTooLarge.Error()})
+		return
+	}
+
+	user, err := h.Service.Upload(c.Request.Context(), userID, file)
+	if err != nil {
+		respondAvatarError(c, err)
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventProfileUpdate, models.SecurityOutcomeSuccess, map[string]any{"avatar": true})
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AvatarHandler) Remove(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	user, err := h.Service.Remove(c.Request.Context(), userID)
+	if err != nil {
+		respondAvatarError(c, err)
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventProfileUpdate, models.SecurityOutcomeSuccess, map[string]any{"avatar": true})
+	c.JSON(http.StatusOK, user)
+}
+
+// GET /api/avatars/:userId/:version?size=64|128|256 — без size отдаётся исходник.
+// Содержимое версии не меняется, поэтому ответ кэшируется навсегда.
+func (h *AvatarHandler) Serve(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrAvatarNotFound.Error()})
+		return
+	}
+	size := 0
+	if raw := c.Query("size"); raw != "" {
+		if size, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid size"})
+			return
+		}
+	}
+
+	avatar, err := h.Service.Open(c.Request.Context(), uint(userID), c.Param("version"), size)
+	if err != nil {
+		respondAvatarError(c, err)
+		return
+	}
+	defer avatar.Body.Close()
+
+	etag := `"` + avatar.ETag + `"`
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.DataFromReader(http.StatusOK, avatar.Size, avatar.ContentType, avatar.Body, nil)
+}
+
+func respondAvatarError(c *gin.Context, err error) {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrAvatarNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAvatarTooLarge), errors.As(err, &maxBytes):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAvatarTooLarge.Error()})
+	case errors.Is(err, services.ErrAvatarInvalid), errors.Is(err, io.ErrUnexpectedEOF):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
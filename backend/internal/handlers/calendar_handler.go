@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// CalendarHandler — задачи видимого диапазона календаря.
+type CalendarHandler struct {
+	Service *services.CalendarService
+}
+
+func NewCalendarHandler(s *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{Service: s}
+}
+
+func (h *CalendarHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth())
+	{
+		api.GET("/calendar", middleware.RequireScope(models.ScopeTasksRead), h.Range)
+	}
+}
+
+// GET /api/calendar?from=2025-06-01&to=2025-06-30&project_id=5|none&status=todo,in_progress
+// Задачи, пересекающиеся с диапазоном: all_day — на весь день, timed — со временем.
+func (h *CalendarHandler) Range(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	query := services.CalendarQuery{From: c.Query("from"), To: c.Query("to")}
+	if raw := c.Query("project_id"); raw != "" {
+		var projectID uint
+		if raw != "none" {
+			parsed, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil || parsed == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
+				return
+			}
+			projectID = uint(parsed)
+		}
+		query.ProjectID = &projectID
+	}
+	for _, raw := range c.QueryArray("status") {
+		for _, status := range strings.Split(raw, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Statuses = append(query.Statuses, status)
+			}
+		}
+	}
+
+	result, err := h.Service.Range(userID, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrCalendarRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// Максимальный размер загружаемого архива при импорте.
+const maxImportArchiveBytes = 50 << 20
+
+// DataExportHandler — выгрузка данных аккаунта (takeout) и импорт такой выгрузки.
+type DataExportHandler struct {
+	Service  *services.DataExportService
+	Security *services.SecurityEventService
+}
+
+func NewDataExportHandler(s *services.DataExportService) *DataExportHandler {
+	return &DataExportHandler{Service: s}
+}
+
+// Выгрузка и импорт доступны только из интерактивной сессии. Скачивание — по
+// временной ссылке без авторизации, чтобы её можно было открыть из письма.
+func (h *DataExportHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET(services.DataExportDownloadPath, h.Download)
+
+	api := r.Group("/api/user", middleware.Auth(), middleware.RequireSession())
+	{
+		api.POST("/exports", h.RequestExport)
+		api.GET("/exports", h.ListExports)
+		api.GET("/exports/:id", h.GetExport)
+		api.POST("/exports/:id/link", h.IssueLink)
+		api.POST("/import", h.Import)
+	}
+}
+
+// POST /api/user/exports — ставит выгрузку в очередь, отвечает 202.
+func (h *DataExportHandler) RequestExport(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	export, err := h.Service.Request(userID)
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventDataExport, models.SecurityOutcomeSuccess, map[string]any{"export_id": export.ID})
+	c.JSON(http.StatusAccepted, export)
+}
+
+func (h *DataExportHandler) ListExports(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	exports, err := h.Service.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, exports)
+}
+
+func (h *DataExportHandler) GetExport(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	export, err := h.Service.Get(userID, id)
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+// POST /api/user/exports/:id/link — новая временная ссылка на готовый архив.
+func (h *DataExportHandler) IssueLink(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	link, err := h.Service.IssueLink(userID, id)
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// GET /api/exports/download?token=...
+func (h *DataExportHandler) Download(c *gin.Context) {
+	export, err := h.Service.Download(c.Query("token"))
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+	recordSecurityEvent(c, h.Security, export.UserID, models.SecurityEventDataDownload, models.SecurityOutcomeSuccess, map[string]any{"export_id": export.ID})
+	filename := fmt.Sprintf("taskmanager-export-%s.zip", export.CompletedAt.UTC().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", export.Archive)
+}
+
+// POST /api/user/import — multipart-поле file с архивом выгрузки.
+func (h *DataExportHandler) Import(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportArchiveBytes+1<<20)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > maxImportArchiveBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "archive is too large"})
+		return
+	}
+	archive, err := io.ReadAll(io.LimitReader(file, maxImportArchiveBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	if len(archive) > maxImportArchiveBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "archive is too large"})
+		return
+	}
+
+	result, err := h.Service.Import(userID, archive)
+	if err != nil {
+		recordSecurityEvent(c, h.Security, userID, models.SecurityEventDataImport, models.SecurityOutcomeFailure, map[string]any{"reason": err.Error()})
+		respondDataExportError(c, err)
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventDataImport, models.SecurityOutcomeSuccess, map[string]any{
+		"projects": result.Projects,
+		"tasks":    result.Tasks,
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+func respondDataExportError(c *gin.Context, err error) {
+	if respondQuotaError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrExportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrExportLinkInvalid):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrExportInProgress), errors.Is(err, services.ErrExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTakeout):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
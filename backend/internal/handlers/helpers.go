@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/services"
 )
 
 func userIDFromContext(c *gin.Context) (uint, bool) {
@@ -19,3 +20,33 @@ func userIDFromContext(c *gin.Context) (uint, bool) {
 	}
 	return id, true
 }
+
+func sessionIDFromContext(c *gin.Context) (uint, bool) {
+	val, ok := c.Get("sessionID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "token is not bound to a session"})
+		return 0, false
+	}
+	id, ok := val.(uint)
+	if !ok || id == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "token is not bound to a session"})
+		return 0, false
+	}
+	return id, true
+}
+
+// recordSecurityEvent пишет событие в журнал безопасности с IP и User-Agent запроса.
+// userID = 0 — аккаунт не определён (например, вход с неизвестным email).
+func recordSecurityEvent(c *gin.Context, log *services.SecurityEventService, userID uint, eventType, outcome string, details map[string]any) {
+	event := services.SecurityEvent{
+		Type:      eventType,
+		Outcome:   outcome,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   details,
+	}
+	if userID != 0 {
+		event.UserID = &userID
+	}
+	log.Record(event)
+}
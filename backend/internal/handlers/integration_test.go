@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/blob"
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/services"
 	"github.com/spozitivom/taskmanager/internal/storage"
@@ -45,6 +48,16 @@ func TestIntegration_TaskCRUD(t *testing.T) {
 	doAuthorizedJSON(t, router, token, http.MethodPatch, "/api/tasks/"+idToStr(created.ID), updatePayload, http.StatusOK, &updated)
 	require.Equal(t, "Spec draft v2", updated.Title)
 
+	// Ошибки проверки — 400 с текстом, 404 — только для несуществующей задачи.
+	var invalid map[string]any
+	doAuthorizedJSON(t, router, token, http.MethodPatch, "/api/tasks/"+idToStr(created.ID), map[string]any{"estimate_hours": -1}, http.StatusBadRequest, &invalid)
+	require.Contains(t, invalid["error"], "estimate_hours")
+	doAuthorizedJSON(t, router, token, http.MethodPut, "/api/tasks/"+idToStr(created.ID), map[string]any{
+		"start_at": "2025-06-12T10:00:00Z", "end_at": "2025-06-11T10:00:00Z",
+	}, http.StatusBadRequest, &invalid)
+	require.Contains(t, invalid["error"], "end_at")
+	doAuthorizedJSON(t, router, token, http.MethodPatch, "/api/tasks/9999", updatePayload, http.StatusNotFound, nil)
+
 	// Delete
 	doAuthorizedJSON(t, router, token, http.MethodDelete, "/api/tasks/"+idToStr(created.ID), nil, http.StatusNoContent, nil)
 
@@ -55,6 +68,320 @@ func TestIntegration_TaskCRUD(t *testing.T) {
 	_ = db // keep for symmetry; sqlite is in-memory and closes with router
 }
 
+func TestIntegration_TaskAttachments(t *testing.T) {
+	router, _ := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+
+	var task models.Task
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Contract"}, http.StatusCreated, &task)
+	taskPath := "/api/tasks/" + idToStr(task.ID) + "/attachments"
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "договор.pdf")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("%PDF-1.4 contract"))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, taskPath, &body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var attachment models.Attachment
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &attachment))
+	require.Equal(t, "application/pdf", attachment.ContentType)
+
+	var list []models.Attachment
+	doAuthorizedJSON(t, router, token, http.MethodGet, taskPath, nil, http.StatusOK, &list)
+	require.Len(t, list, 1)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/attachments/"+idToStr(attachment.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "%PDF-1.4 contract", rec.Body.String())
+	require.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+	require.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	require.Contains(t, rec.Header().Get("Content-Disposition"), "attachment; filename*=utf-8''")
+
+	doAuthorizedJSON(t, router, token, http.MethodDelete, "/api/attachments/"+idToStr(attachment.ID), nil, http.StatusNoContent, nil)
+	doAuthorizedJSON(t, router, token, http.MethodGet, taskPath, nil, http.StatusOK, &list)
+	require.Empty(t, list)
+}
+
+func TestIntegration_TaskLabels(t *testing.T) {
+	router, _ := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+
+	var first, second models.Task
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "First"}, http.StatusCreated, &first)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Second"}, http.StatusCreated, &second)
+
+	var bug, ux models.Label
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/labels", map[string]any{"name": "bug", "color": "#d73a4a"}, http.StatusCreated, &bug)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/labels", map[string]any{"name": "ux"}, http.StatusCreated, &ux)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/labels", map[string]any{"name": "BUG"}, http.StatusConflict, nil)
+
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks/bulk/labels", map[string]any{
+		"ids": []uint{first.ID, second.ID}, "label_ids": []uint{bug.ID},
+	}, http.StatusNoContent, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPut, "/api/tasks/"+idToStr(first.ID)+"/labels", map[string]any{
+		"label_ids": []uint{bug.ID, ux.ID},
+	}, http.StatusNoContent, nil)
+
+	var tasks []models.Task
+	query := fmt.Sprintf("/api/tasks?label=%d,%d&label_mode=all", bug.ID, ux.ID)
+	doAuthorizedJSON(t, router, token, http.MethodGet, query, nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+	require.Equal(t, first.ID, tasks[0].ID)
+	require.Len(t, tasks[0].Labels, 2)
+
+	doAuthorizedJSON(t, router, token, http.MethodGet, fmt.Sprintf("/api/tasks?label=%d&label=%d", bug.ID, ux.ID), nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 2)
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/tasks?label=999", nil, http.StatusNotFound, nil)
+
+	var merged models.Label
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/labels/"+idToStr(ux.ID)+"/merge", map[string]any{"into_id": bug.ID}, http.StatusOK, &merged)
+	require.EqualValues(t, 2, merged.TasksCount)
+	doAuthorizedJSON(t, router, token, http.MethodDelete, "/api/labels/"+idToStr(bug.ID), nil, http.StatusNoContent, nil)
+	var unlabeled []models.Task
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/tasks", nil, http.StatusOK, &unlabeled)
+	require.Len(t, unlabeled, 2)
+	require.Empty(t, unlabeled[0].Labels)
+	require.Empty(t, unlabeled[1].Labels)
+}
+
+func TestIntegration_TaskQuery(t *testing.T) {
+	router, _ := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Urgent", "priority": "high"}, http.StatusCreated, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Dropped", "priority": "high", "status": "cancelled"}, http.StatusCreated, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Later", "priority": "low"}, http.StatusCreated, nil)
+
+	var tasks []models.Task
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/tasks?query="+url.QueryEscape("(priority:high OR is:overdue) -status:cancelled"), nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+	require.Equal(t, "Urgent", tasks[0].Title)
+
+	var syntaxErr struct {
+		Error  string `json:"error"`
+		Column int    `json:"column"`
+	}
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/tasks?query="+url.QueryEscape("priority:high AND (status:todo"), nil, http.StatusBadRequest, &syntaxErr)
+	require.Equal(t, 31, syntaxErr.Column)
+	require.Contains(t, syntaxErr.Error, "expected )")
+}
+
+func TestIntegration_SavedViews(t *testing.T) {
+	router, _ := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Done", "status": "completed"}, http.StatusCreated, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Open"}, http.StatusCreated, nil)
+
+	var view models.SavedView
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/views", map[string]any{
+		"name": "Готово", "filter": map[string]any{"status": "completed", "sort": "asc"},
+	}, http.StatusCreated, &view)
+	require.EqualValues(t, 1, view.TasksCount)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/views", map[string]any{
+		"name": "Bad", "filter": map[string]any{"priority": "urgent"},
+	}, http.StatusBadRequest, nil)
+
+	var tasks []models.Task
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/views/"+idToStr(view.ID)+"/tasks", nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+	require.Equal(t, "Done", tasks[0].Title)
+
+	var views []models.SavedView
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/views", nil, http.StatusOK, &views)
+	require.Len(t, views, 1)
+	doAuthorizedJSON(t, router, mustJWT(t, 2, "user"), http.MethodGet, "/api/views/"+idToStr(view.ID), nil, http.StatusNotFound, nil)
+	doAuthorizedJSON(t, router, token, http.MethodDelete, "/api/views/"+idToStr(view.ID), nil, http.StatusNoContent, nil)
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/views/"+idToStr(view.ID)+"/tasks", nil, http.StatusNotFound, nil)
+}
+
+func TestIntegration_TimeTracking(t *testing.T) {
+	router, _ := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+
+	var task models.Task
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Отчёт"}, http.StatusCreated, &task)
+
+	var timer models.TimeEntry
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/time/timer/start", map[string]any{"task_id": task.ID}, http.StatusCreated, &timer)
+	var conflict struct {
+		Timer models.TimeEntry `json:"timer"`
+	}
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/time/timer/start", map[string]any{"task_id": task.ID}, http.StatusConflict, &conflict)
+	require.Equal(t, timer.ID, conflict.Timer.ID)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/time/timer/stop", nil, http.StatusOK, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/time/timer/stop", nil, http.StatusNotFound, nil)
+
+	day := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Hour)
+	var entry models.TimeEntry
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/time/entries", map[string]any{
+		"task_id": task.ID, "started_at": day, "ended_at": day.Add(90 * time.Minute),
+	}, http.StatusCreated, &entry)
+	require.EqualValues(t, 5400, entry.DurationSeconds)
+	doAuthorizedJSON(t, router, token, http.MethodPatch, "/api/time/entries/"+idToStr(entry.ID), map[string]any{"ended_at": day.Add(-time.Hour)}, http.StatusBadRequest, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/time/report?group_by=task&format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	require.Contains(t, w.Body.String(), "task,name,hours,seconds,entries\n")
+	require.Contains(t, w.Body.String(), idToStr(task.ID)+",Отчёт,1.50,5400,")
+
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/time/report?group_by=week", nil, http.StatusBadRequest, nil)
+	doAuthorizedJSON(t, router, mustJWT(t, 2, "user"), http.MethodDelete, "/api/time/entries/"+idToStr(entry.ID), nil, http.StatusNotFound, nil)
+	doAuthorizedJSON(t, router, token, http.MethodDelete, "/api/time/entries/"+idToStr(entry.ID), nil, http.StatusNoContent, nil)
+}
+
+func TestIntegration_Milestones(t *testing.T) {
+	router, db := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+	project := models.Project{OwnerID: 1, Title: "Site", TasksLimit: 10}
+	require.NoError(t, db.Create(&project).Error)
+
+	var task models.Task
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Меню", "project_id": project.ID}, http.StatusCreated, &task)
+
+	path := "/api/projects/" + idToStr(project.ID) + "/milestones"
+	doAuthorizedJSON(t, router, token, http.MethodPost, path, map[string]any{"name": "Sprint", "start_date": "03.03.2025"}, http.StatusBadRequest, nil)
+	var sprint models.Milestone
+	doAuthorizedJSON(t, router, token, http.MethodPost, path, map[string]any{
+		"name": "Sprint 1", "goal": "MVP", "start_date": "2025-03-03", "end_date": "2025-03-16",
+	}, http.StatusCreated, &sprint)
+	doAuthorizedJSON(t, router, mustJWT(t, 2, "user"), http.MethodGet, path, nil, http.StatusNotFound, nil)
+
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks/bulk/milestone", map[string]any{"ids": []uint{task.ID}, "milestone_id": sprint.ID}, http.StatusNoContent, nil)
+	var tasks []models.Task
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/tasks?milestone_id="+idToStr(sprint.ID), nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+
+	var burndown models.MilestoneBurndown
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/milestones/"+idToStr(sprint.ID)+"/burndown", nil, http.StatusOK, &burndown)
+	require.Len(t, burndown.Days, 14)
+	require.EqualValues(t, 1, *burndown.Days[13].RemainingTasks)
+
+	var closed services.MilestoneCloseResult
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/milestones/"+idToStr(sprint.ID)+"/close", nil, http.StatusOK, &closed)
+	require.EqualValues(t, 1, closed.Moved)
+	require.Nil(t, closed.TargetID)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/milestones/"+idToStr(sprint.ID)+"/close", nil, http.StatusConflict, nil)
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/tasks?milestone_id=none&project_id="+idToStr(project.ID), nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+}
+
+func TestIntegration_ProjectTemplates(t *testing.T) {
+	router, db := setupTaskRouter(t)
+	require.NoError(t, db.Create(&models.User{ID: 2, Email: "friend@example.com", Username: "friend", Password: "hash", Role: "user", MaxProjects: 1}).Error)
+	token, friend := mustJWT(t, 1, "user"), mustJWT(t, 2, "user")
+	project := models.Project{OwnerID: 1, Title: "Site", TasksLimit: 10}
+	require.NoError(t, db.Create(&project).Error)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{
+		"title": "Макет", "project_id": project.ID, "stage": "design", "start_at": "2025-03-03T09:30:00Z", "end_at": "2025-03-04T18:00:00Z",
+	}, http.StatusCreated, nil)
+
+	var template models.ProjectTemplate
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/projects/"+idToStr(project.ID)+"/template", map[string]any{"name": "Лендинг"}, http.StatusCreated, &template)
+	require.Len(t, template.Tasks.Data(), 1)
+	require.Equal(t, 1, *template.Tasks.Data()[0].EndOffsetDays)
+
+	path := "/api/project-templates/" + idToStr(template.ID)
+	doAuthorizedJSON(t, router, friend, http.MethodGet, path, nil, http.StatusNotFound, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPost, path+"/shares", map[string]any{"email": "friend@example.com"}, http.StatusCreated, nil)
+	doAuthorizedJSON(t, router, friend, http.MethodDelete, path, nil, http.StatusForbidden, nil)
+
+	var created models.Project
+	doAuthorizedJSON(t, router, friend, http.MethodPost, path+"/instantiate", map[string]any{"start_date": "2025-06-02"}, http.StatusCreated, &created)
+	require.EqualValues(t, 2, created.OwnerID)
+	var tasks []models.Task
+	doAuthorizedJSON(t, router, friend, http.MethodGet, "/api/tasks?project_id="+idToStr(created.ID), nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+	require.Equal(t, time.Date(2025, 6, 2, 9, 30, 0, 0, time.UTC), tasks[0].StartAt.UTC())
+	require.Equal(t, "design", tasks[0].Stage)
+
+	// MaxProjects у friend — 1.
+	doAuthorizedJSON(t, router, friend, http.MethodPost, path+"/instantiate", nil, http.StatusConflict, nil)
+}
+
+func TestIntegration_ProjectDuplicate(t *testing.T) {
+	router, db := setupTaskRouter(t)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", 1).Update("max_projects", 2).Error)
+	token := mustJWT(t, 1, "user")
+	project := models.Project{OwnerID: 1, Title: "Site", TasksLimit: 10}
+	require.NoError(t, db.Create(&project).Error)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Меню", "project_id": project.ID, "status": "completed"}, http.StatusCreated, nil)
+
+	path := "/api/projects/" + idToStr(project.ID) + "/duplicate"
+	doAuthorizedJSON(t, router, token, http.MethodPost, path, map[string]any{"shift_days": 10000}, http.StatusBadRequest, nil)
+	var copied models.Project
+	doAuthorizedJSON(t, router, token, http.MethodPost, path, map[string]any{"reset_statuses": true}, http.StatusCreated, &copied)
+	require.Equal(t, "Site (copy)", copied.Title)
+	var tasks []models.Task
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/tasks?project_id="+idToStr(copied.ID), nil, http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+	require.Equal(t, models.StatusTodo, tasks[0].Status)
+
+	doAuthorizedJSON(t, router, token, http.MethodPost, path, nil, http.StatusConflict, nil)
+	doAuthorizedJSON(t, router, mustJWT(t, 2, "user"), http.MethodPost, path, nil, http.StatusNotFound, nil)
+}
+
+func TestIntegration_Calendar(t *testing.T) {
+	router, _ := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Созвон", "start_at": "2025-06-10T14:00:00Z", "end_at": "2025-06-10T15:00:00Z"}, http.StatusCreated, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Выходной", "start_at": "2025-06-12T00:00:00Z", "all_day": true}, http.StatusCreated, nil)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Июль", "start_at": "2025-07-02T10:00:00Z"}, http.StatusCreated, nil)
+
+	var result services.CalendarRange
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/calendar?from=2025-06-01&to=2025-06-30", nil, http.StatusOK, &result)
+	require.Len(t, result.Timed, 1)
+	require.Equal(t, "Созвон", result.Timed[0].Title)
+	require.Len(t, result.AllDay, 1)
+	require.True(t, result.AllDay[0].AllDay)
+
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/calendar?from=2025-06-01&to=2025-06-30&status=completed", nil, http.StatusOK, &result)
+	require.Empty(t, result.Timed)
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/calendar?from=2025-06-01", nil, http.StatusBadRequest, nil)
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/calendar?from=2025-06-01&to=2025-06-30&project_id=42", nil, http.StatusNotFound, nil)
+}
+
+func TestIntegration_Quotas(t *testing.T) {
+	router, db := setupTaskRouter(t)
+	token := mustJWT(t, 1, "user")
+	project := models.Project{OwnerID: 1, Title: "Site", TasksLimit: 1}
+	require.NoError(t, db.Create(&project).Error)
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Меню", "project_id": project.ID}, http.StatusCreated, nil)
+
+	var quota struct {
+		Quota string `json:"quota"`
+		Limit int64  `json:"limit"`
+		Used  int64  `json:"used"`
+	}
+	doAuthorizedJSON(t, router, token, http.MethodPost, "/api/tasks", map[string]any{"title": "Подвал", "project_id": project.ID}, http.StatusConflict, &quota)
+	require.Equal(t, services.QuotaProjectTasks, quota.Quota)
+	require.EqualValues(t, 1, quota.Limit)
+	require.EqualValues(t, 1, quota.Used)
+
+	var usage services.UsageReport
+	doAuthorizedJSON(t, router, token, http.MethodGet, "/api/user/usage", nil, http.StatusOK, &usage)
+	require.Equal(t, models.PlanFree, usage.Plan)
+	require.EqualValues(t, 1, usage.Projects.Used)
+	require.EqualValues(t, 1, usage.Tasks.Used)
+	require.Len(t, usage.ByProject, 1)
+	require.Equal(t, services.QuotaUsage{Used: 1, Limit: 1}, usage.ByProject[0].Tasks)
+}
+
 func TestIntegration_ProjectCRUD(t *testing.T) {
 	handler, deps := newProjectHandlerTestEnv(t)
 
@@ -133,7 +460,7 @@ func setupTaskRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 	dsn := fmt.Sprintf("file:integration-tests-%d?mode=memory&cache=shared", time.Now().UnixNano())
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{}))
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Project{}, &models.ProjectMember{}, &models.Task{}, &models.Attachment{}, &models.Label{}, &models.TaskLabel{}, &models.SavedView{}, &models.TimeEntry{}, &models.Milestone{}, &models.ProjectTemplate{}, &models.ProjectTemplateShare{}))
 	require.NoError(t, db.Create(&models.User{
 		ID:       1,
 		Email:    "user@example.com",
@@ -146,14 +473,28 @@ func setupTaskRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 	projectStorage := storage.NewProjectStorage(db)
 	userStorage := storage.NewUserStorage(db)
 
-	taskService := services.NewTaskService(taskStorage)
+	taskService := services.NewTaskService(taskStorage, projectStorage, userStorage)
 	projectService := services.NewProjectService(projectStorage, taskStorage, userStorage)
 
+	labelService := services.NewLabelService(storage.NewLabelStorage(db), taskStorage, projectStorage)
 	taskHandler := NewTaskHandler(taskService, projectService)
+	taskHandler.Labels = labelService
 	projectHandler := NewProjectHandler(projectService)
+	store, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	attachmentService := services.NewAttachmentService(storage.NewAttachmentStorage(db), taskStorage, projectStorage, userStorage, store, services.AttachmentQuotas{MaxFileBytes: 1 << 20})
+	attachmentHandler := NewAttachmentHandler(attachmentService)
 
 	router := gin.New()
 	taskHandler.RegisterRoutes(router)
+	attachmentHandler.RegisterRoutes(router)
+	NewLabelHandler(labelService).RegisterRoutes(router)
+	NewSavedViewHandler(services.NewSavedViewService(storage.NewSavedViewStorage(db), taskStorage, projectStorage, userStorage, labelService)).RegisterRoutes(router)
+	NewTimeEntryHandler(services.NewTimeEntryService(storage.NewTimeEntryStorage(db), taskStorage, projectStorage)).RegisterRoutes(router)
+	NewMilestoneHandler(services.NewMilestoneService(storage.NewMilestoneStorage(db), taskStorage, projectStorage, storage.NewUserStorage(db))).RegisterRoutes(router)
+	NewProjectTemplateHandler(services.NewProjectTemplateService(storage.NewProjectTemplateStorage(db), projectStorage, taskStorage, userStorage)).RegisterRoutes(router)
+	NewCalendarHandler(services.NewCalendarService(taskStorage, projectStorage, userStorage)).RegisterRoutes(router)
+	NewQuotaHandler(services.NewQuotaService(projectStorage, taskStorage, userStorage, storage.NewAttachmentStorage(db), services.AttachmentQuotas{})).RegisterRoutes(router)
 	projectHandler.RegisterRoutes(router)
 	return router, db
 }
@@ -163,7 +504,7 @@ func newProjectHandlerTestEnv(t *testing.T) (*ProjectHandler, handlerTestDeps) {
 	dsn := fmt.Sprintf("file:project-handler-%d?mode=memory&cache=shared", time.Now().UnixNano())
 	dbConn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
-	require.NoError(t, dbConn.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{}))
+	require.NoError(t, dbConn.AutoMigrate(&models.User{}, &models.Project{}, &models.ProjectMember{}, &models.Task{}, &models.Attachment{}, &models.Label{}, &models.TaskLabel{}, &models.SavedView{}, &models.TimeEntry{}, &models.Milestone{}, &models.ProjectTemplate{}, &models.ProjectTemplateShare{}))
 	require.NoError(t, dbConn.Create(&models.User{
 		ID:          1,
 		Email:       "owner@example.com",
@@ -197,7 +538,9 @@ func doAuthorizedJSON(t *testing.T, router *gin.Engine, token, method, path stri
 		body = bytes.NewReader(nil)
 	}
 	req := httptest.NewRequest(method, path, body)
-	req.Header.Set("Authorization", "Bearer "+token)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	req.Header.Set("Content-Type", "application/json")
 	if payload != nil {
 		req.ContentLength = int64(body.Len())
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// LabelHandler — управление метками и их установка на задачи.
+type LabelHandler struct {
+	Service *services.LabelService
+}
+
+func NewLabelHandler(s *services.LabelService) *LabelHandler {
+	return &LabelHandler{Service: s}
+}
+
+func (h *LabelHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth())
+	{
+		read := middleware.RequireScope(models.ScopeTasksRead)
+		write := middleware.RequireScope(models.ScopeTasksWrite)
+
+		api.GET("/labels", read, h.List)
+		api.POST("/labels", write, h.Create)
+		api.PATCH("/labels/:id", write, h.Update)
+		api.DELETE("/labels/:id", write, h.Delete)
+		api.POST("/labels/:id/merge", write, h.Merge)
+		api.PUT("/tasks/:id/labels", write, h.SetTaskLabels)
+		api.POST("/tasks/bulk/labels", write, h.BulkSet)
+	}
+}
+
+type labelMergePayload struct {
+	IntoID uint `json:"into_id"`
+}
+
+type taskLabelsPayload struct {
+	LabelIDs []uint `json:"label_ids"`
+}
+
+type bulkLabelsPayload struct {
+	IDs      []uint `json:"ids"`
+	LabelIDs []uint `json:"label_ids"`
+	Mode     string `json:"mode"`
+}
+
+// GET /api/labels?project_id= — личные метки и метки доступных проектов.
+func (h *LabelHandler) List(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var projectID *uint
+	if raw := c.Query("project_id"); raw != "" {
+		pid, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
+			return
+		}
+		parsed := uint(pid)
+		projectID = &parsed
+	}
+	labels, err := h.Service.List(userID, projectID)
+	if err != nil {
+		respondLabelError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, labels)
+}
+
+func (h *LabelHandler) Create(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var input services.LabelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	label, err := h.Service.Create(userID, input)
+	if err != nil {
+		respondLabelError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, label)
+}
+
+// PATCH /api/labels/:id — переименование и смена цвета.
+func (h *LabelHandler) Update(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var patch services.LabelPatch
+	if err := c.ShouldBindJSON(&patch); err != nil || (patch.Name == nil && patch.Color == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name or color is required"})
+		return
+	}
+	label, err := h.Service.Update(userID, id, patch)
+	if err != nil {
+		respondLabelError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, label)
+}
+
+func (h *LabelHandler) Delete(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.Delete(userID, id); err != nil {
+		respondLabelError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/labels/:id/merge {"into_id": 5} — задачи метки :id переходят к into_id,
+// сама метка удаляется. Ответ — итоговая метка.
+func (h *LabelHandler) Merge(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var payload labelMergePayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.IntoID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "into_id is required"})
+		return
+	}
+	label, err := h.Service.Merge(userID, id, payload.IntoID)
+	if err != nil {
+		respondLabelError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, label)
+}
+
+// PUT /api/tasks/:id/labels {"label_ids": [...]} — заменяет метки задачи.
+func (h *LabelHandler) SetTaskLabels(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var payload taskLabelsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if err := h.Service.SetOnTasks(userID, []uint{id}, payload.LabelIDs, services.LabelsReplace); err != nil {
+		respondLabelError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/tasks/bulk/labels {"ids": [...], "label_ids": [...], "mode": "add|remove|replace"}
+func (h *LabelHandler) BulkSet(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload bulkLabelsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids are required"})
+		return
+	}
+	if len(payload.LabelIDs) == 0 && payload.Mode != services.LabelsReplace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "label_ids are required"})
+		return
+	}
+	if err := h.Service.SetOnTasks(userID, payload.IDs, payload.LabelIDs, payload.Mode); err != nil {
+		respondLabelError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondLabelError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrLabelNotFound), errors.Is(err, services.ErrTaskNotFound), errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLabelForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLabelExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLabelInvalid), errors.Is(err, services.ErrLabelScope),
+		errors.Is(err, services.ErrLabelMerge), errors.Is(err, services.ErrLabelMode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// MilestoneHandler — спринты проектов, перенос задач и burndown.
+type MilestoneHandler struct {
+	Service *services.MilestoneService
+}
+
+func NewMilestoneHandler(s *services.MilestoneService) *MilestoneHandler {
+	return &MilestoneHandler{Service: s}
+}
+
+func (h *MilestoneHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth())
+	{
+		read := middleware.RequireScope(models.ScopeProjectsRead)
+		write := middleware.RequireScope(models.ScopeProjectsWrite)
+
+		api.GET("/projects/:id/milestones", read, h.List)
+		api.POST("/projects/:id/milestones", write, h.Create)
+		api.GET("/milestones/:id", read, h.Get)
+		api.PATCH("/milestones/:id", write, h.Update)
+		api.DELETE("/milestones/:id", write, h.Delete)
+		api.POST("/milestones/:id/close", write, h.Close)
+		api.GET("/milestones/:id/burndown", read, h.Burndown)
+		api.POST("/tasks/bulk/milestone", write, middleware.RequireScope(models.ScopeTasksWrite), h.AssignTasks)
+	}
+}
+
+type bulkMilestonePayload struct {
+	IDs         []uint `json:"ids"`
+	MilestoneID *uint  `json:"milestone_id"`
+}
+
+// GET /api/projects/:id/milestones — спринты проекта по дате начала.
+func (h *MilestoneHandler) List(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	milestones, err := h.Service.List(userID, projectID)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestones)
+}
+
+// POST /api/projects/:id/milestones {"name", "goal", "start_date": "2025-03-03", "end_date": "2025-03-16"}
+func (h *MilestoneHandler) Create(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.MilestoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	milestone, err := h.Service.Create(userID, projectID, input)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, milestone)
+}
+
+func (h *MilestoneHandler) Get(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	milestone, err := h.Service.Get(userID, id)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestone)
+}
+
+func (h *MilestoneHandler) Update(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var patch services.MilestonePatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if patch.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "empty payload"})
+		return
+	}
+	milestone, err := h.Service.Update(userID, id, patch)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestone)
+}
+
+// DELETE /api/milestones/:id — задачи спринта возвращаются в бэклог.
+func (h *MilestoneHandler) Delete(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.Delete(userID, id); err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/milestones/:id/close {"target_id": 5} | {"backlog": true} | {}
+// Пустое тело — незавершённые задачи уходят в следующий открытый спринт, а если его нет — в бэклог.
+func (h *MilestoneHandler) Close(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.MilestoneCloseInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+	result, err := h.Service.Close(userID, id, input)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /api/milestones/:id/burndown — остаток задач и story points по дням спринта.
+func (h *MilestoneHandler) Burndown(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	burndown, err := h.Service.Burndown(userID, id)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, burndown)
+}
+
+// POST /api/tasks/bulk/milestone {"ids": [...], "milestone_id": 5} — milestone_id null убирает задачи в бэклог.
+func (h *MilestoneHandler) AssignTasks(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload bulkMilestonePayload
+	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids are required"})
+		return
+	}
+	if err := h.Service.AssignTasks(userID, payload.MilestoneID, payload.IDs); err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondMilestoneError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMilestoneNotFound), errors.Is(err, services.ErrProjectNotFound), errors.Is(err, services.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMilestoneForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMilestoneClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMilestoneInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -22,15 +23,25 @@ func NewProjectHandler(s *services.ProjectService) *ProjectHandler {
 func (h *ProjectHandler) RegisterRoutes(r *gin.Engine) {
 	api := r.Group("/api", middleware.Auth())
 	{
-		api.GET("/projects", h.ListProjects)
-		api.POST("/projects", h.CreateProject)
-		api.GET("/projects/:id", h.GetProject)
-		api.PATCH("/projects/:id", h.UpdateProject)
-		api.POST("/projects/:id/archive", h.ArchiveProject)
-		api.POST("/projects/:id/restore", h.RestoreProject)
-		api.POST("/projects/:id/toggle-completed", h.ToggleCompleted)
-		api.DELETE("/projects/:id", h.DeleteProject)
-		api.POST("/projects/from-tasks", h.CreateFromTasks)
+		read := middleware.RequireScope(models.ScopeProjectsRead)
+		write := middleware.RequireScope(models.ScopeProjectsWrite)
+		admin := middleware.RequireScope(models.ScopeProjectsAdmin)
+
+		api.GET("/projects", read, h.ListProjects)
+		create := middleware.RequireCapability(services.CapabilityCreateProjects)
+
+		api.POST("/projects", write, create, h.CreateProject)
+		api.GET("/projects/:id", read, h.GetProject)
+		api.PATCH("/projects/:id", write, h.UpdateProject)
+		api.POST("/projects/:id/archive", admin, h.ArchiveProject)
+		api.POST("/projects/:id/restore", admin, h.RestoreProject)
+		api.POST("/projects/:id/toggle-completed", write, h.ToggleCompleted)
+		api.DELETE("/projects/:id", admin, h.DeleteProject)
+		api.POST("/projects/from-tasks", write, middleware.RequireScope(models.ScopeTasksWrite), create, h.CreateFromTasks)
+		api.POST("/projects/:id/duplicate", write, middleware.RequireScope(models.ScopeTasksWrite), create, h.Duplicate)
+		api.GET("/projects/:id/members", read, h.ListMembers)
+		api.POST("/projects/:id/members", admin, h.AddMember)
+		api.DELETE("/projects/:id/members/:userId", admin, h.RemoveMember)
 	}
 }
 
@@ -60,6 +71,9 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	}
 	project, err := h.Service.Create(ownerID, &payload)
 	if err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,7 +89,7 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 	if !ok {
 		return
 	}
-	project, err := h.Service.Get(ownerID, projectID)
+	project, err := h.Service.GetAccessible(ownerID, projectID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -131,6 +145,9 @@ func (h *ProjectHandler) RestoreProject(c *gin.Context) {
 		return
 	}
 	if err := h.Service.Restore(ownerID, projectID); err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -183,12 +200,124 @@ func (h *ProjectHandler) CreateFromTasks(c *gin.Context) {
 	}
 	project, err := h.Service.CreateFromTasks(ownerID, payload)
 	if err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, project)
 }
 
+// POST /api/projects/:id/duplicate {"title", "reset_statuses", "shift_days", "include_deleted"}
+func (h *ProjectHandler) Duplicate(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseProjectID(c)
+	if !ok {
+		return
+	}
+	var opts models.ProjectDuplicateOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+	project, err := h.Service.Duplicate(userID, projectID, opts)
+	if err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) ListMembers(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseProjectID(c)
+	if !ok {
+		return
+	}
+	members, err := h.Service.ListMembers(userID, projectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// POST /api/projects/:id/members
+// Принимает: { "email": "colleague@example.com" } — участник получает доступ на чтение.
+func (h *ProjectHandler) AddMember(c *gin.Context) {
+	ownerID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseProjectID(c)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	member, err := h.Service.AddMember(ownerID, projectID, payload.Email)
+	if err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrProjectNotFound), errors.Is(err, services.ErrMemberNoUser):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, member)
+}
+
+func (h *ProjectHandler) RemoveMember(c *gin.Context) {
+	ownerID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseProjectID(c)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || memberID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if err := h.Service.RemoveMember(ownerID, projectID, uint(memberID)); err != nil {
+		if errors.Is(err, services.ErrProjectNotFound) || errors.Is(err, services.ErrMemberNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 func parseProjectID(c *gin.Context) (uint, bool) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
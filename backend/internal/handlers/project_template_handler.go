@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// ProjectTemplateHandler — шаблоны проектов, их открытие другим пользователям
+// и создание проектов по шаблону.
+type ProjectTemplateHandler struct {
+	Service *services.ProjectTemplateService
+}
+
+func NewProjectTemplateHandler(s *services.ProjectTemplateService) *ProjectTemplateHandler {
+	return &ProjectTemplateHandler{Service: s}
+}
+
+func (h *ProjectTemplateHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth())
+	{
+		read := middleware.RequireScope(models.ScopeProjectsRead)
+		write := middleware.RequireScope(models.ScopeProjectsWrite)
+		create := middleware.RequireCapability(services.CapabilityCreateProjects)
+
+		api.GET("/project-templates", read, h.List)
+		api.POST("/project-templates", write, h.Create)
+		api.GET("/project-templates/:id", read, h.Get)
+		api.PUT("/project-templates/:id", write, h.Update)
+		api.DELETE("/project-templates/:id", write, h.Delete)
+		api.POST("/project-templates/:id/shares", write, h.Share)
+		api.DELETE("/project-templates/:id/shares/:userId", write, h.Unshare)
+		api.POST("/project-templates/:id/instantiate", write, create, middleware.RequireScope(models.ScopeTasksWrite), h.Instantiate)
+		api.POST("/projects/:id/template", write, h.SaveProject)
+	}
+}
+
+type templateSharePayload struct {
+	Email string `json:"email"`
+}
+
+// GET /api/project-templates — свои шаблоны и открытые мне.
+func (h *ProjectTemplateHandler) List(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	templates, err := h.Service.List(userID)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *ProjectTemplateHandler) Create(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var input services.ProjectTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	template, err := h.Service.Create(userID, input)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *ProjectTemplateHandler) Get(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	template, err := h.Service.Get(userID, id)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// PUT /api/project-templates/:id — замена шаблона целиком.
+func (h *ProjectTemplateHandler) Update(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.ProjectTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	template, err := h.Service.Update(userID, id, input)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *ProjectTemplateHandler) Delete(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.Delete(userID, id); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/project-templates/:id/shares {"email": "..."}
+func (h *ProjectTemplateHandler) Share(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var payload templateSharePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	share, err := h.Service.Share(userID, id, payload.Email)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, share)
+}
+
+func (h *ProjectTemplateHandler) Unshare(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	target, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if err := h.Service.Unshare(userID, id, uint(target)); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/project-templates/:id/instantiate {"start_date": "2025-03-03", "title": "..."}
+func (h *ProjectTemplateHandler) Instantiate(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.InstantiateTemplateInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+	project, err := h.Service.Instantiate(userID, id, input)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+// POST /api/projects/:id/template {"name", "description", "start_date"} — сохранить проект шаблоном.
+func (h *ProjectTemplateHandler) SaveProject(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.SaveAsTemplateInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+	template, err := h.Service.SaveProject(userID, projectID, input)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+func respondTemplateError(c *gin.Context, err error) {
+	if respondQuotaError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound), errors.Is(err, services.ErrProjectNotFound),
+		errors.Is(err, services.ErrMemberNoUser), errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTemplateForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTemplateInvalid), errors.Is(err, services.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// QuotaHandler — тариф и потребление текущего пользователя.
+type QuotaHandler struct {
+	Service *services.QuotaService
+}
+
+func NewQuotaHandler(s *services.QuotaService) *QuotaHandler {
+	return &QuotaHandler{Service: s}
+}
+
+func (h *QuotaHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth())
+	{
+		api.GET("/user/usage", middleware.RequireScope(models.ScopeUserRead), h.Usage)
+	}
+}
+
+// GET /api/user/usage — тариф, лимиты и потребление: проекты, задачи, файлы и по каждому проекту.
+func (h *QuotaHandler) Usage(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	report, err := h.Service.Usage(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// respondQuotaError отвечает на исчерпанную квоту: 409 для квот объёма и 429 с
+// Retry-After для квот частоты (в том числе задержки входа); в теле — название
+// квоты, лимит и потребление. Возвращает false, если err — не квота.
+func respondQuotaError(c *gin.Context, err error) bool {
+	var throttled *services.ThrottleError
+	if errors.As(err, &throttled) {
+		retryAfter := setRetryAfter(c, throttled.RetryAfter)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       throttled.Error(),
+			"quota":       services.QuotaLoginAttempts,
+			"retry_after": retryAfter,
+			"locked":      throttled.Locked,
+		})
+		return true
+	}
+	var quota *services.QuotaError
+	if !errors.As(err, &quota) {
+		return false
+	}
+	body := gin.H{
+		"error": quota.Error(),
+		"quota": quota.Quota,
+		"limit": quota.Limit,
+		"used":  quota.Used,
+	}
+	if !quota.Rate() {
+		c.JSON(http.StatusConflict, body)
+		return true
+	}
+	if quota.RetryAfter > 0 {
+		body["retry_after"] = setRetryAfter(c, quota.RetryAfter)
+	}
+	c.JSON(http.StatusTooManyRequests, body)
+	return true
+}
+
+// setRetryAfter ставит заголовок Retry-After в целых секундах (с округлением вверх).
+func setRetryAfter(c *gin.Context, d time.Duration) int64 {
+	seconds := int64(math.Ceil(d.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	return seconds
+}
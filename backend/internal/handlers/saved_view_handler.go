@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// SavedViewHandler — сохранённые представления (фильтры) задач.
+type SavedViewHandler struct {
+	Service *services.SavedViewService
+}
+
+func NewSavedViewHandler(s *services.SavedViewService) *SavedViewHandler {
+	return &SavedViewHandler{Service: s}
+}
+
+func (h *SavedViewHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/views", middleware.Auth())
+	{
+		read := middleware.RequireScope(models.ScopeTasksRead)
+		write := middleware.RequireScope(models.ScopeTasksWrite)
+
+		api.GET("", read, h.List)
+		api.POST("", write, h.Create)
+		api.GET("/:id", read, h.Get)
+		api.PATCH("/:id", write, h.Update)
+		api.DELETE("/:id", write, h.Delete)
+		api.GET("/:id/tasks", read, h.Tasks)
+	}
+}
+
+// GET /api/views — свои и открытые для моих проектов представления с tasks_count.
+func (h *SavedViewHandler) List(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	views, err := h.Service.List(userID)
+	if err != nil {
+		respondSavedViewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *SavedViewHandler) Get(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	view, err := h.Service.Get(userID, id)
+	if err != nil {
+		respondSavedViewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// POST /api/views {"name": "...", "filter": {...}, "shared_project_id": 3}
+func (h *SavedViewHandler) Create(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var input services.SavedViewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	view, err := h.Service.Create(userID, input)
+	if err != nil {
+		respondSavedViewError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+func (h *SavedViewHandler) Update(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.SavedViewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if input.Name == nil && input.Filter == nil && input.SharedProjectID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "empty payload"})
+		return
+	}
+	view, err := h.Service.Update(userID, id, input)
+	if err != nil {
+		respondSavedViewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+func (h *SavedViewHandler) Delete(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.Delete(userID, id); err != nil {
+		respondSavedViewError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /api/views/:id/tasks — задачи по фильтру и сортировке представления.
+func (h *SavedViewHandler) Tasks(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.Tasks(userID, id)
+	if err != nil {
+		respondSavedViewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+func respondSavedViewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrViewNotFound), errors.Is(err, services.ErrProjectNotFound), errors.Is(err, services.ErrLabelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrViewForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrViewInvalid), errors.Is(err, services.ErrLabelPersonal):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// StatsHandler — сводная статистика по задачам для дашборда.
+type StatsHandler struct {
+	Service *services.StatsService
+}
+
+func NewStatsHandler(s *services.StatsService) *StatsHandler {
+	return &StatsHandler{Service: s}
+}
+
+func (h *StatsHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api", middleware.Auth())
+	{
+		read := middleware.RequireScope(models.ScopeTasksRead)
+
+		api.GET("/stats", read, h.Overview)
+		api.GET("/projects/:id/stats", read, middleware.RequireScope(models.ScopeProjectsRead), h.Project)
+		api.GET("/projects/:id/velocity", read, middleware.RequireScope(models.ScopeProjectsRead), h.Velocity)
+	}
+}
+
+// GET /api/stats?from=2025-01-01&to=2025-01-31 — счётчики по всем задачам,
+// активность по дням и завершённость проектов.
+func (h *StatsHandler) Overview(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	stats, err := h.Service.Overview(userID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		respondStatsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GET /api/projects/:id/stats?from=&to= — то же по задачам одного проекта.
+func (h *StatsHandler) Project(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	stats, err := h.Service.Project(userID, id, c.Query("from"), c.Query("to"))
+	if err != nil {
+		respondStatsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GET /api/projects/:id/velocity?weeks=8 — story points по неделям и прогноз
+// окончания оставшейся работы.
+func (h *StatsHandler) Velocity(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	weeks := 0
+	if raw := c.Query("weeks"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weeks must be a number"})
+			return
+		}
+		weeks = n
+	}
+	velocity, err := h.Service.Velocity(userID, id, weeks)
+	if err != nil {
+		respondStatsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, velocity)
+}
+
+func respondStatsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrStatsRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
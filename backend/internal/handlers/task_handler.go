@@ -1,19 +1,27 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spozitivom/taskmanager/internal/middleware"
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/services"
+	"github.com/spozitivom/taskmanager/internal/taskquery"
+	"gorm.io/gorm"
 )
 
 // TaskHandler — слой HTTP-обработчиков поверх бизнес-логики (TaskService)
 type TaskHandler struct {
 	Service  *services.TaskService
 	Projects *services.ProjectService
+	// Labels — необязательно: фильтр label и метки в ответе работают, если задан.
+	Labels *services.LabelService
 }
 
 // Конструктор
@@ -27,14 +35,18 @@ func NewTaskHandler(s *services.TaskService, p *services.ProjectService) *TaskHa
 func (h *TaskHandler) RegisterRoutes(r *gin.Engine) {
 	api := r.Group("/api", middleware.Auth())
 	{
-		api.GET("/tasks", h.GetTasks)
-		api.POST("/tasks", h.CreateTask)
-		api.PUT("/tasks/:id", h.UpdateTask)    // совместимость со старым контрактом
-		api.PATCH("/tasks/:id", h.PatchTask)   // частичные обновления через TaskPatch
-		api.DELETE("/tasks/:id", h.DeleteTask) // 204 No Content — без тела
-		api.POST("/tasks/bulk/delete", h.BulkDelete)
-		api.POST("/tasks/bulk/status", h.BulkStatus)
-		api.POST("/tasks/bulk/assign", h.BulkAssign)
+		read := middleware.RequireScope(models.ScopeTasksRead)
+		write := middleware.RequireScope(models.ScopeTasksWrite)
+
+		api.GET("/tasks", read, h.GetTasks)
+		api.POST("/tasks", write, h.CreateTask)
+		api.PUT("/tasks/:id", write, h.UpdateTask)    // совместимость со старым контрактом
+		api.PATCH("/tasks/:id", write, h.PatchTask)   // частичные обновления через TaskPatch
+		api.DELETE("/tasks/:id", write, h.DeleteTask) // 204 No Content — без тела
+		api.POST("/tasks/bulk/delete", write, h.BulkDelete)
+		api.POST("/tasks/bulk/status", write, h.BulkStatus)
+		api.POST("/tasks/bulk/assign", write, h.BulkAssign)
+		api.POST("/tasks/bulk/patch", write, h.BulkPatch)
 	}
 }
 
@@ -42,30 +54,90 @@ func (h *TaskHandler) RegisterRoutes(r *gin.Engine) {
 // Handlers
 // -------------------------
 
-// GET /api/tasks?sort=desc&status=todo&priority=high&stage=Бэкенд
+// GET /api/tasks?sort=desc&status=todo&priority=high&stage=Бэкенд&label=1,2&label_mode=all
 // Возвращает список задач с учётом фильтров и сортировки.
+// milestone_id — id спринта или none (бэклог).
+// label — id меток через запятую (или несколько параметров), label_mode — any (по умолчанию) или all.
+// query — выражение на языке taskquery, например `priority:high OR is:overdue`; ошибка разбора — 400 с column.
 // Код 200, тело — JSON-массив задач.
 func (h *TaskHandler) GetTasks(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
 	sort := c.DefaultQuery("sort", "desc")
-	status := c.Query("status")
-	prio := c.Query("priority")
-	stage := c.Query("stage")
-	var projectID *uint
+	filter := services.TaskFilter{
+		Status:   c.Query("status"),
+		Priority: c.Query("priority"),
+		Stage:    c.Query("stage"),
+	}
 	if pidStr := c.Query("project_id"); pidStr != "" {
 		if pidStr == "none" {
 			zero := uint(0)
-			projectID = &zero
+			filter.ProjectID = &zero
 		} else if pid, err := strconv.ParseUint(pidStr, 10, 64); err == nil {
 			parsed := uint(pid)
-			projectID = &parsed
+			filter.ProjectID = &parsed
+		}
+	}
+	if raw := c.Query("milestone_id"); raw != "" {
+		var milestoneID uint
+		if raw != "none" {
+			parsed, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil || parsed == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid milestone_id"})
+				return
+			}
+			milestoneID = uint(parsed)
+		}
+		filter.MilestoneID = &milestoneID
+	}
+	labelIDs, err := parseIDList(c.QueryArray("label"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid label"})
+		return
+	}
+	if len(labelIDs) > 0 {
+		if h.Labels == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "labels are not supported"})
+			return
+		}
+		if err := h.Labels.CheckVisible(userID, labelIDs); err != nil {
+			respondLabelError(c, err)
+			return
+		}
+		filter.LabelIDs = labelIDs
+		filter.LabelMode = c.DefaultQuery("label_mode", services.LabelMatchAny)
+		if filter.LabelMode != services.LabelMatchAny && filter.LabelMode != services.LabelMatchAll {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "label_mode must be any or all"})
+			return
 		}
 	}
+	if raw := c.Query("query"); raw != "" {
+		loc, err := h.Service.Location(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tasks"})
+			return
+		}
+		condition, err := h.Service.CompileQuery(raw, time.Now().In(loc))
+		if err != nil {
+			respondQueryError(c, err)
+			return
+		}
+		filter.Query = condition
+	}
 
-	tasks, err := h.Service.GetFilteredTasks(sort, status, prio, stage, projectID)
+	tasks, err := h.Service.ListTasks(sort, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tasks"})
 		return
 	}
+	if h.Labels != nil {
+		if err := h.Labels.Attach(userID, tasks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tasks"})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, tasks)
 }
 
@@ -75,7 +147,8 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 // internal/handlers/task_handler.go
 
 func (h *TaskHandler) CreateTask(c *gin.Context) {
-	if _, ok := userIDFromContext(c); !ok {
+	loc, ok := h.userLocation(c)
+	if !ok {
 		return
 	}
 	var t models.Task
@@ -84,7 +157,10 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	if err := h.Service.CreateTask(&t); err != nil {
+	if err := h.Service.CreateTask(&t, loc); err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -96,7 +172,8 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 // Полное обновление (оставлено для совместимости).
 // ВАЖНО: в сервисе оно теперь проксируется в Patch-логику, чтобы не затирать поля.
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
-	if _, ok := userIDFromContext(c); !ok {
+	loc, ok := h.userLocation(c)
+	if !ok {
 		return
 	}
 	id, ok := parseID(c)
@@ -115,9 +192,9 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	upd, err := h.Service.PatchTask(id, p)
+	upd, err := h.Service.PatchTask(id, p, loc)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		respondTaskPatchError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, upd)
@@ -126,7 +203,8 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 // PATCH /api/tasks/:id
 // Частичное обновление. Меняем только присланные поля (через TaskPatch).
 func (h *TaskHandler) PatchTask(c *gin.Context) {
-	if _, ok := userIDFromContext(c); !ok {
+	loc, ok := h.userLocation(c)
+	if !ok {
 		return
 	}
 	id, ok := parseID(c)
@@ -145,14 +223,30 @@ func (h *TaskHandler) PatchTask(c *gin.Context) {
 		return
 	}
 
-	upd, err := h.Service.PatchTask(id, p)
+	upd, err := h.Service.PatchTask(id, p, loc)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		respondTaskPatchError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, upd)
 }
 
+// respondTaskPatchError: 404 — только если задачи или целевого проекта нет, ошибки
+// проверки полей (оценки, порядок дат и т. д.) — 400 с текстом, как при создании.
+func respondTaskPatchError(c *gin.Context, err error) {
+	if respondQuotaError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
 // DELETE /api/tasks/:id
 // Удаление. Возвращаем 204 No Content (без тела), чтобы фронт не пытался парсить JSON.
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
@@ -180,6 +274,11 @@ type bulkStatusPayload struct {
 	Status string `json:"status"`
 }
 
+type bulkPatchPayload struct {
+	IDs   []uint           `json:"ids"`
+	Patch models.TaskPatch `json:"patch"`
+}
+
 type bulkAssignPayload struct {
 	IDs              []uint `json:"ids"`
 	ProjectID        *uint  `json:"project_id"`
@@ -237,6 +336,32 @@ func (h *TaskHandler) BulkAssign(c *gin.Context) {
 		return
 	}
 	if err := h.Projects.AssignTasks(userID, *payload.ProjectID, payload.IDs, payload.ReassignAttached); err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/tasks/bulk/patch {"ids": [...], "patch": {"story_points": 3, "priority": "high"}}
+// Те же поля, что и в PATCH /api/tasks/:id, кроме project_id.
+func (h *TaskHandler) BulkPatch(c *gin.Context) {
+	loc, ok := h.userLocation(c)
+	if !ok {
+		return
+	}
+	var payload bulkPatchPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids are required"})
+		return
+	}
+	if payload.Patch.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "empty patch"})
+		return
+	}
+	if err := h.Service.BulkPatch(payload.IDs, payload.Patch, loc); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -247,6 +372,35 @@ func (h *TaskHandler) BulkAssign(c *gin.Context) {
 // Helpers
 // -------------------------
 
+// parseIDList разбирает id из повторяющихся параметров и/или списков через запятую.
+func parseIDList(values []string) ([]uint, error) {
+	var ids []uint
+	for _, value := range values {
+		for _, raw := range strings.Split(value, ",") {
+			raw = strings.TrimSpace(raw)
+			if raw == "" {
+				continue
+			}
+			n, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil || n == 0 || n > uint64(^uint(0)) {
+				return nil, fmt.Errorf("invalid id %q", raw)
+			}
+			ids = append(ids, uint(n))
+		}
+	}
+	return ids, nil
+}
+
+// respondQueryError отдаёт 400 с колонкой, в которой разбор запроса споткнулся.
+func respondQueryError(c *gin.Context, err error) {
+	var syntaxErr *taskquery.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": syntaxErr.Msg, "column": syntaxErr.Column})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
 // parseID — безопасно парсит :id, отдает 400 при ошибке.
 func parseID(c *gin.Context) (uint, bool) {
 	raw := c.Param("id")
@@ -257,3 +411,17 @@ func parseID(c *gin.Context) (uint, bool) {
 	}
 	return uint(n), true
 }
+
+// userLocation — часовой пояс текущего пользователя для дат задач.
+func (h *TaskHandler) userLocation(c *gin.Context) (*time.Location, bool) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return nil, false
+	}
+	loc, err := h.Service.Location(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return loc, true
+}
@@ -165,13 +165,13 @@ func newTaskHandlerTestEnv(t *testing.T) (*TaskHandler, handlerTestDeps) {
 	dsn := fmt.Sprintf("file:handler-tests-%d?mode=memory&cache=shared", time.Now().UnixNano())
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{}))
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{}, &models.Attachment{}, &models.Label{}, &models.TaskLabel{}, &models.SavedView{}, &models.TimeEntry{}, &models.Milestone{}, &models.ProjectTemplate{}, &models.ProjectTemplateShare{}))
 
 	taskStorage := storage.NewTaskStorage(db)
 	projectStorage := storage.NewProjectStorage(db)
 	userStorage := storage.NewUserStorage(db)
 
-	taskService := services.NewTaskService(taskStorage)
+	taskService := services.NewTaskService(taskStorage, projectStorage, userStorage)
 	projectService := services.NewProjectService(projectStorage, taskStorage, userStorage)
 
 	handler := NewTaskHandler(taskService, projectService)
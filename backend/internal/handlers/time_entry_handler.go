@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// TimeEntryHandler — таймер, записи времени и отчёты.
+type TimeEntryHandler struct {
+	Service *services.TimeEntryService
+}
+
+func NewTimeEntryHandler(s *services.TimeEntryService) *TimeEntryHandler {
+	return &TimeEntryHandler{Service: s}
+}
+
+func (h *TimeEntryHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/time", middleware.Auth())
+	{
+		read := middleware.RequireScope(models.ScopeTasksRead)
+		write := middleware.RequireScope(models.ScopeTasksWrite)
+
+		api.GET("/timer", read, h.Timer)
+		api.POST("/timer/start", write, h.Start)
+		api.POST("/timer/stop", write, h.Stop)
+		api.GET("/entries", read, h.List)
+		api.POST("/entries", write, h.Create)
+		api.PATCH("/entries/:id", write, h.Update)
+		api.DELETE("/entries/:id", write, h.Delete)
+		api.GET("/report", read, h.Report)
+	}
+}
+
+// GET /api/time/timer — идущий таймер; {"timer": null}, если его нет.
+func (h *TimeEntryHandler) Timer(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	entry, err := h.Service.Timer(userID)
+	if err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"timer": entry})
+}
+
+// POST /api/time/timer/start {"task_id": 1, "note": "..."}
+// Если таймер уже идёт — 409 и он сам в поле timer.
+func (h *TimeEntryHandler) Start(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var input services.TimerInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.TaskID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task_id is required"})
+		return
+	}
+	entry, err := h.Service.Start(userID, input)
+	if errors.Is(err, services.ErrTimerRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "timer": entry})
+		return
+	}
+	if err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// POST /api/time/timer/stop — останавливает таймер и возвращает готовую запись.
+func (h *TimeEntryHandler) Stop(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	entry, err := h.Service.Stop(userID)
+	if err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// GET /api/time/entries?from=&to=&task_id=&project_id= — свои записи, новые сверху.
+func (h *TimeEntryHandler) List(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	filter := services.TimeEntryListFilter{From: c.Query("from"), To: c.Query("to")}
+	if !queryID(c, "task_id", &filter.TaskID) || !queryID(c, "project_id", &filter.ProjectID) {
+		return
+	}
+	entries, err := h.Service.List(userID, filter)
+	if err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// POST /api/time/entries {"task_id": 1, "started_at": "...", "ended_at": "...", "note": "..."}
+func (h *TimeEntryHandler) Create(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var input services.TimeEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	entry, err := h.Service.Create(userID, input)
+	if err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *TimeEntryHandler) Update(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.TimeEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if input.TaskID == nil && input.StartedAt == nil && input.EndedAt == nil && input.Note == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "empty payload"})
+		return
+	}
+	entry, err := h.Service.Update(userID, id, input)
+	if err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *TimeEntryHandler) Delete(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.Delete(userID, id); err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /api/time/report?group_by=project|task|user|day&from=&to=&project_id=&user_id=&format=json|csv
+func (h *TimeEntryHandler) Report(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	query := services.TimeReportQuery{
+		GroupBy: strings.ToLower(c.Query("group_by")),
+		From:    c.Query("from"),
+		To:      c.Query("to"),
+	}
+	if !queryID(c, "project_id", &query.ProjectID) || !queryID(c, "user_id", &query.UserID) {
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	report, err := h.Service.Report(userID, query)
+	if err != nil {
+		respondTimeEntryError(c, err)
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	filename := fmt.Sprintf("time-by-%s-%s-%s.csv", report.GroupBy, report.From, report.To)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{report.GroupBy, "name", "hours", "seconds", "entries"})
+	for _, row := range report.Rows {
+		_ = w.Write([]string{
+			row.Key,
+			row.Label,
+			formatHours(row.Seconds),
+			strconv.FormatInt(row.Seconds, 10),
+			strconv.FormatInt(row.Entries, 10),
+		})
+	}
+	_ = w.Write([]string{"total", "", formatHours(report.TotalSeconds), strconv.FormatInt(report.TotalSeconds, 10), ""})
+	w.Flush()
+}
+
+// queryID читает необязательный числовой параметр; при ошибке отвечает 400.
+func queryID(c *gin.Context, name string, dst *uint) bool {
+	raw := c.Query(name)
+	if raw == "" {
+		return true
+	}
+	n, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || n == 0 || n > uint64(^uint(0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return false
+	}
+	*dst = uint(n)
+	return true
+}
+
+func formatHours(seconds int64) string {
+	return strconv.FormatFloat(float64(seconds)/3600, 'f', 2, 64)
+}
+
+func respondTimeEntryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTimeEntryNotFound), errors.Is(err, services.ErrTaskNotFound), errors.Is(err, services.ErrNoTimer):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTimerRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTimeEntryInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
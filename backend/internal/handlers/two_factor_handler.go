@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/services"
+)
+
+// TwoFactorHandler — подключение и отключение TOTP 2FA.
+type TwoFactorHandler struct {
+	Service  *services.TwoFactorService
+	Security *services.SecurityEventService
+}
+
+func NewTwoFactorHandler(s *services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{Service: s}
+}
+
+func (h *TwoFactorHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/user/2fa", middleware.Auth(), middleware.RequireSession())
+	{
+		api.GET("", h.Status)
+		api.POST("/enroll", h.BeginEnrollment)
+		api.POST("/confirm", h.ConfirmEnrollment)
+		api.POST("/disable", h.Disable)
+	}
+}
+
+// GET /api/user/2fa — включена ли 2FA и сколько осталось резервных кодов.
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	remaining, err := h.Service.RemainingRecoveryCodes(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	enabled, err := h.Service.IsEnabled(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  enabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// POST /api/user/2fa/enroll — возвращает секрет и otpauth URI для QR-кода.
+func (h *TwoFactorHandler) BeginEnrollment(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	enrollment, err := h.Service.BeginEnrollment(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// POST /api/user/2fa/confirm
+// Принимает: { "code": "123456" }. Возвращает резервные коды — они показываются один раз.
+func (h *TwoFactorHandler) ConfirmEnrollment(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	codes, err := h.Service.ConfirmEnrollment(userID, payload.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTwoFactorNotEnrolling), errors.Is(err, services.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventTwoFactorEnable, models.SecurityOutcomeSuccess, nil)
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// POST /api/user/2fa/disable
+// Принимает: { "password": "..." }.
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password is required"})
+		return
+	}
+	if err := h.Service.Disable(userID, payload.Password); err != nil {
+		recordSecurityEvent(c, h.Security, userID, models.SecurityEventTwoFactorDisable, models.SecurityOutcomeFailure, map[string]any{"reason": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventTwoFactorDisable, models.SecurityOutcomeSuccess, nil)
+	c.Status(http.StatusNoContent)
+}
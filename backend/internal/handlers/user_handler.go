@@ -1,29 +1,41 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spozitivom/taskmanager/internal/middleware"
+	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/services"
 )
 
 type UserHandler struct {
-	Service *services.UserService
+	Service      *services.UserService
+	Verification *services.EmailVerificationService
+	Security     *services.SecurityEventService
+	Avatars      *services.AvatarService // удаляет файлы аватара при замене ссылкой и удалении аккаунта
 }
 
-func NewUserHandler(s *services.UserService) *UserHandler {
-	return &UserHandler{Service: s}
+func NewUserHandler(s *services.UserService, v *services.EmailVerificationService) *UserHandler {
+	return &UserHandler{Service: s, Verification: v}
 }
 
 func (h *UserHandler) RegisterRoutes(r *gin.Engine) {
 	api := r.Group("/api", middleware.Auth())
 	{
-		api.GET("/user/profile", h.GetProfile)
-		api.PATCH("/user/profile", h.UpdateProfile)
-		api.PATCH("/user/password", h.UpdatePassword)
-		api.PATCH("/user/settings", h.UpdateSettings)
-		api.DELETE("/user", h.DeleteAccount)
+		read := middleware.RequireScope(models.ScopeUserRead)
+		write := middleware.RequireScope(models.ScopeUserWrite)
+
+		api.GET("/user/profile", read, h.GetProfile)
+		api.PATCH("/user/profile", write, h.UpdateProfile)
+		api.PATCH("/user/password", middleware.RequireSession(), h.UpdatePassword)
+		api.PATCH("/user/email", middleware.RequireSession(), h.ChangeEmail)
+		api.PATCH("/user/settings", write, h.UpdateSettings)
+		api.POST("/user/deactivate", middleware.RequireSession(), h.Deactivate)
+		api.DELETE("/user", middleware.RequireSession(), h.DeleteAccount)
+		api.GET("/user/security-events", read, h.ListSecurityEvents)
 	}
 }
 
@@ -59,11 +71,23 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	previous, err := h.Service.GetProfile(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
 	updated, err := h.Service.UpdateProfile(userID, payload.FullName, payload.Avatar, payload.RemoveAvatar)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if h.Avatars != nil && previous.AvatarVersion != updated.AvatarVersion {
+		h.Avatars.Discard(c.Request.Context(), userID, previous.AvatarVersion, previous.AvatarFormat)
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventProfileUpdate, models.SecurityOutcomeSuccess, map[string]any{
+		"full_name": payload.FullName != nil,
+		"avatar":    payload.Avatar != nil || payload.RemoveAvatar,
+	})
 	c.JSON(http.StatusOK, updated)
 }
 
@@ -95,12 +119,56 @@ func (h *UserHandler) UpdatePassword(c *gin.Context) {
 	}
 
 	if err := h.Service.UpdatePassword(userID, payload.Current, payload.New); err != nil {
+		recordSecurityEvent(c, h.Security, userID, models.SecurityEventPasswordChange, models.SecurityOutcomeFailure, map[string]any{"reason": err.Error()})
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventPasswordChange, models.SecurityOutcomeSuccess, nil)
 	c.Status(http.StatusNoContent)
 }
 
+// PATCH /api/user/email
+// Принимает: { "email": "...", "password": "..." }. После смены адрес нужно подтвердить заново.
+func (h *UserHandler) ChangeEmail(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if payload.Email == "" || payload.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email and password are required"})
+		return
+	}
+
+	previous, _ := h.Service.GetProfile(userID)
+	user, err := h.Service.ChangeEmail(userID, payload.Password, payload.Email)
+	if err != nil {
+		recordSecurityEvent(c, h.Security, userID, models.SecurityEventEmailChange, models.SecurityOutcomeFailure, map[string]any{"reason": err.Error()})
+		if errors.Is(err, services.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if previous != nil && previous.Email != user.Email {
+		recordSecurityEvent(c, h.Security, userID, models.SecurityEventEmailChange, models.SecurityOutcomeSuccess, map[string]any{"from": previous.Email, "to": user.Email})
+	}
+	if !user.IsEmailVerified() {
+		if err := h.Verification.Send(user); err != nil {
+			log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, user)
+}
+
 func (h *UserHandler) UpdateSettings(c *gin.Context) {
 	userID, ok := userIDFromContext(c)
 	if !ok {
@@ -109,24 +177,60 @@ func (h *UserHandler) UpdateSettings(c *gin.Context) {
 	var payload struct {
 		Language string `json:"language"`
 		Theme    string `json:"theme"`
+		TimeZone string `json:"time_zone"`
 	}
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
-	if payload.Language == "" && payload.Theme == "" {
+	if payload.Language == "" && payload.Theme == "" && payload.TimeZone == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
 		return
 	}
 
-	user, err := h.Service.UpdateSettings(userID, payload.Language, payload.Theme)
+	user, err := h.Service.UpdateSettings(userID, payload.Language, payload.Theme, payload.TimeZone)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	changed := map[string]any{}
+	if payload.Language != "" {
+		changed["language"] = user.Language
+	}
+	if payload.Theme != "" {
+		changed["theme"] = user.Theme
+	}
+	if payload.TimeZone != "" {
+		changed["time_zone"] = user.TimeZone
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventSettingsChange, models.SecurityOutcomeSuccess, changed)
 	c.JSON(http.StatusOK, user)
 }
 
+// POST /api/user/deactivate
+// Принимает: { "password": "...", "reason": "..." }. В отличие от удаления, данные сохраняются.
+func (h *UserHandler) Deactivate(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Password string `json:"password"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if err := h.Service.Deactivate(userID, payload.Password, payload.Reason); err != nil {
+		recordSecurityEvent(c, h.Security, userID, models.SecurityEventAccountDeactivate, models.SecurityOutcomeFailure, map[string]any{"reason": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventAccountDeactivate, models.SecurityOutcomeSuccess, nil)
+	c.Status(http.StatusNoContent)
+}
+
 func (h *UserHandler) DeleteAccount(c *gin.Context) {
 	userID, ok := userIDFromContext(c)
 	if !ok {
@@ -155,8 +259,30 @@ func (h *UserHandler) DeleteAccount(c *gin.Context) {
 	}
 
 	if err := h.Service.DeleteAccount(userID); err != nil {
+		recordSecurityEvent(c, h.Security, userID, models.SecurityEventAccountDelete, models.SecurityOutcomeFailure, map[string]any{"reason": err.Error()})
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if h.Avatars != nil {
+		h.Avatars.Discard(c.Request.Context(), userID, user.AvatarVersion, user.AvatarFormat)
+	}
+	// Журнал безопасности переживает удаление аккаунта: запись остаётся для расследований.
+	recordSecurityEvent(c, h.Security, userID, models.SecurityEventAccountDelete, models.SecurityOutcomeSuccess, map[string]any{"email": user.Email})
 	c.Status(http.StatusNoContent)
 }
+
+// GET /api/user/security-events?limit=&offset=
+// Возвращает события безопасности своего аккаунта, от новых к старым.
+func (h *UserHandler) ListSecurityEvents(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	limit, offset := pageFromQuery(c)
+	events, err := h.Security.ListForUser(userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
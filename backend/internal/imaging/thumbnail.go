@@ -0,0 +1,60 @@
+// Package imaging — обработка изображений на стандартной библиотеке.
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail вырезает из src центральный квадрат и приводит его к size×size.
+// Каждый пиксель результата — среднее по соответствующей области исходника,
+// поэтому уменьшение не даёт «лесенки» даже при большом коэффициенте.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if side == 0 || size <= 0 {
+		return dst
+	}
+	origin := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), src, origin, draw.Src)
+
+	for dy := 0; dy < size; dy++ {
+		sy0, sy1 := span(dy, size, side)
+		for dx := 0; dx < size; dx++ {
+			sx0, sx1 := span(dx, size, side)
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				row := square.Pix[sy*square.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					bl += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span — диапазон исходных координат [from, to) для пикселя i результата размера size.
+func span(i, size, side int) (int, int) {
+	from := i * side / size
+	to := (i + 1) * side / size
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
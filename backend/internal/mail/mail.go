@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Message — письмо в виде, не зависящем от транспорта.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Kind помечает назначение письма (password_reset, verify_email ...) для выборок по outbox.
+	Kind string
+}
+
+// Sender — транспорт отправки писем.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// LogSender пишет письма в лог — используется, когда SMTP не настроен.
+// В письмах бывают ссылки со сбросом пароля и выгрузкой данных, поэтому по
+// умолчанию в лог попадают только получатель и тема; текст — лишь с LogBodies.
+type LogSender struct {
+	LogBodies bool
+}
+
+// NewLogSenderFromEnv включает вывод текста писем, если MAIL_LOG_BODIES=true
+// (для локальной разработки).
+func NewLogSenderFromEnv() LogSender {
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv("MAIL_LOG_BODIES")))
+	return LogSender{LogBodies: enabled}
+}
+
+func (s LogSender) Send(msg Message) error {
+	if !s.LogBodies {
+		log.Printf("✉️ mail to=%s subject=%q kind=%s (body hidden, %d bytes)", msg.To, msg.Subject, msg.Kind, len(msg.Body))
+		return nil
+	}
+	log.Printf("✉️ mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+func normalizeAddress(addr string) string {
+	return strings.ToLower(strings.TrimSpace(addr))
+}
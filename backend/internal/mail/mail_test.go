@@ -0,0 +1,26 @@
+package mail
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSender_HidesBodyByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	msg := Message{To: "user@example.com", Subject: "Сброс пароля", Body: "token=secret-token", Kind: "password_reset"}
+
+	require.NoError(t, LogSender{}.Send(msg))
+	require.Contains(t, buf.String(), "user@example.com")
+	require.NotContains(t, buf.String(), "secret-token")
+
+	t.Setenv("MAIL_LOG_BODIES", "true")
+	buf.Reset()
+	require.NoError(t, NewLogSenderFromEnv().Send(msg))
+	require.Contains(t, buf.String(), "secret-token")
+}
@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"log"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+const maxDeliveryAttempts = 5
+
+// OutboxSender реализует Sender записью письма в таблицу outbox.
+type OutboxSender struct {
+	db *gorm.DB
+}
+
+func NewOutboxSender(db *gorm.DB) *OutboxSender {
+	return &OutboxSender{db: db}
+}
+
+func (s *OutboxSender) Send(msg Message) error {
+	return s.db.Create(&models.OutboxEmail{
+		Recipient: normalizeAddress(msg.To),
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+		Kind:      msg.Kind,
+		Status:    models.OutboxStatusPending,
+	}).Error
+}
+
+// Dispatcher доставляет письма из outbox через реальный транспорт.
+type Dispatcher struct {
+	db        *gorm.DB
+	transport Sender
+}
+
+func NewDispatcher(db *gorm.DB, transport Sender) *Dispatcher {
+	return &Dispatcher{db: db, transport: transport}
+}
+
+// DispatchPending отправляет до limit ожидающих писем и возвращает число доставленных.
+func (d *Dispatcher) DispatchPending(limit int) (int, error) {
+	var pending []models.OutboxEmail
+	if err := d.db.Where("status = ?", models.OutboxStatusPending).
+		Order("id ASC").
+		Limit(limit).
+		Find(&pending).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range pending {
+		email := &pending[i]
+		// Захватываем письмо, чтобы параллельный диспетчер не отправил его повторно.
+		claim := d.db.Model(&models.OutboxEmail{}).
+			Where("id = ? AND status = ? AND attempts = ?", email.ID, models.OutboxStatusPending, email.Attempts).
+			Update("attempts", email.Attempts+1)
+		if claim.Error != nil {
+			return sent, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		email.Attempts++
+
+		updates := map[string]any{}
+		if err := d.transport.Send(Message{To: email.Recipient, Subject: email.Subject, Body: email.Body, Kind: email.Kind}); err != nil {
+			updates["last_error"] = err.Error()
+			if email.Attempts >= maxDeliveryAttempts {
+				updates["status"] = models.OutboxStatusFailed
+			}
+		} else {
+			now := time.Now()
+			updates["status"] = models.OutboxStatusSent
+			updates["sent_at"] = &now
+			updates["last_error"] = ""
+			sent++
+		}
+		if err := d.db.Model(&models.OutboxEmail{}).Where("id = ?", email.ID).Updates(updates).Error; err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// Run периодически разбирает outbox, пока не закрыт stop.
+func (d *Dispatcher) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchPending(50); err != nil {
+			log.Printf("outbox dispatch error: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
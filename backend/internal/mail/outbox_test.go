@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type recordingSender struct {
+	sent []Message
+	err  error
+}
+
+func (r *recordingSender) Send(msg Message) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func setupOutboxDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:outbox-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.OutboxEmail{}))
+	return db
+}
+
+func TestDispatcher_SendsPendingOnce(t *testing.T) {
+	db := setupOutboxDB(t)
+	outbox := NewOutboxSender(db)
+	require.NoError(t, outbox.Send(Message{To: "a@example.com", Subject: "Hi", Body: "body", Kind: "test"}))
+
+	transport := &recordingSender{}
+	dispatcher := NewDispatcher(db, transport)
+
+	sent, err := dispatcher.DispatchPending(10)
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	require.Len(t, transport.sent, 1)
+	require.Equal(t, "a@example.com", transport.sent[0].To)
+
+	sent, err = dispatcher.DispatchPending(10)
+	require.NoError(t, err)
+	require.Zero(t, sent)
+
+	var stored models.OutboxEmail
+	require.NoError(t, db.First(&stored).Error)
+	require.Equal(t, models.OutboxStatusSent, stored.Status)
+	require.NotNil(t, stored.SentAt)
+}
+
+func TestDispatcher_MarksFailedAfterRetries(t *testing.T) {
+	db := setupOutboxDB(t)
+	require.NoError(t, NewOutboxSender(db).Send(Message{To: "b@example.com", Subject: "Hi"}))
+
+	dispatcher := NewDispatcher(db, &recordingSender{err: errors.New("smtp down")})
+	for i := 0; i < maxDeliveryAttempts; i++ {
+		_, err := dispatcher.DispatchPending(10)
+		require.NoError(t, err)
+	}
+
+	var stored models.OutboxEmail
+	require.NoError(t, db.First(&stored).Error)
+	require.Equal(t, models.OutboxStatusFailed, stored.Status)
+	require.Equal(t, maxDeliveryAttempts, stored.Attempts)
+	require.Equal(t, "smtp down", stored.LastError)
+}
@@ -0,0 +1,69 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// SMTPSender отправляет письма через SMTP-сервер.
+type SMTPSender struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// NewSMTPSenderFromEnv читает SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS и SMTP_FROM.
+// Возвращает nil, если SMTP_HOST не задан.
+func NewSMTPSenderFromEnv() *SMTPSender {
+	host := strings.TrimSpace(os.Getenv("SMTP_HOST"))
+	if host == "" {
+		return nil
+	}
+	port := strings.TrimSpace(os.Getenv("SMTP_PORT"))
+	if port == "" {
+		port = "587"
+	}
+	from := strings.TrimSpace(os.Getenv("SMTP_FROM"))
+	if from == "" {
+		from = "no-reply@" + host
+	}
+	sender := &SMTPSender{Addr: net.JoinHostPort(host, port), From: from}
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		sender.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASS"), host)
+	}
+	return sender
+}
+
+func (s *SMTPSender) Send(msg Message) error {
+	to := normalizeAddress(msg.To)
+	if to == "" {
+		return errors.New("recipient is required")
+	}
+	if strings.ContainsAny(to, "\r\n") {
+		return errors.New("invalid recipient")
+	}
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{to}, buildMessage(s.From, to, msg))
+}
+
+// buildMessage собирает простое text/plain письмо в UTF-8.
+func buildMessage(from, to string, msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer — минимальный SMTP-сервер, принимающий одно письмо.
+type fakeSMTPServer struct {
+	addr     string
+	received chan string
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	srv := &fakeSMTPServer{addr: ln.Addr().String(), received: make(chan string, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		write := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+		write("220 fake.local ESMTP")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					srv.received <- data.String()
+					write("250 OK queued")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				write("250-fake.local")
+				write("250 8BITMIME")
+			case cmd == "DATA":
+				inData = true
+				write("354 End data with <CR><LF>.<CR><LF>")
+			case cmd == "QUIT":
+				write("221 Bye")
+				return
+			default:
+				write("250 OK")
+			}
+		}
+	}()
+	return srv
+}
+
+func TestSMTPSender_DeliversToServer(t *testing.T) {
+	srv := startFakeSMTPServer(t)
+	sender := &SMTPSender{Addr: srv.addr, From: "no-reply@taskmanager.local"}
+
+	err := sender.Send(Message{
+		To:      " User@Example.com ",
+		Subject: "Восстановление пароля",
+		Body:    "line one\nline two",
+	})
+	require.NoError(t, err)
+
+	raw := <-srv.received
+	require.Contains(t, raw, "To: user@example.com\r\n")
+	require.Contains(t, raw, "Subject: =?utf-8?q?")
+	require.Contains(t, raw, "Content-Type: text/plain; charset=UTF-8")
+	require.Contains(t, raw, "line one\r\nline two")
+}
+
+func TestSMTPSender_RejectsHeaderInjection(t *testing.T) {
+	sender := &SMTPSender{Addr: "127.0.0.1:1", From: "no-reply@taskmanager.local"}
+	require.Error(t, sender.Send(Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "x"}))
+}
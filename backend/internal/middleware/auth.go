@@ -9,8 +9,60 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/spozitivom/taskmanager/internal/models"
 )
 
+// SessionValidator проверяет, что сессия, на которую ссылается access-токен, ещё активна.
+type SessionValidator interface {
+	ValidateSession(userID, sessionID uint) error
+}
+
+// TokenAuthenticator проверяет персональные токены доступа (PAT) и возвращает
+// владельца, его роль и выданные токену области доступа.
+type TokenAuthenticator interface {
+	AuthenticateToken(raw string) (userID uint, role string, scopes []string, err error)
+}
+
+// CapabilityChecker решает, доступно ли пользователю действие
+// (например, пока его email не подтверждён).
+type CapabilityChecker interface {
+	CheckCapability(userID uint, capability string) error
+}
+
+// AccountStatusChecker возвращает статус учётной записи (models.AccountStatus*).
+type AccountStatusChecker interface {
+	AccountStatus(userID uint) (string, error)
+}
+
+var (
+	sessionValidator   SessionValidator
+	tokenAuthenticator TokenAuthenticator
+	capabilityChecker  CapabilityChecker
+	accountChecker     AccountStatusChecker
+)
+
+// SetSessionValidator подключает проверку отзыва сессий. Вызывается один раз при старте;
+// без валидатора middleware проверяет только подпись и срок действия JWT.
+func SetSessionValidator(v SessionValidator) {
+	sessionValidator = v
+}
+
+// SetTokenAuthenticator подключает приём персональных токенов доступа.
+func SetTokenAuthenticator(a TokenAuthenticator) {
+	tokenAuthenticator = a
+}
+
+// SetCapabilityChecker подключает проверку действий, ограниченных политикой аккаунта.
+func SetCapabilityChecker(c CapabilityChecker) {
+	capabilityChecker = c
+}
+
+// SetAccountStatusChecker подключает проверку статуса аккаунта на каждом запросе:
+// заблокированные и отключённые пользователи теряют доступ даже с уже выданными токенами.
+func SetAccountStatusChecker(c AccountStatusChecker) {
+	accountChecker = c
+}
+
 // Auth — middleware для проверки JWT токена в заголовке Authorization.
 // Требуется заголовок: Authorization: Bearer <token>
 func Auth() gin.HandlerFunc {
@@ -30,6 +82,12 @@ func Auth() gin.HandlerFunc {
 		}
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
+		// Персональный токен доступа — отдельная ветка, JWT-проверки к нему не применяются.
+		if strings.HasPrefix(tokenStr, models.PersonalAccessTokenPrefix) {
+			authenticatePAT(c, tokenStr)
+			return
+		}
+
 		// --- 2️⃣ Парсим токен ---
 		claims := jwt.MapClaims{}
 		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
@@ -52,19 +110,163 @@ func Auth() gin.HandlerFunc {
 			return
 		}
 
+		// Служебные токены (например, второй шаг входа с 2FA) доступа к API не дают.
+		if typ, ok := claims["typ"].(string); ok && typ != "access" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
 		// --- 4️⃣ Извлекаем полезные данные ---
-		if sub, ok := claims["sub"].(float64); ok {
-			c.Set("userID", uint(sub))
-		} else if subStr, ok := claims["sub"].(string); ok {
-			if id, err := strconv.ParseUint(subStr, 10, 64); err == nil && id > 0 {
-				c.Set("userID", uint(id))
-			}
+		userID := uintClaim(claims, "sub")
+		if userID > 0 {
+			c.Set("userID", userID)
 		}
 		if role, ok := claims["role"].(string); ok {
 			c.Set("role", role)
 		}
+		sessionID := uintClaim(claims, "sid")
+		if sessionID > 0 {
+			c.Set("sessionID", sessionID)
+		}
+
+		// --- 5️⃣ Проверяем, что сессия не отозвана ---
+		if sessionValidator != nil {
+			if sessionID == 0 || userID == 0 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				return
+			}
+			if err := sessionValidator.ValidateSession(userID, sessionID); err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+				return
+			}
+		}
+
+		// --- 6️⃣ Проверяем статус аккаунта ---
+		if !checkAccountActive(c, userID) {
+			return
+		}
+
+		// --- 7️⃣ Продолжаем выполнение ---
+		c.Next()
+	}
+}
+
+func authenticatePAT(c *gin.Context, raw string) {
+	if tokenAuthenticator == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	userID, role, scopes, err := tokenAuthenticator.AuthenticateToken(raw)
+	if err != nil || userID == 0 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	if !checkAccountActive(c, userID) {
+		return
+	}
+	c.Set("userID", userID)
+	c.Set("role", role)
+	c.Set("scopes", scopes)
+	c.Next()
+}
+
+// checkAccountActive прерывает запрос, если аккаунт не активен. При отказе ответ уже отправлен.
+func checkAccountActive(c *gin.Context, userID uint) bool {
+	if accountChecker == nil {
+		return true
+	}
+	if userID == 0 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return false
+	}
+	status, err := accountChecker.AccountStatus(userID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid user"})
+		return false
+	}
+	if status != models.AccountStatusActive {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is " + status, "status": status})
+		return false
+	}
+	return true
+}
+
+// RequireScope пропускает запрос, если персональный токен содержит нужную область.
+// Интерактивные сессии (JWT) имеют полный доступ и проверку не проходят.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		val, ok := c.Get("scopes")
+		if !ok {
+			c.Next()
+			return
+		}
+		scopes, _ := val.([]string)
+		if !models.ScopeAllows(scopes, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope", "required_scope": scope})
+			return
+		}
+		c.Next()
+	}
+}
 
-		// --- 5️⃣ Продолжаем выполнение ---
+// RequireSession закрывает эндпоинты, доступные только после интерактивного входа
+// (управление токенами, сессиями, пароль, удаление аккаунта).
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("scopes"); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "personal access tokens are not allowed here"})
+			return
+		}
 		c.Next()
 	}
 }
+
+// RequireCapability пропускает запрос, если политика разрешает пользователю действие.
+// Без подключённого CapabilityChecker ограничений нет.
+func RequireCapability(capability string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if capabilityChecker == nil {
+			c.Next()
+			return
+		}
+		userID, _ := c.Get("userID")
+		id, _ := userID.(uint)
+		if err := capabilityChecker.CheckCapability(id, capability); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "capability": capability})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole пропускает только пользователей с одной из указанных ролей.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		allowed[role] = struct{}{}
+	}
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		name, _ := role.(string)
+		if _, ok := allowed[name]; !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// uintClaim читает числовой claim, который может прийти строкой или числом.
+func uintClaim(claims jwt.MapClaims, key string) uint {
+	switch v := claims[key].(type) {
+	case float64:
+		if v > 0 {
+			return uint(v)
+		}
+	case string:
+		if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return uint(id)
+		}
+	}
+	return 0
+}
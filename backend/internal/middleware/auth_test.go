@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/services"
 	"github.com/stretchr/testify/require"
 )
@@ -50,3 +52,159 @@ func TestAuthMiddlewarePassesValidToken(t *testing.T) {
 	require.Equal(t, http.StatusOK, w.Code)
 	require.Equal(t, "ok", w.Body.String())
 }
+
+type stubSessionValidator struct {
+	revoked map[uint]bool
+}
+
+func (s stubSessionValidator) ValidateSession(_ uint, sessionID uint) error {
+	if s.revoked[sessionID] {
+		return services.ErrSessionRevoked
+	}
+	return nil
+}
+
+func TestAuthMiddlewareRejectsRevokedSession(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	gin.SetMode(gin.TestMode)
+
+	SetSessionValidator(stubSessionValidator{revoked: map[uint]bool{2: true}})
+	t.Cleanup(func() { SetSessionValidator(nil) })
+
+	router := gin.New()
+	router.Use(Auth())
+	router.GET("/protected", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	cases := []struct {
+		name      string
+		sessionID uint
+		want      int
+	}{
+		{"active session", 1, http.StatusOK},
+		{"revoked session", 2, http.StatusUnauthorized},
+		{"token without session", 0, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := services.GenerateAccessToken(7, "user", tc.sessionID)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tc.want, w.Code)
+		})
+	}
+}
+
+type stubTokenAuthenticator struct {
+	scopes []string
+}
+
+func (s stubTokenAuthenticator) AuthenticateToken(raw string) (uint, string, []string, error) {
+	if raw != models.PersonalAccessTokenPrefix+"valid" {
+		return 0, "", nil, errors.New("invalid")
+	}
+	return 3, "user", s.scopes, nil
+}
+
+func TestAuthMiddlewareEnforcesTokenScopes(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	gin.SetMode(gin.TestMode)
+
+	SetTokenAuthenticator(stubTokenAuthenticator{scopes: []string{models.ScopeTasksWrite}})
+	t.Cleanup(func() { SetTokenAuthenticator(nil) })
+
+	router := gin.New()
+	router.Use(Auth())
+	router.GET("/tasks", RequireScope(models.ScopeTasksRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.DELETE("/projects/1", RequireScope(models.ScopeProjectsAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/tokens", RequireSession(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	jwtToken, err := services.GenerateJWT(3, "user")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{"write scope implies read", models.PersonalAccessTokenPrefix + "valid", http.MethodGet, "/tasks", http.StatusOK},
+		{"missing scope", models.PersonalAccessTokenPrefix + "valid", http.MethodDelete, "/projects/1", http.StatusForbidden},
+		{"token management needs session", models.PersonalAccessTokenPrefix + "valid", http.MethodGet, "/tokens", http.StatusForbidden},
+		{"unknown token", models.PersonalAccessTokenPrefix + "nope", http.MethodGet, "/tasks", http.StatusUnauthorized},
+		{"jwt has full access", jwtToken, http.MethodDelete, "/projects/1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tc.want, w.Code)
+		})
+	}
+}
+
+type stubAccountChecker map[uint]string
+
+func (s stubAccountChecker) AccountStatus(userID uint) (string, error) {
+	status, ok := s[userID]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return status, nil
+}
+
+func TestAuthMiddlewareRejectsInactiveAccounts(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	gin.SetMode(gin.TestMode)
+
+	SetAccountStatusChecker(stubAccountChecker{
+		1: models.AccountStatusActive,
+		2: models.AccountStatusSuspended,
+		3: models.AccountStatusDeactivated,
+	})
+	t.Cleanup(func() { SetAccountStatusChecker(nil) })
+	SetTokenAuthenticator(stubTokenAuthenticator{scopes: []string{models.ScopeTasksRead}})
+	t.Cleanup(func() { SetTokenAuthenticator(nil) })
+
+	router := gin.New()
+	router.Use(Auth())
+	router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name   string
+		userID uint
+		want   int
+	}{
+		{"active", 1, http.StatusOK},
+		{"suspended", 2, http.StatusForbidden},
+		{"deactivated", 3, http.StatusForbidden},
+		{"unknown user", 4, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := services.GenerateJWT(tc.userID, "user")
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tc.want, w.Code)
+		})
+	}
+
+	// Персональный токен пользователя 3 (деактивирован) тоже не проходит.
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+models.PersonalAccessTokenPrefix+"valid")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// PersonalAccessTokenPrefix отличает персональные токены от JWT в заголовке Authorization.
+const PersonalAccessTokenPrefix = "tmpat_"
+
+// Области доступа персональных токенов. Для одного ресурса старшая область
+// включает младшие: admin ⊃ write ⊃ read.
+const (
+	ScopeTasksRead     = "tasks:read"
+	ScopeTasksWrite    = "tasks:write"
+	ScopeProjectsRead  = "projects:read"
+	ScopeProjectsWrite = "projects:write"
+	ScopeProjectsAdmin = "projects:admin"
+	ScopeUserRead      = "user:read"
+	ScopeUserWrite     = "user:write"
+)
+
+var errInvalidScope = errors.New("invalid token scope")
+
+// scopeLevels — ранг области внутри ресурса.
+var scopeLevels = map[string]int{
+	"read":  1,
+	"write": 2,
+	"admin": 3,
+}
+
+var validScopes = map[string]struct{}{
+	ScopeTasksRead:     {},
+	ScopeTasksWrite:    {},
+	ScopeProjectsRead:  {},
+	ScopeProjectsWrite: {},
+	ScopeProjectsAdmin: {},
+	ScopeUserRead:      {},
+	ScopeUserWrite:     {},
+}
+
+// PersonalAccessToken — токен для скриптов и CI. В БД хранится только хэш.
+type PersonalAccessToken struct {
+	ID         uint                        `gorm:"primaryKey" json:"id"`
+	UserID     uint                        `gorm:"index;not null" json:"user_id"`
+	Name       string                      `gorm:"type:varchar(100);not null" json:"name"`
+	TokenHash  string                      `gorm:"type:varchar(64);uniqueIndex:uniq_pat_hash" json:"-"`
+	Hint       string                      `gorm:"type:varchar(16)" json:"hint"`
+	Scopes     datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"scopes"`
+	ExpiresAt  *time.Time                  `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time                  `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time                  `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time                   `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// NormalizeScopes проверяет список областей и убирает дубликаты.
+func NormalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if _, ok := validScopes[scope]; !ok {
+			return nil, errInvalidScope
+		}
+		if _, dup := seen[scope]; dup {
+			continue
+		}
+		seen[scope] = struct{}{}
+		result = append(result, scope)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return result, nil
+}
+
+// ScopeAllows сообщает, покрывает ли набор granted требуемую область required.
+func ScopeAllows(granted []string, required string) bool {
+	reqResource, reqLevel := splitScope(required)
+	for _, scope := range granted {
+		resource, level := splitScope(scope)
+		if resource == reqResource && level >= reqLevel && level > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func splitScope(scope string) (string, int) {
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok {
+		return "", 0
+	}
+	return resource, scopeLevels[action]
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Действия администратора, попадающие в журнал.
+const (
+	AdminActionUpdateMaxProjects  = "user.max_projects"
+	AdminActionChangeRole         = "user.role"
+	AdminActionChangePlan         = "user.plan"
+	AdminActionForcePasswordReset = "user.force_password_reset"
+	AdminActionSuspend            = "user.suspend"
+	AdminActionReactivate         = "user.reactivate"
+	AdminActionUnlockLogin        = "login.unlock"
+)
+
+// AdminAuditEntry — запись журнала действий администраторов.
+type AdminAuditEntry struct {
+	ID           uint              `gorm:"primaryKey" json:"id"`
+	ActorID      uint              `gorm:"index;not null" json:"actor_id"`
+	Action       string            `gorm:"type:varchar(64);not null;index" json:"action"`
+	TargetUserID *uint             `gorm:"index" json:"target_user_id,omitempty"`
+	Details      datatypes.JSONMap `gorm:"type:jsonb" json:"details,omitempty"`
+	CreatedAt    time.Time         `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// UserUsage — сколько ресурсов занимает пользователь.
+type UserUsage struct {
+	Projects int64 `json:"projects"`
+	Tasks    int64 `json:"tasks"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Attachment — файл, приложенный к задаче. Содержимое лежит в blob-хранилище
+// под StorageKey; DeletedAt повторяет жизненный цикл задачи (архив проекта).
+type Attachment struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	TaskID      uint           `gorm:"index;not null" json:"task_id"`
+	UserID      uint           `gorm:"index;not null" json:"user_id"`
+	FileName    string         `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType string         `gorm:"type:varchar(128);not null" json:"content_type"`
+	Size        int64          `gorm:"not null" json:"size"`
+	SHA256      string         `gorm:"type:varchar(64)" json:"sha256"`
+	StorageKey  string         `gorm:"type:varchar(255);not null" json:"-"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Статусы выгрузки данных пользователя.
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusRunning = "running"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+	DataExportStatusExpired = "expired"
+)
+
+// DataExport — асинхронная выгрузка данных пользователя (takeout). Архив хранится
+// до ExpiresAt; скачать его можно по одноразово выданной ссылке до LinkExpiresAt.
+type DataExport struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Status        string     `gorm:"type:varchar(16);not null;index" json:"status"`
+	ClaimedAt     *time.Time `json:"-"`
+	Error         string     `gorm:"type:varchar(255);default:''" json:"error,omitempty"`
+	Size          int64      `gorm:"default:0" json:"size"`
+	Archive       []byte     `json:"-"`
+	TokenHash     string     `gorm:"type:varchar(64);index" json:"-"`
+	LinkExpiresAt *time.Time `json:"link_expires_at,omitempty"`
+	ExpiresAt     *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// EmailVerificationToken подтверждает конкретный адрес: после смены email
+// старые токены перестают подходить. Храним только хэш.
+type EmailVerificationToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index;not null"`
+	Email     string    `gorm:"type:varchar(255);not null"`
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex:uniq_email_verifications_hash"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
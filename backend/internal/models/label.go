@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	LabelColorDefault  = "#64748b"
+	labelNameMaxLength = 64
+)
+
+var (
+	errLabelNameRequired = errors.New("label name is required")
+	errLabelNameTooLong  = errors.New("label name must be 64 characters or fewer")
+	errLabelColor        = errors.New("label color must be a hex color like #1e90ff")
+
+	labelColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+)
+
+// Label — метка задач. Личная метка принадлежит пользователю (OwnerID), метка
+// проекта (ProjectID) общая для всех его задач; заполнено ровно одно из полей.
+type Label struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OwnerID   *uint     `gorm:"index" json:"owner_id,omitempty"`
+	ProjectID *uint     `gorm:"index" json:"project_id,omitempty"`
+	Name      string    `gorm:"type:varchar(64);not null" json:"name"`
+	Color     string    `gorm:"type:varchar(7);not null;default:'#64748b'" json:"color"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	TasksCount int64 `gorm:"-" json:"tasks_count"`
+}
+
+// TaskLabel — связь задачи с меткой (многие ко многим).
+type TaskLabel struct {
+	TaskID    uint      `gorm:"primaryKey" json:"task_id"`
+	LabelID   uint      `gorm:"primaryKey;index" json:"label_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// NormalizeLabelName подрезает пробелы и проверяет длину имени метки.
+func NormalizeLabelName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errLabelNameRequired
+	}
+	if utf8.RuneCountInString(name) > labelNameMaxLength {
+		return "", errLabelNameTooLong
+	}
+	return name, nil
+}
+
+// NormalizeLabelColor приводит цвет к виду #rrggbb; пустое значение — LabelColorDefault.
+func NormalizeLabelColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" {
+		return LabelColorDefault, nil
+	}
+	if !strings.HasPrefix(color, "#") {
+		color = "#" + color
+	}
+	if !labelColorPattern.MatchString(color) {
+		return "", errLabelColor
+	}
+	return color, nil
+}
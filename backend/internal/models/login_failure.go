@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginFailure — неудачная попытка входа. По этим записям считаются задержки
+// и блокировки, поэтому ограничения общие для всех экземпляров сервера.
+type LoginFailure struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Identifier string    `gorm:"type:varchar(255);index:idx_login_failures_identifier" json:"identifier"`
+	IP         string    `gorm:"type:varchar(64);index:idx_login_failures_ip" json:"ip"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	milestoneNameMaxLength = 255
+	milestoneGoalMaxLength = 2000
+)
+
+var (
+	errMilestoneNameRequired = errors.New("milestone name is required")
+	errMilestoneNameTooLong  = errors.New("milestone name must be 255 characters or fewer")
+	errMilestoneGoalTooLong  = errors.New("milestone goal must be 2000 characters or fewer")
+)
+
+// Milestone — спринт или веха проекта: отрезок дат с целью. Задача входит
+// не более чем в один спринт (Task.MilestoneID); задачи без спринта — бэклог.
+type Milestone struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID uint   `gorm:"index;not null" json:"project_id"`
+	Name      string `gorm:"type:varchar(255);not null" json:"name"`
+	Goal      string `gorm:"type:text" json:"goal"`
+	// Даты — дни в UTC (полночь), конец включительно.
+	StartDate time.Time  `gorm:"not null;index" json:"start_date"`
+	EndDate   time.Time  `gorm:"not null" json:"end_date"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+
+	TasksCount     int64 `gorm:"-" json:"tasks_count"`
+	CompletedCount int64 `gorm:"-" json:"completed_count"`
+}
+
+func (m *Milestone) Closed() bool {
+	return m.ClosedAt != nil
+}
+
+func NormalizeMilestoneName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errMilestoneNameRequired
+	}
+	if utf8.RuneCountInString(name) > milestoneNameMaxLength {
+		return "", errMilestoneNameTooLong
+	}
+	return name, nil
+}
+
+func NormalizeMilestoneGoal(goal string) (string, error) {
+	goal = strings.TrimSpace(goal)
+	if utf8.RuneCountInString(goal) > milestoneGoalMaxLength {
+		return "", errMilestoneGoalTooLong
+	}
+	return goal, nil
+}
+
+// MilestoneBurndown — остаток работы спринта по дням.
+type MilestoneBurndown struct {
+	MilestoneID uint   `json:"milestone_id"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	TotalTasks  int64  `json:"total_tasks"`
+	// TotalPoints — сумма story points; неоценённые задачи считаются как 0.
+	TotalPoints float64       `json:"total_points"`
+	Days        []BurndownDay `json:"days"`
+}
+
+// BurndownDay — остаток на конец дня. Remaining* пусты для дней, которые ещё не наступили;
+// Ideal* — равномерное сгорание от полного объёма до нуля к последнему дню.
+type BurndownDay struct {
+	Date            string   `json:"date"`
+	RemainingTasks  *int64   `json:"remaining_tasks"`
+	RemainingPoints *float64 `json:"remaining_points"`
+	IdealTasks      float64  `json:"ideal_tasks"`
+	IdealPoints     float64  `json:"ideal_points"`
+}
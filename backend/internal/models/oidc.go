@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// OIDCIdentity связывает учётную запись провайдера (issuer + sub) с пользователем.
+type OIDCIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"-"`
+	Issuer    string    `gorm:"type:varchar(255);not null;uniqueIndex:uniq_oidc_identities_subject" json:"issuer"`
+	Subject   string    `gorm:"type:varchar(255);not null;uniqueIndex:uniq_oidc_identities_subject" json:"subject"`
+	Email     string    `gorm:"type:varchar(255)" json:"email"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	LastLogin time.Time `json:"last_login"`
+}
+
+// OIDCAuthRequest — незавершённый вход через провайдера: state (хэш), nonce и PKCE verifier.
+type OIDCAuthRequest struct {
+	ID           uint      `gorm:"primaryKey"`
+	StateHash    string    `gorm:"type:varchar(64);uniqueIndex:uniq_oidc_auth_requests_state"`
+	Nonce        string    `gorm:"type:varchar(64);not null"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
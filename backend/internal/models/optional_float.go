@@ -0,0 +1,23 @@
+package models
+
+import "encoding/json"
+
+// OptionalFloat — число с флагом присутствия поля в payload; null сбрасывает значение.
+type OptionalFloat struct {
+	Value   *float64
+	Present bool
+}
+
+func (of *OptionalFloat) UnmarshalJSON(data []byte) error {
+	of.Present = true
+	if string(data) == "null" {
+		of.Value = nil
+		return nil
+	}
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	of.Value = &value
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
+
+// OutboxEmail — письмо в очереди на отправку. Бизнес-логика только пишет сюда,
+// доставку выполняет фоновый диспетчер, поэтому сбой SMTP не ломает запрос пользователя.
+type OutboxEmail struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Recipient string     `gorm:"type:varchar(255);not null;index" json:"recipient"`
+	Subject   string     `gorm:"type:varchar(255);not null" json:"subject"`
+	Body      string     `gorm:"type:text" json:"body"`
+	Kind      string     `gorm:"type:varchar(32);index" json:"kind"`
+	Status    string     `gorm:"type:varchar(16);default:pending;index" json:"status"`
+	Attempts  int        `gorm:"default:0" json:"attempts"`
+	LastError string     `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PasswordResetToken — одноразовый токен восстановления пароля. Храним только хэш.
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index;not null"`
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex:uniq_password_resets_hash"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
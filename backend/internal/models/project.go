@@ -25,6 +25,9 @@ const (
 
 const DefaultProjectTasksLimit = 100
 
+// Роль участника проекта: пока только просмотр, изменять проект может владелец.
+const ProjectMemberRoleViewer = "viewer"
+
 var (
 	errInvalidProjectStatus   = errors.New("invalid project status")
 	errInvalidProjectPriority = errors.New("invalid project priority")
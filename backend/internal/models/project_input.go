@@ -20,3 +20,15 @@ type ProjectFromTasksPayload struct {
 	TaskIDs          []uint       `json:"task_ids"`
 	ReassignAttached bool         `json:"reassign_attached"`
 }
+
+// ProjectDuplicateOptions управляет копированием проекта.
+type ProjectDuplicateOptions struct {
+	// Title — название копии; по умолчанию «<название> (copy)».
+	Title string `json:"title"`
+	// ResetStatuses возвращает задачи в todo, а проект — в planned.
+	ResetStatuses bool `json:"reset_statuses"`
+	// ShiftDays сдвигает срок проекта и даты задач на столько дней (может быть отрицательным).
+	ShiftDays int `json:"shift_days"`
+	// IncludeDeleted копирует и удалённые задачи, в том числе скрытые архивированием проекта.
+	IncludeDeleted bool `json:"include_deleted"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Ограничения шаблонов.
+const (
+	TemplateTasksMax      = 500
+	TemplateOffsetMaxDays = 3660
+)
+
+// TaskBlueprint — задача шаблона. Даты заданы смещением в днях от даты старта
+// проекта; у задач со временем (не all_day) ещё и минутами от начала дня (UTC).
+type TaskBlueprint struct {
+	Title           string   `json:"title"`
+	Description     string   `json:"description,omitempty"`
+	Priority        string   `json:"priority,omitempty"`
+	Stage           string   `json:"stage,omitempty"`
+	StartOffsetDays *int     `json:"start_offset_days,omitempty"`
+	EndOffsetDays   *int     `json:"end_offset_days,omitempty"`
+	StartMinutes    int      `json:"start_minutes,omitempty"`
+	EndMinutes      int      `json:"end_minutes,omitempty"`
+	AllDay          bool     `json:"all_day,omitempty"`
+	StoryPoints     *float64 `json:"story_points,omitempty"`
+	EstimateHours   *float64 `json:"estimate_hours,omitempty"`
+}
+
+// ProjectTemplate — шаблон проекта: настройки, теги и задачи-заготовки.
+// Владелец может открыть шаблон другим пользователям (ProjectTemplateShare).
+type ProjectTemplate struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	OwnerID     uint   `gorm:"index;not null" json:"owner_id"`
+	Name        string `gorm:"type:varchar(255);not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+
+	// Настройки создаваемого проекта.
+	ProjectTitle       string                              `gorm:"type:varchar(255);not null" json:"project_title"`
+	ProjectDescription string                              `gorm:"type:text" json:"project_description"`
+	Priority           string                              `gorm:"type:varchar(16);default:medium" json:"priority"`
+	TasksLimit         int                                 `gorm:"default:100" json:"tasks_limit"`
+	Tags               datatypes.JSONType[[]string]        `gorm:"type:jsonb" json:"tags"`
+	DeadlineOffsetDays *int                                `json:"deadline_offset_days,omitempty"`
+	Tasks              datatypes.JSONType[[]TaskBlueprint] `gorm:"type:jsonb" json:"tasks"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// Shares заполняется только для владельца.
+	Shares []ProjectTemplateShare `gorm:"foreignKey:TemplateID" json:"shares,omitempty"`
+}
+
+// ProjectTemplateShare — пользователь, которому открыт шаблон: он видит его
+// и создаёт по нему проекты, но не меняет.
+type ProjectTemplateShare struct {
+	TemplateID uint      `gorm:"primaryKey" json:"template_id"`
+	UserID     uint      `gorm:"primaryKey;index" json:"user_id"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
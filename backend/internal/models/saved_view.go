@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ViewFilter — условия выборки задач, те же, что у GET /api/tasks.
+type ViewFilter struct {
+	Status    string `json:"status,omitempty"`
+	Priority  string `json:"priority,omitempty"`
+	Stage     string `json:"stage,omitempty"`
+	ProjectID string `json:"project_id,omitempty"` // id проекта или "none"
+	LabelIDs  []uint `json:"label_ids,omitempty"`
+	LabelMode string `json:"label_mode,omitempty"`
+	Sort      string `json:"sort,omitempty"`
+	Query     string `json:"query,omitempty"` // выражение на языке taskquery
+}
+
+// SavedView — именованный фильтр задач пользователя. С SharedProjectID
+// представление видят (но не меняют) участники этого проекта.
+type SavedView struct {
+	ID              uint                           `gorm:"primaryKey" json:"id"`
+	OwnerID         uint                           `gorm:"index;not null" json:"owner_id"`
+	Name            string                         `gorm:"type:varchar(128);not null" json:"name"`
+	Filter          datatypes.JSONType[ViewFilter] `gorm:"type:jsonb" json:"filter"`
+	SharedProjectID *uint                          `gorm:"index" json:"shared_project_id,omitempty"`
+	CreatedAt       time.Time                      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time                      `gorm:"autoUpdateTime" json:"updated_at"`
+
+	TasksCount int64 `gorm:"-" json:"tasks_count"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Типы событий журнала безопасности.
+const (
+	SecurityEventLogin             = "login"
+	SecurityEventLogout            = "logout"
+	SecurityEventPasswordChange    = "password_change"
+	SecurityEventPasswordReset     = "password_reset"
+	SecurityEventEmailChange       = "email_change"
+	SecurityEventProfileUpdate     = "profile_update"
+	SecurityEventSettingsChange    = "settings_change"
+	SecurityEventTwoFactorEnable   = "two_factor_enable"
+	SecurityEventTwoFactorDisable  = "two_factor_disable"
+	SecurityEventTokenCreate       = "token_create"
+	SecurityEventTokenRevoke       = "token_revoke"
+	SecurityEventAccountDeactivate = "account_deactivate"
+	SecurityEventAccountReactivate = "account_reactivate"
+	SecurityEventAccountDelete     = "account_delete"
+	SecurityEventDataExport        = "data_export"
+	SecurityEventDataDownload      = "data_export_download"
+	SecurityEventDataImport        = "data_import"
+)
+
+// Результат события.
+const (
+	SecurityOutcomeSuccess = "success"
+	SecurityOutcomeFailure = "failure"
+)
+
+// SecurityEvent — запись журнала безопасности. UserID — чей аккаунт затронут
+// (nil, если аккаунт не найден, например при входе с неизвестным email), ActorID — кто действовал.
+type SecurityEvent struct {
+	ID        uint              `gorm:"primaryKey" json:"id"`
+	UserID    *uint             `gorm:"index" json:"user_id,omitempty"`
+	ActorID   *uint             `json:"actor_id,omitempty"`
+	EventType string            `gorm:"type:varchar(64);not null;index" json:"event_type"`
+	Outcome   string            `gorm:"type:varchar(16);not null;index" json:"outcome"`
+	IP        string            `gorm:"type:varchar(64);index" json:"ip"`
+	UserAgent string            `gorm:"type:varchar(255)" json:"user_agent"`
+	Details   datatypes.JSONMap `gorm:"type:jsonb" json:"details,omitempty"`
+	CreatedAt time.Time         `gorm:"autoCreateTime;index" json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Session — серверная сессия входа. Access-токены ссылаются на неё через claim sid,
+// поэтому отзыв сессии сразу делает недействительными все выданные для неё токены.
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IP         string     `gorm:"type:varchar(64)" json:"ip"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+
+	Current bool `gorm:"-" json:"current"`
+}
+
+// RefreshToken — одно звено цепочки ротации внутри сессии.
+// Храним только SHA-256 хэш; UsedAt выставляется при обмене на новую пару.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"-"`
+	SessionID uint       `gorm:"index;not null" json:"-"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex:uniq_refresh_tokens_hash" json:"-"`
+	ExpiresAt time.Time  `json:"-"`
+	UsedAt    *time.Time `json:"-"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"-"`
+}
@@ -0,0 +1,67 @@
+package models
+
+// TaskStats — сводка по задачам для дашборда.
+type TaskStats struct {
+	Total       int64            `json:"total"`
+	Completed   int64            `json:"completed"`
+	Overdue     int64            `json:"overdue"`
+	DueThisWeek int64            `json:"due_this_week"`
+	ByStatus    map[string]int64 `json:"by_status"`
+	ByPriority  map[string]int64 `json:"by_priority"`
+	ByStage     map[string]int64 `json:"by_stage"`
+
+	// Daily — созданные и завершённые задачи по дням диапазона From..To (включительно).
+	From  string     `json:"from"`
+	To    string     `json:"to"`
+	Daily []DayStats `json:"daily"`
+
+	// Projects — завершённость доступных проектов; только в общей сводке.
+	Projects []ProjectCompletion `json:"projects,omitempty"`
+}
+
+// DayStats — активность за день (YYYY-MM-DD).
+type DayStats struct {
+	Date      string `json:"date"`
+	Created   int64  `json:"created"`
+	Completed int64  `json:"completed"`
+}
+
+// ProjectCompletion — доля завершённых задач проекта в процентах.
+type ProjectCompletion struct {
+	ProjectID uint    `json:"project_id"`
+	Title     string  `json:"title"`
+	Total     int64   `json:"total"`
+	Completed int64   `json:"completed"`
+	Percent   float64 `json:"percent"`
+}
+
+// ProjectVelocity — story points, завершённые по неделям, и прогноз окончания работ.
+type ProjectVelocity struct {
+	ProjectID uint           `json:"project_id"`
+	Weeks     []WeekVelocity `json:"weeks"`
+	// Средние за полную неделю; текущая неделя в среднее не входит.
+	AveragePoints float64          `json:"average_points"`
+	AverageHours  float64          `json:"average_hours"`
+	Forecast      VelocityForecast `json:"forecast"`
+}
+
+// WeekVelocity — оценки задач, завершённых за неделю с понедельника WeekStart.
+type WeekVelocity struct {
+	WeekStart string  `json:"week_start"`
+	Points    float64 `json:"points"`
+	Hours     float64 `json:"hours"`
+	Tasks     int64   `json:"tasks"`
+	// Partial — текущая, ещё не закончившаяся неделя.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// VelocityForecast — оставшаяся работа и когда она закончится при средней скорости.
+// WeeksLeft и CompletionDate пусты, если скорость нулевая, а работа ещё есть.
+type VelocityForecast struct {
+	RemainingTasks   int64    `json:"remaining_tasks"`
+	UnestimatedTasks int64    `json:"unestimated_tasks"`
+	RemainingPoints  float64  `json:"remaining_points"`
+	RemainingHours   float64  `json:"remaining_hours"`
+	WeeksLeft        *float64 `json:"weeks_left"`
+	CompletionDate   *string  `json:"completion_date"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"math"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -33,10 +34,18 @@ const (
 
 const stageMaxLength = 64
 
+// Верхние границы оценок: больше — почти наверняка опечатка.
+const (
+	StoryPointsMax   = 1000
+	EstimateHoursMax = 10000
+)
+
 var (
-	errInvalidPriority = errors.New("priority must be low, medium or high")
-	errStageTooLong    = errors.New("stage must be 64 characters or fewer")
-	errInvalidStatus   = errors.New("invalid task status")
+	errInvalidPriority    = errors.New("priority must be low, medium or high")
+	errStageTooLong       = errors.New("stage must be 64 characters or fewer")
+	errInvalidStatus      = errors.New("invalid task status")
+	errInvalidStoryPoints = errors.New("story_points must be between 0 and 1000")
+	errInvalidEstimate    = errors.New("estimate_hours must be between 0 and 10000")
 )
 
 var validPriorities = map[string]struct{}{
@@ -64,16 +73,28 @@ type Task struct {
 	Title       string `gorm:"type:varchar(255);not null" json:"title"`
 	Description string `gorm:"type:text" json:"description"`
 
-	Status         string     `gorm:"type:varchar(32);default:todo;index:idx_tasks_status" json:"status"`
-	PreviousStatus string     `gorm:"type:varchar(32);default:'';" json:"previous_status,omitempty"`
-	Priority       string     `gorm:"type:varchar(16);default:medium;index:idx_tasks_priority" json:"priority"`
-	Stage          string     `gorm:"type:varchar(64);default:todo;index:idx_tasks_stage" json:"stage"`
-	StartAt        *time.Time `json:"start_at,omitempty"`
-	EndAt          *time.Time `json:"end_at,omitempty"`
-	AllDay         bool       `json:"all_day"`
+	Status         string `gorm:"type:varchar(32);default:todo;index:idx_tasks_status" json:"status"`
+	PreviousStatus string `gorm:"type:varchar(32);default:'';" json:"previous_status,omitempty"`
+	Priority       string `gorm:"type:varchar(16);default:medium;index:idx_tasks_priority" json:"priority"`
+	Stage          string `gorm:"type:varchar(64);default:todo;index:idx_tasks_stage" json:"stage"`
+	// StartAt и EndAt — интервал в календаре; составной индекс нужен запросу пересечения с диапазоном.
+	StartAt *time.Time `gorm:"index:idx_tasks_schedule,priority:1" json:"start_at,omitempty"`
+	EndAt   *time.Time `gorm:"index:idx_tasks_schedule,priority:2" json:"end_at,omitempty"`
+	AllDay  bool       `json:"all_day"`
+	// CompletedAt — когда задача перешла в completed; сбрасывается при возврате в работу.
+	CompletedAt *time.Time `gorm:"index" json:"completed_at,omitempty"`
+
+	// Оценка размера: story points и/или часы; nil — задача не оценена.
+	StoryPoints   *float64 `json:"story_points,omitempty"`
+	EstimateHours *float64 `json:"estimate_hours,omitempty"`
 
 	ProjectID *uint    `gorm:"index" json:"project_id,omitempty"`
 	Project   *Project `json:"project,omitempty"`
+	// MilestoneID — спринт проекта задачи; сбрасывается при смене проекта.
+	MilestoneID *uint `gorm:"index" json:"milestone_id,omitempty"`
+
+	// Метки, видимые запрашивающему: метки проекта и его личные. Заполняются при выдаче списка.
+	Labels []Label `gorm:"-" json:"labels,omitempty"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime;index:idx_tasks_created_at" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
@@ -117,14 +138,38 @@ func NormalizeStage(stage string) (string, error) {
 	return stage, nil
 }
 
+// NormalizeEstimates проверяет оценки задачи и округляет их до сотых.
+func (t *Task) NormalizeEstimates() error {
+	var err error
+	if t.StoryPoints, err = normalizeEstimate(t.StoryPoints, StoryPointsMax, errInvalidStoryPoints); err != nil {
+		return err
+	}
+	t.EstimateHours, err = normalizeEstimate(t.EstimateHours, EstimateHoursMax, errInvalidEstimate)
+	return err
+}
+
+func normalizeEstimate(value *float64, limit float64, invalid error) (*float64, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if math.IsNaN(*value) || *value < 0 || *value > limit {
+		return nil, invalid
+	}
+	rounded := math.Round(*value*100) / 100
+	return &rounded, nil
+}
+
 // ApplyStatusTransition обновляет статус и previous_status согласно правилам чекбокса.
 func (t *Task) ApplyStatusTransition(next string) {
 	if next == StatusCompleted && t.Status != StatusCompleted {
 		t.PreviousStatus = t.Status
+		now := time.Now()
+		t.CompletedAt = &now
 	}
 	if next != StatusCompleted && t.Status == StatusCompleted {
 		// сбрасываем previous_status, так как задача возвращается в активное состояние
 		t.PreviousStatus = ""
+		t.CompletedAt = nil
 	}
 	t.Status = next
 }
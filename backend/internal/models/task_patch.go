@@ -11,6 +11,9 @@ type TaskPatch struct {
 	StartAt     OptionalTime `json:"start_at"`
 	EndAt       OptionalTime `json:"end_at"`
 	AllDay      *bool        `json:"all_day,omitempty"`
+
+	StoryPoints   OptionalFloat `json:"story_points"`
+	EstimateHours OptionalFloat `json:"estimate_hours"`
 }
 
 func (p TaskPatch) ApplyTo(t *Task) {
@@ -30,6 +33,10 @@ func (p TaskPatch) ApplyTo(t *Task) {
 		t.Stage = *p.Stage
 	}
 	if p.ProjectID != nil {
+		if t.ProjectID == nil || *t.ProjectID != *p.ProjectID {
+			// спринты принадлежат проекту и вместе с задачей не переезжают
+			t.MilestoneID = nil
+		}
 		t.ProjectID = p.ProjectID
 	}
 	if p.StartAt.Present {
@@ -41,6 +48,12 @@ func (p TaskPatch) ApplyTo(t *Task) {
 	if p.AllDay != nil {
 		t.AllDay = *p.AllDay
 	}
+	if p.StoryPoints.Present {
+		t.StoryPoints = p.StoryPoints.Value
+	}
+	if p.EstimateHours.Present {
+		t.EstimateHours = p.EstimateHours.Value
+	}
 }
 
 // IsEmpty помогает понять, пришли ли какие-либо поля в патче.
@@ -53,5 +66,7 @@ func (p TaskPatch) IsEmpty() bool {
 		p.ProjectID == nil &&
 		!p.StartAt.Present &&
 		!p.EndAt.Present &&
-		p.AllDay == nil
+		p.AllDay == nil &&
+		!p.StoryPoints.Present &&
+		!p.EstimateHours.Present
 }
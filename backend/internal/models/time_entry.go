@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// TimeEntry — отрезок времени, потраченный пользователем на задачу. Пока таймер
+// идёт, EndedAt пуст; у пользователя может быть только одна такая запись.
+// Проект не хранится: отчёты берут его из задачи, поэтому записи переезжают
+// вместе с задачей при переносе между проектами.
+type TimeEntry struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	TaskID          uint       `gorm:"index;not null" json:"task_id"`
+	UserID          uint       `gorm:"index;not null;uniqueIndex:idx_time_entries_running,where:ended_at IS NULL" json:"user_id"`
+	StartedAt       time.Time  `gorm:"index;not null" json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationSeconds int64      `gorm:"not null;default:0" json:"duration_seconds"`
+	Note            string     `gorm:"type:varchar(500)" json:"note"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Running — идёт ли таймер.
+func (e *TimeEntry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// TimeReportRow — сумма времени по одной группе отчёта.
+type TimeReportRow struct {
+	Key     string `json:"key"`
+	Label   string `json:"label"`
+	Seconds int64  `json:"seconds"`
+	Entries int64  `json:"entries"`
+}
+
+// TimeReport — отчёт по затраченному времени за период From..To (включительно).
+type TimeReport struct {
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	GroupBy      string          `json:"group_by"`
+	TotalSeconds int64           `json:"total_seconds"`
+	Rows         []TimeReportRow `json:"rows"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecoveryCode — одноразовый резервный код 2FA. Храним только хэш.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
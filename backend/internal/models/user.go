@@ -2,6 +2,24 @@ package models
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Тарифы. Их ограничения настраиваются на сервере (services.QuotaPlan).
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+)
+
+// Статусы учётной записи
+const (
+	AccountStatusActive      = "active"
+	AccountStatusSuspended   = "suspended"   // заблокирован администратором
+	AccountStatusDeactivated = "deactivated" // отключён самим пользователем, можно вернуть входом
+)
+
 // User — учетная запись
 type User struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
@@ -13,7 +31,51 @@ type User struct {
 	AvatarURL   string    `gorm:"type:text" json:"avatar_url,omitempty"`
 	Language    string    `gorm:"type:varchar(16);default:'en'" json:"language"`
 	Theme       string    `gorm:"type:varchar(16);default:'light'" json:"theme"`
+	TimeZone    string    `gorm:"type:varchar(64);default:UTC" json:"time_zone"` // пояс IANA: по нему считаются границы дней
 	MaxProjects int       `gorm:"default:50" json:"max_projects"`
+	Plan        string    `gorm:"type:varchar(32);default:free" json:"plan"`
 	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"autoUpdateTime"  json:"updated_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"` // nil — адрес не подтверждён
+
+	// Загруженный аватар лежит в blob-хранилище: версия — префикс SHA-256 содержимого,
+	// формат — jpeg/png/gif (webp — у загруженных раньше, без миниатюр). Для внешней ссылки в AvatarURL оба поля пустые.
+	AvatarVersion string `gorm:"type:varchar(32);default:''" json:"-"`
+	AvatarFormat  string `gorm:"type:varchar(8);default:''" json:"-"`
+
+	// Статус учётной записи: вход и доступ к API есть только у active.
+	Status        string     `gorm:"type:varchar(16);default:active;index" json:"status"`
+	StatusReason  string     `gorm:"type:varchar(255);default:''" json:"status_reason,omitempty"`
+	SuspendedAt   *time.Time `json:"suspended_at,omitempty"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+
+	// Двухфакторная аутентификация (TOTP). Секрет появляется при начале подключения,
+	// TwoFactorEnabled — только после подтверждения первым кодом.
+	TOTPSecret       string `gorm:"type:varchar(64)" json:"-"`
+	TOTPLastStep     int64  `gorm:"default:0" json:"-"`
+	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"`
+}
+
+// IsActive сообщает, может ли пользователь входить и работать с API.
+// Пустой статус встречается у записей, созданных до появления статусов.
+func (u *User) IsActive() bool {
+	return u.Status == "" || u.Status == AccountStatusActive
+}
+
+// IsEmailVerified сообщает, подтверждён ли текущий email пользователя.
+func (u *User) IsEmailVerified() bool {
+	return u.Email != "" && u.EmailVerifiedAt != nil
+}
+
+// Location — часовой пояс пользователя; UTC, если пояс не задан или неизвестен.
+func (u *User) Location() *time.Location {
+	if u == nil || u.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
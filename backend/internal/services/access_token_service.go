@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+const maxAccessTokensPerUser = 50
+
+var (
+	ErrAccessTokenInvalid  = errors.New("invalid access token")
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrAccessTokenLimit    = errors.New("access token limit reached")
+)
+
+// AccessTokenInput — параметры создания персонального токена.
+type AccessTokenInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAccessToken возвращается один раз при создании: открытое значение больше нигде не хранится.
+type CreatedAccessToken struct {
+	models.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// AccessTokenService управляет персональными токенами доступа (PAT).
+type AccessTokenService struct {
+	tokens *storage.AccessTokenStorage
+	users  *storage.UserStorage
+	now    func() time.Time
+}
+
+func NewAccessTokenService(tokens *storage.AccessTokenStorage, users *storage.UserStorage) *AccessTokenService {
+	return &AccessTokenService{tokens: tokens, users: users, now: time.Now}
+}
+
+func (s *AccessTokenService) List(userID uint) ([]models.PersonalAccessToken, error) {
+	return s.tokens.ListByUser(userID)
+}
+
+func (s *AccessTokenService) Create(userID uint, input AccessTokenInput) (*CreatedAccessToken, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return nil, errors.New("name must be 100 characters or fewer")
+	}
+	scopes, err := models.NormalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(s.now()) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+
+	count, err := s.tokens.CountActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAccessTokensPerUser {
+		return nil, ErrAccessTokenLimit
+	}
+
+	secret, _, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	raw := models.PersonalAccessTokenPrefix + secret
+	token := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: HashOpaqueToken(raw),
+		Hint:      raw[len(raw)-4:],
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.tokens.Create(&token); err != nil {
+		return nil, err
+	}
+	return &CreatedAccessToken{PersonalAccessToken: token, Token: raw}, nil
+}
+
+func (s *AccessTokenService) Revoke(userID, id uint) error {
+	revoked, err := s.tokens.Revoke(userID, id, s.now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateToken проверяет открытое значение PAT и возвращает владельца и области доступа.
+func (s *AccessTokenService) AuthenticateToken(raw string) (uint, string, []string, error) {
+	token, err := s.tokens.GetByHash(HashOpaqueToken(raw))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, "", nil, ErrAccessTokenInvalid
+		}
+		return 0, "", nil, err
+	}
+	now := s.now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && !now.Before(*token.ExpiresAt)) {
+		return 0, "", nil, ErrAccessTokenInvalid
+	}
+	user, err := s.users.GetByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, "", nil, ErrAccessTokenInvalid
+		}
+		return 0, "", nil, err
+	}
+	if !user.IsActive() {
+		return 0, "", nil, ErrAccessTokenInvalid
+	}
+	if err := s.tokens.TouchLastUsed(token.ID, now); err != nil {
+		return 0, "", nil, err
+	}
+	return user.ID, user.Role, []string(token.Scopes), nil
+}
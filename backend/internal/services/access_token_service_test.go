@@ -0,0 +1,64 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessTokenService_CreateAndAuthenticate(t *testing.T) {
+	db := setupTestDB(t)
+	user := &models.User{Email: "ci@example.com", Username: "ci", Password: "hash", Role: "user"}
+	require.NoError(t, db.Create(user).Error)
+	service := NewAccessTokenService(storage.NewAccessTokenStorage(db), storage.NewUserStorage(db))
+
+	created, err := service.Create(user.ID, AccessTokenInput{
+		Name:   "CI",
+		Scopes: []string{"tasks:write", "tasks:write", " projects:read "},
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(created.Token, models.PersonalAccessTokenPrefix))
+	require.Equal(t, []string{models.ScopeTasksWrite, models.ScopeProjectsRead}, []string(created.Scopes))
+
+	// В БД лежит только хэш.
+	var stored models.PersonalAccessToken
+	require.NoError(t, db.First(&stored, created.ID).Error)
+	require.NotContains(t, stored.TokenHash, created.Token)
+	require.Equal(t, HashOpaqueToken(created.Token), stored.TokenHash)
+
+	userID, role, scopes, err := service.AuthenticateToken(created.Token)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, userID)
+	require.Equal(t, "user", role)
+	require.ElementsMatch(t, []string{models.ScopeTasksWrite, models.ScopeProjectsRead}, scopes)
+
+	require.NoError(t, service.Revoke(user.ID, created.ID))
+	_, _, _, err = service.AuthenticateToken(created.Token)
+	require.ErrorIs(t, err, ErrAccessTokenInvalid)
+}
+
+func TestAccessTokenService_ValidatesInputAndExpiry(t *testing.T) {
+	db := setupTestDB(t)
+	user := &models.User{Email: "bot@example.com", Username: "bot", Password: "hash"}
+	require.NoError(t, db.Create(user).Error)
+	service := NewAccessTokenService(storage.NewAccessTokenStorage(db), storage.NewUserStorage(db))
+
+	_, err := service.Create(user.ID, AccessTokenInput{Name: "bad", Scopes: []string{"everything"}})
+	require.Error(t, err)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = service.Create(user.ID, AccessTokenInput{Name: "old", Scopes: []string{"tasks:read"}, ExpiresAt: &past})
+	require.Error(t, err)
+
+	future := time.Now().Add(time.Hour)
+	created, err := service.Create(user.ID, AccessTokenInput{Name: "short", Scopes: []string{"tasks:read"}, ExpiresAt: &future})
+	require.NoError(t, err)
+
+	service.now = func() time.Time { return future.Add(time.Minute) }
+	_, _, _, err = service.AuthenticateToken(created.Token)
+	require.ErrorIs(t, err, ErrAccessTokenInvalid)
+}
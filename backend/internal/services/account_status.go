@@ -0,0 +1,24 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+)
+
+var (
+	ErrAccountSuspended   = errors.New("account is suspended")
+	ErrAccountDeactivated = errors.New("account is deactivated")
+)
+
+// AccountStatusError возвращает nil для активного аккаунта и причину отказа для остальных.
+func AccountStatusError(user *models.User) error {
+	switch {
+	case user.IsActive():
+		return nil
+	case user.Status == models.AccountStatusDeactivated:
+		return ErrAccountDeactivated
+	default:
+		return ErrAccountSuspended
+	}
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	adminDefaultPageSize  = 50
+	adminMaxPageSize      = 200
+	maxProjectsUpperLimit = 10000
+)
+
+var (
+	ErrAdminUserNotFound     = errors.New("user not found")
+	ErrAdminSelfAction       = errors.New("administrators cannot change their own role or suspend themselves")
+	ErrAdminLastAdmin        = errors.New("cannot remove the last active administrator")
+	ErrAdminInvalidRole      = errors.New("role must be user or admin")
+	ErrAdminInvalidLimit     = errors.New("max_projects must be between 0 and 10000")
+	ErrAdminLimitAbovePlan   = errors.New("max_projects cannot exceed the plan's project limit")
+	ErrAdminAlreadySuspended = errors.New("account is already suspended")
+	ErrAdminAlreadyActive    = errors.New("account is already active")
+)
+
+// AdminUser — пользователь в админке вместе с потреблением ресурсов.
+type AdminUser struct {
+	models.User
+	Usage models.UserUsage `json:"usage"`
+}
+
+// AdminUserList — страница результатов поиска.
+type AdminUserList struct {
+	Users []AdminUser `json:"users"`
+	Total int64       `json:"total"`
+}
+
+// AdminUserPatch — изменяемые администратором поля; nil — не менять.
+type AdminUserPatch struct {
+	MaxProjects *int    `json:"max_projects"`
+	Role        *string `json:"role"`
+	Plan        *string `json:"plan"`
+}
+
+// AdminService — операции администраторов над пользователями. Каждое изменение
+// записывается в журнал admin_audit_entries.
+type AdminService struct {
+	admin         *storage.AdminStorage
+	users         *storage.UserStorage
+	tokens        *storage.AccessTokenStorage
+	sessions      *SessionService
+	passwordReset *PasswordResetService
+	throttle      *LoginThrottleService
+	now           func() time.Time
+}
+
+func NewAdminService(admin *storage.AdminStorage, users *storage.UserStorage, tokens *storage.AccessTokenStorage, sessions *SessionService, passwordReset *PasswordResetService, throttle *LoginThrottleService) *AdminService {
+	return &AdminService{
+		admin:         admin,
+		users:         users,
+		tokens:        tokens,
+		sessions:      sessions,
+		passwordReset: passwordReset,
+		throttle:      throttle,
+		now:           time.Now,
+	}
+}
+
+// ListUsers ищет пользователей и добавляет к каждому число проектов и задач.
+func (s *AdminService) ListUsers(query string, limit, offset int) (*AdminUserList, error) {
+	limit, offset = normalizePage(limit, offset)
+	users, total, err := s.admin.SearchUsers(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	usage, err := s.admin.UsageByUsers(ids)
+	if err != nil {
+		return nil, err
+	}
+	list := &AdminUserList{Users: make([]AdminUser, len(users)), Total: total}
+	for i, u := range users {
+		list.Users[i] = AdminUser{User: u, Usage: usage[u.ID]}
+	}
+	return list, nil
+}
+
+func (s *AdminService) GetUser(userID uint) (*AdminUser, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withUsage(user)
+}
+
+// UpdateUser меняет тариф, лимит проектов и/или роль. Смена тарифа без явного
+// max_projects выставляет личный лимит по тарифу, иначе прежний лимит free
+// продолжал бы действовать как более строгий.
+// Все изменения и записи журнала идут одной транзакцией: ошибка в любом поле
+// откатывает патч целиком.
+func (s *AdminService) UpdateUser(actorID, userID uint, patch AdminUserPatch) (*AdminUser, error) {
+	var user *models.User
+	err := s.admin.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = s.withTx(tx).updateUser(actorID, userID, patch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withUsage(user)
+}
+
+// withTx возвращает копию сервиса, чьи хранилища работают поверх tx.
+func (s *AdminService) withTx(tx *gorm.DB) *AdminService {
+	users := storage.NewUserStorage(tx)
+	txs := *s
+	txs.admin = storage.NewAdminStorage(tx)
+	txs.users = users
+	txs.tokens = storage.NewAccessTokenStorage(tx)
+	txs.sessions = NewSessionService(storage.NewSessionStorage(tx), users)
+	if s.passwordReset != nil {
+		txs.passwordReset = NewPasswordResetService(storage.NewPasswordResetStorage(tx), users, txs.sessions, s.passwordReset.mailer)
+	}
+	return &txs
+}
+
+func (s *AdminService) updateUser(actorID, userID uint, patch AdminUserPatch) (*models.User, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Plan != nil {
+		name := strings.ToLower(strings.TrimSpace(*patch.Plan))
+		plan, ok := QuotaPlanByName(name)
+		if !ok {
+			return nil, ErrUnknownPlan
+		}
+		if name != user.Plan {
+			prev := user.Plan
+			user.Plan = name
+			if patch.MaxProjects == nil && plan.Projects <= maxProjectsUpperLimit {
+				user.MaxProjects = int(plan.Projects)
+			}
+			if err := s.users.Update(user); err != nil {
+				return nil, err
+			}
+			if err := s.audit(actorID, models.AdminActionChangePlan, &user.ID, map[string]any{"from": prev, "to": name}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if patch.MaxProjects != nil {
+		next := *patch.MaxProjects
+		if next < 0 || next > maxProjectsUpperLimit {
+			return nil, ErrAdminInvalidLimit
+		}
+		// Действует меньшее из max_projects и тарифа, поэтому лимит выше тарифа ничего
+		// бы не дал: для большего числа проектов нужен другой тариф.
+		if planName, plan := planOf(user); plan.Projects > 0 && int64(next) > plan.Projects {
+			return nil, fmt.Errorf("%w (%s allows %d)", ErrAdminLimitAbovePlan, planName, plan.Projects)
+		}
+		if next != user.MaxProjects {
+			prev := user.MaxProjects
+			user.MaxProjects = next
+			if err := s.users.Update(user); err != nil {
+				return nil, err
+			}
+			if err := s.audit(actorID, models.AdminActionUpdateMaxProjects, &user.ID, map[string]any{"from": prev, "to": next}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if patch.Role != nil {
+		role := strings.ToLower(strings.TrimSpace(*patch.Role))
+		if role != models.RoleUser && role != models.RoleAdmin {
+			return nil, ErrAdminInvalidRole
+		}
+		if role != user.Role {
+			if err := s.changeRole(actorID, user, role); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return user, nil
+}
+
+func (s *AdminService) changeRole(actorID uint, user *models.User, role string) error {
+	if actorID == user.ID {
+		return ErrAdminSelfAction
+	}
+	if user.Role == models.RoleAdmin {
+		if err := s.ensureAnotherAdmin(user); err != nil {
+			return err
+		}
+	}
+	prev := user.Role
+	user.Role = role
+	if err := s.users.Update(user); err != nil {
+		return err
+	}
+	// Роль зашита в access-токен: при понижении завершаем сессии, чтобы права пропали сразу.
+	if prev == models.RoleAdmin {
+		if err := s.sessions.RevokeAll(user.ID); err != nil {
+			return err
+		}
+	}
+	return s.audit(actorID, models.AdminActionChangeRole, &user.ID, map[string]any{"from": prev, "to": role})
+}
+
+// ForcePasswordReset обнуляет пароль, завершает сессии и отправляет ссылку для задания нового.
+// Изменения и запись журнала идут одной транзакцией, письмо — после её фиксации.
+func (s *AdminService) ForcePasswordReset(actorID, userID uint) error {
+	random, _, err := GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	hash, err := HashPassword(random)
+	if err != nil {
+		return err
+	}
+	var (
+		user *models.User
+		link string
+	)
+	err = s.admin.Transaction(func(tx *gorm.DB) error {
+		txs := s.withTx(tx)
+		var err error
+		if user, err = txs.loadUser(userID); err != nil {
+			return err
+		}
+		user.Password = hash
+		if err := txs.users.Update(user); err != nil {
+			return err
+		}
+		if err := txs.sessions.RevokeAll(user.ID); err != nil {
+			return err
+		}
+		if user.Email != "" {
+			if link, err = txs.passwordReset.issueResetLink(user); err != nil {
+				return err
+			}
+		}
+		return txs.audit(actorID, models.AdminActionForcePasswordReset, &user.ID, map[string]any{"emailed": link != ""})
+	})
+	if err != nil || link == "" {
+		return err
+	}
+	return s.passwordReset.mailResetLink(user, link)
+}
+
+// Suspend блокирует аккаунт: вход и доступ к API запрещены, сессии и персональные токены отозваны.
+func (s *AdminService) Suspend(actorID, userID uint, reason string) (*AdminUser, error) {
+	var user *models.User
+	err := s.admin.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = s.withTx(tx).suspend(actorID, userID, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withUsage(user)
+}
+
+func (s *AdminService) suspend(actorID, userID uint, reason string) (*models.User, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if actorID == user.ID {
+		return nil, ErrAdminSelfAction
+	}
+	if user.Status == models.AccountStatusSuspended {
+		return nil, ErrAdminAlreadySuspended
+	}
+	if user.Role == models.RoleAdmin {
+		if err := s.ensureAnotherAdmin(user); err != nil {
+			return nil, err
+		}
+	}
+	now := s.now()
+	reason = truncate(strings.TrimSpace(reason), 255)
+	user.Status = models.AccountStatusSuspended
+	user.StatusReason = reason
+	user.SuspendedAt = &now
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	if err := s.sessions.RevokeAll(user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.tokens.RevokeAllByUser(user.ID, now); err != nil {
+		return nil, err
+	}
+	if err := s.audit(actorID, models.AdminActionSuspend, &user.ID, map[string]any{"reason": reason}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Reactivate возвращает аккаунт в active (и заблокированный, и отключённый самим пользователем).
+// Отозванные сессии и токены не восстанавливаются.
+func (s *AdminService) Reactivate(actorID, userID uint) (*AdminUser, error) {
+	var user *models.User
+	err := s.admin.Transaction(func(tx *gorm.DB) error {
+		txs := s.withTx(tx)
+		var err error
+		if user, err = txs.loadUser(userID); err != nil {
+			return err
+		}
+		if user.IsActive() {
+			return ErrAdminAlreadyActive
+		}
+		prev := user.Status
+		user.Status = models.AccountStatusActive
+		user.StatusReason = ""
+		user.SuspendedAt = nil
+		user.DeactivatedAt = nil
+		if err := txs.users.Update(user); err != nil {
+			return err
+		}
+		return txs.audit(actorID, models.AdminActionReactivate, &user.ID, map[string]any{"from": prev})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withUsage(user)
+}
+
+// UnlockLogin снимает блокировку входа по идентификатору и/или IP.
+func (s *AdminService) UnlockLogin(actorID uint, identifier, ip string) (int64, error) {
+	removed, err := s.throttle.Unlock(identifier, ip)
+	if err != nil {
+		return removed, err
+	}
+	details := map[string]any{"identifier": identifier, "ip": ip, "cleared_failures": removed}
+	return removed, s.audit(actorID, models.AdminActionUnlockLogin, nil, details)
+}
+
+// AuditLog возвращает журнал действий; targetUserID = 0 — по всем пользователям.
+func (s *AdminService) AuditLog(targetUserID uint, limit, offset int) ([]models.AdminAuditEntry, error) {
+	limit, offset = normalizePage(limit, offset)
+	return s.admin.ListAuditEntries(targetUserID, limit, offset)
+}
+
+func (s *AdminService) loadUser(userID uint) (*models.User, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *AdminService) withUsage(user *models.User) (*AdminUser, error) {
+	usage, err := s.admin.UsageByUsers([]uint{user.ID})
+	if err != nil {
+		return nil, err
+	}
+	return &AdminUser{User: *user, Usage: usage[user.ID]}, nil
+}
+
+// ensureAnotherAdmin не даёт лишить систему последнего активного администратора.
+func (s *AdminService) ensureAnotherAdmin(user *models.User) error {
+	if !user.IsActive() {
+		return nil
+	}
+	count, err := s.admin.CountAdmins()
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrAdminLastAdmin
+	}
+	return nil
+}
+
+func (s *AdminService) audit(actorID uint, action string, targetUserID *uint, details map[string]any) error {
+	entry := &models.AdminAuditEntry{ActorID: actorID, Action: action, TargetUserID: targetUserID}
+	if details != nil {
+		entry.Details = datatypes.JSONMap(details)
+	}
+	return s.admin.CreateAuditEntry(entry)
+}
+
+func normalizePage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = adminDefaultPageSize
+	}
+	if limit > adminMaxPageSize {
+		limit = adminMaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type adminTestEnv struct {
+	db       *gorm.DB
+	svc      *AdminService
+	sessions *SessionService
+	mailer   *capturingMailer
+}
+
+func newAdminTestEnv(t *testing.T) adminTestEnv {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "testsecret")
+	db := setupTestDB(t)
+	users := storage.NewUserStorage(db)
+	sessions := NewSessionService(storage.NewSessionStorage(db), users)
+	mailer := &capturingMailer{}
+	resets := NewPasswordResetService(storage.NewPasswordResetStorage(db), users, sessions, mailer)
+	throttle := NewLoginThrottleService(storage.NewLoginFailureStorage(db), DefaultLoginThrottleConfig())
+	svc := NewAdminService(storage.NewAdminStorage(db), users, storage.NewAccessTokenStorage(db), sessions, resets, throttle)
+	return adminTestEnv{db: db, svc: svc, sessions: sessions, mailer: mailer}
+}
+
+func (env adminTestEnv) createUser(t *testing.T, email, role string) *models.User {
+	t.Helper()
+	user := &models.User{Email: email, Username: email, Password: "hash", Role: role, MaxProjects: 50}
+	require.NoError(t, env.db.Create(user).Error)
+	return user
+}
+
+func TestAdminService_ListUsersWithUsage(t *testing.T) {
+	env := newAdminTestEnv(t)
+	ann := env.createUser(t, "ann@example.com", models.RoleUser)
+	env.createUser(t, "bob@example.com", models.RoleUser)
+
+	project := &models.Project{OwnerID: ann.ID, Title: "P"}
+	require.NoError(t, env.db.Create(project).Error)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, env.db.Create(&models.Task{Title: "t", ProjectID: &project.ID}).Error)
+	}
+
+	list, err := env.svc.ListUsers("ANN", 0, 0)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, list.Total)
+	require.Equal(t, ann.ID, list.Users[0].ID)
+	require.Equal(t, models.UserUsage{Projects: 1, Tasks: 3}, list.Users[0].Usage)
+
+	list, err = env.svc.ListUsers("", 1, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, list.Total)
+	require.Len(t, list.Users, 1)
+}
+
+func TestAdminService_UpdateUserAndAudit(t *testing.T) {
+	env := newAdminTestEnv(t)
+	admin := env.createUser(t, "root@example.com", models.RoleAdmin)
+	user := env.createUser(t, "ann@example.com", models.RoleUser)
+
+	limit, role := 5, "admin"
+	updated, err := env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{MaxProjects: &limit, Role: &role})
+	require.NoError(t, err)
+	require.Equal(t, 5, updated.MaxProjects)
+	require.Equal(t, models.RoleAdmin, updated.Role)
+
+	// Понижение завершает сессии: роль в уже выданных токенах устаревает.
+	session, err := env.sessions.Start(&updated.User, "", "")
+	require.NoError(t, err)
+	role = "user"
+	_, err = env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{Role: &role})
+	require.NoError(t, err)
+	require.ErrorIs(t, env.sessions.ValidateSession(user.ID, session.SessionID), ErrSessionRevoked)
+
+	entries, err := env.svc.AuditLog(user.ID, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, models.AdminActionChangeRole, entries[0].Action)
+	require.Equal(t, admin.ID, entries[0].ActorID)
+
+	bad := -1
+	_, err = env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{MaxProjects: &bad})
+	require.ErrorIs(t, err, ErrAdminInvalidLimit)
+
+	// Смена тарифа без max_projects выставляет лимит проектов по тарифу.
+	plan := "Pro"
+	updated, err = env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{Plan: &plan})
+	require.NoError(t, err)
+	require.Equal(t, models.PlanPro, updated.Plan)
+	require.Equal(t, 500, updated.MaxProjects)
+	plan = "gold"
+	_, err = env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{Plan: &plan})
+	require.ErrorIs(t, err, ErrUnknownPlan)
+}
+
+func TestAdminService_MaxProjectsCannotExceedPlan(t *testing.T) {
+	env := newAdminTestEnv(t)
+	admin := env.createUser(t, "root@example.com", models.RoleAdmin)
+	user := env.createUser(t, "ann@example.com", models.RoleUser)
+	quotas := NewQuotaService(storage.NewProjectStorage(env.db), storage.NewTaskStorage(env.db), storage.NewUserStorage(env.db),
+		storage.NewAttachmentStorage(env.db), AttachmentQuotas{})
+
+	// На free (50 проектов) лимит 100 не подействовал бы: такой патч отклоняется.
+	limit := 100
+	_, err := env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{MaxProjects: &limit})
+	require.ErrorIs(t, err, ErrAdminLimitAbovePlan)
+	report, err := quotas.Usage(user.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 50, report.Projects.Limit)
+
+	// Вместе со сменой тарифа тот же лимит допустим и виден в потреблении.
+	plan := "pro"
+	updated, err := env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{Plan: &plan, MaxProjects: &limit})
+	require.NoError(t, err)
+	require.Equal(t, 100, updated.MaxProjects)
+	report, err = quotas.Usage(user.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, report.Projects.Limit)
+}
+
+func TestAdminService_UpdateUserIsAtomic(t *testing.T) {
+	env := newAdminTestEnv(t)
+	admin := env.createUser(t, "root@example.com", models.RoleAdmin)
+	user := env.createUser(t, "ann@example.com", models.RoleUser)
+
+	// Тариф и лимит валидны, роль — нет: патч откатывается целиком, без записей в журнале.
+	plan, limit, role := "pro", 7, "owner"
+	_, err := env.svc.UpdateUser(admin.ID, user.ID, AdminUserPatch{Plan: &plan, MaxProjects: &limit, Role: &role})
+	require.ErrorIs(t, err, ErrAdminInvalidRole)
+
+	var reloaded models.User
+	require.NoError(t, env.db.First(&reloaded, user.ID).Error)
+	require.Equal(t, user.Plan, reloaded.Plan)
+	require.Equal(t, 50, reloaded.MaxProjects)
+	require.Equal(t, models.RoleUser, reloaded.Role)
+	entries, err := env.svc.AuditLog(user.ID, 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// Попытка снять права с себя откатывает и уже применённую смену тарифа.
+	role = "user"
+	_, err = env.svc.UpdateUser(admin.ID, admin.ID, AdminUserPatch{Plan: &plan, Role: &role})
+	require.ErrorIs(t, err, ErrAdminSelfAction)
+	var reloadedAdmin models.User
+	require.NoError(t, env.db.First(&reloadedAdmin, admin.ID).Error)
+	require.Equal(t, admin.Plan, reloadedAdmin.Plan)
+	entries, err = env.svc.AuditLog(admin.ID, 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestAdminService_GuardsAgainstLockingOutAdmins(t *testing.T) {
+	env := newAdminTestEnv(t)
+	admin := env.createUser(t, "root@example.com", models.RoleAdmin)
+	other := env.createUser(t, "other@example.com", models.RoleAdmin)
+
+	role := "user"
+	_, err := env.svc.UpdateUser(admin.ID, admin.ID, AdminUserPatch{Role: &role})
+	require.ErrorIs(t, err, ErrAdminSelfAction)
+	_, err = env.svc.Suspend(admin.ID, admin.ID, "")
+	require.ErrorIs(t, err, ErrAdminSelfAction)
+
+	_, err = env.svc.Suspend(admin.ID, other.ID, "left the company")
+	require.NoError(t, err)
+	// Заблокированный администратор не считается — остался один активный.
+	_, err = env.svc.UpdateUser(other.ID, admin.ID, AdminUserPatch{Role: &role})
+	require.ErrorIs(t, err, ErrAdminLastAdmin)
+}
+
+func TestAdminService_SuspendAndForceReset(t *testing.T) {
+	env := newAdminTestEnv(t)
+	admin := env.createUser(t, "root@example.com", models.RoleAdmin)
+	user := env.createUser(t, "ann@example.com", models.RoleUser)
+
+	pair, err := env.sessions.Start(user, "", "")
+	require.NoError(t, err)
+	suspended, err := env.svc.Suspend(admin.ID, user.ID, "spam")
+	require.NoError(t, err)
+	require.Equal(t, models.AccountStatusSuspended, suspended.Status)
+	require.Equal(t, "spam", suspended.StatusReason)
+	require.NotNil(t, suspended.SuspendedAt)
+	_, err = env.sessions.Refresh(pair.RefreshToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+
+	reactivated, err := env.svc.Reactivate(admin.ID, user.ID)
+	require.NoError(t, err)
+	require.True(t, reactivated.IsActive())
+	require.Nil(t, reactivated.SuspendedAt)
+	_, err = env.svc.Reactivate(admin.ID, user.ID)
+	require.ErrorIs(t, err, ErrAdminAlreadyActive)
+
+	require.NoError(t, env.svc.ForcePasswordReset(admin.ID, user.ID))
+	require.Len(t, env.mailer.messages, 1)
+	require.Equal(t, "ann@example.com", env.mailer.messages[0].To)
+	var reloaded models.User
+	require.NoError(t, env.db.First(&reloaded, user.ID).Error)
+	require.NotEqual(t, "hash", reloaded.Password)
+
+	_, err = env.svc.GetUser(9999)
+	require.ErrorIs(t, err, ErrAdminUserNotFound)
+}
+
+func TestAdminService_ActionsRollBackWithoutAuditEntry(t *testing.T) {
+	env := newAdminTestEnv(t)
+	admin := env.createUser(t, "root@example.com", models.RoleAdmin)
+	user := env.createUser(t, "ann@example.com", models.RoleUser)
+	pair, err := env.sessions.Start(user, "", "")
+	require.NoError(t, err)
+
+	// Журнал недоступен: ни одно действие не должно пройти без записи в нём.
+	require.NoError(t, env.db.Migrator().DropTable(&models.AdminAuditEntry{}))
+
+	_, err = env.svc.Suspend(admin.ID, user.ID, "spam")
+	require.Error(t, err)
+	require.Error(t, env.svc.ForcePasswordReset(admin.ID, user.ID))
+	require.Empty(t, env.mailer.messages)
+
+	var reloaded models.User
+	require.NoError(t, env.db.First(&reloaded, user.ID).Error)
+	require.True(t, reloaded.IsActive())
+	require.Equal(t, "hash", reloaded.Password)
+	_, err = env.sessions.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+	var resets int64
+	require.NoError(t, env.db.Model(&models.PasswordResetToken{}).Count(&resets).Error)
+	require.Zero(t, resets)
+
+	require.NoError(t, env.db.Model(&models.User{}).Where("id = ?", user.ID).Update("status", models.AccountStatusSuspended).Error)
+	_, err = env.svc.Reactivate(admin.ID, user.ID)
+	require.Error(t, err)
+	require.NoError(t, env.db.First(&reloaded, user.ID).Error)
+	require.Equal(t, models.AccountStatusSuspended, reloaded.Status)
+}
+
+func TestAdminService_MutualSuspendKeepsAnAdmin(t *testing.T) {
+	env := newAdminTestEnv(t)
+	sqlDB, err := env.db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	ann := env.createUser(t, "ann@example.com", models.RoleAdmin)
+	bob := env.createUser(t, "bob@example.com", models.RoleAdmin)
+	// now вызывается между проверкой числа администраторов и записью: расширяем окно гонки.
+	env.svc.now = func() time.Time {
+		time.Sleep(5 * time.Millisecond)
+		return time.Now()
+	}
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, env.db.Model(&models.User{}).Where("id IN ?", []uint{ann.ID, bob.ID}).
+			Updates(map[string]any{"status": models.AccountStatusActive, "suspended_at": nil}).Error)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 2)
+		for _, pair := range [][2]uint{{ann.ID, bob.ID}, {bob.ID, ann.ID}} {
+			wg.Add(1)
+			go func(actor, target uint) {
+				defer wg.Done()
+				_, err := env.svc.Suspend(actor, target, "")
+				errs <- err
+			}(pair[0], pair[1])
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				require.ErrorIs(t, err, ErrAdminLastAdmin)
+			}
+		}
+		count, err := storage.NewAdminStorage(env.db).CountAdmins()
+		require.NoError(t, err)
+		require.EqualValues(t, 1, count)
+	}
+}
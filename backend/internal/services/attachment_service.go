@@ -0,0 +1,348 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spozitivom/taskmanager/internal/blob"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAttachmentMaxFileMB    = 25
+	defaultAttachmentUserQuotaMB  = 500
+	defaultAttachmentProjectQuota = 1024
+	attachmentFileNameMaxLength   = 255
+)
+
+var (
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+	ErrAttachmentForbidden    = errors.New("only the project owner can change attachments")
+	ErrAttachmentEmpty        = errors.New("file is empty")
+	ErrAttachmentTooLarge     = errors.New("file is too large")
+	ErrAttachmentUserQuota    = errors.New("attachment storage quota exceeded")
+	ErrAttachmentProjectQuota = errors.New("project attachment storage quota exceeded")
+	ErrTaskNotFound           = errors.New("task not found")
+)
+
+// Типы, которые браузер исполняет как страницу: отдаём их как обычный текст.
+var activeContentTypes = map[string]struct{}{
+	"text/html":     {},
+	"text/xml":      {},
+	"image/svg+xml": {},
+}
+
+// AttachmentQuotas — ограничения на вложения, байты. Ноль отключает ограничение.
+type AttachmentQuotas struct {
+	MaxFileBytes    int64
+	PerUserBytes    int64
+	PerProjectBytes int64
+}
+
+// AttachmentQuotasFromEnv читает ATTACHMENT_MAX_FILE_MB, ATTACHMENT_USER_QUOTA_MB и
+// ATTACHMENT_PROJECT_QUOTA_MB. По умолчанию: 25 МБ на файл, 500 МБ на пользователя
+// и 1 ГБ на проект.
+func AttachmentQuotasFromEnv() AttachmentQuotas {
+	return AttachmentQuotas{
+		MaxFileBytes:    megabytesFromEnv("ATTACHMENT_MAX_FILE_MB", defaultAttachmentMaxFileMB),
+		PerUserBytes:    megabytesFromEnv("ATTACHMENT_USER_QUOTA_MB", defaultAttachmentUserQuotaMB),
+		PerProjectBytes: megabytesFromEnv("ATTACHMENT_PROJECT_QUOTA_MB", defaultAttachmentProjectQuota),
+	}
+}
+
+func megabytesFromEnv(key string, fallback int64) int64 {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback << 20
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 0 {
+		return fallback << 20
+	}
+	return value << 20
+}
+
+// AttachmentDownload — содержимое вложения для отдачи клиенту.
+type AttachmentDownload struct {
+	Attachment  *models.Attachment
+	Body        io.ReadCloser
+	ContentType string
+}
+
+// AttachmentService — файлы задач. Читать вложения может тот, кому виден проект
+// задачи; загружать и удалять — владелец проекта. Задачи без проекта общие.
+type AttachmentService struct {
+	attachments *storage.AttachmentStorage
+	tasks       *storage.TaskStorage
+	projects    *storage.ProjectStorage
+	store       blob.Store
+	files       AttachmentQuotas
+	quotas      quotaChecker
+}
+
+func NewAttachmentService(a *storage.AttachmentStorage, t *storage.TaskStorage, p *storage.ProjectStorage, u *storage.UserStorage, store blob.Store, files AttachmentQuotas) *AttachmentService {
+	return &AttachmentService{
+		attachments: a, tasks: t, projects: p, store: store, files: files,
+		quotas: quotaChecker{projects: p, tasks: t, users: u, attachments: a, files: files},
+	}
+}
+
+// MaxFileBytes — предельный размер одного файла (0 — без ограничения).
+func (s *AttachmentService) MaxFileBytes() int64 {
+	return s.files.MaxFileBytes
+}
+
+func (s *AttachmentService) List(userID, taskID uint) ([]models.Attachment, error) {
+	if _, err := s.task(userID, taskID, false); err != nil {
+		return nil, err
+	}
+	return s.attachments.ListByTask(taskID)
+}
+
+// Upload сохраняет файл размера size. Тип определяется по содержимому, а не по
+// заголовку клиента. Квоты пользователя и проекта проверяются до записи файла,
+// чтобы не принимать заведомо лишнее, и ещё раз в транзакции вместе с созданием
+// записи: параллельные загрузки считают объём по очереди и квоту не превысят.
+func (s *AttachmentService) Upload(ctx context.Context, userID, taskID uint, fileName string, r io.Reader, size int64) (*models.Attachment, error) {
+	task, err := s.task(userID, taskID, true)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, ErrAttachmentEmpty
+	}
+	if s.files.MaxFileBytes > 0 && size > s.files.MaxFileBytes {
+		return nil, fmt.Errorf("%w (max %d bytes)", ErrAttachmentTooLarge, s.files.MaxFileBytes)
+	}
+	if err := s.checkQuotas(userID, task, size); err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReaderSize(r, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	contentType := sniffAttachmentType(head)
+
+	key, err := attachmentKey(taskID)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	counted := &countingReader{r: io.TeeReader(io.LimitReader(buffered, size+1), hash)}
+	if err := s.store.Put(ctx, key, counted, size, contentType); err != nil {
+		return nil, err
+	}
+	if counted.n != size {
+		s.discard(ctx, key)
+		return nil, fmt.Errorf("%w: declared %d bytes, received %d", io.ErrUnexpectedEOF, size, counted.n)
+	}
+
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		UserID:      userID,
+		FileName:    normalizeAttachmentName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	err = s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.checkStorage(userID, task.ProjectID, size); err != nil {
+			return err
+		}
+		return q.attachments.Create(attachment)
+	})
+	if err != nil {
+		s.discard(ctx, key)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// Open возвращает содержимое вложения. Вложения скрытых задач не отдаются.
+func (s *AttachmentService) Open(ctx context.Context, userID, attachmentID uint) (*AttachmentDownload, error) {
+	attachment, err := s.get(userID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := s.store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	contentType := attachment.ContentType
+	if _, active := activeContentTypes[strings.SplitN(contentType, ";", 2)[0]]; active {
+		contentType = "text/plain; charset=utf-8"
+	}
+	return &AttachmentDownload{Attachment: attachment, Body: body, ContentType: contentType}, nil
+}
+
+// Delete удаляет вложение окончательно. Удалить может владелец проекта или автор файла.
+func (s *AttachmentService) Delete(ctx context.Context, userID, attachmentID uint) error {
+	attachment, err := s.get(userID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if attachment.UserID != userID {
+		if _, err := s.task(userID, attachment.TaskID, true); err != nil {
+			return err
+		}
+	}
+	if err := s.attachments.Delete(attachment.ID); err != nil {
+		return err
+	}
+	s.discard(ctx, attachment.StorageKey)
+	return nil
+}
+
+// PurgeOrphans удаляет файлы вложений, чьи задачи удалены окончательно.
+func (s *AttachmentService) PurgeOrphans(ctx context.Context) (int, error) {
+	purged := 0
+	for {
+		orphans, err := s.attachments.Orphans(100)
+		if err != nil || len(orphans) == 0 {
+			return purged, err
+		}
+		for _, attachment := range orphans {
+			if err := s.store.Delete(ctx, attachment.StorageKey); err != nil {
+				return purged, err
+			}
+			if err := s.attachments.Delete(attachment.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+}
+
+func (s *AttachmentService) get(userID, attachmentID uint) (*models.Attachment, error) {
+	attachment, err := s.attachments.Get(attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	if _, err := s.task(userID, attachment.TaskID, false); err != nil {
+		if errors.Is(err, ErrTaskNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// task проверяет доступ к задаче: на чтение — владелец или участник проекта,
+// на изменение — только владелец. Чужой проект выглядит как отсутствующая задача.
+func (s *AttachmentService) task(userID, taskID uint, write bool) (*models.Task, error) {
+	task, err := s.tasks.GetByID(taskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	if task.ProjectID == nil {
+		return task, nil
+	}
+	project, err := s.projects.GetAccessible(userID, *task.ProjectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	if write && project.OwnerID != userID {
+		return nil, ErrAttachmentForbidden
+	}
+	return task, nil
+}
+
+// checkQuotas — квоты на объём вложений загружающего и проекта задачи.
+func (s *AttachmentService) checkQuotas(userID uint, task *models.Task, size int64) error {
+	return s.quotas.checkStorage(userID, task.ProjectID, size)
+}
+
+func (s *AttachmentService) discard(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete attachment blob %s: %v", key, err)
+	}
+}
+
+// sniffAttachmentType определяет тип по первым байтам файла (алгоритм WHATWG).
+func sniffAttachmentType(head []byte) string {
+	contentType := http.DetectContentType(head)
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil {
+		if charset, ok := params["charset"]; ok {
+			return mediaType + "; charset=" + charset
+		}
+		return mediaType
+	}
+	return "application/octet-stream"
+}
+
+// normalizeAttachmentName оставляет только имя файла без каталогов и управляющих символов.
+func normalizeAttachmentName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(strings.TrimSpace(name))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	if utf8.RuneCountInString(name) > attachmentFileNameMaxLength {
+		// обрезаем начало имени, расширение сохраняем
+		ext := []rune(filepath.Ext(name))
+		if len(ext) > 16 {
+			ext = nil
+		}
+		runes := []rune(name)
+		name = string(runes[:attachmentFileNameMaxLength-len(ext)]) + string(ext)
+	}
+	return name
+}
+
+// attachmentKey — случайный ключ: имя файла в хранилище не попадает.
+func attachmentKey(taskID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%d-%s", taskID, time.Now().Unix(), hex.EncodeToString(buf)), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
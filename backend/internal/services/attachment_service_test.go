@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/blob"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type attachmentTestEnv struct {
+	db          *gorm.DB
+	service     *AttachmentService
+	projects    *ProjectService
+	attachments *storage.AttachmentStorage
+	store       blob.Store
+}
+
+func newAttachmentTestEnv(t *testing.T, quotas AttachmentQuotas) attachmentTestEnv {
+	t.Helper()
+	db := setupTestDB(t)
+	store, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	attachments := storage.NewAttachmentStorage(db)
+	return attachmentTestEnv{
+		db:          db,
+		service:     NewAttachmentService(attachments, taskStorage, projectStorage, storage.NewUserStorage(db), store, quotas),
+		projects:    NewProjectService(projectStorage, taskStorage, storage.NewUserStorage(db)),
+		attachments: attachments,
+		store:       store,
+	}
+}
+
+func (e attachmentTestEnv) upload(userID, taskID uint, name, content string) (*models.Attachment, error) {
+	return e.service.Upload(context.Background(), userID, taskID, name, strings.NewReader(content), int64(len(content)))
+}
+
+func TestAttachmentService_UploadSniffsAndEnforcesAccessAndQuotas(t *testing.T) {
+	env := newAttachmentTestEnv(t, AttachmentQuotas{MaxFileBytes: 64, PerUserBytes: 100, PerProjectBytes: 110})
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	viewer := models.User{ID: 2, Email: "viewer@example.com", Username: "viewer", Password: "x"}
+	stranger := models.User{ID: 3, Email: "stranger@example.com", Username: "stranger", Password: "x"}
+	require.NoError(t, env.db.Create([]*models.User{&owner, &viewer, &stranger}).Error)
+	project, err := env.projects.Create(owner.ID, &models.ProjectInput{Title: "Docs"})
+	require.NoError(t, err)
+	_, err = env.projects.AddMember(owner.ID, project.ID, viewer.Email)
+	require.NoError(t, err)
+	task := models.Task{Title: "Spec", ProjectID: &project.ID}
+	require.NoError(t, env.db.Create(&task).Error)
+
+	// Тип берётся из содержимого: HTML под видом картинки отдаётся как текст.
+	html, err := env.upload(owner.ID, task.ID, "../../photo.png", "<!DOCTYPE html><script>alert(1)</script>")
+	require.NoError(t, err)
+	require.Equal(t, "photo.png", html.FileName)
+	require.Equal(t, "text/html; charset=utf-8", html.ContentType)
+	download, err := env.service.Open(context.Background(), viewer.ID, html.ID)
+	require.NoError(t, err)
+	require.Equal(t, "text/plain; charset=utf-8", download.ContentType)
+	data, err := io.ReadAll(download.Body)
+	require.NoError(t, download.Body.Close())
+	require.NoError(t, err)
+	require.Equal(t, "<!DOCTYPE html><script>alert(1)</script>", string(data))
+
+	pdf, err := env.upload(owner.ID, task.ID, "spec.pdf", "%PDF-1.7 minimal")
+	require.NoError(t, err)
+	require.Equal(t, "application/pdf", pdf.ContentType)
+
+	// Участник видит вложения, но не загружает; посторонний не видит задачу.
+	list, err := env.service.List(viewer.ID, task.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	_, err = env.upload(viewer.ID, task.ID, "notes.txt", "hello")
+	require.ErrorIs(t, err, ErrAttachmentForbidden)
+	require.ErrorIs(t, env.service.Delete(context.Background(), viewer.ID, pdf.ID), ErrAttachmentForbidden)
+	_, err = env.service.List(stranger.ID, task.ID)
+	require.ErrorIs(t, err, ErrTaskNotFound)
+	_, err = env.service.Open(context.Background(), stranger.ID, pdf.ID)
+	require.ErrorIs(t, err, ErrAttachmentNotFound)
+
+	// Ограничения: размер файла, объём пользователя и объём проекта.
+	_, err = env.upload(owner.ID, task.ID, "big.bin", strings.Repeat("x", 65))
+	require.ErrorIs(t, err, ErrAttachmentTooLarge)
+	_, err = env.upload(owner.ID, task.ID, "fill.bin", strings.Repeat("x", 60))
+	require.ErrorIs(t, err, ErrAttachmentUserQuota)
+
+	// Проект переходит к пользователю без своих файлов: упираемся в объём проекта.
+	require.NoError(t, env.db.Model(&models.Project{}).Where("id = ?", project.ID).Update("owner_id", stranger.ID).Error)
+	_, err = env.upload(stranger.ID, task.ID, "fill.bin", strings.Repeat("x", 64))
+	require.ErrorIs(t, err, ErrAttachmentProjectQuota)
+
+	// Объявленный размер должен совпасть с полученным.
+	_, err = env.service.Upload(context.Background(), stranger.ID, task.ID, "short.bin", bytes.NewReader([]byte("abc")), 10)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// Автор может удалить свой файл; файл пропадает из хранилища.
+	require.NoError(t, env.db.Model(&models.Project{}).Where("id = ?", project.ID).Update("owner_id", owner.ID).Error)
+	require.NoError(t, env.service.Delete(context.Background(), owner.ID, pdf.ID))
+	_, _, err = env.store.Get(context.Background(), pdf.StorageKey)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+type slowPutStore struct {
+	blob.Store
+}
+
+func (s slowPutStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	time.Sleep(20 * time.Millisecond)
+	return s.Store.Put(ctx, key, r, size, contentType)
+}
+
+func TestAttachmentService_ConcurrentUploadsStayWithinQuota(t *testing.T) {
+	env := newAttachmentTestEnv(t, AttachmentQuotas{PerUserBytes: 100})
+	sqlDB, err := env.db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	require.NoError(t, env.db.Create(&owner).Error)
+	project, err := env.projects.Create(owner.ID, &models.ProjectInput{Title: "Docs"})
+	require.NoError(t, err)
+	task := models.Task{Title: "Spec", ProjectID: &project.ID}
+	require.NoError(t, env.db.Create(&task).Error)
+	// Медленная запись файла: все загрузки успевают пройти предварительную проверку.
+	env.service.store = slowPutStore{Store: env.store}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := env.upload(owner.ID, task.ID, "part.bin", strings.Repeat("x", 10))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			require.ErrorIs(t, err, ErrAttachmentUserQuota)
+		}
+	}
+
+	used, err := env.attachments.UsageByUser(owner.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, used)
+}
+
+func TestAttachmentService_FollowsTaskLifecycle(t *testing.T) {
+	env := newAttachmentTestEnv(t, AttachmentQuotas{})
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	require.NoError(t, env.db.Create(&owner).Error)
+	project, err := env.projects.Create(owner.ID, &models.ProjectInput{Title: "Docs"})
+	require.NoError(t, err)
+	task := models.Task{Title: "Spec", ProjectID: &project.ID}
+	require.NoError(t, env.db.Create(&task).Error)
+	removed := models.Task{Title: "Old", ProjectID: &project.ID}
+	require.NoError(t, env.db.Create(&removed).Error)
+
+	kept, err := env.upload(owner.ID, task.ID, "a.txt", "alpha")
+	require.NoError(t, err)
+	deleted, err := env.upload(owner.ID, task.ID, "b.txt", "beta")
+	require.NoError(t, err)
+	require.NoError(t, env.service.Delete(context.Background(), owner.ID, deleted.ID))
+
+	// Архив проекта скрывает вложения вместе с задачами, восстановление возвращает.
+	require.NoError(t, env.projects.Archive(owner.ID, project.ID))
+	_, err = env.service.List(owner.ID, task.ID)
+	require.ErrorIs(t, err, ErrTaskNotFound)
+	_, err = env.service.Open(context.Background(), owner.ID, kept.ID)
+	require.ErrorIs(t, err, ErrAttachmentNotFound)
+	var hidden int64
+	require.NoError(t, env.db.Unscoped().Model(&models.Attachment{}).Where("deleted_at IS NOT NULL").Count(&hidden).Error)
+	require.EqualValues(t, 1, hidden)
+
+	require.NoError(t, env.projects.Restore(owner.ID, project.ID))
+	list, err := env.service.List(owner.ID, task.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1, "удалённое вручную вложение не возвращается")
+	require.Equal(t, kept.ID, list[0].ID)
+
+	// Файлы окончательно удалённых задач убирает PurgeOrphans.
+	orphan, err := env.upload(owner.ID, removed.ID, "c.txt", "gamma")
+	require.NoError(t, err)
+	require.NoError(t, env.db.Unscoped().Delete(&models.Task{}, removed.ID).Error)
+	purged, err := env.service.PurgeOrphans(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+	_, _, err = env.store.Get(context.Background(), orphan.StorageKey)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+	_, err = env.attachments.Get(kept.ID)
+	require.NoError(t, err)
+
+	// Окончательное удаление проекта: вложения и скрытых раньше задач уходят в PurgeOrphans.
+	hiddenTask := models.Task{Title: "Hidden", ProjectID: &project.ID}
+	require.NoError(t, env.db.Create(&hiddenTask).Error)
+	buried, err := env.upload(owner.ID, hiddenTask.ID, "d.txt", "delta")
+	require.NoError(t, err)
+	require.NoError(t, env.db.Delete(&hiddenTask).Error)
+	require.NoError(t, env.db.Where("task_id = ?", hiddenTask.ID).Delete(&models.Attachment{}).Error)
+	require.NoError(t, env.projects.HardDelete(owner.ID, project.ID))
+	purged, err = env.service.PurgeOrphans(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	for _, key := range []string{kept.StorageKey, buried.StorageKey} {
+		_, _, err = env.store.Get(context.Background(), key)
+		require.ErrorIs(t, err, blob.ErrNotFound)
+	}
+	var left int64
+	require.NoError(t, env.db.Unscoped().Model(&models.Attachment{}).Count(&left).Error)
+	require.Zero(t, left)
+}
@@ -1,14 +1,24 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 // Хешируем пароль (возвращаем как строку)
 func HashPassword(password string) (string, error) {
 	hashBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -20,13 +30,63 @@ func CheckPassword(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-// Генерируем JWT
+// Генерируем JWT без привязки к сессии (служебные сценарии и тесты).
 func GenerateJWT(userID uint, role string) (string, error) {
+	return GenerateAccessToken(userID, role, 0)
+}
+
+// GenerateAccessToken выпускает короткоживущий access-токен.
+// sessionID попадает в claim sid — по нему middleware проверяет, не отозвана ли сессия.
+func GenerateAccessToken(userID uint, role string, sessionID uint) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub":  strconv.Itoa(int(userID)),
 		"role": role,
-		"exp":  time.Now().AddDate(1, 0, 0).Unix(), // токен живёт 1 год
+		"typ":  "access",
+		"iat":  now.Unix(),
+		"exp":  now.Add(AccessTokenTTL()).Unix(),
+	}
+	if sessionID > 0 {
+		claims["sid"] = strconv.Itoa(int(sessionID))
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 }
+
+// AccessTokenTTL — время жизни access-токена (ACCESS_TOKEN_TTL, например "15m").
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL — время жизни сессии и refresh-токена (REFRESH_TOKEN_TTL, например "720h").
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+// GenerateOpaqueToken создаёт случайный токен для выдачи клиенту и его хэш для хранения в БД.
+func GenerateOpaqueToken() (raw, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashOpaqueToken(raw), nil
+}
+
+// HashOpaqueToken возвращает SHA-256 (hex) от непрозрачного токена.
+func HashOpaqueToken(raw string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(raw)))
+	return hex.EncodeToString(sum[:])
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
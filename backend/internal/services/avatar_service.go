@@ -0,0 +1,312 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // регистрирует декодер GIF для image.Decode
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/spozitivom/taskmanager/internal/blob"
+	"github.com/spozitivom/taskmanager/internal/imaging"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAvatarUploadBytes — предельный размер загружаемого файла аватара.
+	MaxAvatarUploadBytes = 5 << 20
+	// Предельные размеры исходника: проверяются по заголовку до декодирования.
+	maxAvatarDimension = 4096
+	// AvatarPathPrefix — адрес раздачи аватаров: <prefix>/<userID>/<version>?size=128.
+	AvatarPathPrefix = "/api/avatars"
+)
+
+// AvatarSizes — стандартные размеры миниатюр (квадрат, px).
+var AvatarSizes = []int{64, 128, 256}
+
+var (
+	ErrAvatarInvalid  = errors.New("avatar must be a JPEG, PNG or GIF image")
+	ErrAvatarTooLarge = fmt.Errorf("avatar is too large (max %d bytes, %dx%d px)", MaxAvatarUploadBytes, maxAvatarDimension, maxAvatarDimension)
+	ErrAvatarNotFound = errors.New("avatar not found")
+)
+
+// AvatarObject — содержимое аватара для отдачи клиенту.
+type AvatarObject struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	ETag        string
+}
+
+// AvatarService хранит аватары в blob-хранилище: исходник и миниатюры AvatarSizes.
+// Ключи: avatars/<userID>/<version>/original и avatars/<userID>/<version>/<size>.
+type AvatarService struct {
+	store blob.Store
+	users *storage.UserStorage
+	db    *gorm.DB
+}
+
+func NewAvatarService(db *gorm.DB, store blob.Store, users *storage.UserStorage) *AvatarService {
+	return &AvatarService{store: store, users: users, db: db}
+}
+
+// Upload проверяет реальный тип изображения, сохраняет исходник и миниатюры и
+// переключает пользователя на новую версию. Прежняя версия удаляется.
+func (s *AvatarService) Upload(ctx context.Context, userID uint, r io.Reader) (*models.User, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxAvatarUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAvatarUploadBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, user, data); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Remove удаляет загруженный аватар (или внешнюю ссылку) пользователя.
+func (s *AvatarService) Remove(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	version, format := user.AvatarVersion, user.AvatarFormat
+	user.AvatarURL = ""
+	user.AvatarVersion = ""
+	user.AvatarFormat = ""
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	s.Discard(ctx, userID, version, format)
+	return user, nil
+}
+
+// Open возвращает аватар заданной версии; size = 0 — исходник. У аватаров, загруженных
+// до отказа от WebP, миниатюр нет, поэтому для них отдаётся исходник.
+func (s *AvatarService) Open(ctx context.Context, userID uint, version string, size int) (*AvatarObject, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAvatarNotFound
+		}
+		return nil, err
+	}
+	if user.AvatarVersion == "" || user.AvatarVersion != version {
+		return nil, ErrAvatarNotFound
+	}
+	if size != 0 && !validAvatarSize(size) {
+		return nil, fmt.Errorf("%w: size must be one of %v", ErrAvatarInvalid, AvatarSizes)
+	}
+	if thumbnailFormat(user.AvatarFormat) == "" {
+		size = 0
+	}
+
+	key, contentType := avatarKey(userID, version, "original"), avatarContentType(user.AvatarFormat)
+	etag := version
+	if size != 0 {
+		key = avatarKey(userID, version, fmt.Sprint(size))
+		contentType = avatarContentType(thumbnailFormat(user.AvatarFormat))
+		etag = fmt.Sprintf("%s-%d", version, size)
+	}
+	body, obj, err := s.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, ErrAvatarNotFound
+		}
+		return nil, err
+	}
+	return &AvatarObject{Body: body, ContentType: contentType, Size: obj.Size, ETag: etag}, nil
+}
+
+// Discard удаляет объекты версии аватара; ошибки только логируются.
+func (s *AvatarService) Discard(ctx context.Context, userID uint, version, format string) {
+	if version == "" {
+		return
+	}
+	keys := []string{avatarKey(userID, version, "original")}
+	if thumbnailFormat(format) != "" {
+		for _, size := range AvatarSizes {
+			keys = append(keys, avatarKey(userID, version, fmt.Sprint(size)))
+		}
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete avatar blob %s: %v", key, err)
+		}
+	}
+}
+
+// MigrateDataURLs переносит аватары, сохранённые data URL в users.avatar_url, в
+// blob-хранилище. Нераспознаваемые изображения сбрасываются. Возвращает число
+// перенесённых и сброшенных аватаров.
+func (s *AvatarService) MigrateDataURLs(ctx context.Context) (int, int, error) {
+	migrated, dropped := 0, 0
+	var lastID uint
+	for {
+		var batch []models.User
+		if err := s.db.Where("id > ? AND avatar_url LIKE ?", lastID, "data:%").
+			Order("id ASC").Limit(50).Find(&batch).Error; err != nil {
+			return migrated, dropped, err
+		}
+		if len(batch) == 0 {
+			return migrated, dropped, nil
+		}
+		for i := range batch {
+			user := &batch[i]
+			lastID = user.ID
+			data, err := decodeDataURL(user.AvatarURL)
+			if err == nil {
+				err = s.save(ctx, user, data)
+			}
+			if err == nil {
+				migrated++
+				continue
+			}
+			if !errors.Is(err, ErrAvatarInvalid) && !errors.Is(err, ErrAvatarTooLarge) {
+				return migrated, dropped, err
+			}
+			log.Printf("avatar migration: dropping avatar of user %d: %v", user.ID, err)
+			if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Update("avatar_url", "").Error; err != nil {
+				return migrated, dropped, err
+			}
+			dropped++
+		}
+	}
+}
+
+func (s *AvatarService) save(ctx context.Context, user *models.User, data []byte) error {
+	format, thumbnails, err := processAvatar(data)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:8])
+
+	if err := s.store.Put(ctx, avatarKey(user.ID, version, "original"), bytes.NewReader(data), int64(len(data)), avatarContentType(format)); err != nil {
+		return err
+	}
+	thumbType := avatarContentType(thumbnailFormat(format))
+	for size, thumb := range thumbnails {
+		if err := s.store.Put(ctx, avatarKey(user.ID, version, fmt.Sprint(size)), bytes.NewReader(thumb), int64(len(thumb)), thumbType); err != nil {
+			return err
+		}
+	}
+
+	prevVersion, prevFormat := user.AvatarVersion, user.AvatarFormat
+	user.AvatarURL = fmt.Sprintf("%s/%d/%s", AvatarPathPrefix, user.ID, version)
+	user.AvatarVersion = version
+	user.AvatarFormat = format
+	if err := s.users.Update(user); err != nil {
+		return err
+	}
+	if prevVersion != version {
+		s.Discard(ctx, user.ID, prevVersion, prevFormat)
+	}
+	return nil
+}
+
+// processAvatar определяет тип по содержимому, декодирует изображение и готовит миниатюры.
+func processAvatar(data []byte) (string, map[int][]byte, error) {
+	var format string
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		format = "jpeg"
+	case "image/png":
+		format = "png"
+	case "image/gif":
+		format = "gif"
+	default:
+		// WebP не принимаем: декодера в стандартной библиотеке нет, а без декодирования
+		// нельзя проверить содержимое, размеры и подготовить миниатюры.
+		return "", nil, ErrAvatarInvalid
+	}
+
+	cfg, decodedFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || decodedFormat != format {
+		return "", nil, ErrAvatarInvalid
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return "", nil, ErrAvatarInvalid
+	}
+	if cfg.Width > maxAvatarDimension || cfg.Height > maxAvatarDimension {
+		return "", nil, ErrAvatarTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, ErrAvatarInvalid
+	}
+
+	thumbnails := make(map[int][]byte, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		var buf bytes.Buffer
+		thumb := imaging.Thumbnail(img, size)
+		if thumbnailFormat(format) == "jpeg" {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, thumb)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+	return format, thumbnails, nil
+}
+
+func decodeDataURL(value string) ([]byte, error) {
+	idx := strings.Index(value, ",")
+	if idx == -1 || !strings.Contains(value[:idx], ";base64") {
+		return nil, ErrAvatarInvalid
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[idx+1:]))
+	if err != nil {
+		return nil, ErrAvatarInvalid
+	}
+	return data, nil
+}
+
+// thumbnailFormat — формат миниатюр: JPEG для JPEG, PNG для PNG и GIF (сохраняет прозрачность).
+func thumbnailFormat(format string) string {
+	switch format {
+	case "jpeg":
+		return "jpeg"
+	case "png", "gif":
+		return "png"
+	}
+	return ""
+}
+
+func avatarContentType(format string) string {
+	return "image/" + format
+}
+
+func avatarKey(userID uint, version, name string) string {
+	return fmt.Sprintf("avatars/%d/%s/%s", userID, version, name)
+}
+
+func validAvatarSize(size int) bool {
+	for _, s := range AvatarSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
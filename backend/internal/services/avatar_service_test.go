@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/blob"
+	"github.com/spozitivom/taskmanager/internal/blob/blobtest"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestAvatarService_UploadToS3AndServeThumbnails(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Email: "a@example.com", Username: "a", Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+
+	minio := blobtest.NewS3Server("avatars")
+	t.Cleanup(minio.Close)
+	store, err := blob.NewS3Store(minio.Config("avatars"), nil)
+	require.NoError(t, err)
+	service := NewAvatarService(db, store, storage.NewUserStorage(db))
+	ctx := context.Background()
+
+	updated, err := service.Upload(ctx, user.ID, bytes.NewReader(testPNG(t, 300, 200)))
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("/api/avatars/%d/%s", user.ID, updated.AvatarVersion), updated.AvatarURL)
+	require.Equal(t, "png", updated.AvatarFormat)
+	require.Equal(t, 1+len(AvatarSizes), minio.Len())
+
+	for _, size := range AvatarSizes {
+		obj, err := service.Open(ctx, user.ID, updated.AvatarVersion, size)
+		require.NoError(t, err)
+		require.Equal(t, "image/png", obj.ContentType)
+		cfg, err := png.DecodeConfig(obj.Body)
+		require.NoError(t, obj.Body.Close())
+		require.NoError(t, err)
+		require.Equal(t, size, cfg.Width)
+		require.Equal(t, size, cfg.Height)
+	}
+	_, err = service.Open(ctx, user.ID, updated.AvatarVersion, 100)
+	require.ErrorIs(t, err, ErrAvatarInvalid)
+	_, err = service.Open(ctx, user.ID, "stale", 64)
+	require.ErrorIs(t, err, ErrAvatarNotFound)
+
+	// Расширение и заявленный тип не важны: проверяется содержимое.
+	_, err = service.Upload(ctx, user.ID, bytes.NewReader([]byte("<svg onload=alert(1)>")))
+	require.ErrorIs(t, err, ErrAvatarInvalid)
+	_, err = service.Upload(ctx, user.ID, bytes.NewReader(testPNG(t, 10, 10)[:40]))
+	require.ErrorIs(t, err, ErrAvatarInvalid)
+	// WebP без декодера не проверить и не уменьшить — не принимается.
+	webp := append([]byte("RIFF\x24\x00\x00\x00WEBPVP8 "), make([]byte, 32)...)
+	_, err = service.Upload(ctx, user.ID, bytes.NewReader(webp))
+	require.ErrorIs(t, err, ErrAvatarInvalid)
+
+	// Новая версия заменяет старую, старые файлы удаляются.
+	second, err := service.Upload(ctx, user.ID, bytes.NewReader(testPNG(t, 64, 64)))
+	require.NoError(t, err)
+	require.NotEqual(t, updated.AvatarVersion, second.AvatarVersion)
+	require.Equal(t, 1+len(AvatarSizes), minio.Len())
+
+	_, err = service.Remove(ctx, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, minio.Len())
+}
+
+func TestAvatarService_MigrateDataURLs(t *testing.T) {
+	db := setupTestDB(t)
+	store, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	service := NewAvatarService(db, store, storage.NewUserStorage(db))
+
+	good := models.User{Email: "g@example.com", Username: "g", Password: "x",
+		AvatarURL: "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG(t, 32, 32))}
+	broken := models.User{Email: "b@example.com", Username: "b", Password: "x", AvatarURL: "data:image/png;base64,bm90IGFuIGltYWdl"}
+	external := models.User{Email: "e@example.com", Username: "e", Password: "x", AvatarURL: "https://cdn.example.com/e.png"}
+	require.NoError(t, db.Create(&good).Error)
+	require.NoError(t, db.Create(&broken).Error)
+	require.NoError(t, db.Create(&external).Error)
+
+	migrated, dropped, err := service.MigrateDataURLs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, migrated)
+	require.Equal(t, 1, dropped)
+
+	var reloaded models.User
+	require.NoError(t, db.First(&reloaded, good.ID).Error)
+	require.Equal(t, fmt.Sprintf("/api/avatars/%d/%s", good.ID, reloaded.AvatarVersion), reloaded.AvatarURL)
+	obj, err := service.Open(context.Background(), good.ID, reloaded.AvatarVersion, 0)
+	require.NoError(t, err)
+	data, err := io.ReadAll(obj.Body)
+	require.NoError(t, obj.Body.Close())
+	require.NoError(t, err)
+	require.Equal(t, testPNG(t, 32, 32), data)
+
+	var brokenUser, externalUser models.User
+	require.NoError(t, db.First(&brokenUser, broken.ID).Error)
+	require.Empty(t, brokenUser.AvatarURL)
+	require.NoError(t, db.First(&externalUser, external.ID).Error)
+	require.Equal(t, "https://cdn.example.com/e.png", externalUser.AvatarURL)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Диапазон календаря: месяц с соседними неделями легко помещается, больше года — нет.
+const calendarMaxDays = 366
+
+var ErrCalendarRange = errors.New("invalid calendar range")
+
+// CalendarQuery — параметры GET /api/calendar. From и To — дата YYYY-MM-DD (полночь
+// в поясе пользователя) или момент RFC 3339; дата в To включает весь день.
+type CalendarQuery struct {
+	From      string
+	To        string
+	ProjectID *uint
+	Statuses  []string
+}
+
+// CalendarRange — задачи диапазона: на весь день и со временем отдельно, обе
+// группы по началу. From и To — границы диапазона, To не включается.
+type CalendarRange struct {
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	AllDay []models.Task `json:"all_day"`
+	Timed  []models.Task `json:"timed"`
+}
+
+// CalendarService выбирает задачи, попадающие в видимый диапазон календаря.
+type CalendarService struct {
+	tasks    *storage.TaskStorage
+	projects *storage.ProjectStorage
+	users    *storage.UserStorage
+}
+
+func NewCalendarService(t *storage.TaskStorage, p *storage.ProjectStorage, u *storage.UserStorage) *CalendarService {
+	return &CalendarService{tasks: t, projects: p, users: u}
+}
+
+// Range — задачи, чей интервал [start_at, end_at] пересекается с диапазоном.
+// Фильтр по проекту проверяет доступ; ProjectID 0 — задачи без проекта.
+func (s *CalendarService) Range(userID uint, query CalendarQuery) (*CalendarRange, error) {
+	loc, err := userLocation(s.users, userID)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := calendarBounds(query.From, query.To, loc)
+	if err != nil {
+		return nil, err
+	}
+	filter := storage.TaskFilter{ProjectID: query.ProjectID}
+	if query.ProjectID != nil && *query.ProjectID != 0 {
+		if _, err := s.projects.GetAccessible(userID, *query.ProjectID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+	}
+	for _, raw := range query.Statuses {
+		status, err := models.NormalizeTaskStatus(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown status %q", ErrCalendarRange, raw)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	tasks, err := s.tasks.ListScheduled(from, to, filter)
+	if err != nil {
+		return nil, err
+	}
+	result := &CalendarRange{From: from, To: to, AllDay: []models.Task{}, Timed: []models.Task{}}
+	for _, task := range tasks {
+		if task.AllDay {
+			result.AllDay = append(result.AllDay, task)
+		} else {
+			result.Timed = append(result.Timed, task)
+		}
+	}
+	return result, nil
+}
+
+// calendarBounds разбирает обе границы; обе обязательны. Дата в to означает конец
+// этого дня, поэтому to превращается в начало следующего.
+func calendarBounds(rawFrom, rawTo string, loc *time.Location) (time.Time, time.Time, error) {
+	if strings.TrimSpace(rawFrom) == "" || strings.TrimSpace(rawTo) == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from and to are required", ErrCalendarRange)
+	}
+	from, _, err := parseCalendarBound(rawFrom, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD or RFC 3339", ErrCalendarRange)
+	}
+	to, isDay, err := parseCalendarBound(rawTo, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD or RFC 3339", ErrCalendarRange)
+	}
+	if isDay {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from is after to", ErrCalendarRange)
+	}
+	if to.Sub(from) > calendarMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: range is longer than %d days", ErrCalendarRange, calendarMaxDays)
+	}
+	return from, to, nil
+}
+
+// parseCalendarBound — дата (полночь в поясе loc, isDay = true) или момент времени.
+func parseCalendarBound(raw string, loc *time.Location) (time.Time, bool, error) {
+	raw = strings.TrimSpace(raw)
+	if day, err := time.ParseInLocation(dayLayout, raw, loc); err == nil {
+		return day, true, nil
+	}
+	moment, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return moment.In(loc), false, nil
+}
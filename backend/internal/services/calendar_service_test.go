@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarService_Range(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x"}).Error)
+	project := models.Project{OwnerID: 1, Title: "Site"}
+	require.NoError(t, db.Create(&project).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	tasks := NewTaskService(taskStorage, projectStorage, storage.NewUserStorage(db))
+	at := func(day, hour int) *time.Time {
+		v := time.Date(2025, 6, day, hour, 0, 0, 0, time.UTC)
+		return &v
+	}
+	create := func(task models.Task) {
+		require.NoError(t, tasks.CreateTask(&task, nil))
+	}
+	create(models.Task{Title: "before", StartAt: at(1, 9), EndAt: at(1, 10)})
+	create(models.Task{Title: "spans", StartAt: at(5, 9), EndAt: at(12, 18), ProjectID: &project.ID})
+	create(models.Task{Title: "meeting", StartAt: at(10, 14), Status: models.StatusCompleted})
+	create(models.Task{Title: "holiday", StartAt: at(11, 0), AllDay: true, ProjectID: &project.ID})
+	create(models.Task{Title: "after", StartAt: at(16, 0), AllDay: true})
+	create(models.Task{Title: "undated"})
+
+	service := NewCalendarService(taskStorage, projectStorage, storage.NewUserStorage(db))
+	result, err := service.Range(1, CalendarQuery{From: "2025-06-10", To: "2025-06-15"})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC), result.To)
+	require.Len(t, result.AllDay, 1)
+	require.Equal(t, "holiday", result.AllDay[0].Title)
+	require.Len(t, result.Timed, 2)
+	require.Equal(t, "spans", result.Timed[0].Title)
+	require.Equal(t, "meeting", result.Timed[1].Title)
+
+	// Задача без времени конца занимает один момент: правая граница не включается.
+	result, err = service.Range(1, CalendarQuery{From: "2025-06-10T00:00:00Z", To: "2025-06-10T14:00:00Z"})
+	require.NoError(t, err)
+	require.Len(t, result.Timed, 1)
+
+	result, err = service.Range(1, CalendarQuery{From: "2025-06-01", To: "2025-06-30", ProjectID: &project.ID, Statuses: []string{"todo"}})
+	require.NoError(t, err)
+	require.Len(t, result.Timed, 1)
+	require.Len(t, result.AllDay, 1)
+
+	none := uint(0)
+	result, err = service.Range(1, CalendarQuery{From: "2025-06-01", To: "2025-06-30", ProjectID: &none, Statuses: []string{models.StatusCompleted}})
+	require.NoError(t, err)
+	require.Len(t, result.Timed, 1)
+	require.Empty(t, result.AllDay)
+
+	missing := uint(99)
+	_, err = service.Range(1, CalendarQuery{From: "2025-06-01", To: "2025-06-30", ProjectID: &missing})
+	require.ErrorIs(t, err, ErrProjectNotFound)
+	for _, query := range []CalendarQuery{
+		{From: "2025-06-01"},
+		{From: "2025-06-30", To: "2025-06-01"},
+		{From: "2025-01-01", To: "2026-06-01"},
+		{From: "June", To: "2025-06-30"},
+		{From: "2025-06-01", To: "2025-06-30", Statuses: []string{"done"}},
+	} {
+		_, err = service.Range(1, query)
+		require.ErrorIs(t, err, ErrCalendarRange)
+	}
+}
+
+func TestCalendarService_DatesInUserTimeZone(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.User{ID: 1, Email: "msk@example.com", Username: "msk", Password: "x", TimeZone: "Europe/Moscow"}).Error)
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	userStorage := storage.NewUserStorage(db)
+	tasks := NewTaskService(taskStorage, projectStorage, userStorage)
+	loc, err := tasks.Location(1)
+	require.NoError(t, err)
+
+	// 23:30 по Москве 10 июня — это ещё 10 июня, хотя в UTC уже 20:30.
+	late := time.Date(2025, 6, 10, 23, 30, 0, 0, loc).UTC()
+	require.NoError(t, tasks.CreateTask(&models.Task{Title: "late", StartAt: &late}, loc))
+	day := time.Date(2025, 6, 11, 12, 0, 0, 0, loc)
+	require.NoError(t, tasks.CreateTask(&models.Task{Title: "day", StartAt: &day, AllDay: true}, loc))
+
+	service := NewCalendarService(taskStorage, projectStorage, userStorage)
+	result, err := service.Range(1, CalendarQuery{From: "2025-06-10", To: "2025-06-10"})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2025, 6, 9, 21, 0, 0, 0, time.UTC), result.From.UTC())
+	require.Equal(t, time.Date(2025, 6, 10, 21, 0, 0, 0, time.UTC), result.To.UTC())
+	require.Len(t, result.Timed, 1)
+	require.Equal(t, "late", result.Timed[0].Title)
+	require.Empty(t, result.AllDay)
+
+	result, err = service.Range(1, CalendarQuery{From: "2025-06-11", To: "2025-06-11"})
+	require.NoError(t, err)
+	require.Empty(t, result.Timed)
+	require.Len(t, result.AllDay, 1)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/mail"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	dataExportRetention = 7 * 24 * time.Hour
+	dataExportLinkTTL   = 24 * time.Hour
+	// dataExportClaimTimeout — через сколько выгрузка в running считается брошенной
+	// и снова забирается обработчиком.
+	dataExportClaimTimeout = 30 * time.Minute
+	// DataExportDownloadPath — публичный адрес скачивания; токен передаётся в параметре token.
+	DataExportDownloadPath = "/api/exports/download"
+)
+
+var (
+	ErrExportInProgress  = errors.New("an export is already in progress")
+	ErrExportNotFound    = errors.New("export not found")
+	ErrExportNotReady    = errors.New("export is not ready")
+	ErrExportLinkInvalid = errors.New("download link is invalid or expired")
+)
+
+// DataExportLink — временная ссылка на скачивание архива.
+type DataExportLink struct {
+	URL       string    `json:"download_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DataExportService готовит выгрузки данных пользователя в фоне и выдаёт ссылки на них.
+type DataExportService struct {
+	db      *gorm.DB
+	exports *storage.DataExportStorage
+	users   *storage.UserStorage
+	mailer  mail.Sender
+	now     func() time.Time
+}
+
+func NewDataExportService(db *gorm.DB, exports *storage.DataExportStorage, users *storage.UserStorage, mailer mail.Sender) *DataExportService {
+	return &DataExportService{db: db, exports: exports, users: users, mailer: mailer, now: time.Now}
+}
+
+// Request ставит выгрузку в очередь; одновременно у пользователя может готовиться только одна.
+func (s *DataExportService) Request(userID uint) (*models.DataExport, error) {
+	active, err := s.exports.HasActive(userID)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrExportInProgress
+	}
+	export := &models.DataExport{UserID: userID, Status: models.DataExportStatusPending}
+	if err := s.exports.Create(export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *DataExportService) List(userID uint) ([]models.DataExport, error) {
+	return s.exports.ListByUser(userID)
+}
+
+func (s *DataExportService) Get(userID, id uint) (*models.DataExport, error) {
+	export, err := s.exports.Get(userID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// IssueLink выпускает новую ссылку на готовый архив; прежняя ссылка перестаёт работать.
+func (s *DataExportService) IssueLink(userID, id uint) (*DataExportLink, error) {
+	export, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != models.DataExportStatusReady {
+		return nil, ErrExportNotReady
+	}
+	return s.issueLink(export)
+}
+
+func (s *DataExportService) issueLink(export *models.DataExport) (*DataExportLink, error) {
+	raw, hash, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := s.now().Add(dataExportLinkTTL)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expiresAt) {
+		expiresAt = *export.ExpiresAt
+	}
+	if err := s.db.Model(&models.DataExport{}).Where("id = ?", export.ID).Updates(map[string]any{
+		"token_hash":      hash,
+		"link_expires_at": expiresAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+	export.TokenHash = hash
+	export.LinkExpiresAt = &expiresAt
+	return &DataExportLink{URL: DataExportDownloadPath + "?token=" + raw, ExpiresAt: expiresAt}, nil
+}
+
+// Download возвращает архив по токену из ссылки. Неизвестный токен (в том числе
+// от перевыпущенной ссылки или удалённого аккаунта) — ErrExportNotFound,
+// просроченная ссылка на существующий архив — ErrExportLinkInvalid.
+func (s *DataExportService) Download(rawToken string) (*models.DataExport, error) {
+	if rawToken == "" {
+		return nil, ErrExportNotFound
+	}
+	export, err := s.exports.GetByTokenHash(HashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	now := s.now()
+	if export.Status != models.DataExportStatusReady ||
+		export.LinkExpiresAt == nil || !now.Before(*export.LinkExpiresAt) ||
+		(export.ExpiresAt != nil && !now.Before(*export.ExpiresAt)) {
+		return nil, ErrExportLinkInvalid
+	}
+	return export, nil
+}
+
+// ProcessPending собирает до limit выгрузок из очереди и возвращает число готовых.
+func (s *DataExportService) ProcessPending(limit int) (int, error) {
+	now := s.now()
+	staleBefore := now.Add(-dataExportClaimTimeout)
+	pending, err := s.exports.ListPending(staleBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := range pending {
+		export := &pending[i]
+		claimed, err := s.exports.Claim(export.ID, now, staleBefore)
+		if err != nil {
+			return done, err
+		}
+		if !claimed {
+			continue
+		}
+		export.Status = models.DataExportStatusRunning
+		export.ClaimedAt = &now
+		if s.process(export) {
+			done++
+		}
+	}
+	return done, nil
+}
+
+func (s *DataExportService) process(export *models.DataExport) bool {
+	archive, err := s.buildTakeout(export.UserID)
+	now := s.now()
+	if err != nil {
+		log.Printf("data export %d failed: %v", export.ID, err)
+		export.Status = models.DataExportStatusFailed
+		export.Error = "failed to build archive"
+		export.CompletedAt = &now
+		if err := s.exports.Update(export); err != nil {
+			log.Printf("data export %d: %v", export.ID, err)
+		}
+		return false
+	}
+
+	expiresAt := now.Add(dataExportRetention)
+	export.Status = models.DataExportStatusReady
+	export.Archive = archive
+	export.Size = int64(len(archive))
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	if err := s.exports.Update(export); err != nil {
+		log.Printf("data export %d: %v", export.ID, err)
+		return false
+	}
+	link, err := s.issueLink(export)
+	if err != nil {
+		log.Printf("data export %d: failed to issue link: %v", export.ID, err)
+		return true
+	}
+	s.notify(export.UserID, link)
+	return true
+}
+
+func (s *DataExportService) notify(userID uint, link *DataExportLink) {
+	user, err := s.users.GetByID(userID)
+	if err != nil || user.Email == "" {
+		return
+	}
+	err = s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Выгрузка данных TaskManager готова",
+		Body: "Архив с вашими данными можно скачать по ссылке:\n" + AppBaseURL() + link.URL +
+			fmt.Sprintf("\n\nСсылка действует до %s (UTC).", link.ExpiresAt.UTC().Format("2006-01-02 15:04")) +
+			"\nНовую ссылку можно получить в настройках аккаунта, пока архив хранится.",
+		Kind: "data_export",
+	})
+	if err != nil {
+		log.Printf("failed to send data export email to user %d: %v", userID, err)
+	}
+}
+
+// Purge удаляет архивы с истёкшим сроком хранения.
+func (s *DataExportService) Purge() error {
+	return s.exports.ExpireBefore(s.now())
+}
+
+// Run периодически обрабатывает очередь выгрузок, пока не закрыт stop.
+func (s *DataExportService) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.ProcessPending(5); err != nil {
+			log.Printf("data export worker: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
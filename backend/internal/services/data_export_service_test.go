@@ -0,0 +1,185 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/mail"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExportService_ExportDownloadAndReimport(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{Email: "owner@example.com", Username: "owner", Password: "x", FullName: "Owner", Language: "ru", Theme: "dark", MaxProjects: 5}
+	friend := models.User{Email: "friend@example.com", Username: "friend", Password: "x", MaxProjects: 3}
+	require.NoError(t, db.Create(&owner).Error)
+	require.NoError(t, db.Create(&friend).Error)
+
+	projects := storage.NewProjectStorage(db)
+	tasks := storage.NewTaskStorage(db)
+	projectService := NewProjectService(projects, tasks, storage.NewUserStorage(db))
+	active, err := projectService.Create(owner.ID, &models.ProjectInput{Title: "Active", Status: models.ProjectStatusActive})
+	require.NoError(t, err)
+	archived, err := projectService.Create(owner.ID, &models.ProjectInput{Title: "Old", Status: models.ProjectStatusCompleted})
+	require.NoError(t, err)
+	require.NoError(t, tasks.Create(&models.Task{Title: "Ship", Status: models.StatusInProgress, Priority: models.PriorityHigh, Stage: "doing", ProjectID: &active.ID}))
+	require.NoError(t, tasks.Create(&models.Task{Title: "Legacy", Status: models.StatusCompleted, Priority: models.PriorityLow, Stage: "done", ProjectID: &archived.ID}))
+	require.NoError(t, projectService.Archive(owner.ID, archived.ID))
+	_, err = projectService.AddMember(owner.ID, active.ID, friend.Email)
+	require.NoError(t, err)
+
+	service := NewDataExportService(db, storage.NewDataExportStorage(db), storage.NewUserStorage(db), mail.NewOutboxSender(db))
+	export, err := service.Request(owner.ID)
+	require.NoError(t, err)
+	_, err = service.Request(owner.ID)
+	require.ErrorIs(t, err, ErrExportInProgress)
+
+	done, err := service.ProcessPending(10)
+	require.NoError(t, err)
+	require.Equal(t, 1, done)
+
+	// Ссылка приходит письмом; по ней отдаётся ZIP с манифестом.
+	var outbox models.OutboxEmail
+	require.NoError(t, db.Where("kind = ?", "data_export").First(&outbox).Error)
+	idx := strings.Index(outbox.Body, DataExportDownloadPath+"?token=")
+	require.Positive(t, idx)
+	linkURL, err := url.Parse(strings.Fields(outbox.Body[idx:])[0])
+	require.NoError(t, err)
+	ready, err := service.Download(linkURL.Query().Get("token"))
+	require.NoError(t, err)
+	require.Equal(t, export.ID, ready.ID)
+
+	files := unzipForTest(t, ready.Archive)
+	require.Contains(t, files, "README.md")
+	var manifest TakeoutManifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	require.Equal(t, TakeoutFormat, manifest.Format)
+	require.Equal(t, 2, manifest.Files["projects.json"])
+	require.Equal(t, 2, manifest.Files["tasks.json"])
+	require.Equal(t, 1, manifest.Files["memberships.json"])
+
+	// Новая ссылка отменяет старую, просроченная не работает.
+	fresh, err := service.IssueLink(owner.ID, export.ID)
+	require.NoError(t, err)
+	_, err = service.Download(linkURL.Query().Get("token"))
+	require.ErrorIs(t, err, ErrExportNotFound)
+	freshURL, err := url.Parse(fresh.URL)
+	require.NoError(t, err)
+	service.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	_, err = service.Download(freshURL.Query().Get("token"))
+	require.ErrorIs(t, err, ErrExportLinkInvalid)
+	service.now = time.Now
+
+	// Архив импортируется в другой аккаунт с сохранением архивности и настроек.
+	result, err := service.Import(friend.ID, ready.Archive)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Projects)
+	require.Equal(t, 2, result.Tasks)
+	require.True(t, result.SettingsApplied)
+	require.Equal(t, 1, result.MembershipsSkipped)
+
+	imported, err := projects.List(friend.ID, true)
+	require.NoError(t, err)
+	var importedArchived *models.Project
+	for i := range imported {
+		if imported[i].Title == "Old" && imported[i].OwnerID == friend.ID {
+			importedArchived = &imported[i]
+		}
+	}
+	require.NotNil(t, importedArchived)
+	require.NotNil(t, importedArchived.ArchivedAt)
+	var hidden models.Task
+	require.NoError(t, db.Unscoped().Where("project_id = ?", importedArchived.ID).First(&hidden).Error)
+	require.Equal(t, "Legacy", hidden.Title)
+	require.True(t, hidden.DeletedAt.Valid)
+
+	var reloaded models.User
+	require.NoError(t, db.First(&reloaded, friend.ID).Error)
+	require.Equal(t, "dark", reloaded.Theme)
+	require.Equal(t, "Owner", reloaded.FullName)
+
+	// Лимит проектов соблюдается: повторный импорт превысил бы его.
+	_, err = service.Import(friend.ID, ready.Archive)
+	require.ErrorIs(t, err, ErrProjectLimit)
+
+	// Истёкшие архивы удаляются.
+	service.now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+	require.NoError(t, service.Purge())
+	expired, err := service.Get(owner.ID, export.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.DataExportStatusExpired, expired.Status)
+}
+
+func TestDataExportService_ReclaimsStaleRunningExport(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Email: "owner@example.com", Username: "owner", Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	service := NewDataExportService(db, storage.NewDataExportStorage(db), storage.NewUserStorage(db), mail.NewOutboxSender(db))
+
+	// Процесс упал посреди сборки: выгрузка осталась в running.
+	export, err := service.Request(user.ID)
+	require.NoError(t, err)
+	claimedAt := time.Now().Add(-time.Hour)
+	require.NoError(t, db.Model(export).Updates(map[string]any{"status": models.DataExportStatusRunning, "claimed_at": claimedAt}).Error)
+	_, err = service.Request(user.ID)
+	require.ErrorIs(t, err, ErrExportInProgress)
+
+	done, err := service.ProcessPending(10)
+	require.NoError(t, err)
+	require.Equal(t, 1, done)
+	reloaded, err := service.Get(user.ID, export.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.DataExportStatusReady, reloaded.Status)
+
+	// Свежую выгрузку в работе второй обработчик не трогает.
+	second, err := service.Request(user.ID)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(second).Updates(map[string]any{"status": models.DataExportStatusRunning, "claimed_at": time.Now()}).Error)
+	done, err = service.ProcessPending(10)
+	require.NoError(t, err)
+	require.Zero(t, done)
+}
+
+func TestDataExportService_ImportRejectsForeignArchive(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Email: "u@example.com", Username: "u", Password: "x", MaxProjects: 5}
+	require.NoError(t, db.Create(&user).Error)
+	service := NewDataExportService(db, storage.NewDataExportStorage(db), storage.NewUserStorage(db), mail.NewOutboxSender(db))
+
+	_, err := service.Import(user.ID, []byte("not a zip"))
+	require.ErrorIs(t, err, ErrInvalidTakeout)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	require.NoError(t, writeZipJSON(zw, "manifest.json", TakeoutManifest{Format: TakeoutFormat, Version: TakeoutVersion}))
+	require.NoError(t, writeZipJSON(zw, "projects.json", []TakeoutProject{{ID: 1, Title: "A"}}))
+	require.NoError(t, writeZipJSON(zw, "tasks.json", []TakeoutTask{{ID: 1, ProjectID: 9, Title: "orphan"}}))
+	require.NoError(t, zw.Close())
+	_, err = service.Import(user.ID, buf.Bytes())
+	require.ErrorIs(t, err, ErrInvalidTakeout)
+	require.Contains(t, err.Error(), "unknown project 9")
+}
+
+func unzipForTest(t *testing.T, archive []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = data
+	}
+	return files
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/mail"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Действия, доступность которых для пользователей с неподтверждённым email
+// задаётся политикой (UNVERIFIED_USER_CAPABILITIES).
+const (
+	CapabilityLogin          = "login"
+	CapabilityCreateProjects = "create_projects"
+	CapabilityAccessTokens   = "access_tokens"
+)
+
+const (
+	emailVerificationTTL       = 48 * time.Hour
+	emailVerificationWindow    = time.Hour
+	emailVerificationPerWindow = 3
+
+	defaultUnverifiedCapabilities = CapabilityLogin + "," + CapabilityCreateProjects
+)
+
+var (
+	ErrEmailNotVerified         = errors.New("email verification required")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailAlreadyVerified     = errors.New("email already verified")
+	ErrVerificationRateLimited  = errors.New("too many verification emails, try again later")
+	ErrInvalidEmail             = errors.New("invalid email address")
+)
+
+// VerificationPolicy — что разрешено пользователю, пока его email не подтверждён.
+type VerificationPolicy struct {
+	allowed map[string]struct{}
+}
+
+// NewVerificationPolicy строит политику из списка разрешённых действий.
+func NewVerificationPolicy(capabilities ...string) VerificationPolicy {
+	allowed := make(map[string]struct{}, len(capabilities))
+	for _, c := range capabilities {
+		if c = strings.TrimSpace(c); c != "" {
+			allowed[c] = struct{}{}
+		}
+	}
+	return VerificationPolicy{allowed: allowed}
+}
+
+// VerificationPolicyFromEnv читает UNVERIFIED_USER_CAPABILITIES (список через запятую).
+// По умолчанию неподтверждённый пользователь может входить и создавать свои проекты.
+func VerificationPolicyFromEnv() VerificationPolicy {
+	raw, ok := os.LookupEnv("UNVERIFIED_USER_CAPABILITIES")
+	if !ok {
+		raw = defaultUnverifiedCapabilities
+	}
+	return NewVerificationPolicy(strings.Split(raw, ",")...)
+}
+
+// Allows сообщает, может ли пользователь выполнить действие.
+func (p VerificationPolicy) Allows(user *models.User, capability string) bool {
+	if user.IsEmailVerified() {
+		return true
+	}
+	_, ok := p.allowed[capability]
+	return ok
+}
+
+// EmailVerificationService отправляет и проверяет ссылки подтверждения email.
+type EmailVerificationService struct {
+	tokens *storage.EmailVerificationStorage
+	users  *storage.UserStorage
+	mailer mail.Sender
+	policy VerificationPolicy
+	now    func() time.Time
+}
+
+func NewEmailVerificationService(tokens *storage.EmailVerificationStorage, users *storage.UserStorage, mailer mail.Sender, policy VerificationPolicy) *EmailVerificationService {
+	return &EmailVerificationService{tokens: tokens, users: users, mailer: mailer, policy: policy, now: time.Now}
+}
+
+// Require возвращает ErrEmailNotVerified, если политика запрещает действие.
+func (s *EmailVerificationService) Require(user *models.User, capability string) error {
+	if s.policy.Allows(user, capability) {
+		return nil
+	}
+	return ErrEmailNotVerified
+}
+
+// CheckCapability — то же по ID пользователя (используется middleware).
+func (s *EmailVerificationService) CheckCapability(userID uint, capability string) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.Require(user, capability)
+}
+
+// Send выпускает токен для текущего адреса пользователя и кладёт письмо в outbox.
+func (s *EmailVerificationService) Send(user *models.User) error {
+	if user.Email == "" {
+		return ErrInvalidEmail
+	}
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+	now := s.now()
+	sent, err := s.tokens.CountSince(user.ID, now.Add(-emailVerificationWindow))
+	if err != nil {
+		return err
+	}
+	if sent >= emailVerificationPerWindow {
+		return rateQuotaError(QuotaVerificationEmails, emailVerificationPerWindow, sent, emailVerificationWindow)
+	}
+
+	raw, hash, err := GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := s.tokens.Create(&models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hash,
+		ExpiresAt: now.Add(emailVerificationTTL),
+	}); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", AppBaseURL(), raw)
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Подтвердите email в TaskManager",
+		Body: "Чтобы подтвердить адрес " + user.Email + ", перейдите по ссылке:\n" + link +
+			"\n\nСсылка действует 48 часов.",
+		Kind: "verify_email",
+	})
+}
+
+// Resend повторно отправляет письмо пользователю по ID.
+func (s *EmailVerificationService) Resend(userID uint) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.Send(user)
+}
+
+// Confirm подтверждает адрес по токену из письма.
+func (s *EmailVerificationService) Confirm(rawToken string) error {
+	token, err := s.tokens.GetByHash(HashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+	now := s.now()
+	if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+	confirmed, err := s.tokens.Confirm(token, now)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		// токен уже использован или адрес с тех пор сменился
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+// NormalizeEmail приводит адрес к нижнему регистру и проверяет формат.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+	parsed, err := netmail.ParseAddress(email)
+	if err != nil || parsed.Address != email || len(email) > 255 {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationService_ConfirmAndReverifyOnChange(t *testing.T) {
+	db := setupTestDB(t)
+	hash, err := HashPassword("secret1")
+	require.NoError(t, err)
+	user := &models.User{Email: "new@example.com", Username: "new", Password: hash}
+	require.NoError(t, db.Create(user).Error)
+
+	users := storage.NewUserStorage(db)
+	mailer := &capturingMailer{}
+	verification := NewEmailVerificationService(storage.NewEmailVerificationStorage(db), users, mailer, NewVerificationPolicy(CapabilityLogin))
+	userService := NewUserService(db, users, storage.NewProjectStorage(db), storage.NewTaskStorage(db))
+
+	require.NoError(t, verification.Send(user))
+	require.Len(t, mailer.messages, 1)
+	firstToken := resetTokenPattern.FindStringSubmatch(mailer.messages[0].Body)[1]
+
+	require.NoError(t, verification.Confirm(firstToken))
+	reloaded, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	require.True(t, reloaded.IsEmailVerified())
+	require.ErrorIs(t, verification.Confirm(firstToken), ErrInvalidVerificationToken)
+
+	// Смена адреса снимает подтверждение; ссылка, выпущенная для старого адреса, не подходит.
+	require.NoError(t, db.Model(reloaded).Update("email_verified_at", nil).Error)
+	reloaded.EmailVerifiedAt = nil
+	require.NoError(t, verification.Send(reloaded))
+	staleToken := resetTokenPattern.FindStringSubmatch(mailer.messages[1].Body)[1]
+
+	changed, err := userService.ChangeEmail(user.ID, "secret1", "Other@Example.com")
+	require.NoError(t, err)
+	require.Equal(t, "other@example.com", changed.Email)
+	require.False(t, changed.IsEmailVerified())
+	require.ErrorIs(t, verification.Confirm(staleToken), ErrInvalidVerificationToken)
+}
+
+func TestVerificationPolicy_Allows(t *testing.T) {
+	policy := NewVerificationPolicy(CapabilityLogin)
+	unverified := &models.User{Email: "u@example.com"}
+
+	require.True(t, policy.Allows(unverified, CapabilityLogin))
+	require.False(t, policy.Allows(unverified, CapabilityAccessTokens))
+
+	now := unverified.CreatedAt
+	unverified.EmailVerifiedAt = &now
+	require.True(t, policy.Allows(unverified, CapabilityAccessTokens))
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	email, err := NormalizeEmail("  Jane.Doe@Example.COM ")
+	require.NoError(t, err)
+	require.Equal(t, "jane.doe@example.com", email)
+
+	_, err = NormalizeEmail("not-an-email")
+	require.ErrorIs(t, err, ErrInvalidEmail)
+	_, err = NormalizeEmail("Jane <jane@example.com>")
+	require.ErrorIs(t, err, ErrInvalidEmail)
+}
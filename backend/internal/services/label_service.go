@@ -0,0 +1,382 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Способы массовой установки меток.
+const (
+	LabelsAdd     = "add"
+	LabelsRemove  = "remove"
+	LabelsReplace = "replace"
+)
+
+var (
+	ErrLabelNotFound  = errors.New("label not found")
+	ErrLabelInvalid   = errors.New("invalid label")
+	ErrLabelExists    = errors.New("label with this name already exists")
+	ErrLabelForbidden = errors.New("only the project owner can change project labels")
+	ErrLabelScope     = errors.New("project labels can only be used on tasks of the same project")
+	ErrLabelMerge     = errors.New("labels can only be merged within the same owner or project")
+	ErrLabelMode      = errors.New("mode must be add, remove or replace")
+	ErrLabelPersonal  = errors.New("personal labels cannot be shared")
+)
+
+// LabelInput — тело создания метки. Без project_id метка личная.
+type LabelInput struct {
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	ProjectID *uint  `json:"project_id"`
+}
+
+// LabelPatch — переименование и смена цвета.
+type LabelPatch struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+// LabelService — метки задач. Личные метки видит и меняет только владелец;
+// метки проекта видят его участники, а меняет владелец проекта.
+type LabelService struct {
+	labels   *storage.LabelStorage
+	tasks    *storage.TaskStorage
+	projects *storage.ProjectStorage
+}
+
+func NewLabelService(l *storage.LabelStorage, t *storage.TaskStorage, p *storage.ProjectStorage) *LabelService {
+	return &LabelService{labels: l, tasks: t, projects: p}
+}
+
+// List возвращает личные метки и метки доступных проектов с числом задач.
+func (s *LabelService) List(userID uint, projectID *uint) ([]models.Label, error) {
+	labels, err := s.labels.ListVisible(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(labels))
+	for i := range labels {
+		ids[i] = labels[i].ID
+	}
+	counts, err := s.labels.CountTasks(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range labels {
+		labels[i].TasksCount = counts[labels[i].ID]
+	}
+	return labels, nil
+}
+
+func (s *LabelService) Create(userID uint, input LabelInput) (*models.Label, error) {
+	name, err := models.NormalizeLabelName(input.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLabelInvalid, err)
+	}
+	color, err := models.NormalizeLabelColor(input.Color)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLabelInvalid, err)
+	}
+	label := &models.Label{Name: name, Color: color}
+	if input.ProjectID != nil {
+		project, err := s.projects.GetAccessible(userID, *input.ProjectID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+		if project.OwnerID != userID {
+			return nil, ErrLabelForbidden
+		}
+		label.ProjectID = &project.ID
+	} else {
+		label.OwnerID = &userID
+	}
+	if err := s.ensureUniqueName(label, name); err != nil {
+		return nil, err
+	}
+	if err := s.labels.Create(label); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// Update переименовывает метку и/или меняет цвет.
+func (s *LabelService) Update(userID, id uint, patch LabelPatch) (*models.Label, error) {
+	label, err := s.writable(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Name != nil {
+		name, err := models.NormalizeLabelName(*patch.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLabelInvalid, err)
+		}
+		if err := s.ensureUniqueName(label, name); err != nil {
+			return nil, err
+		}
+		label.Name = name
+	}
+	if patch.Color != nil {
+		if label.Color, err = models.NormalizeLabelColor(*patch.Color); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLabelInvalid, err)
+		}
+	}
+	if err := s.labels.Update(label); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// Merge переносит задачи метки sourceID на targetID и удаляет sourceID.
+// Обе метки должны принадлежать одному пользователю или одному проекту.
+func (s *LabelService) Merge(userID, sourceID, targetID uint) (*models.Label, error) {
+	if sourceID == targetID {
+		return nil, ErrLabelMerge
+	}
+	source, err := s.writable(userID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.writable(userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameID(source.OwnerID, target.OwnerID) || !sameID(source.ProjectID, target.ProjectID) {
+		return nil, ErrLabelMerge
+	}
+	if err := s.labels.Merge(source.ID, target.ID); err != nil {
+		return nil, err
+	}
+	counts, err := s.labels.CountTasks([]uint{target.ID})
+	if err != nil {
+		return nil, err
+	}
+	target.TasksCount = counts[target.ID]
+	return target, nil
+}
+
+func (s *LabelService) Delete(userID, id uint) error {
+	label, err := s.writable(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.labels.Delete(label.ID)
+}
+
+// SetOnTasks добавляет, снимает или заменяет метки у задач. При замене снимаются
+// только метки, которыми пользователь может распоряжаться; чужие личные остаются.
+func (s *LabelService) SetOnTasks(userID uint, taskIDs, labelIDs []uint, mode string) error {
+	if mode == "" {
+		mode = LabelsAdd
+	}
+	if mode != LabelsAdd && mode != LabelsRemove && mode != LabelsReplace {
+		return ErrLabelMode
+	}
+	tasks, err := s.tasks.GetByIDs(taskIDs)
+	if err != nil {
+		return err
+	}
+	if len(tasks) != len(uniqueUints(taskIDs)) {
+		return ErrTaskNotFound
+	}
+	labels, err := s.labels.GetByIDs(labelIDs)
+	if err != nil {
+		return err
+	}
+	if len(labels) != len(uniqueUints(labelIDs)) {
+		return ErrLabelNotFound
+	}
+
+	owned := make(map[uint]bool)
+	for i := range tasks {
+		if tasks[i].ProjectID == nil {
+			continue
+		}
+		if _, ok := owned[*tasks[i].ProjectID]; ok {
+			continue
+		}
+		project, err := s.projects.GetAccessible(userID, *tasks[i].ProjectID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTaskNotFound
+			}
+			return err
+		}
+		owned[project.ID] = project.OwnerID == userID
+	}
+	for i := range labels {
+		if err := s.checkWritable(userID, &labels[i]); err != nil {
+			return err
+		}
+		if labels[i].ProjectID == nil {
+			continue
+		}
+		for j := range tasks {
+			if !sameID(tasks[j].ProjectID, labels[i].ProjectID) {
+				return fmt.Errorf("%w: label %q, task %d", ErrLabelScope, labels[i].Name, tasks[j].ID)
+			}
+		}
+	}
+
+	ids := make([]uint, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	switch mode {
+	case LabelsAdd:
+		return s.labels.AddToTasks(labelIDs, ids)
+	case LabelsRemove:
+		return s.labels.RemoveFromTasks(labelIDs, ids)
+	}
+
+	current, err := s.labels.ForTasks(userID, ids)
+	if err != nil {
+		return err
+	}
+	var removable []uint
+	for _, row := range current {
+		if row.OwnerID != nil || (row.ProjectID != nil && owned[*row.ProjectID]) {
+			removable = append(removable, row.ID)
+		}
+	}
+	if err := s.labels.RemoveFromTasks(uniqueUints(removable), ids); err != nil {
+		return err
+	}
+	return s.labels.AddToTasks(labelIDs, ids)
+}
+
+// CheckVisible проверяет, что все метки фильтра видны пользователю.
+func (s *LabelService) CheckVisible(userID uint, labelIDs []uint) error {
+	labels, err := s.labels.GetByIDs(labelIDs)
+	if err != nil {
+		return err
+	}
+	if len(labels) != len(uniqueUints(labelIDs)) {
+		return ErrLabelNotFound
+	}
+	for i := range labels {
+		if err := s.checkVisible(userID, &labels[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckShared проверяет, что среди меток нет личных: такие нельзя показывать другим.
+func (s *LabelService) CheckShared(labelIDs []uint) error {
+	labels, err := s.labels.GetByIDs(labelIDs)
+	if err != nil {
+		return err
+	}
+	for i := range labels {
+		if labels[i].ProjectID == nil {
+			return fmt.Errorf("%w: %q", ErrLabelPersonal, labels[i].Name)
+		}
+	}
+	return nil
+}
+
+// Attach заполняет Task.Labels метками, видимыми пользователю.
+func (s *LabelService) Attach(userID uint, tasks []models.Task) error {
+	ids := make([]uint, len(tasks))
+	index := make(map[uint]int, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+		index[tasks[i].ID] = i
+	}
+	rows, err := s.labels.ForTasks(userID, ids)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		task := &tasks[index[row.TaskID]]
+		task.Labels = append(task.Labels, row.Label)
+	}
+	return nil
+}
+
+func (s *LabelService) writable(userID, id uint) (*models.Label, error) {
+	label, err := s.labels.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLabelNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkWritable(userID, label); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// checkVisible: чужая личная метка или метка недоступного проекта выглядят как отсутствующие.
+func (s *LabelService) checkVisible(userID uint, label *models.Label) error {
+	if label.OwnerID != nil {
+		if *label.OwnerID != userID {
+			return ErrLabelNotFound
+		}
+		return nil
+	}
+	if _, err := s.projects.GetAccessible(userID, *label.ProjectID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrLabelNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *LabelService) checkWritable(userID uint, label *models.Label) error {
+	if err := s.checkVisible(userID, label); err != nil {
+		return err
+	}
+	if label.ProjectID != nil {
+		if _, err := s.projects.Get(userID, *label.ProjectID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLabelForbidden
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LabelService) ensureUniqueName(label *models.Label, name string) error {
+	existing, err := s.labels.FindByName(label.OwnerID, label.ProjectID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if existing.ID != label.ID {
+		return ErrLabelExists
+	}
+	return nil
+}
+
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func uniqueUints(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelService_ManageAndFilter(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	viewer := models.User{ID: 2, Email: "viewer@example.com", Username: "viewer", Password: "x"}
+	require.NoError(t, db.Create([]*models.User{&owner, &viewer}).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, storage.NewUserStorage(db))
+	service := NewLabelService(storage.NewLabelStorage(db), taskStorage, projectStorage)
+
+	project, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site"})
+	require.NoError(t, err)
+	_, err = projects.AddMember(owner.ID, project.ID, viewer.Email)
+	require.NoError(t, err)
+	inbox := models.Task{Title: "Inbox"}
+	design := models.Task{Title: "Design", ProjectID: &project.ID}
+	backend := models.Task{Title: "Backend", ProjectID: &project.ID}
+	require.NoError(t, db.Create([]*models.Task{&inbox, &design, &backend}).Error)
+
+	urgent, err := service.Create(owner.ID, LabelInput{Name: "  Urgent ", Color: "FF0000"})
+	require.NoError(t, err)
+	require.Equal(t, "Urgent", urgent.Name)
+	require.Equal(t, "#ff0000", urgent.Color)
+	_, err = service.Create(owner.ID, LabelInput{Name: "urgent"})
+	require.ErrorIs(t, err, ErrLabelExists)
+	_, err = service.Create(owner.ID, LabelInput{Name: "bad", Color: "red"})
+	require.ErrorIs(t, err, ErrLabelInvalid)
+
+	ui, err := service.Create(owner.ID, LabelInput{Name: "UI", ProjectID: &project.ID})
+	require.NoError(t, err)
+	frontend, err := service.Create(owner.ID, LabelInput{Name: "Frontend", ProjectID: &project.ID})
+	require.NoError(t, err)
+	_, err = service.Create(viewer.ID, LabelInput{Name: "Mine", ProjectID: &project.ID})
+	require.ErrorIs(t, err, ErrLabelForbidden)
+	mine, err := service.Create(viewer.ID, LabelInput{Name: "Mine"})
+	require.NoError(t, err)
+
+	// Метки проекта — только на задачи проекта; личные — на любые видимые.
+	require.ErrorIs(t, service.SetOnTasks(owner.ID, []uint{inbox.ID}, []uint{ui.ID}, LabelsAdd), ErrLabelScope)
+	require.NoError(t, service.SetOnTasks(owner.ID, []uint{inbox.ID, design.ID}, []uint{urgent.ID}, LabelsAdd))
+	require.NoError(t, service.SetOnTasks(owner.ID, []uint{design.ID, backend.ID}, []uint{ui.ID}, ""))
+	require.NoError(t, service.SetOnTasks(owner.ID, []uint{design.ID}, []uint{frontend.ID}, LabelsAdd))
+	require.NoError(t, service.SetOnTasks(viewer.ID, []uint{design.ID}, []uint{mine.ID}, LabelsAdd))
+	require.ErrorIs(t, service.SetOnTasks(viewer.ID, []uint{design.ID}, []uint{ui.ID}, LabelsRemove), ErrLabelForbidden)
+	require.ErrorIs(t, service.SetOnTasks(viewer.ID, []uint{design.ID}, []uint{urgent.ID}, LabelsRemove), ErrLabelNotFound)
+
+	ids := func(tasks []models.Task) []uint {
+		result := make([]uint, len(tasks))
+		for i := range tasks {
+			result[i] = tasks[i].ID
+		}
+		return result
+	}
+	anyOf, err := taskStorage.List("asc", TaskFilter{LabelIDs: []uint{urgent.ID, ui.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []uint{inbox.ID, design.ID, backend.ID}, ids(anyOf))
+	allOf, err := taskStorage.List("asc", TaskFilter{LabelIDs: []uint{urgent.ID, ui.ID, ui.ID}, LabelMode: LabelMatchAll})
+	require.NoError(t, err)
+	require.Equal(t, []uint{design.ID}, ids(allOf))
+
+	// Каждый видит метки проекта и свои личные.
+	require.NoError(t, service.Attach(viewer.ID, allOf))
+	names := []string{}
+	for _, label := range allOf[0].Labels {
+		names = append(names, label.Name)
+	}
+	require.Equal(t, []string{"Frontend", "Mine", "UI"}, names)
+	require.NoError(t, service.CheckVisible(viewer.ID, []uint{ui.ID, mine.ID}))
+	require.ErrorIs(t, service.CheckVisible(viewer.ID, []uint{urgent.ID}), ErrLabelNotFound)
+
+	// Замена снимает только свои метки: личная метка участника остаётся.
+	require.NoError(t, service.SetOnTasks(owner.ID, []uint{design.ID}, []uint{frontend.ID}, LabelsReplace))
+	tasks, err := taskStorage.List("asc", TaskFilter{LabelIDs: []uint{mine.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []uint{design.ID}, ids(tasks))
+	tasks, err = taskStorage.List("asc", TaskFilter{LabelIDs: []uint{urgent.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []uint{inbox.ID}, ids(tasks))
+
+	// Переименование, слияние и удаление.
+	interfaceName, frontendName, black := "Interface", "frontend", "#000000"
+	renamed, err := service.Update(owner.ID, ui.ID, LabelPatch{Name: &interfaceName})
+	require.NoError(t, err)
+	require.Equal(t, "Interface", renamed.Name)
+	_, err = service.Update(owner.ID, ui.ID, LabelPatch{Name: &frontendName})
+	require.ErrorIs(t, err, ErrLabelExists)
+	_, err = service.Merge(owner.ID, ui.ID, urgent.ID)
+	require.ErrorIs(t, err, ErrLabelMerge)
+	merged, err := service.Merge(owner.ID, ui.ID, frontend.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, merged.TasksCount)
+	_, err = service.Update(owner.ID, ui.ID, LabelPatch{Color: &black})
+	require.ErrorIs(t, err, ErrLabelNotFound)
+
+	require.NoError(t, service.Delete(owner.ID, urgent.ID))
+	list, err := service.List(owner.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, frontend.ID, list[0].ID)
+	var links int64
+	require.NoError(t, db.Model(&models.TaskLabel{}).Where("label_id = ?", urgent.ID).Count(&links).Error)
+	require.Zero(t, links)
+}
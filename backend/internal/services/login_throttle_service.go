@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+)
+
+// ThrottleLimits — пороги для одного ключа (идентификатор или IP).
+type ThrottleLimits struct {
+	DelayAfter int           // после стольких неудач включаются нарастающие задержки
+	LockAfter  int           // после стольких неудач ключ блокируется на Lockout
+	BaseDelay  time.Duration // задержка после DelayAfter неудач, дальше удваивается
+	MaxDelay   time.Duration
+}
+
+// LoginThrottleConfig — настройки защиты от перебора паролей.
+type LoginThrottleConfig struct {
+	Window     time.Duration // за какой период считаются неудачи
+	Lockout    time.Duration
+	Identifier ThrottleLimits
+	IP         ThrottleLimits
+}
+
+// DefaultLoginThrottleConfig — значения по умолчанию: аккаунт блокируется после
+// 10 неудач за 15 минут, IP — после 50.
+func DefaultLoginThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		Window:  15 * time.Minute,
+		Lockout: 15 * time.Minute,
+		Identifier: ThrottleLimits{
+			DelayAfter: 3,
+			LockAfter:  10,
+			BaseDelay:  time.Second,
+			MaxDelay:   time.Minute,
+		},
+		IP: ThrottleLimits{
+			DelayAfter: 20,
+			LockAfter:  50,
+			BaseDelay:  time.Second,
+			MaxDelay:   time.Minute,
+		},
+	}
+}
+
+// ThrottleError сообщает, через сколько можно повторить попытку.
+type ThrottleError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottleError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, account temporarily locked"
+	}
+	return "too many failed login attempts, slow down"
+}
+
+// LoginThrottleService ограничивает частоту попыток входа по идентификатору и IP.
+type LoginThrottleService struct {
+	failures *storage.LoginFailureStorage
+	cfg      LoginThrottleConfig
+	now      func() time.Time
+}
+
+func NewLoginThrottleService(failures *storage.LoginFailureStorage, cfg LoginThrottleConfig) *LoginThrottleService {
+	return &LoginThrottleService{failures: failures, cfg: cfg, now: time.Now}
+}
+
+// Check возвращает *ThrottleError, если попытку нужно отклонить без проверки пароля.
+func (s *LoginThrottleService) Check(identifier, ip string) error {
+	now := s.now()
+	since := now.Add(-s.cfg.Window)
+	var worst *ThrottleError
+
+	keys := []struct {
+		column string
+		value  string
+		limits ThrottleLimits
+	}{
+		{"identifier", normalizeIdentifier(identifier), s.cfg.Identifier},
+		{"ip", strings.TrimSpace(ip), s.cfg.IP},
+	}
+	for _, key := range keys {
+		if key.value == "" {
+			continue
+		}
+		count, last, err := s.failures.Stats(key.column, key.value, since)
+		if err != nil {
+			return err
+		}
+		if verdict := s.evaluate(int(count), last, key.limits, now); verdict != nil {
+			if worst == nil || verdict.RetryAfter > worst.RetryAfter {
+				worst = verdict
+			}
+		}
+	}
+	if worst != nil {
+		return worst
+	}
+	return nil
+}
+
+// RecordFailure фиксирует неудачную попытку.
+func (s *LoginThrottleService) RecordFailure(identifier, ip string) error {
+	return s.failures.Create(&models.LoginFailure{
+		Identifier: normalizeIdentifier(identifier),
+		IP:         truncate(strings.TrimSpace(ip), 64),
+		CreatedAt:  s.now(),
+	})
+}
+
+// RecordSuccess сбрасывает счётчик идентификатора после успешного входа.
+// Счётчик IP не сбрасывается: иначе перебор можно маскировать своим аккаунтом.
+func (s *LoginThrottleService) RecordSuccess(identifier string) error {
+	return s.failures.DetachIdentifier(normalizeIdentifier(identifier))
+}
+
+// Unlock снимает блокировку с идентификатора и/или IP (действие администратора).
+func (s *LoginThrottleService) Unlock(identifier, ip string) (int64, error) {
+	var removed int64
+	if id := normalizeIdentifier(identifier); id != "" {
+		n, err := s.failures.DeleteByIdentifier(id)
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	if ip = strings.TrimSpace(ip); ip != "" {
+		n, err := s.failures.DeleteByIP(ip)
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// Purge удаляет записи, которые уже не влияют на ограничения.
+func (s *LoginThrottleService) Purge() error {
+	horizon := s.cfg.Window
+	if s.cfg.Lockout > horizon {
+		horizon = s.cfg.Lockout
+	}
+	return s.failures.PurgeBefore(s.now().Add(-2 * horizon))
+}
+
+func (s *LoginThrottleService) evaluate(count int, last *time.Time, limits ThrottleLimits, now time.Time) *ThrottleError {
+	if last == nil || count < limits.DelayAfter {
+		return nil
+	}
+	if limits.LockAfter > 0 && count >= limits.LockAfter {
+		if until := last.Add(s.cfg.Lockout); now.Before(until) {
+			return &ThrottleError{RetryAfter: until.Sub(now), Locked: true}
+		}
+		return nil
+	}
+	delay := limits.BaseDelay
+	for i := limits.DelayAfter; i < count && delay < limits.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > limits.MaxDelay {
+		delay = limits.MaxDelay
+	}
+	if until := last.Add(delay); now.Before(until) {
+		return &ThrottleError{RetryAfter: until.Sub(now)}
+	}
+	return nil
+}
+
+// TwoFactorThrottleKey — ключ для ограничения попыток ввода кода 2FA.
+func TwoFactorThrottleKey(userID uint) string {
+	return fmt.Sprintf("2fa:%d", userID)
+}
+
+func normalizeIdentifier(identifier string) string {
+	return truncate(strings.ToLower(strings.TrimSpace(identifier)), 255)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spozitivom/taskmanager/internal/storage"
+)
+
+func newTestThrottle(t *testing.T) (*LoginThrottleService, *time.Time) {
+	t.Helper()
+	db := setupTestDB(t)
+	now := time.Now()
+	svc := NewLoginThrottleService(storage.NewLoginFailureStorage(db), DefaultLoginThrottleConfig())
+	svc.now = func() time.Time { return now }
+	return svc, &now
+}
+
+func requireThrottled(t *testing.T, err error, locked bool) *ThrottleError {
+	t.Helper()
+	var throttled *ThrottleError
+	require.True(t, errors.As(err, &throttled), "expected ThrottleError, got %v", err)
+	require.Equal(t, locked, throttled.Locked)
+	return throttled
+}
+
+func TestLoginThrottle_ProgressiveDelay(t *testing.T) {
+	svc, now := newTestThrottle(t)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, svc.Check("User@Example.com", "10.0.0.1"))
+		require.NoError(t, svc.RecordFailure("user@example.com", "10.0.0.1"))
+	}
+	first := requireThrottled(t, svc.Check("user@example.com", "10.0.0.2"), false)
+	require.Equal(t, time.Second, first.RetryAfter)
+
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, svc.Check("user@example.com", "10.0.0.1"))
+	require.NoError(t, svc.RecordFailure("user@example.com", "10.0.0.1"))
+	second := requireThrottled(t, svc.Check("user@example.com", "10.0.0.1"), false)
+	require.Greater(t, second.RetryAfter, first.RetryAfter)
+
+	// Другой аккаунт с того же IP пока не затронут.
+	require.NoError(t, svc.Check("other@example.com", "10.0.0.1"))
+}
+
+func TestLoginThrottle_LockoutAndUnlock(t *testing.T) {
+	svc, now := newTestThrottle(t)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, svc.RecordFailure("user@example.com", ""))
+	}
+	requireThrottled(t, svc.Check("user@example.com", ""), true)
+
+	*now = now.Add(16 * time.Minute)
+	require.NoError(t, svc.Check("user@example.com", ""))
+
+	*now = now.Add(-16 * time.Minute)
+	removed, err := svc.Unlock("user@example.com", "")
+	require.NoError(t, err)
+	require.EqualValues(t, 10, removed)
+	require.NoError(t, svc.Check("user@example.com", ""))
+}
+
+func TestLoginThrottle_SuccessResetsIdentifierOnly(t *testing.T) {
+	svc, _ := newTestThrottle(t)
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, svc.RecordFailure("user@example.com", "10.0.0.1"))
+	}
+	require.NoError(t, svc.RecordSuccess("user@example.com"))
+
+	// Счётчик IP сохраняется: перебор с этого адреса по-прежнему заблокирован.
+	requireThrottled(t, svc.Check("another@example.com", "10.0.0.1"), true)
+	require.NoError(t, svc.Check("user@example.com", "10.0.0.9"))
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Спринт не длиннее года: burndown строится по дням.
+const milestoneMaxDays = 366
+
+var (
+	ErrMilestoneNotFound  = errors.New("milestone not found")
+	ErrMilestoneInvalid   = errors.New("invalid milestone")
+	ErrMilestoneForbidden = errors.New("only the project owner can change milestones")
+	ErrMilestoneClosed    = errors.New("milestone is closed")
+)
+
+// MilestoneInput — создание спринта; даты — YYYY-MM-DD, конец включительно.
+type MilestoneInput struct {
+	Name      string `json:"name"`
+	Goal      string `json:"goal"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// MilestonePatch — правка спринта; nil-поля не меняются.
+type MilestonePatch struct {
+	Name      *string `json:"name"`
+	Goal      *string `json:"goal"`
+	StartDate *string `json:"start_date"`
+	EndDate   *string `json:"end_date"`
+}
+
+func (p MilestonePatch) IsEmpty() bool {
+	return p.Name == nil && p.Goal == nil && p.StartDate == nil && p.EndDate == nil
+}
+
+// MilestoneCloseInput — куда перенести незавершённые задачи: в спринт TargetID,
+// в бэклог (Backlog) или, если ничего не указано, в следующий открытый спринт.
+type MilestoneCloseInput struct {
+	TargetID *uint `json:"target_id"`
+	Backlog  bool  `json:"backlog"`
+}
+
+// MilestoneCloseResult — закрытый спринт и куда ушли незавершённые задачи.
+type MilestoneCloseResult struct {
+	Milestone *models.Milestone `json:"milestone"`
+	Moved     int64             `json:"moved"`
+	// TargetID — спринт, куда перенесены задачи; nil — бэклог.
+	TargetID *uint `json:"target_id"`
+}
+
+// MilestoneService — спринты проектов. Видят их владелец и участники проекта,
+// меняет и наполняет задачами владелец.
+type MilestoneService struct {
+	milestones *storage.MilestoneStorage
+	tasks      *storage.TaskStorage
+	projects   *storage.ProjectStorage
+	users      *storage.UserStorage
+	now        func() time.Time
+}
+
+func NewMilestoneService(m *storage.MilestoneStorage, t *storage.TaskStorage, p *storage.ProjectStorage, u *storage.UserStorage) *MilestoneService {
+	return &MilestoneService{milestones: m, tasks: t, projects: p, users: u, now: time.Now}
+}
+
+// List — спринты доступного проекта по дате начала, с числом задач.
+func (s *MilestoneService) List(userID, projectID uint) ([]models.Milestone, error) {
+	if _, err := s.projects.GetAccessible(userID, projectID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	milestones, err := s.milestones.ListByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachCounts(milestones); err != nil {
+		return nil, err
+	}
+	return milestones, nil
+}
+
+func (s *MilestoneService) Get(userID, id uint) (*models.Milestone, error) {
+	milestone, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	list := []models.Milestone{*milestone}
+	if err := s.attachCounts(list); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+func (s *MilestoneService) Create(userID, projectID uint, input MilestoneInput) (*models.Milestone, error) {
+	if err := s.checkOwner(userID, projectID); err != nil {
+		return nil, err
+	}
+	milestone := &models.Milestone{ProjectID: projectID}
+	patch := MilestonePatch{Name: &input.Name, Goal: &input.Goal, StartDate: &input.StartDate, EndDate: &input.EndDate}
+	if err := applyMilestonePatch(milestone, patch); err != nil {
+		return nil, err
+	}
+	if err := s.milestones.Create(milestone); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (s *MilestoneService) Update(userID, id uint, patch MilestonePatch) (*models.Milestone, error) {
+	milestone, err := s.writable(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyMilestonePatch(milestone, patch); err != nil {
+		return nil, err
+	}
+	if err := s.milestones.Update(milestone); err != nil {
+		return nil, err
+	}
+	return s.Get(userID, milestone.ID)
+}
+
+// Delete удаляет спринт; его задачи возвращаются в бэклог.
+func (s *MilestoneService) Delete(userID, id uint) error {
+	milestone, err := s.writable(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.milestones.Delete(milestone.ID)
+}
+
+// Close закрывает спринт и переносит незавершённые задачи (не completed и не cancelled).
+func (s *MilestoneService) Close(userID, id uint, input MilestoneCloseInput) (*MilestoneCloseResult, error) {
+	milestone, err := s.writable(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if milestone.Closed() {
+		return nil, ErrMilestoneClosed
+	}
+	var target *models.Milestone
+	switch {
+	case input.TargetID != nil && input.Backlog:
+		return nil, fmt.Errorf("%w: target_id and backlog cannot be used together", ErrMilestoneInvalid)
+	case input.TargetID != nil:
+		if *input.TargetID == milestone.ID {
+			return nil, fmt.Errorf("%w: cannot move tasks into the milestone being closed", ErrMilestoneInvalid)
+		}
+		if target, err = s.writable(userID, *input.TargetID); err != nil {
+			return nil, err
+		}
+		if target.ProjectID != milestone.ProjectID {
+			return nil, fmt.Errorf("%w: target milestone belongs to another project", ErrMilestoneInvalid)
+		}
+		if target.Closed() {
+			return nil, ErrMilestoneClosed
+		}
+	case !input.Backlog:
+		target, err = s.milestones.NextOpen(milestone)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	result := &MilestoneCloseResult{Milestone: milestone}
+	if target != nil {
+		result.TargetID = &target.ID
+	}
+	if result.Moved, err = s.milestones.Close(milestone, result.TargetID, s.now().UTC()); err != nil {
+		return nil, err
+	}
+	if result.Milestone, err = s.Get(userID, milestone.ID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AssignTasks ставит задачи в спринт (milestoneID nil — в бэклог). Задачи должны
+// быть в проекте спринта, закрытый спринт новых задач не принимает.
+func (s *MilestoneService) AssignTasks(userID uint, milestoneID *uint, taskIDs []uint) error {
+	tasks, err := s.tasks.GetByIDs(taskIDs)
+	if err != nil {
+		return err
+	}
+	if len(tasks) != len(uniqueUints(taskIDs)) {
+		return ErrTaskNotFound
+	}
+	var projectID uint
+	if milestoneID != nil {
+		milestone, err := s.writable(userID, *milestoneID)
+		if err != nil {
+			return err
+		}
+		if milestone.Closed() {
+			return ErrMilestoneClosed
+		}
+		projectID = milestone.ProjectID
+	}
+	checked := make(map[uint]bool)
+	for i := range tasks {
+		if tasks[i].ProjectID == nil {
+			return fmt.Errorf("%w: task %d has no project", ErrMilestoneInvalid, tasks[i].ID)
+		}
+		if milestoneID != nil {
+			if *tasks[i].ProjectID != projectID {
+				return fmt.Errorf("%w: task %d belongs to another project", ErrMilestoneInvalid, tasks[i].ID)
+			}
+			continue
+		}
+		if checked[*tasks[i].ProjectID] {
+			continue
+		}
+		if err := s.checkOwner(userID, *tasks[i].ProjectID); err != nil {
+			if errors.Is(err, ErrProjectNotFound) {
+				return ErrTaskNotFound
+			}
+			return err
+		}
+		checked[*tasks[i].ProjectID] = true
+	}
+	return s.milestones.AssignTasks(uniqueUints(taskIDs), milestoneID)
+}
+
+// Burndown — остаток задач и story points спринта на конец каждого дня по completed_at.
+// Объём — текущие задачи спринта без отменённых: задачи, перенесённые при закрытии,
+// в burndown закрытого спринта уже не входят. Дни спринта считаются в поясе того, кто смотрит.
+func (s *MilestoneService) Burndown(userID, id uint) (*models.MilestoneBurndown, error) {
+	milestone, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.milestones.Tasks(milestone.ID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(s.users, userID)
+	if err != nil {
+		return nil, err
+	}
+	return milestoneBurndown(milestone, tasks, s.now().In(loc)), nil
+}
+
+func milestoneBurndown(milestone *models.Milestone, tasks []storage.MilestoneTask, now time.Time) *models.MilestoneBurndown {
+	burndown := &models.MilestoneBurndown{
+		MilestoneID: milestone.ID,
+		From:        milestone.StartDate.Format(dayLayout),
+		To:          milestone.EndDate.Format(dayLayout),
+	}
+	scope := tasks[:0:0]
+	for _, task := range tasks {
+		if task.Status == models.StatusCancelled {
+			continue
+		}
+		scope = append(scope, task)
+		burndown.TotalTasks++
+		if task.StoryPoints != nil {
+			burndown.TotalPoints += *task.StoryPoints
+		}
+	}
+	burndown.TotalPoints = round2(burndown.TotalPoints)
+
+	// Даты спринта календарные: день начинается в полночь пояса now.
+	loc := now.Location()
+	first := milestone.StartDate.UTC()
+	start := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	days := daysBetween(first, milestone.EndDate.UTC()) + 1
+	today := startOfDayIn(now, loc)
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i)
+		item := models.BurndownDay{Date: day.Format(dayLayout)}
+		if days > 1 {
+			share := 1 - float64(i)/float64(days-1)
+			item.IdealTasks = round2(float64(burndown.TotalTasks) * share)
+			item.IdealPoints = round2(burndown.TotalPoints * share)
+		}
+		if !day.After(today) {
+			end := day.AddDate(0, 0, 1)
+			remainingTasks, remainingPoints := burndown.TotalTasks, burndown.TotalPoints
+			for _, task := range scope {
+				// завершённые до появления completed_at считаем закрытыми с самого начала
+				if task.Status != models.StatusCompleted || (task.CompletedAt != nil && !task.CompletedAt.Before(end)) {
+					continue
+				}
+				remainingTasks--
+				if task.StoryPoints != nil {
+					remainingPoints -= *task.StoryPoints
+				}
+			}
+			remainingPoints = round2(remainingPoints)
+			item.RemainingTasks, item.RemainingPoints = &remainingTasks, &remainingPoints
+		}
+		burndown.Days = append(burndown.Days, item)
+	}
+	return burndown
+}
+
+func applyMilestonePatch(milestone *models.Milestone, patch MilestonePatch) error {
+	var err error
+	if patch.Name != nil {
+		if milestone.Name, err = models.NormalizeMilestoneName(*patch.Name); err != nil {
+			return fmt.Errorf("%w: %v", ErrMilestoneInvalid, err)
+		}
+	}
+	if patch.Goal != nil {
+		if milestone.Goal, err = models.NormalizeMilestoneGoal(*patch.Goal); err != nil {
+			return fmt.Errorf("%w: %v", ErrMilestoneInvalid, err)
+		}
+	}
+	if patch.StartDate != nil {
+		if milestone.StartDate, err = time.Parse(dayLayout, *patch.StartDate); err != nil {
+			return fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrMilestoneInvalid)
+		}
+	}
+	if patch.EndDate != nil {
+		if milestone.EndDate, err = time.Parse(dayLayout, *patch.EndDate); err != nil {
+			return fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrMilestoneInvalid)
+		}
+	}
+	if milestone.EndDate.Before(milestone.StartDate) {
+		return fmt.Errorf("%w: end_date is before start_date", ErrMilestoneInvalid)
+	}
+	if milestone.EndDate.Sub(milestone.StartDate) >= milestoneMaxDays*24*time.Hour {
+		return fmt.Errorf("%w: a milestone cannot be longer than %d days", ErrMilestoneInvalid, milestoneMaxDays)
+	}
+	return nil
+}
+
+func (s *MilestoneService) attachCounts(milestones []models.Milestone) error {
+	ids := make([]uint, len(milestones))
+	for i := range milestones {
+		ids[i] = milestones[i].ID
+	}
+	counts, err := s.milestones.CountTasks(ids)
+	if err != nil {
+		return err
+	}
+	for i := range milestones {
+		milestones[i].TasksCount = counts[milestones[i].ID][0]
+		milestones[i].CompletedCount = counts[milestones[i].ID][1]
+	}
+	return nil
+}
+
+// visible: спринт недоступного проекта выглядит как отсутствующий.
+func (s *MilestoneService) visible(userID, id uint) (*models.Milestone, error) {
+	milestone, err := s.milestones.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMilestoneNotFound
+		}
+		return nil, err
+	}
+	if _, err := s.projects.GetAccessible(userID, milestone.ProjectID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMilestoneNotFound
+		}
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (s *MilestoneService) writable(userID, id uint) (*models.Milestone, error) {
+	milestone, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(userID, milestone.ProjectID); err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return nil, ErrMilestoneNotFound
+		}
+		return nil, err
+	}
+	return milestone, nil
+}
+
+// checkOwner: участнику — ErrMilestoneForbidden, постороннему — ErrProjectNotFound.
+func (s *MilestoneService) checkOwner(userID, projectID uint) error {
+	project, err := s.projects.GetAccessible(userID, projectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProjectNotFound
+		}
+		return err
+	}
+	if project.OwnerID != userID {
+		return ErrMilestoneForbidden
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMilestoneService_SprintsCloseAndBurndown(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	member := models.User{ID: 2, Email: "member@example.com", Username: "member", Password: "x"}
+	require.NoError(t, db.Create([]*models.User{&owner, &member}).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, storage.NewUserStorage(db))
+	service := NewMilestoneService(storage.NewMilestoneStorage(db), taskStorage, projectStorage, storage.NewUserStorage(db))
+
+	now := time.Date(2025, 3, 6, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	site, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site"})
+	require.NoError(t, err)
+	app, err := projects.Create(owner.ID, &models.ProjectInput{Title: "App"})
+	require.NoError(t, err)
+	_, err = projects.AddMember(owner.ID, site.ID, member.Email)
+	require.NoError(t, err)
+
+	_, err = service.Create(owner.ID, site.ID, MilestoneInput{Name: "Bad", StartDate: "2025-03-10", EndDate: "2025-03-01"})
+	require.ErrorIs(t, err, ErrMilestoneInvalid)
+	_, err = service.Create(owner.ID, site.ID, MilestoneInput{Name: " ", StartDate: "2025-03-01", EndDate: "2025-03-10"})
+	require.ErrorIs(t, err, ErrMilestoneInvalid)
+	_, err = service.Create(member.ID, site.ID, MilestoneInput{Name: "Sprint", StartDate: "2025-03-03", EndDate: "2025-03-09"})
+	require.ErrorIs(t, err, ErrMilestoneForbidden)
+
+	sprint2, err := service.Create(owner.ID, site.ID, MilestoneInput{Name: "Sprint 2", StartDate: "2025-03-10", EndDate: "2025-03-16"})
+	require.NoError(t, err)
+	sprint1, err := service.Create(owner.ID, site.ID, MilestoneInput{Name: "Sprint 1", Goal: "Лендинг", StartDate: "2025-03-03", EndDate: "2025-03-09"})
+	require.NoError(t, err)
+
+	points := func(v float64) *float64 { return &v }
+	at := func(day int) *time.Time {
+		v := time.Date(2025, 3, day, 10, 0, 0, 0, time.UTC)
+		return &v
+	}
+	tasks := []models.Task{
+		{Title: "Open", Status: models.StatusInProgress, StoryPoints: points(3), ProjectID: &site.ID},
+		{Title: "Done", Status: models.StatusCompleted, StoryPoints: points(5), CompletedAt: at(5), ProjectID: &site.ID},
+		{Title: "Dropped", Status: models.StatusCancelled, StoryPoints: points(2), ProjectID: &site.ID},
+		{Title: "Other", Status: models.StatusTodo, ProjectID: &app.ID},
+	}
+	require.NoError(t, db.Create(&tasks).Error)
+	open, done, dropped, other := tasks[0].ID, tasks[1].ID, tasks[2].ID, tasks[3].ID
+
+	require.ErrorIs(t, service.AssignTasks(owner.ID, &sprint1.ID, []uint{open, other}), ErrMilestoneInvalid)
+	require.ErrorIs(t, service.AssignTasks(member.ID, &sprint1.ID, []uint{open}), ErrMilestoneForbidden)
+	require.NoError(t, service.AssignTasks(owner.ID, &sprint1.ID, []uint{open, done, dropped}))
+
+	list, err := service.List(member.ID, site.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "Sprint 1", list[0].Name)
+	require.EqualValues(t, 3, list[0].TasksCount)
+	require.EqualValues(t, 1, list[0].CompletedCount)
+
+	// Отменённые задачи в объём не входят; дни после сегодняшнего без остатка.
+	burndown, err := service.Burndown(member.ID, sprint1.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, burndown.TotalTasks)
+	require.Equal(t, 8.0, burndown.TotalPoints)
+	require.Len(t, burndown.Days, 7)
+	require.Equal(t, 8.0, *burndown.Days[0].RemainingPoints)
+	require.Equal(t, 8.0, *burndown.Days[1].RemainingPoints)
+	require.Equal(t, 3.0, *burndown.Days[2].RemainingPoints)
+	require.EqualValues(t, 1, *burndown.Days[3].RemainingTasks)
+	require.Nil(t, burndown.Days[4].RemainingPoints)
+	require.Equal(t, 8.0, burndown.Days[0].IdealPoints)
+	require.Equal(t, 4.0, burndown.Days[3].IdealPoints)
+	require.Zero(t, burndown.Days[6].IdealPoints)
+
+	// Закрытие без цели переносит незавершённое в следующий спринт.
+	result, err := service.Close(owner.ID, sprint1.ID, MilestoneCloseInput{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, result.Moved)
+	require.Equal(t, sprint2.ID, *result.TargetID)
+	require.True(t, result.Milestone.Closed())
+	stored, err := taskStorage.GetByID(open)
+	require.NoError(t, err)
+	require.Equal(t, sprint2.ID, *stored.MilestoneID)
+	stored, err = taskStorage.GetByID(done)
+	require.NoError(t, err)
+	require.Equal(t, sprint1.ID, *stored.MilestoneID)
+
+	_, err = service.Close(owner.ID, sprint1.ID, MilestoneCloseInput{})
+	require.ErrorIs(t, err, ErrMilestoneClosed)
+	require.ErrorIs(t, service.AssignTasks(owner.ID, &sprint1.ID, []uint{open}), ErrMilestoneClosed)
+
+	// Последний спринт — незавершённое уходит в бэклог.
+	result, err = service.Close(owner.ID, sprint2.ID, MilestoneCloseInput{})
+	require.NoError(t, err)
+	require.Nil(t, result.TargetID)
+	stored, err = taskStorage.GetByID(open)
+	require.NoError(t, err)
+	require.Nil(t, stored.MilestoneID)
+
+	// Спринты принадлежат проекту: перенос задачи в другой проект убирает её из спринта.
+	sprint3, err := service.Create(owner.ID, site.ID, MilestoneInput{Name: "Sprint 3", StartDate: "2025-03-17", EndDate: "2025-03-23"})
+	require.NoError(t, err)
+	require.NoError(t, service.AssignTasks(owner.ID, &sprint3.ID, []uint{open}))
+	require.NoError(t, projects.AssignTasks(owner.ID, app.ID, []uint{open}, true))
+	stored, err = taskStorage.GetByID(open)
+	require.NoError(t, err)
+	require.Nil(t, stored.MilestoneID)
+
+	// Удаление спринта возвращает задачи в бэклог.
+	require.ErrorIs(t, service.Delete(member.ID, sprint1.ID), ErrMilestoneForbidden)
+	require.NoError(t, service.Delete(owner.ID, sprint1.ID))
+	stored, err = taskStorage.GetByID(done)
+	require.NoError(t, err)
+	require.Nil(t, stored.MilestoneID)
+	_, err = service.Get(owner.ID, sprint1.ID)
+	require.ErrorIs(t, err, ErrMilestoneNotFound)
+}
+
+func TestMilestoneBurndown_ViewerTimeZone(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	milestone := &models.Milestone{
+		StartDate: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC),
+	}
+	// 22:00 UTC 4 марта — в Москве уже 01:00 5 марта.
+	completed := time.Date(2025, 3, 4, 22, 0, 0, 0, time.UTC)
+	tasks := []storage.MilestoneTask{{Status: models.StatusCompleted, CompletedAt: &completed}, {Status: models.StatusTodo}}
+	now := time.Date(2025, 3, 6, 12, 0, 0, 0, time.UTC)
+
+	utc := milestoneBurndown(milestone, tasks, now)
+	require.Len(t, utc.Days, 7)
+	require.EqualValues(t, 1, *utc.Days[1].RemainingTasks)
+
+	local := milestoneBurndown(milestone, tasks, now.In(moscow))
+	require.Len(t, local.Days, 7)
+	require.Equal(t, "2025-03-04", local.Days[1].Date)
+	require.EqualValues(t, 2, *local.Days[1].RemainingTasks)
+	require.EqualValues(t, 1, *local.Days[2].RemainingTasks)
+	require.Nil(t, local.Days[4].RemainingTasks)
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/oidc"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// OIDCRequestTTL — сколько живёт начатый вход через провайдера (и cookie со state).
+const OIDCRequestTTL = 10 * time.Minute
+
+var (
+	ErrOIDCDisabled          = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState      = errors.New("invalid or expired sign-on request")
+	ErrOIDCProvider          = errors.New("identity provider rejected the sign-on")
+	ErrOIDCEmailNotVerified  = errors.New("identity provider did not confirm the email address")
+	ErrOIDCNoAccount         = errors.New("no account is linked to this identity")
+	ErrOIDCAccountUnverified = errors.New("confirm the email of the existing account before signing in with SSO")
+	errOIDCUsernameExhausted = errors.New("could not pick a free username")
+)
+
+// OIDCRoleMapping — группа провайдера и роль, которую она даёт.
+type OIDCRoleMapping struct {
+	Group string
+	Role  string
+}
+
+// OIDCSettings — политика входа через провайдера.
+type OIDCSettings struct {
+	// AutoProvision создаёт пользователя при первом входе, если аккаунта с таким email нет.
+	AutoProvision bool
+	// GroupsClaim — claim ID-токена со списком групп (по умолчанию "groups").
+	GroupsClaim string
+	// RoleMappings проверяются по порядку, побеждает первое совпадение. Пустой список —
+	// роли не синхронизируются; иначе пользователь без подходящей группы получает RoleUser.
+	RoleMappings []OIDCRoleMapping
+}
+
+// OIDCSettingsFromEnv читает OIDC_AUTO_PROVISION, OIDC_GROUPS_CLAIM и OIDC_ROLE_MAPPING
+// (например "tm-admins=admin,staff=user").
+func OIDCSettingsFromEnv() OIDCSettings {
+	settings := OIDCSettings{GroupsClaim: strings.TrimSpace(os.Getenv("OIDC_GROUPS_CLAIM"))}
+	settings.AutoProvision, _ = strconv.ParseBool(os.Getenv("OIDC_AUTO_PROVISION"))
+	for _, pair := range strings.Split(os.Getenv("OIDC_ROLE_MAPPING"), ",") {
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || (role != models.RoleUser && role != models.RoleAdmin) {
+			continue
+		}
+		settings.RoleMappings = append(settings.RoleMappings, OIDCRoleMapping{Group: group, Role: role})
+	}
+	return settings
+}
+
+// OIDCService — вход через OpenID Connect: authorization code + PKCE, привязка по подтверждённому email.
+type OIDCService struct {
+	provider *oidc.Provider
+	issuer   string
+	store    *storage.OIDCStorage
+	users    *storage.UserStorage
+	settings OIDCSettings
+	now      func() time.Time
+}
+
+// NewOIDCService возвращает сервис; provider == nil означает, что SSO выключен.
+func NewOIDCService(provider *oidc.Provider, issuer string, store *storage.OIDCStorage, users *storage.UserStorage, settings OIDCSettings) *OIDCService {
+	if settings.GroupsClaim == "" {
+		settings.GroupsClaim = "groups"
+	}
+	return &OIDCService{
+		provider: provider,
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		store:    store,
+		users:    users,
+		settings: settings,
+		now:      time.Now,
+	}
+}
+
+func (s *OIDCService) Enabled() bool {
+	return s != nil && s.provider != nil
+}
+
+// Begin готовит state, nonce и PKCE verifier и возвращает ссылку на провайдера и state:
+// вызывающий привязывает state к браузеру, начавшему вход.
+func (s *OIDCService) Begin(ctx context.Context) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+	state, stateHash, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, _, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, _, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.store.CreateRequest(&models.OIDCAuthRequest{
+		StateHash:    stateHash,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    s.now().Add(OIDCRequestTTL),
+	}); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Complete завершает вход: проверяет state, обменивает code и находит (или создаёт) пользователя.
+func (s *OIDCService) Complete(ctx context.Context, state, code string) (*models.User, error) {
+	if !s.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+	if strings.TrimSpace(state) == "" || strings.TrimSpace(code) == "" {
+		return nil, ErrOIDCInvalidState
+	}
+	req, err := s.store.ConsumeRequest(HashOpaqueToken(state))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOIDCInvalidState
+		}
+		return nil, err
+	}
+	if s.now().After(req.ExpiresAt) {
+		return nil, ErrOIDCInvalidState
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, req.CodeVerifier, req.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCProvider, err)
+	}
+
+	user, err := s.resolveUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRole(user, claims); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// resolveUser ищет пользователя по привязке (issuer, sub), затем по подтверждённому email.
+func (s *OIDCService) resolveUser(claims *oidc.Claims) (*models.User, error) {
+	email, _ := NormalizeEmail(claims.Email)
+	now := s.now()
+
+	identity, err := s.store.GetIdentity(s.issuer, claims.Subject)
+	switch {
+	case err == nil:
+		if err := s.store.TouchIdentity(identity.ID, email, now); err != nil {
+			return nil, err
+		}
+		return s.users.GetByID(identity.UserID)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	// Новой привязке нужен адрес, подтверждённый провайдером: иначе чужой аккаунт
+	// можно захватить, указав у провайдера его email.
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.users.GetByEmail(email)
+	switch {
+	case err == nil:
+		if !user.IsEmailVerified() {
+			return nil, ErrOIDCAccountUnverified
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !s.settings.AutoProvision {
+			return nil, ErrOIDCNoAccount
+		}
+		if user, err = s.provision(email, claims.Name); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.store.CreateIdentity(&models.OIDCIdentity{
+		UserID:    user.ID,
+		Issuer:    s.issuer,
+		Subject:   claims.Subject,
+		Email:     email,
+		LastLogin: now,
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// provision создаёт пользователя с подтверждённым email и случайным паролем
+// (задать свой можно через восстановление пароля).
+func (s *OIDCService) provision(email, name string) (*models.User, error) {
+	username, err := s.freeUsername(email)
+	if err != nil {
+		return nil, err
+	}
+	password, _, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	user := &models.User{
+		Email:           email,
+		Username:        username,
+		Password:        hash,
+		Role:            models.RoleUser,
+		FullName:        truncate(strings.TrimSpace(name), 255),
+		Language:        "en",
+		Theme:           "light",
+		EmailVerifiedAt: &now,
+	}
+	if err := s.users.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *OIDCService) freeUsername(email string) (string, error) {
+	local, _, _ := strings.Cut(email, "@")
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, strings.ToLower(local))
+	if base == "" {
+		base = "user"
+	}
+	base = truncate(base, 56)
+	for i := 0; i < 100; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = base + strconv.Itoa(i+1)
+		}
+		taken, err := s.users.UsernameExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+	return "", errOIDCUsernameExhausted
+}
+
+// applyRole синхронизирует роль с группами провайдера, если настроено сопоставление.
+func (s *OIDCService) applyRole(user *models.User, claims *oidc.Claims) error {
+	if len(s.settings.RoleMappings) == 0 {
+		return nil
+	}
+	groups := make(map[string]struct{})
+	for _, group := range claims.Strings(s.settings.GroupsClaim) {
+		groups[group] = struct{}{}
+	}
+	role := models.RoleUser
+	for _, mapping := range s.settings.RoleMappings {
+		if _, ok := groups[mapping.Group]; ok {
+			role = mapping.Role
+			break
+		}
+	}
+	if user.Role == role {
+		return nil
+	}
+	user.Role = role
+	return s.users.Update(user)
+}
+
+// Purge удаляет просроченные незавершённые входы.
+func (s *OIDCService) Purge() error {
+	return s.store.PurgeRequests(s.now())
+}
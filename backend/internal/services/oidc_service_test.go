@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/oidc"
+	"github.com/spozitivom/taskmanager/internal/oidc/oidctest"
+	"github.com/spozitivom/taskmanager/internal/storage"
+)
+
+type oidcTestEnv struct {
+	svc      *OIDCService
+	provider *oidctest.Provider
+	users    *storage.UserStorage
+}
+
+func newOIDCTestEnv(t *testing.T, settings OIDCSettings) oidcTestEnv {
+	t.Helper()
+	db := setupTestDB(t)
+	provider := oidctest.NewProvider("taskmanager", "s3cret")
+	t.Cleanup(provider.Close)
+
+	client := oidc.NewProvider(oidc.Config{
+		Issuer:       provider.Issuer(),
+		ClientID:     "taskmanager",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:5173/sso/callback",
+	}, provider.Server.Client())
+	users := storage.NewUserStorage(db)
+	svc := NewOIDCService(client, provider.Issuer(), storage.NewOIDCStorage(db), users, settings)
+	return oidcTestEnv{svc: svc, provider: provider, users: users}
+}
+
+func (env oidcTestEnv) login(t *testing.T, identity oidctest.Identity) (*models.User, error) {
+	t.Helper()
+	authURL, _, err := env.svc.Begin(context.Background())
+	require.NoError(t, err)
+	code, state, err := env.provider.Authorize(authURL, identity)
+	require.NoError(t, err)
+	return env.svc.Complete(context.Background(), state, code)
+}
+
+func TestOIDCService_LinksExistingUserByVerifiedEmail(t *testing.T) {
+	env := newOIDCTestEnv(t, OIDCSettings{})
+	verified := time.Now()
+	existing := &models.User{Email: "ann@example.com", Username: "ann", Password: "x", Role: models.RoleUser, EmailVerifiedAt: &verified}
+	require.NoError(t, env.users.Create(existing))
+
+	user, err := env.login(t, oidctest.Identity{Subject: "sub-1", Email: "Ann@Example.com", EmailVerified: true})
+	require.NoError(t, err)
+	require.Equal(t, existing.ID, user.ID)
+
+	// Привязка по sub сохраняется, даже если email у провайдера сменился.
+	user, err = env.login(t, oidctest.Identity{Subject: "sub-1", Email: "ann.new@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	require.Equal(t, existing.ID, user.ID)
+}
+
+func TestOIDCService_RejectsUnverifiedOrUnknown(t *testing.T) {
+	env := newOIDCTestEnv(t, OIDCSettings{})
+	require.NoError(t, env.users.Create(&models.User{Email: "bob@example.com", Username: "bob", Password: "x"}))
+
+	_, err := env.login(t, oidctest.Identity{Subject: "sub-2", Email: "bob@example.com", EmailVerified: false})
+	require.ErrorIs(t, err, ErrOIDCEmailNotVerified)
+
+	_, err = env.login(t, oidctest.Identity{Subject: "sub-2", Email: "bob@example.com", EmailVerified: true})
+	require.ErrorIs(t, err, ErrOIDCAccountUnverified)
+
+	_, err = env.login(t, oidctest.Identity{Subject: "sub-3", Email: "nobody@example.com", EmailVerified: true})
+	require.ErrorIs(t, err, ErrOIDCNoAccount)
+}
+
+func TestOIDCService_AutoProvisionAndRoleMapping(t *testing.T) {
+	env := newOIDCTestEnv(t, OIDCSettings{
+		AutoProvision: true,
+		RoleMappings:  []OIDCRoleMapping{{Group: "tm-admins", Role: models.RoleAdmin}},
+	})
+	require.NoError(t, env.users.Create(&models.User{Email: "other@example.com", Username: "carol", Password: "x"}))
+
+	user, err := env.login(t, oidctest.Identity{
+		Subject: "sub-4", Email: "carol@example.com", EmailVerified: true, Name: "Carol", Groups: []string{"tm-admins"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "carol2", user.Username)
+	require.Equal(t, "Carol", user.FullName)
+	require.Equal(t, models.RoleAdmin, user.Role)
+	require.True(t, user.IsEmailVerified())
+
+	// Из группы убрали — роль понижается при следующем входе.
+	user, err = env.login(t, oidctest.Identity{Subject: "sub-4", Email: "carol@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	require.Equal(t, models.RoleUser, user.Role)
+}
+
+func TestOIDCService_StateIsSingleUse(t *testing.T) {
+	env := newOIDCTestEnv(t, OIDCSettings{AutoProvision: true})
+	authURL, _, err := env.svc.Begin(context.Background())
+	require.NoError(t, err)
+	code, state, err := env.provider.Authorize(authURL, oidctest.Identity{Subject: "sub-5", Email: "dan@example.com", EmailVerified: true})
+	require.NoError(t, err)
+
+	_, err = env.svc.Complete(context.Background(), state, code)
+	require.NoError(t, err)
+	_, err = env.svc.Complete(context.Background(), state, code)
+	require.ErrorIs(t, err, ErrOIDCInvalidState)
+
+	_, err = env.svc.Complete(context.Background(), "forged", code)
+	require.ErrorIs(t, err, ErrOIDCInvalidState)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/mail"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL       = time.Hour
+	passwordResetWindow    = time.Hour
+	passwordResetPerWindow = 3
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetService реализует восстановление пароля по одноразовой ссылке.
+type PasswordResetService struct {
+	resets   *storage.PasswordResetStorage
+	users    *storage.UserStorage
+	sessions *SessionService
+	mailer   mail.Sender
+	now      func() time.Time
+}
+
+func NewPasswordResetService(resets *storage.PasswordResetStorage, users *storage.UserStorage, sessions *SessionService, mailer mail.Sender) *PasswordResetService {
+	return &PasswordResetService{resets: resets, users: users, sessions: sessions, mailer: mailer, now: time.Now}
+}
+
+// RequestReset отправляет ссылку сброса, если аккаунт существует.
+// Отсутствие аккаунта и превышение лимита не считаются ошибкой — вызывающий
+// код отвечает одинаково, чтобы не раскрывать, зарегистрирован ли адрес.
+func (s *PasswordResetService) RequestReset(email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+	user, err := s.users.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	now := s.now()
+	sent, err := s.resets.CountSince(user.ID, now.Add(-passwordResetWindow))
+	if err != nil {
+		return err
+	}
+	if sent >= passwordResetPerWindow {
+		log.Printf("password reset rate limit reached for user %d", user.ID)
+		return nil
+	}
+	return s.SendResetLink(user)
+}
+
+// SendResetLink выпускает токен и отправляет ссылку сброса без проверки лимита
+// (используется и администратором при принудительном сбросе).
+func (s *PasswordResetService) SendResetLink(user *models.User) error {
+	link, err := s.issueResetLink(user)
+	if err != nil {
+		return err
+	}
+	return s.mailResetLink(user, link)
+}
+
+// issueResetLink сохраняет токен сброса и возвращает ссылку с ним.
+func (s *PasswordResetService) issueResetLink(user *models.User) (string, error) {
+	raw, hash, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.resets.Create(&models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: s.now().Add(passwordResetTTL),
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/reset-password?token=%s", AppBaseURL(), raw), nil
+}
+
+func (s *PasswordResetService) mailResetLink(user *models.User, link string) error {
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Восстановление пароля TaskManager",
+		Body: "Чтобы задать новый пароль, перейдите по ссылке:\n" + link +
+			"\n\nСсылка действует 1 час и может быть использована один раз." +
+			"\nЕсли вы не запрашивали сброс, просто проигнорируйте это письмо.",
+		Kind: "password_reset",
+	})
+}
+
+// ResetPassword задаёт новый пароль по токену и завершает все сессии пользователя.
+func (s *PasswordResetService) ResetPassword(rawToken, next string) error {
+	next = strings.TrimSpace(next)
+	if err := validateNewPassword(next); err != nil {
+		return err
+	}
+	token, err := s.resets.GetByHash(HashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	now := s.now()
+	if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	consumed, err := s.resets.Consume(token, now)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.users.GetByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	hash, err := HashPassword(next)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	if err := s.users.Update(user); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(user.ID)
+}
+
+// AppBaseURL — адрес фронтенда для ссылок в письмах (APP_BASE_URL).
+func AppBaseURL() string {
+	base := strings.TrimSpace(os.Getenv("APP_BASE_URL"))
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/")
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/mail"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type capturingMailer struct {
+	messages []mail.Message
+}
+
+func (m *capturingMailer) Send(msg mail.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+var resetTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func TestPasswordResetService_ResetFlow(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	db := setupTestDB(t)
+	hash, err := HashPassword("old-secret")
+	require.NoError(t, err)
+	user := &models.User{Email: "forgot@example.com", Username: "forgot", Password: hash}
+	require.NoError(t, db.Create(user).Error)
+
+	users := storage.NewUserStorage(db)
+	sessions := NewSessionService(storage.NewSessionStorage(db), users)
+	mailer := &capturingMailer{}
+	service := NewPasswordResetService(storage.NewPasswordResetStorage(db), users, sessions, mailer)
+
+	session, err := sessions.Start(user, "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, service.RequestReset(" Forgot@Example.com "))
+	require.Len(t, mailer.messages, 1)
+	match := resetTokenPattern.FindStringSubmatch(mailer.messages[0].Body)
+	require.Len(t, match, 2)
+
+	require.NoError(t, service.ResetPassword(match[1], "new-secret"))
+	reloaded, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	require.NoError(t, CheckPassword(reloaded.Password, "new-secret"))
+	require.ErrorIs(t, sessions.ValidateSession(user.ID, session.SessionID), ErrSessionRevoked)
+
+	// Токен одноразовый.
+	require.ErrorIs(t, service.ResetPassword(match[1], "another-secret"), ErrInvalidResetToken)
+}
+
+func TestPasswordResetService_UnknownAddressAndRateLimit(t *testing.T) {
+	db := setupTestDB(t)
+	user := &models.User{Email: "limit@example.com", Username: "limit", Password: "hash"}
+	require.NoError(t, db.Create(user).Error)
+
+	users := storage.NewUserStorage(db)
+	mailer := &capturingMailer{}
+	service := NewPasswordResetService(
+		storage.NewPasswordResetStorage(db),
+		users,
+		NewSessionService(storage.NewSessionStorage(db), users),
+		mailer,
+	)
+
+	require.NoError(t, service.RequestReset("nobody@example.com"))
+	require.Empty(t, mailer.messages)
+
+	for i := 0; i < passwordResetPerWindow+2; i++ {
+		require.NoError(t, service.RequestReset("limit@example.com"))
+	}
+	require.Len(t, mailer.messages, passwordResetPerWindow)
+}
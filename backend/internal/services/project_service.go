@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/storage"
@@ -15,6 +16,9 @@ var (
 	ErrProjectLimit    = errors.New("project limit reached")
 	ErrTasksLimit      = errors.New("tasks limit reached")
 	ErrProjectNotFound = errors.New("project not found")
+	ErrMemberNotFound  = errors.New("project member not found")
+	ErrMemberIsOwner   = errors.New("project owner cannot be added as a member")
+	ErrMemberNoUser    = errors.New("no user with this email")
 )
 
 // ProjectService инкапсулирует бизнес-логику проектов и связанных задач.
@@ -22,10 +26,11 @@ type ProjectService struct {
 	projects *storage.ProjectStorage
 	tasks    *storage.TaskStorage
 	users    *storage.UserStorage
+	quotas   quotaChecker
 }
 
 func NewProjectService(p *storage.ProjectStorage, t *storage.TaskStorage, u *storage.UserStorage) *ProjectService {
-	return &ProjectService{projects: p, tasks: t, users: u}
+	return &ProjectService{projects: p, tasks: t, users: u, quotas: quotaChecker{projects: p, tasks: t, users: u}}
 }
 
 func (s *ProjectService) List(ownerID uint, includeArchived bool) ([]models.Project, error) {
@@ -54,21 +59,92 @@ func (s *ProjectService) Get(ownerID, projectID uint) (*models.Project, error) {
 	return project, nil
 }
 
-func (s *ProjectService) Create(ownerID uint, payload *models.ProjectInput) (*models.Project, error) {
-	owner, err := s.users.GetByID(ownerID)
+// GetAccessible — чтение проекта владельцем или участником.
+func (s *ProjectService) GetAccessible(userID, projectID uint) (*models.Project, error) {
+	project, err := s.projects.GetAccessible(userID, projectID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("owner not found")
+			return nil, ErrProjectNotFound
 		}
 		return nil, err
 	}
+	return project, nil
+}
+
+// ListMembers доступен владельцу и участникам проекта.
+func (s *ProjectService) ListMembers(userID, projectID uint) ([]models.ProjectMember, error) {
+	project, err := s.GetAccessible(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.projects.ListMembers(project.ID)
+}
 
-	count, err := s.projects.CountByOwner(ownerID)
+// AddMember открывает проект на чтение пользователю с указанным email.
+func (s *ProjectService) AddMember(ownerID, projectID uint, email string) (*models.ProjectMember, error) {
+	project, err := s.Get(ownerID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByEmail(normalized)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNoUser
+		}
+		return nil, err
+	}
+	if user.ID == project.OwnerID {
+		return nil, ErrMemberIsOwner
+	}
+	added := &models.ProjectMember{ProjectID: project.ID, UserID: user.ID, Role: models.ProjectMemberRoleViewer}
+	err = s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.lockOwner(project.OwnerID); err != nil {
+			return err
+		}
+		// Повторное добавление только обновляет роль и квоту не расходует.
+		member, err := q.projects.IsMember(project.ID, user.ID)
+		if err != nil {
+			return err
+		}
+		if !member {
+			if err := q.checkMembers(project, 1); err != nil {
+				return err
+			}
+		}
+		return q.projects.AddMember(added)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if owner.MaxProjects > 0 && count >= int64(owner.MaxProjects) {
-		return nil, ErrProjectLimit
+	return added, nil
+}
+
+func (s *ProjectService) RemoveMember(ownerID, projectID, userID uint) error {
+	project, err := s.Get(ownerID, projectID)
+	if err != nil {
+		return err
+	}
+	removed, err := s.projects.RemoveMember(project.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+func (s *ProjectService) Create(ownerID uint, payload *models.ProjectInput) (*models.Project, error) {
+	owner, err := s.users.GetByID(ownerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("owner not found")
+		}
+		return nil, err
 	}
 
 	normalized, err := normalizeProjectPayload(payload)
@@ -92,12 +168,102 @@ func (s *ProjectService) Create(ownerID uint, payload *models.ProjectInput) (*mo
 		project.Tags = data
 	}
 
-	if err := s.projects.Create(project); err != nil {
+	err = s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.checkProjects(owner, 1); err != nil {
+			return err
+		}
+		return q.projects.Create(project)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// Duplicate копирует доступный пользователю проект вместе с задачами в новый
+// проект пользователя. Проект и задачи создаются одной транзакцией; метки,
+// спринты и участники не копируются.
+func (s *ProjectService) Duplicate(userID, projectID uint, opts models.ProjectDuplicateOptions) (*models.Project, error) {
+	source, err := s.GetAccessible(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ShiftDays < -models.TemplateOffsetMaxDays || opts.ShiftDays > models.TemplateOffsetMaxDays {
+		return nil, fmt.Errorf("shift_days must be within %d days", models.TemplateOffsetMaxDays)
+	}
+	owner, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.tasks.ListByProject(source.ID, opts.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(opts.Title)
+	if title == "" {
+		title = source.Title + " (copy)"
+	}
+	project := &models.Project{
+		OwnerID:     userID,
+		Title:       title,
+		Description: source.Description,
+		Status:      source.Status,
+		Priority:    source.Priority,
+		Deadline:    shiftDays(source.Deadline, opts.ShiftDays),
+		ProgressPct: source.ProgressPct,
+		TasksLimit:  source.TasksLimit,
+		Tags:        source.Tags,
+	}
+	if opts.ResetStatuses {
+		project.Status = models.ProjectStatusPlanned
+		project.ProgressPct = 0
+	}
+
+	copies := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		clone := models.Task{
+			Title:          task.Title,
+			Description:    task.Description,
+			Status:         task.Status,
+			PreviousStatus: task.PreviousStatus,
+			Priority:       task.Priority,
+			Stage:          task.Stage,
+			StartAt:        shiftDays(task.StartAt, opts.ShiftDays),
+			EndAt:          shiftDays(task.EndAt, opts.ShiftDays),
+			AllDay:         task.AllDay,
+			CompletedAt:    task.CompletedAt,
+			StoryPoints:    task.StoryPoints,
+			EstimateHours:  task.EstimateHours,
+		}
+		if opts.ResetStatuses {
+			clone.Status = models.StatusTodo
+			clone.PreviousStatus = ""
+			clone.CompletedAt = nil
+		}
+		copies = append(copies, clone)
+	}
+	err = s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.checkNewProject(owner, source.TasksLimit, int64(len(copies))); err != nil {
+			return err
+		}
+		return q.projects.CreateWithTasks(project, copies)
+	})
+	if err != nil {
 		return nil, err
 	}
+	project.TasksCount = int64(len(copies))
 	return project, nil
 }
 
+func shiftDays(t *time.Time, days int) *time.Time {
+	if t == nil {
+		return nil
+	}
+	shifted := t.AddDate(0, 0, days)
+	return &shifted
+}
+
 func (s *ProjectService) Update(ownerID, id uint, payload *models.ProjectInput) (*models.Project, error) {
 	project, err := s.Get(ownerID, id)
 	if err != nil {
@@ -154,10 +320,23 @@ func (s *ProjectService) Restore(ownerID, id uint) error {
 	if project.ArchivedAt == nil {
 		return nil
 	}
-	if err := s.projects.Restore(project); err != nil {
-		return err
-	}
-	return s.tasks.RestoreByProject(project.ID)
+	return s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.lockOwner(project.OwnerID); err != nil {
+			return err
+		}
+		// Архивирование скрыло задачи проекта; вернувшись, они снова занимают квоту.
+		hidden, err := q.tasks.CountDeletedByProject(project.ID)
+		if err != nil {
+			return err
+		}
+		if err := q.checkProjectTasks(project, hidden, 0); err != nil {
+			return err
+		}
+		if err := q.projects.Restore(project); err != nil {
+			return err
+		}
+		return q.tasks.RestoreByProject(project.ID)
+	})
 }
 
 func (s *ProjectService) HardDelete(ownerID, id uint) error {
@@ -224,12 +403,9 @@ func (s *ProjectService) AssignTasks(ownerID, projectID uint, taskIDs []uint, re
 		return nil
 	}
 
-	// enforce tasks limit
-	count, err := s.tasks.CountByProject(project.ID)
-	if err != nil {
-		return err
-	}
-	added := 0
+	// Квоты: задачи из других проектов того же владельца его общий счёт не меняют.
+	owned := map[uint]bool{}
+	var added, fromOwner int64
 	for i := range tasks {
 		if tasks[i].ProjectID != nil {
 			if *tasks[i].ProjectID == project.ID {
@@ -238,12 +414,21 @@ func (s *ProjectService) AssignTasks(ownerID, projectID uint, taskIDs []uint, re
 			if !reassign {
 				continue
 			}
+			from, ok := owned[*tasks[i].ProjectID]
+			if !ok {
+				source, err := s.projects.GetByID(*tasks[i].ProjectID)
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+				from = source != nil && source.OwnerID == project.OwnerID
+				owned[*tasks[i].ProjectID] = from
+			}
+			if from {
+				fromOwner++
+			}
 		}
 		added++
 	}
-	if int(count)+added > project.TasksLimit {
-		return ErrTasksLimit
-	}
 
 	for i := range tasks {
 		if tasks[i].ProjectID != nil {
@@ -255,8 +440,14 @@ func (s *ProjectService) AssignTasks(ownerID, projectID uint, taskIDs []uint, re
 			}
 		}
 		tasks[i].ProjectID = &project.ID
+		tasks[i].MilestoneID = nil
 	}
-	return s.tasks.SaveAll(tasks)
+	return s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.checkProjectTasks(project, added, fromOwner); err != nil {
+			return err
+		}
+		return q.tasks.SaveAll(tasks)
+	})
 }
 
 func (s *ProjectService) CreateFromTasks(ownerID uint, payload models.ProjectFromTasksPayload) (*models.Project, error) {
@@ -2,6 +2,7 @@ package services
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/storage"
@@ -36,6 +37,87 @@ func TestProjectService_CreateRespectsOwnerLimit(t *testing.T) {
 	require.ErrorIs(t, err, ErrProjectLimit)
 }
 
+func TestProjectService_Duplicate(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 3}
+	member := models.User{ID: 2, Email: "member@example.com", Username: "member", Password: "x", MaxProjects: 1}
+	require.NoError(t, db.Create([]*models.User{&owner, &member}).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	service := NewProjectService(storage.NewProjectStorage(db), taskStorage, storage.NewUserStorage(db))
+
+	deadline := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	source, err := service.Create(owner.ID, &models.ProjectInput{
+		Title: "Site", Status: models.ProjectStatusActive, ProgressPct: 40, TasksLimit: 3, Tags: []string{"web"}, Deadline: &deadline,
+	})
+	require.NoError(t, err)
+	_, err = service.AddMember(owner.ID, source.ID, member.Email)
+	require.NoError(t, err)
+
+	start := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	done := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	points := 3.0
+	tasks := []models.Task{
+		{Title: "Open", Status: models.StatusInProgress, Stage: "dev", StartAt: &start, EndAt: &start, StoryPoints: &points, ProjectID: &source.ID},
+		{Title: "Done", Status: models.StatusCompleted, CompletedAt: &done, ProjectID: &source.ID},
+		{Title: "Deleted", Status: models.StatusTodo, ProjectID: &source.ID},
+	}
+	require.NoError(t, db.Create(&tasks).Error)
+	require.NoError(t, taskStorage.Delete(tasks[2].ID))
+
+	// Копия как есть: только живые задачи, статусы и даты сохраняются.
+	copied, err := service.Duplicate(owner.ID, source.ID, models.ProjectDuplicateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "Site (copy)", copied.Title)
+	require.Equal(t, models.ProjectStatusActive, copied.Status)
+	require.Equal(t, 40, copied.ProgressPct)
+	require.JSONEq(t, `["web"]`, string(copied.Tags))
+	require.EqualValues(t, 2, copied.TasksCount)
+	copies, err := taskStorage.ListByProject(copied.ID, false)
+	require.NoError(t, err)
+	require.Len(t, copies, 2)
+	require.Equal(t, models.StatusInProgress, copies[0].Status)
+	require.Equal(t, "dev", copies[0].Stage)
+	require.Equal(t, 3.0, *copies[0].StoryPoints)
+	require.Equal(t, start, copies[0].StartAt.UTC())
+	require.NotNil(t, copies[1].CompletedAt)
+
+	// Сброс статусов, сдвиг дат и удалённые задачи.
+	shifted, err := service.Duplicate(owner.ID, source.ID, models.ProjectDuplicateOptions{
+		Title: "Site v2", ResetStatuses: true, ShiftDays: 7, IncludeDeleted: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.ProjectStatusPlanned, shifted.Status)
+	require.Zero(t, shifted.ProgressPct)
+	require.Equal(t, deadline.AddDate(0, 0, 7), shifted.Deadline.UTC())
+	copies, err = taskStorage.ListByProject(shifted.ID, false)
+	require.NoError(t, err)
+	require.Len(t, copies, 3)
+	for _, task := range copies {
+		require.Equal(t, models.StatusTodo, task.Status)
+		require.Nil(t, task.CompletedAt)
+	}
+	require.Equal(t, start.AddDate(0, 0, 7), copies[0].StartAt.UTC())
+	require.Equal(t, "Deleted", copies[2].Title)
+
+	// Исходные задачи не тронуты.
+	original, err := taskStorage.GetByID(tasks[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, source.ID, *original.ProjectID)
+	require.Equal(t, models.StatusInProgress, original.Status)
+
+	// Лимит проектов владельца исчерпан; участник копирует себе, пока позволяет его лимит.
+	_, err = service.Duplicate(owner.ID, source.ID, models.ProjectDuplicateOptions{})
+	require.ErrorIs(t, err, ErrProjectLimit)
+	mine, err := service.Duplicate(member.ID, source.ID, models.ProjectDuplicateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, member.ID, mine.OwnerID)
+	_, err = service.Duplicate(member.ID, source.ID, models.ProjectDuplicateOptions{})
+	require.ErrorIs(t, err, ErrProjectLimit)
+	_, err = service.Duplicate(3, source.ID, models.ProjectDuplicateOptions{})
+	require.ErrorIs(t, err, ErrProjectNotFound)
+}
+
 func TestProjectService_ToggleCompletedCascade(t *testing.T) {
 	db := setupTestDB(t)
 	require.NoError(t, db.Create(&models.User{
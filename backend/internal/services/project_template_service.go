@@ -0,0 +1,528 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const minutesPerDay = 24 * 60
+
+var (
+	ErrTemplateNotFound  = errors.New("project template not found")
+	ErrTemplateInvalid   = errors.New("invalid project template")
+	ErrTemplateForbidden = errors.New("only the template owner can change it")
+)
+
+// ProjectTemplateInput — шаблон целиком: создание и замена (PUT).
+type ProjectTemplateInput struct {
+	Name               string                 `json:"name"`
+	Description        string                 `json:"description"`
+	ProjectTitle       string                 `json:"project_title"`
+	ProjectDescription string                 `json:"project_description"`
+	Priority           string                 `json:"priority"`
+	TasksLimit         int                    `json:"tasks_limit"`
+	Tags               []string               `json:"tags"`
+	DeadlineOffsetDays *int                   `json:"deadline_offset_days"`
+	Tasks              []models.TaskBlueprint `json:"tasks"`
+}
+
+// SaveAsTemplateInput — сохранение проекта шаблоном. StartDate (YYYY-MM-DD) —
+// от какого дня считать смещения; по умолчанию — самое раннее начало задачи,
+// а без дат — день создания проекта.
+type SaveAsTemplateInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	StartDate   string `json:"start_date"`
+}
+
+// InstantiateTemplateInput — создание проекта по шаблону. StartDate (YYYY-MM-DD)
+// по умолчанию — сегодня, Title — название проекта из шаблона.
+type InstantiateTemplateInput struct {
+	StartDate string `json:"start_date"`
+	Title     string `json:"title"`
+}
+
+// ProjectTemplateService — шаблоны проектов. Меняет шаблон владелец; пользователи,
+// которым он открыт, видят его и создают по нему свои проекты.
+type ProjectTemplateService struct {
+	templates *storage.ProjectTemplateStorage
+	projects  *storage.ProjectStorage
+	tasks     *storage.TaskStorage
+	users     *storage.UserStorage
+	quotas    quotaChecker
+	now       func() time.Time
+}
+
+func NewProjectTemplateService(tpl *storage.ProjectTemplateStorage, p *storage.ProjectStorage, t *storage.TaskStorage, u *storage.UserStorage) *ProjectTemplateService {
+	return &ProjectTemplateService{
+		templates: tpl, projects: p, tasks: t, users: u,
+		quotas: quotaChecker{projects: p, tasks: t, users: u},
+		now:    time.Now,
+	}
+}
+
+// List — свои шаблоны и открытые пользователю.
+func (s *ProjectTemplateService) List(userID uint) ([]models.ProjectTemplate, error) {
+	return s.templates.ListVisible(userID)
+}
+
+// Get возвращает шаблон; владельцу — вместе со списком тех, кому он открыт.
+func (s *ProjectTemplateService) Get(userID, id uint) (*models.ProjectTemplate, error) {
+	template, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.OwnerID == userID {
+		if template.Shares, err = s.templates.Shares(template.ID); err != nil {
+			return nil, err
+		}
+	}
+	return template, nil
+}
+
+func (s *ProjectTemplateService) Create(userID uint, input ProjectTemplateInput) (*models.ProjectTemplate, error) {
+	template := &models.ProjectTemplate{OwnerID: userID}
+	if err := applyTemplateInput(template, input); err != nil {
+		return nil, err
+	}
+	if err := s.templates.Create(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Update заменяет шаблон целиком, включая список задач.
+func (s *ProjectTemplateService) Update(userID, id uint, input ProjectTemplateInput) (*models.ProjectTemplate, error) {
+	template, err := s.writable(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTemplateInput(template, input); err != nil {
+		return nil, err
+	}
+	if err := s.templates.Update(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *ProjectTemplateService) Delete(userID, id uint) error {
+	template, err := s.writable(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.templates.Delete(template.ID)
+}
+
+// SaveProject сохраняет доступный пользователю проект шаблоном: настройки, теги и
+// задачи (кроме удалённых и отменённых) с датами относительно дня старта.
+func (s *ProjectTemplateService) SaveProject(userID, projectID uint, input SaveAsTemplateInput) (*models.ProjectTemplate, error) {
+	project, err := s.projects.GetAccessible(userID, projectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	tasks, err := s.tasks.List("asc", storage.TaskFilter{ProjectID: &project.ID})
+	if err != nil {
+		return nil, err
+	}
+	kept := tasks[:0]
+	for _, task := range tasks {
+		if task.Status != models.StatusCancelled {
+			kept = append(kept, task)
+		}
+	}
+
+	// Смещения и минуты считаются в поясе сохраняющего: 9:30 у него — 9:30 в шаблоне.
+	loc, err := userLocation(s.users, userID)
+	if err != nil {
+		return nil, err
+	}
+	start, err := templateStart(input.StartDate, project, kept, loc)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	if len(project.Tags) > 0 {
+		if err := json.Unmarshal(project.Tags, &tags); err != nil {
+			return nil, err
+		}
+	}
+	name := input.Name
+	if strings.TrimSpace(name) == "" {
+		name = project.Title
+	}
+	templateInput := ProjectTemplateInput{
+		Name:               name,
+		Description:        input.Description,
+		ProjectTitle:       project.Title,
+		ProjectDescription: project.Description,
+		Priority:           project.Priority,
+		TasksLimit:         project.TasksLimit,
+		Tags:               tags,
+		Tasks:              make([]models.TaskBlueprint, 0, len(kept)),
+	}
+	if project.Deadline != nil {
+		offset := daysBetween(start, project.Deadline.UTC())
+		templateInput.DeadlineOffsetDays = &offset
+	}
+	for _, task := range kept {
+		templateInput.Tasks = append(templateInput.Tasks, blueprintFromTask(task, start))
+	}
+	return s.Create(userID, templateInput)
+}
+
+// Share открывает шаблон пользователю с указанным email.
+func (s *ProjectTemplateService) Share(ownerID, id uint, email string) (*models.ProjectTemplateShare, error) {
+	template, err := s.writable(ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByEmail(normalized)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNoUser
+		}
+		return nil, err
+	}
+	if user.ID == template.OwnerID {
+		return nil, fmt.Errorf("%w: template owner already has access", ErrTemplateInvalid)
+	}
+	share := &models.ProjectTemplateShare{TemplateID: template.ID, UserID: user.ID}
+	if err := s.templates.AddShare(share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+func (s *ProjectTemplateService) Unshare(ownerID, id, userID uint) error {
+	template, err := s.writable(ownerID, id)
+	if err != nil {
+		return err
+	}
+	removed, err := s.templates.RemoveShare(template.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// Instantiate создаёт по шаблону проект пользователя: смещения дат отсчитываются
+// от StartDate, задачи начинаются в статусе todo. Проект и задачи создаются одной
+// транзакцией, с учётом квот пользователя и TasksLimit шаблона.
+func (s *ProjectTemplateService) Instantiate(userID, id uint, input InstantiateTemplateInput) (*models.Project, error) {
+	template, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	// День старта и время задач — в поясе пользователя, «сегодня» тоже его.
+	loc := owner.Location()
+	start := startOfDayIn(s.now(), loc)
+	if input.StartDate != "" {
+		if start, err = time.ParseInLocation(dayLayout, input.StartDate, loc); err != nil {
+			return nil, fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrTemplateInvalid)
+		}
+	}
+	blueprints := template.Tasks.Data()
+
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		title = template.ProjectTitle
+	}
+	payload := &models.ProjectInput{
+		Title:       title,
+		Description: template.ProjectDescription,
+		Priority:    template.Priority,
+		TasksLimit:  template.TasksLimit,
+		Tags:        template.Tags.Data(),
+	}
+	if template.DeadlineOffsetDays != nil {
+		deadline := time.Date(start.Year(), start.Month(), start.Day()+*template.DeadlineOffsetDays, 0, 0, 0, 0, time.UTC)
+		payload.Deadline = &deadline
+	}
+	normalized, err := normalizeProjectPayload(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+	project := &models.Project{
+		OwnerID:     userID,
+		Title:       normalized.Title,
+		Description: normalized.Description,
+		Status:      normalized.Status,
+		Priority:    normalized.Priority,
+		Deadline:    normalized.Deadline,
+		TasksLimit:  normalized.TasksLimit,
+	}
+	if len(normalized.Tags) > 0 {
+		data, _ := json.Marshal(normalized.Tags)
+		project.Tags = data
+	}
+
+	tasks := make([]models.Task, 0, len(blueprints))
+	for i, blueprint := range blueprints {
+		task, err := taskFromBlueprint(blueprint, start)
+		if err != nil {
+			return nil, fmt.Errorf("%w: tasks[%d]: %v", ErrTemplateInvalid, i, err)
+		}
+		tasks = append(tasks, task)
+	}
+	err = s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.checkNewProject(owner, template.TasksLimit, int64(len(tasks))); err != nil {
+			return err
+		}
+		return q.projects.CreateWithTasks(project, tasks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	project.TasksCount = int64(len(tasks))
+	return project, nil
+}
+
+func (s *ProjectTemplateService) visible(userID, id uint) (*models.ProjectTemplate, error) {
+	template, err := s.templates.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	if template.OwnerID == userID {
+		return template, nil
+	}
+	shared, err := s.templates.IsSharedWith(template.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !shared {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+func (s *ProjectTemplateService) writable(userID, id uint) (*models.ProjectTemplate, error) {
+	template, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.OwnerID != userID {
+		return nil, ErrTemplateForbidden
+	}
+	return template, nil
+}
+
+// applyTemplateInput проверяет шаблон и переносит его в модель.
+func applyTemplateInput(template *models.ProjectTemplate, input ProjectTemplateInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 255 {
+		return fmt.Errorf("%w: name is required and must be 255 characters or fewer", ErrTemplateInvalid)
+	}
+	projectTitle := strings.TrimSpace(input.ProjectTitle)
+	if projectTitle == "" || utf8.RuneCountInString(projectTitle) > 255 {
+		return fmt.Errorf("%w: project_title is required and must be 255 characters or fewer", ErrTemplateInvalid)
+	}
+	priority, err := models.NormalizeProjectPriority(input.Priority)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+	limit := input.TasksLimit
+	if limit <= 0 {
+		limit = models.DefaultProjectTasksLimit
+	}
+	if limit > models.TemplateTasksMax {
+		limit = models.TemplateTasksMax
+	}
+	if len(input.Tasks) > limit {
+		return fmt.Errorf("%w: template has %d tasks, tasks_limit is %d", ErrTemplateInvalid, len(input.Tasks), limit)
+	}
+	if input.DeadlineOffsetDays != nil && !validOffset(*input.DeadlineOffsetDays) {
+		return fmt.Errorf("%w: deadline_offset_days must be within %d days", ErrTemplateInvalid, models.TemplateOffsetMaxDays)
+	}
+
+	tags := make([]string, 0, len(input.Tags))
+	for _, tag := range input.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	blueprints := make([]models.TaskBlueprint, 0, len(input.Tasks))
+	for i, blueprint := range input.Tasks {
+		normalized, err := normalizeBlueprint(blueprint)
+		if err != nil {
+			return fmt.Errorf("%w: tasks[%d]: %v", ErrTemplateInvalid, i, err)
+		}
+		blueprints = append(blueprints, normalized)
+	}
+
+	template.Name = name
+	template.Description = strings.TrimSpace(input.Description)
+	template.ProjectTitle = projectTitle
+	template.ProjectDescription = input.ProjectDescription
+	template.Priority = priority
+	template.TasksLimit = limit
+	template.Tags = datatypes.NewJSONType(tags)
+	template.DeadlineOffsetDays = input.DeadlineOffsetDays
+	template.Tasks = datatypes.NewJSONType(blueprints)
+	return nil
+}
+
+func normalizeBlueprint(b models.TaskBlueprint) (models.TaskBlueprint, error) {
+	b.Title = strings.TrimSpace(b.Title)
+	if b.Title == "" || utf8.RuneCountInString(b.Title) > 255 {
+		return b, errors.New("title is required and must be 255 characters or fewer")
+	}
+	var err error
+	if b.Priority, err = models.NormalizePriority(b.Priority); err != nil {
+		return b, err
+	}
+	if b.Stage, err = models.NormalizeStage(b.Stage); err != nil {
+		return b, err
+	}
+	for _, offset := range []*int{b.StartOffsetDays, b.EndOffsetDays} {
+		if offset != nil && !validOffset(*offset) {
+			return b, fmt.Errorf("offsets must be within %d days", models.TemplateOffsetMaxDays)
+		}
+	}
+	if b.AllDay {
+		b.StartMinutes, b.EndMinutes = 0, 0
+	}
+	if b.StartMinutes < 0 || b.StartMinutes >= minutesPerDay || b.EndMinutes < 0 || b.EndMinutes >= minutesPerDay {
+		return b, errors.New("start_minutes and end_minutes must be between 0 and 1439")
+	}
+	if b.StartOffsetDays != nil && b.EndOffsetDays != nil &&
+		*b.EndOffsetDays*minutesPerDay+b.EndMinutes < *b.StartOffsetDays*minutesPerDay+b.StartMinutes {
+		return b, errors.New("end must be greater than or equal to start")
+	}
+	estimates := models.Task{StoryPoints: b.StoryPoints, EstimateHours: b.EstimateHours}
+	if err := estimates.NormalizeEstimates(); err != nil {
+		return b, err
+	}
+	b.StoryPoints, b.EstimateHours = estimates.StoryPoints, estimates.EstimateHours
+	return b, nil
+}
+
+func validOffset(days int) bool {
+	return days >= -models.TemplateOffsetMaxDays && days <= models.TemplateOffsetMaxDays
+}
+
+// templateStart — полночь дня в поясе loc, от которого отсчитываются смещения шаблона.
+func templateStart(raw string, project *models.Project, tasks []models.Task, loc *time.Location) (time.Time, error) {
+	if raw != "" {
+		start, err := time.ParseInLocation(dayLayout, raw, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrTemplateInvalid)
+		}
+		return start, nil
+	}
+	var earliest *time.Time
+	for i := range tasks {
+		if at := tasks[i].StartAt; at != nil && (earliest == nil || at.Before(*earliest)) {
+			earliest = at
+		}
+	}
+	if earliest != nil {
+		return startOfDayIn(*earliest, loc), nil
+	}
+	return startOfDayIn(project.CreatedAt, loc), nil
+}
+
+// dayOffset — сколько дней от start до дня, в который попадает at (в поясе start).
+func dayOffset(start, at time.Time) int {
+	return daysBetween(start, at.In(start.Location()))
+}
+
+// daysBetween — разница календарных дат a и b, каждая в своём поясе.
+func daysBetween(a, b time.Time) int {
+	from := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+func minutesOfDay(t time.Time, loc *time.Location) int {
+	t = t.In(loc)
+	return t.Hour()*60 + t.Minute()
+}
+
+func blueprintFromTask(task models.Task, start time.Time) models.TaskBlueprint {
+	blueprint := models.TaskBlueprint{
+		Title:         task.Title,
+		Description:   task.Description,
+		Priority:      task.Priority,
+		Stage:         task.Stage,
+		AllDay:        task.AllDay,
+		StoryPoints:   task.StoryPoints,
+		EstimateHours: task.EstimateHours,
+	}
+	if task.StartAt != nil {
+		offset := dayOffset(start, *task.StartAt)
+		blueprint.StartOffsetDays = &offset
+		if !task.AllDay {
+			blueprint.StartMinutes = minutesOfDay(*task.StartAt, start.Location())
+		}
+	}
+	if task.EndAt != nil {
+		offset := dayOffset(start, *task.EndAt)
+		blueprint.EndOffsetDays = &offset
+		if !task.AllDay {
+			blueprint.EndMinutes = minutesOfDay(*task.EndAt, start.Location())
+		}
+	}
+	return blueprint
+}
+
+// taskFromBlueprint разворачивает заготовку в задачу относительно дня start;
+// минуты отсчитываются от полуночи в поясе start.
+func taskFromBlueprint(b models.TaskBlueprint, start time.Time) (models.Task, error) {
+	task := models.Task{
+		Title:         b.Title,
+		Description:   b.Description,
+		Status:        models.StatusTodo,
+		Priority:      b.Priority,
+		Stage:         b.Stage,
+		AllDay:        b.AllDay,
+		StoryPoints:   b.StoryPoints,
+		EstimateHours: b.EstimateHours,
+	}
+	if b.StartOffsetDays != nil {
+		at := time.Date(start.Year(), start.Month(), start.Day()+*b.StartOffsetDays, 0, b.StartMinutes, 0, 0, start.Location()).UTC()
+		task.StartAt = &at
+	}
+	if b.EndOffsetDays != nil {
+		at := time.Date(start.Year(), start.Month(), start.Day()+*b.EndOffsetDays, 0, b.EndMinutes, 0, 0, start.Location()).UTC()
+		task.EndAt = &at
+	}
+	var err error
+	if task.Priority, err = models.NormalizePriority(task.Priority); err != nil {
+		return task, err
+	}
+	if task.Stage, err = models.NormalizeStage(task.Stage); err != nil {
+		return task, err
+	}
+	if err := normalizeTaskSchedule(&task, start.Location()); err != nil {
+		return task, err
+	}
+	return task, task.NormalizeEstimates()
+}
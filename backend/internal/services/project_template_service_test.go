@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectTemplateService_SaveShareAndInstantiate(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	friend := models.User{ID: 2, Email: "friend@example.com", Username: "friend", Password: "x", MaxProjects: 1}
+	stranger := models.User{ID: 3, Email: "stranger@example.com", Username: "stranger", Password: "x"}
+	require.NoError(t, db.Create([]*models.User{&owner, &friend, &stranger}).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	userStorage := storage.NewUserStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, userStorage)
+	service := NewProjectTemplateService(storage.NewProjectTemplateStorage(db), projectStorage, taskStorage, userStorage)
+	service.now = func() time.Time { return time.Date(2025, 5, 20, 15, 0, 0, 0, time.UTC) }
+
+	deadline := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	project, err := projects.Create(owner.ID, &models.ProjectInput{
+		Title: "Site", Priority: models.ProjectPriorityHigh, TasksLimit: 3, Tags: []string{"web"}, Deadline: &deadline,
+	})
+	require.NoError(t, err)
+
+	points := func(v float64) *float64 { return &v }
+	at := func(day, hour, minute int) *time.Time {
+		v := time.Date(2025, 3, day, hour, minute, 0, 0, time.UTC)
+		return &v
+	}
+	tasks := []models.Task{
+		{Title: "Kickoff", Status: models.StatusCompleted, Stage: "plan", StartAt: at(3, 10, 0), EndAt: at(3, 11, 30), ProjectID: &project.ID},
+		{Title: "Design", Status: models.StatusInProgress, Stage: "design", AllDay: true, StartAt: at(5, 0, 0), EndAt: at(7, 23, 59), StoryPoints: points(5), ProjectID: &project.ID},
+		{Title: "Backlog", Status: models.StatusTodo, ProjectID: &project.ID},
+		{Title: "Dropped", Status: models.StatusCancelled, ProjectID: &project.ID},
+	}
+	require.NoError(t, db.Create(&tasks).Error)
+
+	// Смещения считаются от самого раннего начала задачи — 3 марта.
+	template, err := service.SaveProject(owner.ID, project.ID, SaveAsTemplateInput{Name: "Лендинг"})
+	require.NoError(t, err)
+	require.Equal(t, "Site", template.ProjectTitle)
+	require.Equal(t, []string{"web"}, template.Tags.Data())
+	require.Equal(t, 28, *template.DeadlineOffsetDays)
+	blueprints := template.Tasks.Data()
+	require.Len(t, blueprints, 3)
+	require.Equal(t, 0, *blueprints[0].StartOffsetDays)
+	require.Equal(t, 600, blueprints[0].StartMinutes)
+	require.Equal(t, 690, blueprints[0].EndMinutes)
+	require.Equal(t, 2, *blueprints[1].StartOffsetDays)
+	require.Equal(t, 4, *blueprints[1].EndOffsetDays)
+	require.Zero(t, blueprints[1].StartMinutes)
+	require.Nil(t, blueprints[2].StartOffsetDays)
+
+	// Чужой шаблон не виден, пока его не откроют; менять его может только владелец.
+	_, err = service.Get(friend.ID, template.ID)
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = service.Share(owner.ID, template.ID, "nobody@example.com")
+	require.ErrorIs(t, err, ErrMemberNoUser)
+	_, err = service.Share(owner.ID, template.ID, owner.Email)
+	require.ErrorIs(t, err, ErrTemplateInvalid)
+	_, err = service.Share(owner.ID, template.ID, friend.Email)
+	require.NoError(t, err)
+	list, err := service.List(friend.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	_, err = service.Update(friend.ID, template.ID, ProjectTemplateInput{Name: "X", ProjectTitle: "X"})
+	require.ErrorIs(t, err, ErrTemplateForbidden)
+	shared, err := service.Get(owner.ID, template.ID)
+	require.NoError(t, err)
+	require.Len(t, shared.Shares, 1)
+
+	// Проект по шаблону: даты от выбранного дня, статусы сброшены.
+	created, err := service.Instantiate(friend.ID, template.ID, InstantiateTemplateInput{StartDate: "2025-06-02", Title: "Shop"})
+	require.NoError(t, err)
+	require.Equal(t, friend.ID, created.OwnerID)
+	require.Equal(t, "Shop", created.Title)
+	require.Equal(t, models.ProjectPriorityHigh, created.Priority)
+	require.Equal(t, time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC), created.Deadline.UTC())
+	var tags []string
+	require.NoError(t, json.Unmarshal(created.Tags, &tags))
+	require.Equal(t, []string{"web"}, tags)
+
+	createdTasks, err := taskStorage.List("asc", storage.TaskFilter{ProjectID: &created.ID})
+	require.NoError(t, err)
+	require.Len(t, createdTasks, 3)
+	for _, task := range createdTasks {
+		require.Equal(t, models.StatusTodo, task.Status)
+		require.Nil(t, task.CompletedAt)
+	}
+	require.Equal(t, time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC), createdTasks[0].StartAt.UTC())
+	require.Equal(t, time.Date(2025, 6, 2, 11, 30, 0, 0, time.UTC), createdTasks[0].EndAt.UTC())
+	require.True(t, createdTasks[1].AllDay)
+	require.Equal(t, time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC), createdTasks[1].StartAt.UTC())
+	require.Equal(t, 23, createdTasks[1].EndAt.UTC().Hour())
+	require.Equal(t, 5.0, *createdTasks[1].StoryPoints)
+	require.Equal(t, "design", createdTasks[1].Stage)
+
+	// MaxProjects у friend — 1, и проект уже создан.
+	_, err = service.Instantiate(friend.ID, template.ID, InstantiateTemplateInput{})
+	require.ErrorIs(t, err, ErrProjectLimit)
+
+	// Без даты старта — сегодня; TasksLimit шаблона не меньше числа задач.
+	today, err := service.Instantiate(owner.ID, template.ID, InstantiateTemplateInput{})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2025, 6, 17, 0, 0, 0, 0, time.UTC), today.Deadline.UTC())
+	_, err = service.Update(owner.ID, template.ID, ProjectTemplateInput{
+		Name: "Лендинг", ProjectTitle: "Site", TasksLimit: 2, Tasks: blueprints,
+	})
+	require.ErrorIs(t, err, ErrTemplateInvalid)
+	require.NoError(t, db.Model(&models.ProjectTemplate{}).Where("id = ?", template.ID).Update("tasks_limit", 2).Error)
+	_, err = service.Instantiate(owner.ID, template.ID, InstantiateTemplateInput{})
+	require.ErrorIs(t, err, ErrTasksLimit)
+
+	// Проверка заготовок.
+	offset := models.TemplateOffsetMaxDays + 1
+	_, err = service.Create(owner.ID, ProjectTemplateInput{Name: "Bad", ProjectTitle: "Bad", Tasks: []models.TaskBlueprint{{Title: "Far", StartOffsetDays: &offset}}})
+	require.ErrorIs(t, err, ErrTemplateInvalid)
+	_, err = service.Create(owner.ID, ProjectTemplateInput{Name: "Bad", ProjectTitle: "Bad", Tasks: []models.TaskBlueprint{{Title: "Late", StartMinutes: 1440}}})
+	require.ErrorIs(t, err, ErrTemplateInvalid)
+	_, err = service.Create(owner.ID, ProjectTemplateInput{Name: "Bad", ProjectTitle: "Bad", Tasks: []models.TaskBlueprint{{Title: "Odd", Priority: "urgent"}}})
+	require.ErrorIs(t, err, ErrTemplateInvalid)
+
+	require.NoError(t, service.Unshare(owner.ID, template.ID, friend.ID))
+	_, err = service.Instantiate(friend.ID, template.ID, InstantiateTemplateInput{})
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = service.Get(stranger.ID, template.ID)
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+	require.NoError(t, service.Delete(owner.ID, template.ID))
+}
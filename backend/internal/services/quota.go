@@ -0,0 +1,398 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Названия квот — они же поле quota в ответах 409.
+const (
+	QuotaProjects              = "projects"
+	QuotaTasks                 = "tasks"
+	QuotaProjectTasks          = "project_tasks"
+	QuotaProjectMembers        = "project_members"
+	QuotaAttachmentStorage     = "attachment_storage"
+	QuotaProjectAttachmentSize = "project_attachment_storage"
+)
+
+// Квоты частоты: исчерпаны на время, поэтому ответ 429 с Retry-After, а не 409.
+const (
+	QuotaLoginAttempts      = "login_attempts"
+	QuotaVerificationEmails = "verification_emails"
+)
+
+var rateQuotas = map[string]bool{
+	QuotaLoginAttempts:      true,
+	QuotaVerificationEmails: true,
+}
+
+var (
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrMemberLimit   = errors.New("project member limit reached")
+	ErrUnknownPlan   = errors.New("unknown plan")
+)
+
+// QuotaPlan — ограничения тарифа. Ноль — без ограничения.
+type QuotaPlan struct {
+	// Projects — собственные проекты пользователя (архивные считаются, удалённые — нет).
+	Projects int64 `json:"projects"`
+	// Tasks — живые задачи во всех собственных проектах.
+	Tasks int64 `json:"tasks"`
+	// TasksPerProject — живые задачи в одном проекте.
+	TasksPerProject int64 `json:"tasks_per_project"`
+	// MembersPerProject — участники одного проекта.
+	MembersPerProject int64 `json:"members_per_project"`
+	// StorageBytes и ProjectStorageBytes — объём вложений пользователя и проекта.
+	// Ноль — действуют общие ATTACHMENT_USER_QUOTA_MB и ATTACHMENT_PROJECT_QUOTA_MB.
+	StorageBytes        int64 `json:"storage_bytes"`
+	ProjectStorageBytes int64 `json:"project_storage_bytes"`
+}
+
+// DefaultQuotaPlans — тарифы по умолчанию: free у всех новых пользователей и pro.
+func DefaultQuotaPlans() map[string]QuotaPlan {
+	return map[string]QuotaPlan{
+		models.PlanFree: {Projects: 50, Tasks: 5000, TasksPerProject: 500, MembersPerProject: 10},
+		models.PlanPro: {
+			Projects: 500, Tasks: 50000, TasksPerProject: 500, MembersPerProject: 100,
+			StorageBytes: 10 << 30, ProjectStorageBytes: 10 << 30,
+		},
+	}
+}
+
+// QuotaPlansFromEnv — тарифы по умолчанию, дополненные или заменённые из QUOTA_PLANS:
+// JSON-объект {"team": {"projects": 100, ...}}. Некорректное значение игнорируется.
+func QuotaPlansFromEnv() map[string]QuotaPlan {
+	plans := DefaultQuotaPlans()
+	raw := strings.TrimSpace(os.Getenv("QUOTA_PLANS"))
+	if raw == "" {
+		return plans
+	}
+	var custom map[string]QuotaPlan
+	if err := json.Unmarshal([]byte(raw), &custom); err != nil {
+		log.Printf("QUOTA_PLANS ignored: %v", err)
+		return plans
+	}
+	for name, plan := range custom {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			plans[name] = plan
+		}
+	}
+	return plans
+}
+
+var quotaPlans = DefaultQuotaPlans()
+
+// SetQuotaPlans задаёт тарифы при старте сервера. Тариф free должен быть всегда:
+// он действует для пользователей с неизвестным тарифом.
+func SetQuotaPlans(plans map[string]QuotaPlan) {
+	if _, ok := plans[models.PlanFree]; !ok {
+		plans[models.PlanFree] = DefaultQuotaPlans()[models.PlanFree]
+	}
+	quotaPlans = plans
+}
+
+// QuotaPlanByName возвращает тариф; ok = false, если такого нет.
+func QuotaPlanByName(name string) (QuotaPlan, bool) {
+	plan, ok := quotaPlans[strings.ToLower(strings.TrimSpace(name))]
+	return plan, ok
+}
+
+func planOf(user *models.User) (string, QuotaPlan) {
+	if user != nil {
+		if plan, ok := QuotaPlanByName(user.Plan); ok {
+			return strings.ToLower(strings.TrimSpace(user.Plan)), plan
+		}
+	}
+	return models.PlanFree, quotaPlans[models.PlanFree]
+}
+
+// QuotaError — квота исчерпана. errors.Is находит и ErrQuotaExceeded, и прежнюю
+// ошибку конкретного ограничения (ErrProjectLimit, ErrTasksLimit и т. д.).
+type QuotaError struct {
+	Quota string `json:"quota"`
+	Limit int64  `json:"limit"`
+	Used  int64  `json:"used"`
+	// RetryAfter — для квот частоты: не позже чем через это время квота освободится.
+	RetryAfter time.Duration `json:"-"`
+	cause      error
+}
+
+// Rate сообщает, что квота ограничивает частоту, а не объём.
+func (e *QuotaError) Rate() bool {
+	return rateQuotas[e.Quota]
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s (%d of %d used)", e.cause.Error(), e.Used, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() []error {
+	return []error{ErrQuotaExceeded, e.cause}
+}
+
+var quotaCauses = map[string]error{
+	QuotaProjects:              ErrProjectLimit,
+	QuotaTasks:                 ErrTasksLimit,
+	QuotaProjectTasks:          ErrTasksLimit,
+	QuotaProjectMembers:        ErrMemberLimit,
+	QuotaAttachmentStorage:     ErrAttachmentUserQuota,
+	QuotaProjectAttachmentSize: ErrAttachmentProjectQuota,
+	QuotaVerificationEmails:    ErrVerificationRateLimited,
+}
+
+// checkQuota — хватит ли квоты, чтобы добавить adding к used. limit 0 — без ограничения.
+func checkQuota(name string, used, adding, limit int64) error {
+	if limit <= 0 || adding <= 0 || used+adding <= limit {
+		return nil
+	}
+	return &QuotaError{Quota: name, Limit: limit, Used: used, cause: quotaCauses[name]}
+}
+
+// rateQuotaError — исчерпана квота частоты name: used из limit за окно.
+func rateQuotaError(name string, limit, used int64, retryAfter time.Duration) error {
+	return &QuotaError{Quota: name, Limit: limit, Used: used, RetryAfter: retryAfter, cause: quotaCauses[name]}
+}
+
+// stricter — меньшее из положительных ограничений; 0, если оба не заданы.
+func stricter(a, b int64) int64 {
+	switch {
+	case a <= 0:
+		return b
+	case b <= 0 || a < b:
+		return a
+	default:
+		return b
+	}
+}
+
+// quotaChecker считает потребление и сверяет его с тарифом владельца. Собирается
+// из хранилищ сервиса, поэтому внутри транзакции достаточно хранилищ поверх tx.
+type quotaChecker struct {
+	projects    *storage.ProjectStorage
+	tasks       *storage.TaskStorage
+	users       *storage.UserStorage
+	attachments *storage.AttachmentStorage
+	files       AttachmentQuotas
+	// inTx — проверка идёт внутри транзакции записи: строка владельца блокируется.
+	inTx bool
+}
+
+func newQuotaCheckerTx(tx *gorm.DB) quotaChecker {
+	return quotaChecker{
+		projects:    storage.NewProjectStorage(tx),
+		tasks:       storage.NewTaskStorage(tx),
+		users:       storage.NewUserStorage(tx),
+		attachments: storage.NewAttachmentStorage(tx),
+		inTx:        true,
+	}
+}
+
+// transaction выполняет проверку квоты и запись одной транзакцией: fn получает
+// проверку и хранилища поверх tx. Проверка блокирует строку владельца, поэтому
+// параллельные запросы одного владельца считают потребление по очереди и вдвоём
+// лимит не превысят.
+func (q quotaChecker) transaction(fn func(tx quotaChecker) error) error {
+	return q.projects.Transaction(func(db *gorm.DB) error {
+		tx := newQuotaCheckerTx(db)
+		tx.files = q.files
+		return fn(tx)
+	})
+}
+
+// lockOwner блокирует строку владельца до конца транзакции; вне транзакции ничего не делает.
+func (q quotaChecker) lockOwner(ownerID uint) error {
+	if !q.inTx {
+		return nil
+	}
+	return q.users.LockForUpdate(ownerID)
+}
+
+// owner загружает пользователя; удалённый пользователь получает тариф free.
+func (q quotaChecker) owner(userID uint) (*models.User, error) {
+	if err := q.lockOwner(userID); err != nil {
+		return nil, err
+	}
+	user, err := q.users.GetByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.User{ID: userID}, nil
+	}
+	return user, err
+}
+
+// projectsLimit — тариф и личный max_projects: действует меньшее.
+func projectsLimit(user *models.User) int64 {
+	_, plan := planOf(user)
+	return stricter(plan.Projects, int64(user.MaxProjects))
+}
+
+// projectTasksLimit — тариф и tasks_limit проекта: действует меньшее.
+func projectTasksLimit(owner *models.User, tasksLimit int) int64 {
+	_, plan := planOf(owner)
+	return stricter(plan.TasksPerProject, int64(tasksLimit))
+}
+
+// checkProjects — может ли пользователь завести ещё adding проектов.
+func (q quotaChecker) checkProjects(owner *models.User, adding int64) error {
+	if err := q.lockOwner(owner.ID); err != nil {
+		return err
+	}
+	used, err := q.projects.CountByOwner(owner.ID)
+	if err != nil {
+		return err
+	}
+	return checkQuota(QuotaProjects, used, adding, projectsLimit(owner))
+}
+
+// checkOwnerTasks — хватит ли владельцу квоты на ещё adding задач во всех проектах.
+func (q quotaChecker) checkOwnerTasks(owner *models.User, adding int64) error {
+	_, plan := planOf(owner)
+	if plan.Tasks <= 0 || adding <= 0 {
+		return nil
+	}
+	used, err := q.tasks.CountByOwner(owner.ID)
+	if err != nil {
+		return err
+	}
+	return checkQuota(QuotaTasks, used, adding, plan.Tasks)
+}
+
+// checkNewProject — новый проект владельца сразу с count задачами (копия, шаблон).
+func (q quotaChecker) checkNewProject(owner *models.User, tasksLimit int, count int64) error {
+	if err := q.checkProjects(owner, 1); err != nil {
+		return err
+	}
+	if err := checkQuota(QuotaProjectTasks, 0, count, projectTasksLimit(owner, tasksLimit)); err != nil {
+		return err
+	}
+	return q.checkOwnerTasks(owner, count)
+}
+
+// checkProjectTasks — можно ли добавить в проект adding задач; fromOwner из них уже
+// лежат в других проектах того же владельца и его общий счёт не меняют.
+func (q quotaChecker) checkProjectTasks(project *models.Project, adding, fromOwner int64) error {
+	owner, err := q.owner(project.OwnerID)
+	if err != nil {
+		return err
+	}
+	used, err := q.tasks.CountByProject(project.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkQuota(QuotaProjectTasks, used, adding, projectTasksLimit(owner, project.TasksLimit)); err != nil {
+		return err
+	}
+	return q.checkOwnerTasks(owner, adding-fromOwner)
+}
+
+// checkTaskInProject — одна задача появляется в проекте projectID: создаётся или
+// переносится из проекта from (nil — задача была без проекта).
+func (q quotaChecker) checkTaskInProject(projectID uint, from *uint) error {
+	project, err := q.projects.GetByID(projectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProjectNotFound
+		}
+		return err
+	}
+	var fromOwner int64
+	if from != nil {
+		source, err := q.projects.GetByID(*from)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if source != nil && source.OwnerID == project.OwnerID {
+			fromOwner = 1
+		}
+	}
+	return q.checkProjectTasks(project, 1, fromOwner)
+}
+
+func (q quotaChecker) checkMembers(project *models.Project, adding int64) error {
+	owner, err := q.owner(project.OwnerID)
+	if err != nil {
+		return err
+	}
+	_, plan := planOf(owner)
+	if plan.MembersPerProject <= 0 {
+		return nil
+	}
+	used, err := q.projects.CountMembers(project.ID)
+	if err != nil {
+		return err
+	}
+	return checkQuota(QuotaProjectMembers, used, adding, plan.MembersPerProject)
+}
+
+func (q quotaChecker) storageLimits(user *models.User) (int64, int64) {
+	_, plan := planOf(user)
+	userLimit, projectLimit := q.files.PerUserBytes, q.files.PerProjectBytes
+	if plan.StorageBytes > 0 {
+		userLimit = plan.StorageBytes
+	}
+	if plan.ProjectStorageBytes > 0 {
+		projectLimit = plan.ProjectStorageBytes
+	}
+	return userLimit, projectLimit
+}
+
+// checkStorage — поместится ли файл size байт в квоту загружающего и проекта задачи.
+// Квота проекта берётся по тарифу его владельца.
+func (q quotaChecker) checkStorage(userID uint, projectID *uint, size int64) error {
+	var project *models.Project
+	if projectID != nil {
+		var err error
+		if project, err = q.projects.GetByID(*projectID); err != nil {
+			return err
+		}
+		// Загружающего и владельца проекта блокируем по возрастанию ID, чтобы встречные
+		// загрузки в проекты друг друга не ждали одна другую.
+		first, second := userID, project.OwnerID
+		if first > second {
+			first, second = second, first
+		}
+		if err := q.lockOwner(first); err != nil {
+			return err
+		}
+		if err := q.lockOwner(second); err != nil {
+			return err
+		}
+	}
+	user, err := q.owner(userID)
+	if err != nil {
+		return err
+	}
+	if limit, _ := q.storageLimits(user); limit > 0 {
+		used, err := q.attachments.UsageByUser(userID)
+		if err != nil {
+			return err
+		}
+		if err := checkQuota(QuotaAttachmentStorage, used, size, limit); err != nil {
+			return err
+		}
+	}
+	if project == nil {
+		return nil
+	}
+	owner, err := q.owner(project.OwnerID)
+	if err != nil {
+		return err
+	}
+	if _, limit := q.storageLimits(owner); limit > 0 {
+		used, err := q.attachments.UsageByProject(project.ID)
+		if err != nil {
+			return err
+		}
+		return checkQuota(QuotaProjectAttachmentSize, used, size, limit)
+	}
+	return nil
+}
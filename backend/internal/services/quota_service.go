@@ -0,0 +1,96 @@
+package services
+
+import (
+	"github.com/spozitivom/taskmanager/internal/storage"
+)
+
+// QuotaUsage — потребление и действующее ограничение; Limit 0 — без ограничения.
+type QuotaUsage struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// ProjectQuotaUsage — потребление одного собственного проекта.
+type ProjectQuotaUsage struct {
+	ProjectID uint       `json:"project_id"`
+	Title     string     `json:"title"`
+	Archived  bool       `json:"archived"`
+	Tasks     QuotaUsage `json:"tasks"`
+	Members   QuotaUsage `json:"members"`
+	Storage   QuotaUsage `json:"storage"`
+}
+
+// UsageReport — тариф пользователя и потребление по всем квотам.
+type UsageReport struct {
+	Plan      string              `json:"plan"`
+	Limits    QuotaPlan           `json:"limits"`
+	Projects  QuotaUsage          `json:"projects"`
+	Tasks     QuotaUsage          `json:"tasks"`
+	Storage   QuotaUsage          `json:"storage"`
+	ByProject []ProjectQuotaUsage `json:"by_project"`
+}
+
+// QuotaService показывает пользователю его тариф и потребление. Проверки квот
+// выполняют сами сервисы при создании, переносе и восстановлении.
+type QuotaService struct {
+	quotas quotaChecker
+}
+
+func NewQuotaService(p *storage.ProjectStorage, t *storage.TaskStorage, u *storage.UserStorage, a *storage.AttachmentStorage, files AttachmentQuotas) *QuotaService {
+	return &QuotaService{quotas: quotaChecker{projects: p, tasks: t, users: u, attachments: a, files: files}}
+}
+
+// Usage считает потребление так же, как проверки квот: собственные неудалённые
+// проекты (архивные тоже), живые задачи в них и весь объём загруженных файлов.
+func (s *QuotaService) Usage(userID uint) (*UsageReport, error) {
+	q := s.quotas
+	user, err := q.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	planName, plan := planOf(user)
+	report := &UsageReport{Plan: planName, Limits: plan, ByProject: []ProjectQuotaUsage{}}
+
+	if report.Projects.Used, err = q.projects.CountByOwner(userID); err != nil {
+		return nil, err
+	}
+	report.Projects.Limit = projectsLimit(user)
+	if report.Tasks.Used, err = q.tasks.CountByOwner(userID); err != nil {
+		return nil, err
+	}
+	report.Tasks.Limit = plan.Tasks
+	if report.Storage.Used, err = q.attachments.UsageByUser(userID); err != nil {
+		return nil, err
+	}
+	storageLimit, projectStorageLimit := q.storageLimits(user)
+	report.Storage.Limit = storageLimit
+
+	projects, err := q.projects.List(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		if project.OwnerID != userID {
+			continue
+		}
+		item := ProjectQuotaUsage{
+			ProjectID: project.ID,
+			Title:     project.Title,
+			Archived:  project.ArchivedAt != nil,
+			Tasks:     QuotaUsage{Limit: projectTasksLimit(user, project.TasksLimit)},
+			Members:   QuotaUsage{Limit: plan.MembersPerProject},
+			Storage:   QuotaUsage{Limit: projectStorageLimit},
+		}
+		if item.Tasks.Used, err = q.tasks.CountByProject(project.ID); err != nil {
+			return nil, err
+		}
+		if item.Members.Used, err = q.projects.CountMembers(project.ID); err != nil {
+			return nil, err
+		}
+		if item.Storage.Used, err = q.attachments.UsageByProject(project.ID); err != nil {
+			return nil, err
+		}
+		report.ByProject = append(report.ByProject, item)
+	}
+	return report, nil
+}
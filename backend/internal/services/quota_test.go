@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuota_TasksAndMembers(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	friend := models.User{ID: 2, Email: "friend@example.com", Username: "friend", Password: "x", MaxProjects: 5}
+	require.NoError(t, db.Create([]*models.User{&owner, &friend}).Error)
+
+	SetQuotaPlans(map[string]QuotaPlan{models.PlanFree: {Projects: 5, Tasks: 3, TasksPerProject: 2, MembersPerProject: 1}})
+	t.Cleanup(func() { SetQuotaPlans(DefaultQuotaPlans()) })
+
+	projectStorage := storage.NewProjectStorage(db)
+	taskStorage := storage.NewTaskStorage(db)
+	userStorage := storage.NewUserStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, userStorage)
+	tasks := NewTaskService(taskStorage, projectStorage, userStorage)
+
+	first, err := projects.Create(owner.ID, &models.ProjectInput{Title: "A", Status: models.ProjectStatusActive})
+	require.NoError(t, err)
+	second, err := projects.Create(owner.ID, &models.ProjectInput{Title: "B", Status: models.ProjectStatusActive})
+	require.NoError(t, err)
+
+	// Лимит задач в проекте — по тарифу, даже если tasks_limit проекта не задан.
+	for _, title := range []string{"one", "two"} {
+		require.NoError(t, tasks.CreateTask(&models.Task{Title: title, ProjectID: &first.ID}, nil))
+	}
+	err = tasks.CreateTask(&models.Task{Title: "three", ProjectID: &first.ID}, nil)
+	var quota *QuotaError
+	require.True(t, errors.As(err, &quota))
+	require.Equal(t, QuotaProjectTasks, quota.Quota)
+	require.EqualValues(t, 2, quota.Limit)
+	require.ErrorIs(t, err, ErrTasksLimit)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// Перенос внутри своих проектов не меняет общий счёт владельца.
+	loose := models.Task{Title: "loose"}
+	require.NoError(t, tasks.CreateTask(&loose, nil))
+	require.NoError(t, tasks.CreateTask(&models.Task{Title: "b1", ProjectID: &second.ID}, nil))
+	_, err = tasks.PatchTask(loose.ID, models.TaskPatch{ProjectID: &second.ID}, nil)
+	require.True(t, errors.As(err, &quota))
+	require.Equal(t, QuotaTasks, quota.Quota)
+	moved, err := taskStorage.ListByProject(first.ID, false)
+	require.NoError(t, err)
+	require.NoError(t, projects.AssignTasks(owner.ID, second.ID, []uint{moved[0].ID}, true))
+
+	// Задачи архивного проекта при восстановлении снова занимают квоту.
+	require.NoError(t, projects.Archive(owner.ID, first.ID))
+	spare, err := projects.Create(owner.ID, &models.ProjectInput{Title: "C", Status: models.ProjectStatusActive})
+	require.NoError(t, err)
+	require.NoError(t, tasks.CreateTask(&models.Task{Title: "c1", ProjectID: &spare.ID}, nil))
+	require.ErrorIs(t, projects.Restore(owner.ID, first.ID), ErrQuotaExceeded)
+
+	_, err = projects.AddMember(owner.ID, second.ID, friend.Email)
+	require.NoError(t, err)
+	_, err = projects.AddMember(owner.ID, second.ID, friend.Email)
+	require.NoError(t, err, "повторное добавление участника не расходует квоту")
+	third := models.User{ID: 3, Email: "third@example.com", Username: "third", Password: "x"}
+	require.NoError(t, db.Create(&third).Error)
+	_, err = projects.AddMember(owner.ID, second.ID, third.Email)
+	require.ErrorIs(t, err, ErrMemberLimit)
+}
+
+func TestQuotaService_Usage(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 3, Plan: models.PlanPro}
+	require.NoError(t, db.Create(&owner).Error)
+
+	projectStorage := storage.NewProjectStorage(db)
+	taskStorage := storage.NewTaskStorage(db)
+	userStorage := storage.NewUserStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, userStorage)
+	project, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site", Status: models.ProjectStatusActive, TasksLimit: 10})
+	require.NoError(t, err)
+	require.NoError(t, NewTaskService(taskStorage, projectStorage, userStorage).CreateTask(&models.Task{Title: "one", ProjectID: &project.ID}, nil))
+
+	report, err := NewQuotaService(projectStorage, taskStorage, userStorage, storage.NewAttachmentStorage(db), AttachmentQuotas{}).Usage(owner.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.PlanPro, report.Plan)
+	require.Equal(t, QuotaUsage{Used: 1, Limit: 3}, report.Projects)
+	require.Equal(t, QuotaUsage{Used: 1, Limit: 50000}, report.Tasks)
+	require.EqualValues(t, 10<<30, report.Storage.Limit)
+	require.Len(t, report.ByProject, 1)
+	require.Equal(t, QuotaUsage{Used: 1, Limit: 10}, report.ByProject[0].Tasks)
+}
+
+func TestQuota_ConcurrentCreatesStayWithinLimit(t *testing.T) {
+	db := setupTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Одно соединение: транзакция держит его от проверки до вставки, а запросы без
+	// транзакции могли бы вклиниться между ними.
+	sqlDB.SetMaxOpenConns(1)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 2}
+	require.NoError(t, db.Create(&owner).Error)
+
+	projectStorage := storage.NewProjectStorage(db)
+	taskStorage := storage.NewTaskStorage(db)
+	userStorage := storage.NewUserStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, userStorage)
+	tasks := NewTaskService(taskStorage, projectStorage, userStorage)
+	project, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site", Status: models.ProjectStatusActive, TasksLimit: 3})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- tasks.CreateTask(&models.Task{Title: "task", ProjectID: &project.ID}, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := projects.Create(owner.ID, &models.ProjectInput{Title: "more", Status: models.ProjectStatusActive})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			require.ErrorIs(t, err, ErrQuotaExceeded)
+		}
+	}
+
+	count, err := taskStorage.CountByProject(project.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, count)
+	owned, err := projectStorage.CountByOwner(owner.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, owned)
+}
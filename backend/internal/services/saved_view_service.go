@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/spozitivom/taskmanager/internal/taskquery"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const savedViewNameMaxLength = 128
+
+var (
+	ErrViewNotFound  = errors.New("view not found")
+	ErrViewForbidden = errors.New("only the owner can change a view")
+	ErrViewInvalid   = errors.New("invalid view")
+)
+
+// SavedViewInput — тело создания и изменения представления. При изменении
+// nil-поля не трогаются; shared_project_id = 0 закрывает доступ участникам.
+type SavedViewInput struct {
+	Name            *string            `json:"name"`
+	Filter          *models.ViewFilter `json:"filter"`
+	SharedProjectID *uint              `json:"shared_project_id"`
+}
+
+// SavedViewService — сохранённые фильтры задач. Менять представление может только
+// владелец; открытое для проекта представление видят его участники.
+type SavedViewService struct {
+	views    *storage.SavedViewStorage
+	tasks    *storage.TaskStorage
+	projects *storage.ProjectStorage
+	users    *storage.UserStorage
+	labels   *LabelService
+}
+
+func NewSavedViewService(v *storage.SavedViewStorage, t *storage.TaskStorage, p *storage.ProjectStorage, u *storage.UserStorage, labels *LabelService) *SavedViewService {
+	return &SavedViewService{views: v, tasks: t, projects: p, users: u, labels: labels}
+}
+
+// List возвращает видимые пользователю представления с числом задач в каждом.
+func (s *SavedViewService) List(userID uint) ([]models.SavedView, error) {
+	views, err := s.views.ListVisible(userID)
+	if err != nil {
+		return nil, err
+	}
+	now, err := s.nowFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		filter, err := viewTaskFilter(views[i].Filter.Data(), now)
+		if err != nil {
+			return nil, err
+		}
+		count, err := s.tasks.Count(filter)
+		if err != nil {
+			return nil, err
+		}
+		views[i].TasksCount = count
+	}
+	return views, nil
+}
+
+func (s *SavedViewService) Get(userID, id uint) (*models.SavedView, error) {
+	view, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	now, err := s.nowFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := viewTaskFilter(view.Filter.Data(), now)
+	if err != nil {
+		return nil, err
+	}
+	if view.TasksCount, err = s.tasks.Count(filter); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+func (s *SavedViewService) Create(userID uint, input SavedViewInput) (*models.SavedView, error) {
+	if input.Name == nil {
+		return nil, fmt.Errorf("%w: name is required", ErrViewInvalid)
+	}
+	view := &models.SavedView{OwnerID: userID}
+	if input.Filter == nil {
+		input.Filter = &models.ViewFilter{}
+	}
+	if err := s.apply(userID, view, input); err != nil {
+		return nil, err
+	}
+	if err := s.views.Create(view); err != nil {
+		return nil, err
+	}
+	return s.Get(userID, view.ID)
+}
+
+func (s *SavedViewService) Update(userID, id uint, input SavedViewInput) (*models.SavedView, error) {
+	view, err := s.owned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(userID, view, input); err != nil {
+		return nil, err
+	}
+	if err := s.views.Update(view); err != nil {
+		return nil, err
+	}
+	return s.Get(userID, view.ID)
+}
+
+func (s *SavedViewService) Delete(userID, id uint) error {
+	view, err := s.owned(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.views.Delete(view.ID)
+}
+
+// Tasks вычисляет представление: задачи по его фильтру и сортировке с метками,
+// видимыми пользователю.
+func (s *SavedViewService) Tasks(userID, id uint) ([]models.Task, error) {
+	view, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	now, err := s.nowFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter := view.Filter.Data()
+	taskFilter, err := viewTaskFilter(filter, now)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.tasks.List(filter.Sort, taskFilter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.labels.Attach(userID, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *SavedViewService) apply(userID uint, view *models.SavedView, input SavedViewInput) error {
+	if input.Name != nil {
+		name := strings.Join(strings.Fields(*input.Name), " ")
+		if name == "" || utf8.RuneCountInString(name) > savedViewNameMaxLength {
+			return fmt.Errorf("%w: name must be 1 to %d characters", ErrViewInvalid, savedViewNameMaxLength)
+		}
+		view.Name = name
+	}
+	if input.SharedProjectID != nil {
+		if *input.SharedProjectID == 0 {
+			view.SharedProjectID = nil
+		} else {
+			project, err := s.projects.Get(userID, *input.SharedProjectID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrProjectNotFound
+				}
+				return err
+			}
+			view.SharedProjectID = &project.ID
+		}
+	}
+	filter := view.Filter.Data()
+	if input.Filter != nil {
+		normalized, err := normalizeViewFilter(*input.Filter)
+		if err != nil {
+			return err
+		}
+		filter = normalized
+	}
+	if len(filter.LabelIDs) > 0 {
+		if err := s.labels.CheckVisible(userID, filter.LabelIDs); err != nil {
+			return err
+		}
+		// Личные метки в открытом представлении раскрыли бы участникам чужую разметку.
+		if view.SharedProjectID != nil {
+			if err := s.labels.CheckShared(filter.LabelIDs); err != nil {
+				return err
+			}
+		}
+	}
+	view.Filter = datatypes.NewJSONType(filter)
+	return nil
+}
+
+func (s *SavedViewService) visible(userID, id uint) (*models.SavedView, error) {
+	view, err := s.views.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrViewNotFound
+		}
+		return nil, err
+	}
+	if view.OwnerID == userID {
+		return view, nil
+	}
+	if view.SharedProjectID == nil {
+		return nil, ErrViewNotFound
+	}
+	if _, err := s.projects.GetAccessible(userID, *view.SharedProjectID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrViewNotFound
+		}
+		return nil, err
+	}
+	return view, nil
+}
+
+func (s *SavedViewService) owned(userID, id uint) (*models.SavedView, error) {
+	view, err := s.visible(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if view.OwnerID != userID {
+		return nil, ErrViewForbidden
+	}
+	return view, nil
+}
+
+// normalizeViewFilter проверяет значения так же, как при создании задач.
+func normalizeViewFilter(filter models.ViewFilter) (models.ViewFilter, error) {
+	var err error
+	if filter.Status = strings.TrimSpace(filter.Status); filter.Status != "" {
+		if filter.Status, err = models.NormalizeTaskStatus(filter.Status); err != nil {
+			return filter, fmt.Errorf("%w: %v", ErrViewInvalid, err)
+		}
+	}
+	if filter.Priority = strings.TrimSpace(filter.Priority); filter.Priority != "" {
+		if filter.Priority, err = models.NormalizePriority(filter.Priority); err != nil {
+			return filter, fmt.Errorf("%w: %v", ErrViewInvalid, err)
+		}
+	}
+	if filter.Stage = strings.TrimSpace(filter.Stage); filter.Stage != "" {
+		if filter.Stage, err = models.NormalizeStage(filter.Stage); err != nil {
+			return filter, fmt.Errorf("%w: %v", ErrViewInvalid, err)
+		}
+	}
+	if filter.ProjectID = strings.TrimSpace(filter.ProjectID); filter.ProjectID != "" && filter.ProjectID != "none" {
+		if id, err := strconv.ParseUint(filter.ProjectID, 10, 64); err != nil || id == 0 {
+			return filter, fmt.Errorf("%w: project_id must be a project id or none", ErrViewInvalid)
+		}
+	}
+	filter.LabelIDs = uniqueUints(filter.LabelIDs)
+	switch filter.LabelMode {
+	case "":
+		if len(filter.LabelIDs) > 0 {
+			filter.LabelMode = LabelMatchAny
+		}
+	case LabelMatchAny, LabelMatchAll:
+	default:
+		return filter, fmt.Errorf("%w: label_mode must be any or all", ErrViewInvalid)
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	if _, err := taskquery.Parse(filter.Query); err != nil {
+		return filter, fmt.Errorf("%w: query %v", ErrViewInvalid, err)
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = "desc"
+	case "asc", "desc":
+	default:
+		return filter, fmt.Errorf("%w: sort must be asc or desc", ErrViewInvalid)
+	}
+	return filter, nil
+}
+
+// nowFor — текущее время в поясе пользователя: по нему запрос считает «сегодня».
+// Одно представление у разных участников проекта даёт каждому его «сегодня».
+func (s *SavedViewService) nowFor(userID uint) (time.Time, error) {
+	loc, err := userLocation(s.users, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
+
+// viewTaskFilter переводит сохранённый фильтр в условия выборки; относительные
+// даты запроса отсчитываются от now.
+func viewTaskFilter(filter models.ViewFilter, now time.Time) (TaskFilter, error) {
+	result := TaskFilter{
+		Status:    filter.Status,
+		Priority:  filter.Priority,
+		Stage:     filter.Stage,
+		LabelIDs:  filter.LabelIDs,
+		LabelMode: filter.LabelMode,
+	}
+	if filter.ProjectID == "none" {
+		zero := uint(0)
+		result.ProjectID = &zero
+	} else if id, err := strconv.ParseUint(filter.ProjectID, 10, 64); err == nil {
+		projectID := uint(id)
+		result.ProjectID = &projectID
+	}
+	if filter.Query != "" {
+		node, err := taskquery.Parse(filter.Query)
+		if err != nil {
+			return result, fmt.Errorf("%w: query %v", ErrViewInvalid, err)
+		}
+		result.Query = taskquery.Compile(node, now)
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedViewService_SharingAndEvaluation(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	viewer := models.User{ID: 2, Email: "viewer@example.com", Username: "viewer", Password: "x"}
+	stranger := models.User{ID: 3, Email: "stranger@example.com", Username: "stranger", Password: "x"}
+	require.NoError(t, db.Create([]*models.User{&owner, &viewer, &stranger}).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, storage.NewUserStorage(db))
+	labels := NewLabelService(storage.NewLabelStorage(db), taskStorage, projectStorage)
+	service := NewSavedViewService(storage.NewSavedViewStorage(db), taskStorage, projectStorage, storage.NewUserStorage(db), labels)
+
+	project, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site"})
+	require.NoError(t, err)
+	_, err = projects.AddMember(owner.ID, project.ID, viewer.Email)
+	require.NoError(t, err)
+	first := models.Task{Title: "First", Priority: "high", ProjectID: &project.ID}
+	second := models.Task{Title: "Second", Priority: "high", ProjectID: &project.ID}
+	low := models.Task{Title: "Low", Priority: "low", ProjectID: &project.ID}
+	inbox := models.Task{Title: "Inbox", Priority: "high"}
+	require.NoError(t, db.Create([]*models.Task{&first, &second, &low, &inbox}).Error)
+
+	name := "  Срочное   по сайту "
+	view, err := service.Create(owner.ID, SavedViewInput{
+		Name:   &name,
+		Filter: &models.ViewFilter{Priority: "HIGH", ProjectID: "1", Sort: "asc"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Срочное по сайту", view.Name)
+	require.Equal(t, "high", view.Filter.Data().Priority)
+	require.EqualValues(t, 2, view.TasksCount)
+
+	empty := " "
+	_, err = service.Create(owner.ID, SavedViewInput{Name: &empty})
+	require.ErrorIs(t, err, ErrViewInvalid)
+	_, err = service.Create(owner.ID, SavedViewInput{Name: &name, Filter: &models.ViewFilter{Sort: "random"}})
+	require.ErrorIs(t, err, ErrViewInvalid)
+
+	tasks, err := service.Tasks(owner.ID, view.ID)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	require.Equal(t, first.ID, tasks[0].ID)
+
+	// Пока представление не открыто, участник проекта его не видит.
+	_, err = service.Get(viewer.ID, view.ID)
+	require.ErrorIs(t, err, ErrViewNotFound)
+	_, err = service.Update(viewer.ID, view.ID, SavedViewInput{SharedProjectID: &project.ID})
+	require.ErrorIs(t, err, ErrViewNotFound)
+
+	view, err = service.Update(owner.ID, view.ID, SavedViewInput{SharedProjectID: &project.ID})
+	require.NoError(t, err)
+	visible, err := service.List(viewer.ID)
+	require.NoError(t, err)
+	require.Len(t, visible, 1)
+	require.EqualValues(t, 2, visible[0].TasksCount)
+	_, err = service.Update(viewer.ID, view.ID, SavedViewInput{Name: &empty})
+	require.ErrorIs(t, err, ErrViewForbidden)
+	require.ErrorIs(t, service.Delete(viewer.ID, view.ID), ErrViewForbidden)
+	_, err = service.Tasks(stranger.ID, view.ID)
+	require.ErrorIs(t, err, ErrViewNotFound)
+
+	// Личные метки в открытом представлении недопустимы, метки проекта — да.
+	personal, err := labels.Create(owner.ID, LabelInput{Name: "Mine"})
+	require.NoError(t, err)
+	shared, err := labels.Create(owner.ID, LabelInput{Name: "UI", ProjectID: &project.ID})
+	require.NoError(t, err)
+	require.NoError(t, labels.SetOnTasks(owner.ID, []uint{second.ID}, []uint{shared.ID}, LabelsAdd))
+	_, err = service.Update(owner.ID, view.ID, SavedViewInput{Filter: &models.ViewFilter{LabelIDs: []uint{personal.ID}}})
+	require.ErrorIs(t, err, ErrLabelPersonal)
+	view, err = service.Update(owner.ID, view.ID, SavedViewInput{Filter: &models.ViewFilter{LabelIDs: []uint{shared.ID, shared.ID}}})
+	require.NoError(t, err)
+	require.Equal(t, []uint{shared.ID}, view.Filter.Data().LabelIDs)
+	require.Equal(t, LabelMatchAny, view.Filter.Data().LabelMode)
+
+	tasks, err = service.Tasks(viewer.ID, view.ID)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.Equal(t, second.ID, tasks[0].ID)
+	require.Len(t, tasks[0].Labels, 1)
+
+	// Удаление проекта закрывает доступ, но не удаляет представление владельца.
+	require.NoError(t, projectStorage.HardDelete(project))
+	_, err = service.Get(viewer.ID, view.ID)
+	require.ErrorIs(t, err, ErrViewNotFound)
+	_, err = service.Get(owner.ID, view.ID)
+	require.NoError(t, err)
+}
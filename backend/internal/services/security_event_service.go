@@ -0,0 +1,98 @@
+package services
+
+import (
+	"log"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/datatypes"
+)
+
+const (
+	securityEventsPageSize = 50
+	securityEventsMaxPage  = 500
+	// SecurityEventsExportLimit — сколько событий максимум попадает в одну выгрузку.
+	SecurityEventsExportLimit = 10000
+)
+
+// SecurityEventFilter — фильтры выборки журнала для администраторов.
+type SecurityEventFilter = storage.SecurityEventFilter
+
+// SecurityEvent — данные для записи в журнал безопасности.
+type SecurityEvent struct {
+	UserID    *uint
+	ActorID   *uint
+	Type      string
+	Outcome   string
+	IP        string
+	UserAgent string
+	Details   map[string]any
+}
+
+// SecurityEventService ведёт журнал событий безопасности (входы, смена пароля и т.п.).
+type SecurityEventService struct {
+	events *storage.SecurityEventStorage
+}
+
+func NewSecurityEventService(events *storage.SecurityEventStorage) *SecurityEventService {
+	return &SecurityEventService{events: events}
+}
+
+// Record пишет событие. Сбой записи не должен ломать основное действие, поэтому
+// ошибка только логируется. Nil-сервис (журнал не подключён) ничего не делает.
+func (s *SecurityEventService) Record(event SecurityEvent) {
+	if s == nil {
+		return
+	}
+	row := &models.SecurityEvent{
+		UserID:    event.UserID,
+		ActorID:   event.ActorID,
+		EventType: event.Type,
+		Outcome:   event.Outcome,
+		IP:        truncate(event.IP, 64),
+		UserAgent: truncate(event.UserAgent, 255),
+	}
+	if row.ActorID == nil {
+		row.ActorID = event.UserID
+	}
+	if row.Outcome == "" {
+		row.Outcome = models.SecurityOutcomeSuccess
+	}
+	if len(event.Details) > 0 {
+		row.Details = datatypes.JSONMap(event.Details)
+	}
+	if err := s.events.Create(row); err != nil {
+		log.Printf("failed to record security event %s: %v", event.Type, err)
+	}
+}
+
+// ListForUser — события аккаунта пользователя, от новых к старым.
+func (s *SecurityEventService) ListForUser(userID uint, limit, offset int) ([]models.SecurityEvent, error) {
+	limit, offset = securityEventsPage(limit, offset)
+	return s.events.List(storage.SecurityEventFilter{UserID: userID, Limit: limit, Offset: offset})
+}
+
+// Query — выборка для администраторов.
+func (s *SecurityEventService) Query(filter SecurityEventFilter) ([]models.SecurityEvent, error) {
+	filter.Limit, filter.Offset = securityEventsPage(filter.Limit, filter.Offset)
+	return s.events.List(filter)
+}
+
+// Export возвращает до SecurityEventsExportLimit событий по фильтру (пагинация игнорируется).
+func (s *SecurityEventService) Export(filter SecurityEventFilter) ([]models.SecurityEvent, error) {
+	filter.Limit, filter.Offset = SecurityEventsExportLimit, 0
+	return s.events.List(filter)
+}
+
+func securityEventsPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = securityEventsPageSize
+	}
+	if limit > securityEventsMaxPage {
+		limit = securityEventsMaxPage
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -2,8 +2,8 @@ package services
 
 import (
 	"fmt"
-	"time"
 	"testing"
+	"time"
 
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/stretchr/testify/require"
@@ -16,6 +16,32 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	dsn := fmt.Sprintf("file:test-%d?mode=memory&cache=shared", time.Now().UnixNano())
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{}))
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.Project{},
+		&models.ProjectMember{},
+		&models.Task{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PersonalAccessToken{},
+		&models.PasswordResetToken{},
+		&models.OutboxEmail{},
+		&models.EmailVerificationToken{},
+		&models.RecoveryCode{},
+		&models.LoginFailure{},
+		&models.OIDCIdentity{},
+		&models.OIDCAuthRequest{},
+		&models.AdminAuditEntry{},
+		&models.SecurityEvent{},
+		&models.DataExport{},
+		&models.Attachment{},
+		&models.Label{},
+		&models.TaskLabel{},
+		&models.SavedView{},
+		&models.TimeEntry{},
+		&models.Milestone{},
+		&models.ProjectTemplate{},
+		&models.ProjectTemplateShare{},
+	))
 	return db
 }
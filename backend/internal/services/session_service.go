@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// TokenPair — ответ на логин и refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	SessionID    uint   `json:"session_id"`
+}
+
+// SessionService управляет сессиями входа и ротацией refresh-токенов.
+type SessionService struct {
+	sessions *storage.SessionStorage
+	users    *storage.UserStorage
+	now      func() time.Time
+}
+
+func NewSessionService(sessions *storage.SessionStorage, users *storage.UserStorage) *SessionService {
+	return &SessionService{sessions: sessions, users: users, now: time.Now}
+}
+
+// Start открывает новую сессию и выдаёт первую пару токенов.
+func (s *SessionService) Start(user *models.User, userAgent, ip string) (*TokenPair, error) {
+	now := s.now()
+	raw, hash, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(RefreshTokenTTL())
+	session := &models.Session{
+		UserID:     user.ID,
+		UserAgent:  truncate(userAgent, 255),
+		IP:         truncate(ip, 64),
+		ExpiresAt:  expiresAt,
+		LastUsedAt: now,
+	}
+	token := &models.RefreshToken{TokenHash: hash, ExpiresAt: expiresAt}
+	if err := s.sessions.Create(session, token); err != nil {
+		return nil, err
+	}
+	return s.issue(user, session.ID, raw)
+}
+
+// Refresh обменивает refresh-токен на новую пару. Повторное предъявление
+// уже использованного токена считается кражей: вся сессия отзывается.
+func (s *SessionService) Refresh(rawToken string) (*TokenPair, error) {
+	now := s.now()
+	stored, err := s.sessions.GetRefreshToken(HashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	session, err := s.sessions.GetByID(stored.SessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	if stored.UsedAt != nil {
+		if _, err := s.sessions.Revoke(session.UserID, session.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if !now.Before(stored.ExpiresAt) || !now.Before(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.users.GetByID(session.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if err := AccountStatusError(user); err != nil {
+		return nil, err
+	}
+
+	raw, hash, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	next := &models.RefreshToken{TokenHash: hash, ExpiresAt: session.ExpiresAt}
+	rotated, err := s.sessions.Rotate(stored, next, now)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Кто-то успел обменять этот же токен — поведение как при повторном использовании.
+		if _, err := s.sessions.Revoke(session.UserID, session.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	return s.issue(user, session.ID, raw)
+}
+
+// ValidateSession проверяет, что сессия принадлежит пользователю, не отозвана и не истекла.
+func (s *SessionService) ValidateSession(userID, sessionID uint) error {
+	session, err := s.sessions.GetByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil || !s.now().Before(session.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// List возвращает активные сессии пользователя, помечая текущую.
+func (s *SessionService) List(userID, currentSessionID uint) ([]models.Session, error) {
+	sessions, err := s.sessions.ListActive(userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// Revoke завершает одну сессию пользователя (logout или удаление из списка устройств).
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	revoked, err := s.sessions.Revoke(userID, sessionID, s.now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAll завершает все сессии пользователя (logout everywhere).
+func (s *SessionService) RevokeAll(userID uint) error {
+	return s.sessions.RevokeAllByUser(userID, s.now())
+}
+
+func (s *SessionService) issue(user *models.User, sessionID uint, refreshToken string) (*TokenPair, error) {
+	access, err := GenerateAccessToken(user.ID, user.Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(AccessTokenTTL().Seconds()),
+		SessionID:    sessionID,
+	}, nil
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
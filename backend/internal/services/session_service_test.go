@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionTestService(t *testing.T) (*SessionService, *models.User) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "testsecret")
+	db := setupTestDB(t)
+	user := &models.User{Email: "session@example.com", Username: "session", Password: "hash", Role: "user"}
+	require.NoError(t, db.Create(user).Error)
+	return NewSessionService(storage.NewSessionStorage(db), storage.NewUserStorage(db)), user
+}
+
+func TestSessionService_RefreshRotatesToken(t *testing.T) {
+	service, user := newSessionTestService(t)
+
+	first, err := service.Start(user, "test-agent", "127.0.0.1")
+	require.NoError(t, err)
+	require.NotEmpty(t, first.AccessToken)
+	require.NotEmpty(t, first.RefreshToken)
+
+	second, err := service.Refresh(first.RefreshToken)
+	require.NoError(t, err)
+	require.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	require.Equal(t, first.SessionID, second.SessionID)
+	require.NoError(t, service.ValidateSession(user.ID, second.SessionID))
+}
+
+func TestSessionService_ReuseRevokesChain(t *testing.T) {
+	service, user := newSessionTestService(t)
+
+	first, err := service.Start(user, "", "")
+	require.NoError(t, err)
+	second, err := service.Refresh(first.RefreshToken)
+	require.NoError(t, err)
+
+	// Повторное использование старого токена отзывает всю сессию.
+	_, err = service.Refresh(first.RefreshToken)
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	_, err = service.Refresh(second.RefreshToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+	require.ErrorIs(t, service.ValidateSession(user.ID, first.SessionID), ErrSessionRevoked)
+}
+
+func TestSessionService_RevokeAllEndsEverySession(t *testing.T) {
+	service, user := newSessionTestService(t)
+
+	a, err := service.Start(user, "laptop", "")
+	require.NoError(t, err)
+	_, err = service.Start(user, "phone", "")
+	require.NoError(t, err)
+
+	sessions, err := service.List(user.ID, a.SessionID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	require.NoError(t, service.RevokeAll(user.ID))
+
+	sessions, err = service.List(user.ID, a.SessionID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+	require.ErrorIs(t, service.ValidateSession(user.ID, a.SessionID), ErrSessionRevoked)
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Диапазоны дат в отчётах: по умолчанию последние 30 дней, не длиннее года.
+const (
+	rangeDefaultDays = 30
+	rangeMaxDays     = 366
+	dayLayout        = "2006-01-02"
+)
+
+// Скорость считается по последним полным неделям: по умолчанию 8, не больше года.
+const (
+	velocityDefaultWeeks = 8
+	velocityMaxWeeks     = 52
+)
+
+var ErrStatsRange = errors.New("invalid stats range")
+
+// StatsService собирает сводку по задачам для дашборда.
+type StatsService struct {
+	stats    *storage.StatsStorage
+	projects *storage.ProjectStorage
+	users    *storage.UserStorage
+}
+
+func NewStatsService(st *storage.StatsStorage, p *storage.ProjectStorage, u *storage.UserStorage) *StatsService {
+	return &StatsService{stats: st, projects: p, users: u}
+}
+
+// Overview — сводка по всем задачам (как GET /api/tasks) и завершённость проектов
+// пользователя. from/to — YYYY-MM-DD, по умолчанию последние 30 дней. Дни и
+// «на этой неделе» считаются в часовом поясе пользователя.
+func (s *StatsService) Overview(userID uint, from, to string) (*models.TaskStats, error) {
+	now, err := s.nowFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.collect(nil, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Projects, err = s.stats.ProjectCompletion(userID); err != nil {
+		return nil, err
+	}
+	for i := range stats.Projects {
+		stats.Projects[i].Percent = percent(stats.Projects[i].Completed, stats.Projects[i].Total)
+	}
+	return stats, nil
+}
+
+// Project — сводка по задачам одного доступного пользователю проекта.
+func (s *StatsService) Project(userID, projectID uint, from, to string) (*models.TaskStats, error) {
+	if _, err := s.projects.GetAccessible(userID, projectID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	now, err := s.nowFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.collect(&projectID, from, to, now)
+}
+
+// nowFor — текущее время в поясе пользователя.
+func (s *StatsService) nowFor(userID uint) (time.Time, error) {
+	loc, err := userLocation(s.users, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
+
+func (s *StatsService) collect(projectID *uint, rawFrom, rawTo string, now time.Time) (*models.TaskStats, error) {
+	from, to, err := dayRange(rawFrom, rawTo, now, ErrStatsRange)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// Неделя — с понедельника по воскресенье; «на этой неделе» — от сегодня до её конца.
+	weekEnd := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+
+	totals, err := s.stats.Totals(projectID, now, today, weekEnd)
+	if err != nil {
+		return nil, err
+	}
+	stats := &models.TaskStats{
+		Total:       totals.Total,
+		Completed:   totals.Completed,
+		Overdue:     totals.Overdue,
+		DueThisWeek: totals.DueThisWeek,
+		From:        from.Format(dayLayout),
+		To:          to.Format(dayLayout),
+	}
+	if stats.ByStatus, err = s.stats.CountBy("status", projectID); err != nil {
+		return nil, err
+	}
+	if stats.ByPriority, err = s.stats.CountBy("priority", projectID); err != nil {
+		return nil, err
+	}
+	if stats.ByStage, err = s.stats.CountBy("stage", projectID); err != nil {
+		return nil, err
+	}
+
+	end := to.AddDate(0, 0, 1)
+	created, err := s.stats.DailyCounts("created_at", projectID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.stats.DailyCounts("completed_at", projectID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	// Дни без активности тоже попадают в ряд — графику не нужно заполнять пропуски.
+	for day := from; day.Before(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(dayLayout)
+		stats.Daily = append(stats.Daily, models.DayStats{Date: key, Created: created[key], Completed: completed[key]})
+	}
+	return stats, nil
+}
+
+// Velocity — скорость проекта по неделям и прогноз. weeks — число полных недель
+// для среднего (0 — по умолчанию); текущая неделя показывается отдельно.
+func (s *StatsService) Velocity(userID, projectID uint, weeks int) (*models.ProjectVelocity, error) {
+	if _, err := s.projects.GetAccessible(userID, projectID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	now, err := s.nowFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.velocity(projectID, weeks, now)
+}
+
+func (s *StatsService) velocity(projectID uint, weeks int, now time.Time) (*models.ProjectVelocity, error) {
+	if weeks == 0 {
+		weeks = velocityDefaultWeeks
+	}
+	if weeks < 1 || weeks > velocityMaxWeeks {
+		return nil, fmt.Errorf("%w: weeks must be between 1 and %d", ErrStatsRange, velocityMaxWeeks)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	currentWeek := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	from := currentWeek.AddDate(0, 0, -7*weeks)
+
+	days, err := s.stats.CompletedEstimates(projectID, from, today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	velocity := &models.ProjectVelocity{ProjectID: projectID}
+	for i := 0; i <= weeks; i++ {
+		start := from.AddDate(0, 0, 7*i)
+		week := models.WeekVelocity{WeekStart: start.Format(dayLayout), Partial: i == weeks}
+		for d := 0; d < 7; d++ {
+			day := days[start.AddDate(0, 0, d).Format(dayLayout)]
+			week.Points += day.Points
+			week.Hours += day.Hours
+			week.Tasks += day.Tasks
+		}
+		week.Points, week.Hours = round2(week.Points), round2(week.Hours)
+		if !week.Partial {
+			velocity.AveragePoints += week.Points
+			velocity.AverageHours += week.Hours
+		}
+		velocity.Weeks = append(velocity.Weeks, week)
+	}
+	velocity.AveragePoints = round2(velocity.AveragePoints / float64(weeks))
+	velocity.AverageHours = round2(velocity.AverageHours / float64(weeks))
+
+	remaining, err := s.stats.Remaining(projectID)
+	if err != nil {
+		return nil, err
+	}
+	forecast := models.VelocityForecast{
+		RemainingTasks:   remaining.Tasks,
+		UnestimatedTasks: remaining.Unestimated,
+		RemainingPoints:  round2(remaining.Points),
+		RemainingHours:   round2(remaining.Hours),
+	}
+	// Прогноз идёт по story points: неоценённые задачи в него не входят, их число
+	// отдаётся отдельно, чтобы было видно, насколько прогноз неполный.
+	switch {
+	case forecast.RemainingPoints == 0:
+		zero, date := 0.0, today.Format(dayLayout)
+		forecast.WeeksLeft, forecast.CompletionDate = &zero, &date
+	case velocity.AveragePoints > 0:
+		left := forecast.RemainingPoints / velocity.AveragePoints
+		date := today.AddDate(0, 0, int(math.Ceil(left*7))).Format(dayLayout)
+		left = math.Round(left*10) / 10
+		forecast.WeeksLeft, forecast.CompletionDate = &left, &date
+	}
+	velocity.Forecast = forecast
+	return velocity, nil
+}
+
+// dayRange разбирает границы диапазона (обе включительно, полночь в поясе now);
+// ошибки оборачивают invalid.
+func dayRange(rawFrom, rawTo string, now time.Time, invalid error) (time.Time, time.Time, error) {
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if rawTo != "" {
+		parsed, err := time.ParseInLocation(dayLayout, rawTo, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD", invalid)
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -(rangeDefaultDays - 1))
+	if rawFrom != "" {
+		parsed, err := time.ParseInLocation(dayLayout, rawFrom, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD", invalid)
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from is after to", invalid)
+	}
+	if to.Sub(from) >= rangeMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: range is longer than %d days", invalid, rangeMaxDays)
+	}
+	return from, to, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
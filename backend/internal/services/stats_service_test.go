@@ -0,0 +1,146 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsService_AggregatesInDatabase(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	stranger := models.User{ID: 2, Email: "stranger@example.com", Username: "stranger", Password: "x"}
+	require.NoError(t, db.Create([]*models.User{&owner, &stranger}).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, storage.NewUserStorage(db))
+	service := NewStatsService(storage.NewStatsStorage(db), projectStorage, storage.NewUserStorage(db))
+
+	site, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site"})
+	require.NoError(t, err)
+	_, err = projects.Create(owner.ID, &models.ProjectInput{Title: "Empty"})
+	require.NoError(t, err)
+
+	// Среда, 12 марта 2025: неделя заканчивается в воскресенье 16-го.
+	now := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	at := func(day, hour int) *time.Time {
+		v := time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC)
+		return &v
+	}
+	tasks := []models.Task{
+		{Title: "Overdue", Status: models.StatusTodo, Priority: "high", Stage: "todo", EndAt: at(11, 9), ProjectID: &site.ID, CreatedAt: *at(10, 8)},
+		{Title: "Due friday", Status: models.StatusInProgress, Priority: "medium", Stage: "dev", EndAt: at(14, 18), ProjectID: &site.ID, CreatedAt: *at(10, 9)},
+		{Title: "Next week", Status: models.StatusTodo, Priority: "low", Stage: "todo", EndAt: at(17, 9), CreatedAt: *at(11, 9)},
+		{Title: "Done", Status: models.StatusCompleted, Priority: "high", Stage: "done", EndAt: at(11, 9), CompletedAt: at(11, 23), ProjectID: &site.ID, CreatedAt: *at(11, 10)},
+		{Title: "Old", Status: models.StatusCancelled, Priority: "low", Stage: "todo", CreatedAt: *at(1, 10)},
+	}
+	require.NoError(t, db.Create(&tasks).Error)
+
+	stats, err := service.collect(nil, "2025-03-10", "2025-03-12", now)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, stats.Total)
+	require.EqualValues(t, 1, stats.Completed)
+	require.EqualValues(t, 1, stats.Overdue)
+	require.EqualValues(t, 1, stats.DueThisWeek)
+	require.Equal(t, map[string]int64{"todo": 2, "in_progress": 1, "completed": 1, "cancelled": 1}, stats.ByStatus)
+	require.Equal(t, map[string]int64{"high": 2, "medium": 1, "low": 2}, stats.ByPriority)
+	require.EqualValues(t, 3, stats.ByStage["todo"])
+	require.Equal(t, []models.DayStats{
+		{Date: "2025-03-10", Created: 2},
+		{Date: "2025-03-11", Created: 2, Completed: 1},
+		{Date: "2025-03-12"},
+	}, stats.Daily)
+
+	projectStats, err := service.collect(&site.ID, "", "", now)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, projectStats.Total)
+	require.Len(t, projectStats.Daily, 30)
+	require.Equal(t, "2025-03-12", projectStats.To)
+
+	overview, err := service.Overview(owner.ID, "", "")
+	require.NoError(t, err)
+	require.Len(t, overview.Projects, 2)
+	require.Equal(t, "Empty", overview.Projects[0].Title)
+	require.EqualValues(t, 0, overview.Projects[0].Total)
+	require.Equal(t, models.ProjectCompletion{ProjectID: site.ID, Title: "Site", Total: 3, Completed: 1, Percent: 33.3}, overview.Projects[1])
+
+	_, err = service.Project(stranger.ID, site.ID, "", "")
+	require.ErrorIs(t, err, ErrProjectNotFound)
+	_, err = service.Overview(owner.ID, "2025-03-12", "2025-03-01")
+	require.ErrorIs(t, err, ErrStatsRange)
+	_, err = service.Overview(owner.ID, "2023-01-01", "2025-01-01")
+	require.ErrorIs(t, err, ErrStatsRange)
+
+	// Завершение задачи проставляет completed_at, возврат в работу — сбрасывает.
+	task := tasks[1]
+	task.ApplyStatusTransition(models.StatusCompleted)
+	require.NotNil(t, task.CompletedAt)
+	task.ApplyStatusTransition(models.StatusTodo)
+	require.Nil(t, task.CompletedAt)
+}
+
+func TestStatsService_VelocityAndForecast(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	require.NoError(t, db.Create(&owner).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, storage.NewUserStorage(db))
+	service := NewStatsService(storage.NewStatsStorage(db), projectStorage, storage.NewUserStorage(db))
+
+	site, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site"})
+	require.NoError(t, err)
+
+	// Среда, 12 марта 2025; текущая неделя началась 10-го.
+	now := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	at := func(day int) *time.Time {
+		v := time.Date(2025, 3, 1, 15, 0, 0, 0, time.UTC).AddDate(0, 0, day-1)
+		return &v
+	}
+	points := func(v float64) *float64 { return &v }
+	tasks := []models.Task{
+		{Title: "W1", Status: models.StatusCompleted, StoryPoints: points(3), EstimateHours: points(4), CompletedAt: at(3)},
+		{Title: "W1b", Status: models.StatusCompleted, StoryPoints: points(2), CompletedAt: at(9)},
+		{Title: "Current", Status: models.StatusCompleted, StoryPoints: points(8), CompletedAt: at(11)},
+		{Title: "Too old", Status: models.StatusCompleted, StoryPoints: points(13), CompletedAt: at(-7)},
+		{Title: "Open", Status: models.StatusTodo, StoryPoints: points(5)},
+		{Title: "Open 2", Status: models.StatusInProgress, StoryPoints: points(2.5), EstimateHours: points(6)},
+		{Title: "Unestimated", Status: models.StatusTodo},
+		{Title: "Dropped", Status: models.StatusCancelled, StoryPoints: points(40)},
+	}
+	for i := range tasks {
+		tasks[i].ProjectID = &site.ID
+	}
+	require.NoError(t, db.Create(&tasks).Error)
+
+	velocity, err := service.velocity(site.ID, 2, now)
+	require.NoError(t, err)
+	require.Equal(t, []models.WeekVelocity{
+		{WeekStart: "2025-02-24"},
+		{WeekStart: "2025-03-03", Points: 5, Hours: 4, Tasks: 2},
+		{WeekStart: "2025-03-10", Points: 8, Tasks: 1, Partial: true},
+	}, velocity.Weeks)
+	require.Equal(t, 2.5, velocity.AveragePoints)
+	require.EqualValues(t, 3, velocity.Forecast.RemainingTasks)
+	require.EqualValues(t, 1, velocity.Forecast.UnestimatedTasks)
+	require.Equal(t, 7.5, velocity.Forecast.RemainingPoints)
+	require.Equal(t, 6.0, velocity.Forecast.RemainingHours)
+	require.Equal(t, 3.0, *velocity.Forecast.WeeksLeft)
+	require.Equal(t, "2025-04-02", *velocity.Forecast.CompletionDate)
+
+	// Без завершённых задач за окно прогноза нет.
+	velocity, err = service.velocity(site.ID, 1, now.AddDate(0, 0, 21))
+	require.NoError(t, err)
+	require.Zero(t, velocity.AveragePoints)
+	require.Nil(t, velocity.Forecast.CompletionDate)
+
+	_, err = service.velocity(site.ID, 53, now)
+	require.ErrorIs(t, err, ErrStatsRange)
+	_, err = service.Velocity(2, site.ID, 0)
+	require.ErrorIs(t, err, ErrProjectNotFound)
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Формат архива выгрузки. Версию нужно поднимать при несовместимых изменениях файлов.
+const (
+	TakeoutFormat  = "taskmanager-takeout"
+	TakeoutVersion = 1
+
+	// Ограничение на распакованный размер одного файла при импорте — защита от zip-бомб.
+	takeoutMaxFileBytes = 64 << 20
+)
+
+const (
+	takeoutManifestFile    = "manifest.json"
+	takeoutReadmeFile      = "README.md"
+	takeoutProfileFile     = "profile.json"
+	takeoutSettingsFile    = "settings.json"
+	takeoutProjectsFile    = "projects.json"
+	takeoutTasksFile       = "tasks.json"
+	takeoutMembershipsFile = "memberships.json"
+)
+
+var ErrInvalidTakeout = errors.New("invalid takeout archive")
+
+// TakeoutManifest — машиночитаемое описание архива: формат, версия и число записей в файлах.
+type TakeoutManifest struct {
+	Format      string         `json:"format"`
+	Version     int            `json:"version"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Files       map[string]int `json:"files"`
+}
+
+type TakeoutProfile struct {
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	FullName      string    `json:"full_name"`
+	AvatarURL     string    `json:"avatar_url,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type TakeoutSettings struct {
+	Language string `json:"language"`
+	Theme    string `json:"theme"`
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// TakeoutProject — проект в архиве. ID — идентификатор в исходной системе, на него ссылаются задачи.
+type TakeoutProject struct {
+	ID          uint            `json:"id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Status      string          `json:"status"`
+	Priority    string          `json:"priority"`
+	Deadline    *time.Time      `json:"deadline,omitempty"`
+	ProgressPct int             `json:"progress_pct"`
+	TasksLimit  int             `json:"tasks_limit"`
+	Tags        json.RawMessage `json:"tags,omitempty"`
+	ArchivedAt  *time.Time      `json:"archived_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type TakeoutTask struct {
+	ID             uint       `json:"id"`
+	ProjectID      uint       `json:"project_id"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Status         string     `json:"status"`
+	PreviousStatus string     `json:"previous_status,omitempty"`
+	Priority       string     `json:"priority"`
+	Stage          string     `json:"stage"`
+	StartAt        *time.Time `json:"start_at,omitempty"`
+	EndAt          *time.Time `json:"end_at,omitempty"`
+	AllDay         bool       `json:"all_day"`
+	StoryPoints    *float64   `json:"story_points,omitempty"`
+	EstimateHours  *float64   `json:"estimate_hours,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// TakeoutMemberships — совместный доступ: чужие проекты пользователя и участники его проектов.
+type TakeoutMemberships struct {
+	MemberOf   []storage.Membership   `json:"member_of"`
+	SharedWith []storage.SharedMember `json:"shared_with"`
+}
+
+// TakeoutImportResult — что удалось перенести из архива.
+type TakeoutImportResult struct {
+	Projects           int  `json:"projects"`
+	Tasks              int  `json:"tasks"`
+	SettingsApplied    bool `json:"settings_applied"`
+	MembershipsSkipped int  `json:"memberships_skipped"`
+}
+
+const takeoutReadme = `# Выгрузка данных TaskManager
+
+Архив содержит данные вашей учётной записи в формате JSON (кодировка UTF-8, даты в RFC 3339).
+
+| Файл | Содержимое |
+|------|------------|
+| manifest.json | формат (` + "`" + TakeoutFormat + "`" + `), версия и число записей в каждом файле |
+| profile.json | профиль: email, имя пользователя, полное имя, аватар |
+| settings.json | язык и тема интерфейса |
+| projects.json | ваши проекты, включая архивные (archived_at) |
+| tasks.json | задачи ваших проектов; project_id ссылается на id из projects.json, deleted_at заполнен у скрытых задач |
+| memberships.json | member_of — чужие проекты, к которым у вас есть доступ; shared_with — кому открыты ваши проекты |
+
+Архив можно загрузить в другой экземпляр TaskManager через POST /api/user/import:
+проекты и задачи будут созданы заново, настройки и имя применены к учётной записи.
+Совместный доступ не переносится — пользователи другого экземпляра могут не совпадать.
+`
+
+// buildTakeout собирает ZIP-архив с данными пользователя.
+func (s *DataExportService) buildTakeout(userID uint) ([]byte, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.exports.OwnedProjects(userID)
+	if err != nil {
+		return nil, err
+	}
+	projectIDs := make([]uint, len(projects))
+	takeoutProjects := make([]TakeoutProject, len(projects))
+	for i, p := range projects {
+		projectIDs[i] = p.ID
+		takeoutProjects[i] = TakeoutProject{
+			ID:          p.ID,
+			Title:       p.Title,
+			Description: p.Description,
+			Status:      p.Status,
+			Priority:    p.Priority,
+			Deadline:    p.Deadline,
+			ProgressPct: p.ProgressPct,
+			TasksLimit:  p.TasksLimit,
+			Tags:        json.RawMessage(p.Tags),
+			ArchivedAt:  p.ArchivedAt,
+			CreatedAt:   p.CreatedAt,
+		}
+	}
+	tasks, err := s.exports.TasksByProjects(projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	takeoutTasks := make([]TakeoutTask, len(tasks))
+	for i, t := range tasks {
+		item := TakeoutTask{
+			ID:             t.ID,
+			ProjectID:      *t.ProjectID,
+			Title:          t.Title,
+			Description:    t.Description,
+			Status:         t.Status,
+			PreviousStatus: t.PreviousStatus,
+			Priority:       t.Priority,
+			Stage:          t.Stage,
+			StartAt:        t.StartAt,
+			EndAt:          t.EndAt,
+			AllDay:         t.AllDay,
+			StoryPoints:    t.StoryPoints,
+			EstimateHours:  t.EstimateHours,
+			CreatedAt:      t.CreatedAt,
+		}
+		if t.DeletedAt.Valid {
+			deletedAt := t.DeletedAt.Time
+			item.DeletedAt = &deletedAt
+		}
+		takeoutTasks[i] = item
+	}
+	memberOf, err := s.exports.Memberships(userID)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := s.exports.SharedMembers(projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	if memberOf == nil {
+		memberOf = []storage.Membership{}
+	}
+	if shared == nil {
+		shared = []storage.SharedMember{}
+	}
+
+	files := []struct {
+		name  string
+		count int
+		data  any
+	}{
+		{takeoutProfileFile, 1, TakeoutProfile{
+			Email:         user.Email,
+			Username:      user.Username,
+			FullName:      user.FullName,
+			AvatarURL:     user.AvatarURL,
+			EmailVerified: user.IsEmailVerified(),
+			CreatedAt:     user.CreatedAt,
+		}},
+		{takeoutSettingsFile, 1, TakeoutSettings{Language: user.Language, Theme: user.Theme, TimeZone: user.TimeZone}},
+		{takeoutProjectsFile, len(takeoutProjects), takeoutProjects},
+		{takeoutTasksFile, len(takeoutTasks), takeoutTasks},
+		{takeoutMembershipsFile, len(memberOf) + len(shared), TakeoutMemberships{MemberOf: memberOf, SharedWith: shared}},
+	}
+
+	manifest := TakeoutManifest{
+		Format:      TakeoutFormat,
+		Version:     TakeoutVersion,
+		GeneratedAt: s.now().UTC(),
+		Files:       make(map[string]int, len(files)),
+	}
+	for _, f := range files {
+		manifest.Files[f.name] = f.count
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := writeZipFile(zw, takeoutReadmeFile, []byte(takeoutReadme)); err != nil {
+		return nil, err
+	}
+	if err := writeZipJSON(zw, takeoutManifestFile, manifest); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := writeZipJSON(zw, f.name, f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Import переносит проекты, задачи, настройки и имя из архива выгрузки в аккаунт
+// пользователя. Всё выполняется в одной транзакции и учитывает лимит проектов.
+func (s *DataExportService) Import(userID uint, archive []byte) (*TakeoutImportResult, error) {
+	files, err := readTakeout(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest TakeoutManifest
+	if err := decodeTakeoutFile(files, takeoutManifestFile, &manifest, true); err != nil {
+		return nil, err
+	}
+	if manifest.Format != TakeoutFormat {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidTakeout, manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > TakeoutVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidTakeout, manifest.Version)
+	}
+	var (
+		profile     TakeoutProfile
+		settings    TakeoutSettings
+		projects    []TakeoutProject
+		tasks       []TakeoutTask
+		memberships TakeoutMemberships
+	)
+	for _, f := range []struct {
+		name string
+		dst  any
+	}{
+		{takeoutProfileFile, &profile},
+		{takeoutSettingsFile, &settings},
+		{takeoutProjectsFile, &projects},
+		{takeoutTasksFile, &tasks},
+		{takeoutMembershipsFile, &memberships},
+	} {
+		if err := decodeTakeoutFile(files, f.name, f.dst, false); err != nil {
+			return nil, err
+		}
+	}
+
+	newProjects, err := takeoutProjectsToModels(userID, projects)
+	if err != nil {
+		return nil, err
+	}
+	newTasks, err := takeoutTasksToModels(tasks, newProjects)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TakeoutImportResult{
+		Projects:           len(projects),
+		Tasks:              len(tasks),
+		MembershipsSkipped: len(memberships.MemberOf) + len(memberships.SharedWith),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if len(newProjects) > 0 {
+			if err := checkTakeoutQuotas(newQuotaCheckerTx(tx), &user, newProjects, newTasks); err != nil {
+				return err
+			}
+		}
+
+		// Проекты создаём по одному, чтобы получить новые ID для задач.
+		idMap := make(map[uint]uint, len(projects))
+		for i := range projects {
+			project := newProjects[projects[i].ID]
+			if err := tx.Create(project).Error; err != nil {
+				return err
+			}
+			idMap[projects[i].ID] = project.ID
+		}
+		for i := range newTasks {
+			projectID := idMap[*newTasks[i].ProjectID]
+			newTasks[i].ProjectID = &projectID
+		}
+		if len(newTasks) > 0 {
+			if err := tx.CreateInBatches(newTasks, 200).Error; err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]any{}
+		if _, ok := allowedLanguages[settings.Language]; ok {
+			updates["language"] = settings.Language
+		}
+		if _, ok := allowedThemes[settings.Theme]; ok {
+			updates["theme"] = settings.Theme
+		}
+		if zone, ok := NormalizeTimeZone(settings.TimeZone); ok {
+			updates["time_zone"] = zone
+		}
+		if name := strings.TrimSpace(profile.FullName); name != "" && user.FullName == "" {
+			updates["full_name"] = truncate(name, 255)
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		result.SettingsApplied = true
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func takeoutProjectsToModels(ownerID uint, projects []TakeoutProject) (map[uint]*models.Project, error) {
+	out := make(map[uint]*models.Project, len(projects))
+	for _, p := range projects {
+		if _, dup := out[p.ID]; dup {
+			return nil, fmt.Errorf("%w: duplicate project id %d", ErrInvalidTakeout, p.ID)
+		}
+		title := strings.TrimSpace(p.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%w: project %d has no title", ErrInvalidTakeout, p.ID)
+		}
+		status, err := models.NormalizeProjectStatus(p.Status)
+		if err != nil {
+			return nil, fmt.Errorf("%w: project %d: %v", ErrInvalidTakeout, p.ID, err)
+		}
+		priority, err := models.NormalizeProjectPriority(p.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("%w: project %d: %v", ErrInvalidTakeout, p.ID, err)
+		}
+		limit := p.TasksLimit
+		if limit <= 0 {
+			limit = models.DefaultProjectTasksLimit
+		}
+		project := &models.Project{
+			OwnerID:     ownerID,
+			Title:       truncate(title, 255),
+			Description: p.Description,
+			Status:      status,
+			Priority:    priority,
+			Deadline:    p.Deadline,
+			ProgressPct: p.ProgressPct,
+			TasksLimit:  limit,
+			ArchivedAt:  p.ArchivedAt,
+			CreatedAt:   p.CreatedAt,
+		}
+		if len(p.Tags) > 0 && string(p.Tags) != "null" {
+			project.Tags = []byte(p.Tags)
+		}
+		out[p.ID] = project
+	}
+	return out, nil
+}
+
+// takeoutTasksToModels проверяет задачи; ProjectID пока содержит ID проекта из архива.
+func takeoutTasksToModels(tasks []TakeoutTask, projects map[uint]*models.Project) ([]models.Task, error) {
+	out := make([]models.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if _, ok := projects[t.ProjectID]; !ok {
+			return nil, fmt.Errorf("%w: task %d references unknown project %d", ErrInvalidTakeout, t.ID, t.ProjectID)
+		}
+		title := strings.TrimSpace(t.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%w: task %d has no title", ErrInvalidTakeout, t.ID)
+		}
+		status, err := models.NormalizeTaskStatus(t.Status)
+		if err != nil {
+			return nil, fmt.Errorf("%w: task %d: %v", ErrInvalidTakeout, t.ID, err)
+		}
+		priority, err := models.NormalizePriority(t.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("%w: task %d: %v", ErrInvalidTakeout, t.ID, err)
+		}
+		stage, err := models.NormalizeStage(t.Stage)
+		if err != nil {
+			return nil, fmt.Errorf("%w: task %d: %v", ErrInvalidTakeout, t.ID, err)
+		}
+		projectRef := t.ProjectID
+		task := models.Task{
+			Title:          truncate(title, 255),
+			Description:    t.Description,
+			Status:         status,
+			PreviousStatus: t.PreviousStatus,
+			Priority:       priority,
+			Stage:          stage,
+			StartAt:        t.StartAt,
+			EndAt:          t.EndAt,
+			AllDay:         t.AllDay,
+			StoryPoints:    t.StoryPoints,
+			EstimateHours:  t.EstimateHours,
+			ProjectID:      &projectRef,
+			CreatedAt:      t.CreatedAt,
+		}
+		if err := task.NormalizeEstimates(); err != nil {
+			return nil, fmt.Errorf("%w: task %d: %v", ErrInvalidTakeout, t.ID, err)
+		}
+		if t.DeletedAt != nil {
+			task.DeletedAt = gorm.DeletedAt{Time: *t.DeletedAt, Valid: true}
+		}
+		out = append(out, task)
+	}
+	return out, nil
+}
+
+func readTakeout(archive []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTakeout, err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".json") || strings.Contains(f.Name, "/") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTakeout, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, takeoutMaxFileBytes+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTakeout, err)
+		}
+		if len(data) > takeoutMaxFileBytes {
+			return nil, fmt.Errorf("%w: %s is too large", ErrInvalidTakeout, f.Name)
+		}
+		files[f.Name] = data
+	}
+	return files, nil
+}
+
+func decodeTakeoutFile(files map[string][]byte, name string, dst any, required bool) error {
+	data, ok := files[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("%w: %s is missing", ErrInvalidTakeout, name)
+		}
+		return nil
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidTakeout, name, err)
+	}
+	return nil
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeZipFile(zw, name, data)
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// checkTakeoutQuotas — поместятся ли импортируемые проекты и их задачи в квоты пользователя.
+func checkTakeoutQuotas(q quotaChecker, user *models.User, projects map[uint]*models.Project, tasks []models.Task) error {
+	if err := q.checkProjects(user, int64(len(projects))); err != nil {
+		return err
+	}
+	perProject := make(map[uint]int64, len(projects))
+	for i := range tasks {
+		perProject[*tasks[i].ProjectID]++
+	}
+	for oldID, project := range projects {
+		if err := checkQuota(QuotaProjectTasks, 0, perProject[oldID], projectTasksLimit(user, project.TasksLimit)); err != nil {
+			return err
+		}
+	}
+	return q.checkOwnerTasks(user, int64(len(tasks)))
+}
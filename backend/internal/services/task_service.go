@@ -7,16 +7,19 @@ import (
 
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/spozitivom/taskmanager/internal/taskquery"
 )
 
 // TaskService реализует бизнес-логику для задач.
 type TaskService struct {
 	storage *storage.TaskStorage
+	// quotas — лимиты задач проекта и его владельца при создании и переносе задачи.
+	quotas quotaChecker
 }
 
 // NewTaskService создаёт новый экземпляр TaskService.
-func NewTaskService(storage *storage.TaskStorage) *TaskService {
-	return &TaskService{storage: storage}
+func NewTaskService(t *storage.TaskStorage, p *storage.ProjectStorage, u *storage.UserStorage) *TaskService {
+	return &TaskService{storage: t, quotas: quotaChecker{projects: p, tasks: t, users: u}}
 }
 
 // GetTasks возвращает список всех задач, с сортировкой по дате создания.
@@ -29,14 +32,44 @@ func (s *TaskService) GetFilteredTasks(sortOrder, status, priority, stage string
 	return s.storage.GetFiltered(sortOrder, status, priority, stage, projectID)
 }
 
+// TaskFilter — условия выборки задач (см. storage.TaskFilter).
+type TaskFilter = storage.TaskFilter
+
+const (
+	LabelMatchAny = storage.LabelMatchAny
+	LabelMatchAll = storage.LabelMatchAll
+)
+
+// CompileQuery разбирает строку языка запросов (см. пакет taskquery) в условие выборки.
+// Ошибка синтаксиса — *taskquery.SyntaxError с номером колонки.
+func (s *TaskService) CompileQuery(query string, now time.Time) (*taskquery.Condition, error) {
+	node, err := taskquery.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return taskquery.Compile(node, now), nil
+}
+
+// ListTasks — задачи по фильтру, включая фильтр по меткам.
+func (s *TaskService) ListTasks(sortOrder string, filter TaskFilter) ([]models.Task, error) {
+	return s.storage.List(sortOrder, filter)
+}
+
 // GetTaskByID ищет и возвращает задачу по её ID.
 func (s *TaskService) GetTaskByID(id uint) (*models.Task, error) {
 	return s.storage.GetByID(id)
 }
 
+// Location — часовой пояс пользователя: в нём считаются границы дней задач на весь
+// день и относительные даты запросов.
+func (s *TaskService) Location(userID uint) (*time.Location, error) {
+	return userLocation(s.quotas.users, userID)
+}
+
 // CreateTask сохраняет новую задачу в базе данных.
 // Здесь же можно мягко нормализовать вход и применить дефолты (на случай, если фронт их не прислал).
-func (s *TaskService) CreateTask(task *models.Task) error {
+// loc — пояс автора: задача на весь день занимает его календарный день; nil — UTC.
+func (s *TaskService) CreateTask(task *models.Task, loc *time.Location) error {
 	task.Title = strings.TrimSpace(task.Title)
 	if task.Title == "" {
 		return errors.New("title is required")
@@ -46,6 +79,12 @@ func (s *TaskService) CreateTask(task *models.Task) error {
 		return err
 	}
 	task.Status = status
+	if status != models.StatusCompleted {
+		task.CompletedAt = nil
+	} else if task.CompletedAt == nil {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
 	priority, err := models.NormalizePriority(task.Priority)
 	if err != nil {
 		return err
@@ -56,16 +95,28 @@ func (s *TaskService) CreateTask(task *models.Task) error {
 		return err
 	}
 	task.Stage = stage
-	if err := normalizeTaskSchedule(task); err != nil {
+	if err := normalizeTaskSchedule(task, loc); err != nil {
+		return err
+	}
+	if err := task.NormalizeEstimates(); err != nil {
 		return err
 	}
 	// Completion статус по умолчанию — активный (todo), additional fields заполняются ниже.
-	return s.storage.Create(task)
+	if task.ProjectID == nil {
+		return s.storage.Create(task)
+	}
+	// Проверка квоты и вставка — одной транзакцией, иначе параллельные запросы превысят лимит.
+	return s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.checkTaskInProject(*task.ProjectID, nil); err != nil {
+			return err
+		}
+		return q.tasks.Create(task)
+	})
 }
 
 // PatchTask частично обновляет существующую задачу по ID.
 // Меняем только те поля, которые действительно пришли (указатели != nil).
-func (s *TaskService) PatchTask(id uint, patch models.TaskPatch) (*models.Task, error) {
+func (s *TaskService) PatchTask(id uint, patch models.TaskPatch, loc *time.Location) (*models.Task, error) {
 	task, err := s.storage.GetByID(id)
 	if err != nil {
 		return nil, err
@@ -77,29 +128,74 @@ func (s *TaskService) PatchTask(id uint, patch models.TaskPatch) (*models.Task,
 		patch.Title = &t
 	}
 
+	previous := task.ProjectID
 	patch.ApplyTo(task)
+	if err := validatePatchedTask(task, patch, loc); err != nil {
+		return nil, err
+	}
+	if task.ProjectID == nil || sameID(previous, task.ProjectID) {
+		if err := s.storage.Update(task); err != nil {
+			return nil, err
+		}
+		return task, nil
+	}
+	err = s.quotas.transaction(func(q quotaChecker) error {
+		if err := q.checkTaskInProject(*task.ProjectID, previous); err != nil {
+			return err
+		}
+		return q.tasks.Update(task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
 
-	// Мини-валидация после применения патча (опционально, но полезно).
+// BulkPatch применяет один патч к нескольким задачам. Сначала проверяются все задачи,
+// и только потом что-то сохраняется. Проект меняется через BulkAssign: там проверка доступа.
+func (s *TaskService) BulkPatch(ids []uint, patch models.TaskPatch, loc *time.Location) error {
+	if patch.ProjectID != nil {
+		return errors.New("project_id cannot be changed in bulk patch, use bulk assign")
+	}
+	if patch.Title != nil {
+		t := strings.TrimSpace(*patch.Title)
+		patch.Title = &t
+	}
+	tasks, err := s.storage.GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		patch.ApplyTo(&tasks[i])
+		if err := validatePatchedTask(&tasks[i], patch, loc); err != nil {
+			return err
+		}
+	}
+	return s.storage.SaveAll(tasks)
+}
+
+// validatePatchedTask — мини-валидация задачи после применения патча. Расписание
+// пересчитывается, только если патч его меняет: сохранённые границы дня не сдвигаются.
+func validatePatchedTask(task *models.Task, patch models.TaskPatch, loc *time.Location) error {
+	var err error
 	if strings.TrimSpace(task.Title) == "" {
-		return nil, errors.New("title cannot be empty")
+		return errors.New("title cannot be empty")
 	}
 	if task.Priority, err = models.NormalizePriority(task.Priority); err != nil {
-		return nil, err
+		return err
 	}
 	if task.Stage, err = models.NormalizeStage(task.Stage); err != nil {
-		return nil, err
+		return err
 	}
 	if task.Status, err = models.NormalizeTaskStatus(task.Status); err != nil {
-		return nil, err
-	}
-	if err := normalizeTaskSchedule(task); err != nil {
-		return nil, err
+		return err
 	}
-
-	if err := s.storage.Update(task); err != nil {
-		return nil, err
+	if patch.StartAt.Present || patch.EndAt.Present || patch.AllDay != nil {
+		if err := normalizeTaskSchedule(task, loc); err != nil {
+			return err
+		}
 	}
-	return task, nil
+	return task.NormalizeEstimates()
 }
 
 // DeleteTask удаляет задачу по ID.
@@ -139,16 +235,12 @@ func (s *TaskService) UnassignFromProject(ids []uint) error {
 	}
 	for i := range tasks {
 		tasks[i].ProjectID = nil
+		tasks[i].MilestoneID = nil
 	}
 	return s.storage.SaveAll(tasks)
 }
 
-func normalizeTaskSchedule(task *models.Task) error {
-	if task.StartAt != nil && task.EndAt != nil {
-		if task.EndAt.Before(*task.StartAt) {
-			return errors.New("end_at must be greater than or equal to start_at")
-		}
-	}
+func normalizeTaskSchedule(task *models.Task, loc *time.Location) error {
 	if task.StartAt == nil && task.EndAt != nil {
 		start := *task.EndAt
 		task.StartAt = &start
@@ -157,23 +249,48 @@ func normalizeTaskSchedule(task *models.Task) error {
 		end := *task.StartAt
 		task.EndAt = &end
 	}
+	// Задача на весь день занимает календарные дни в поясе пользователя: в Москве
+	// это 21:00 UTC предыдущего дня, в Нью-Йорке — 04:00 UTC того же дня.
 	if task.AllDay {
+		if loc == nil {
+			loc = time.UTC
+		}
 		if task.StartAt != nil {
-			start := startOfDayUTC(*task.StartAt)
+			start := calendarDay(*task.StartAt, loc).UTC()
 			task.StartAt = &start
 		}
 		if task.EndAt != nil {
-			end := endOfDayUTC(*task.EndAt)
+			end := calendarDay(*task.EndAt, loc).AddDate(0, 0, 1).Add(-time.Nanosecond).UTC()
 			task.EndAt = &end
 		}
 	}
+	// Порядок проверяем после выравнивания: дата конца "…T00:00:00Z" раньше начала дня
+	// в Нью-Йорке, но это тот же день.
+	if task.StartAt != nil && task.EndAt != nil && task.EndAt.Before(*task.StartAt) {
+		return errors.New("end_at must be greater than or equal to start_at")
+	}
 	return nil
 }
 
+// calendarDay — день задачи на весь день в поясе loc. Полночь в собственном смещении
+// значения — это дата, как её написал клиент ("2025-06-12T00:00:00Z" — 12 июня и в
+// Нью-Йорке, и в Москве); любой другой момент относится к своему дню в поясе loc.
+// Правило смотрит на запись, а не на момент: "2025-06-11T20:00:00-04:00" — тот же
+// момент, что "2025-06-12T00:00:00Z", но для Нью-Йорка это 11 июня. Контракт описан
+// в README: клиенты присылают дату полуночью UTC или местную полночь пользователя.
+func calendarDay(t time.Time, loc *time.Location) time.Time {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+	return startOfDayIn(t, loc)
+}
+
 func startOfDayUTC(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
 
-func endOfDayUTC(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, int(time.Second-time.Nanosecond), time.UTC)
+// startOfDayIn — полночь того дня в поясе loc, на который приходится t.
+func startOfDayIn(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
 }
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 func TestTaskService_CreateTaskNormalizesInput(t *testing.T) {
 	db := setupTestDB(t)
 	taskStorage := storage.NewTaskStorage(db)
-	service := NewTaskService(taskStorage)
+	service := NewTaskService(taskStorage, storage.NewProjectStorage(db), storage.NewUserStorage(db))
 
 	task := &models.Task{
 		Title:     "  Write specs  ",
@@ -21,7 +22,7 @@ func TestTaskService_CreateTaskNormalizesInput(t *testing.T) {
 		Status:    "",
 		ProjectID: nil,
 	}
-	err := service.CreateTask(task)
+	err := service.CreateTask(task, nil)
 	require.NoError(t, err)
 
 	stored, err := taskStorage.GetAllSorted("desc")
@@ -36,7 +37,7 @@ func TestTaskService_CreateTaskNormalizesInput(t *testing.T) {
 func TestTaskService_BulkSetStatus(t *testing.T) {
 	db := setupTestDB(t)
 	taskStorage := storage.NewTaskStorage(db)
-	service := NewTaskService(taskStorage)
+	service := NewTaskService(taskStorage, storage.NewProjectStorage(db), storage.NewUserStorage(db))
 
 	t1 := &models.Task{Title: "API", Priority: models.PriorityMedium, Stage: models.StageDefault, Status: models.StatusTodo}
 	t2 := &models.Task{Title: "UI", Priority: models.PriorityMedium, Stage: models.StageDefault, Status: models.StatusInProgress}
@@ -56,10 +57,54 @@ func TestTaskService_BulkSetStatus(t *testing.T) {
 	require.Equal(t, models.StatusInProgress, updated2.PreviousStatus)
 }
 
+func TestTaskService_EstimatesThroughPatchAndBulkPatch(t *testing.T) {
+	db := setupTestDB(t)
+	taskStorage := storage.NewTaskStorage(db)
+	service := NewTaskService(taskStorage, storage.NewProjectStorage(db), storage.NewUserStorage(db))
+
+	points := 2.333
+	t1 := &models.Task{Title: "API", StoryPoints: &points}
+	t2 := &models.Task{Title: "UI"}
+	require.NoError(t, service.CreateTask(t1, nil))
+	require.NoError(t, service.CreateTask(t2, nil))
+	require.Equal(t, 2.33, *t1.StoryPoints)
+
+	negative := -1.0
+	require.Error(t, service.CreateTask(&models.Task{Title: "Bad", EstimateHours: &negative}, nil))
+
+	var patch models.TaskPatch
+	require.NoError(t, json.Unmarshal([]byte(`{"story_points": 5, "estimate_hours": 12.5}`), &patch))
+	require.NoError(t, service.BulkPatch([]uint{t1.ID, t2.ID}, patch, nil))
+	for _, id := range []uint{t1.ID, t2.ID} {
+		stored, err := taskStorage.GetByID(id)
+		require.NoError(t, err)
+		require.Equal(t, 5.0, *stored.StoryPoints)
+		require.Equal(t, 12.5, *stored.EstimateHours)
+	}
+
+	// null сбрасывает оценку, остальные поля не трогаются.
+	patch = models.TaskPatch{}
+	require.NoError(t, json.Unmarshal([]byte(`{"story_points": null}`), &patch))
+	patched, err := service.PatchTask(t1.ID, patch, nil)
+	require.NoError(t, err)
+	require.Nil(t, patched.StoryPoints)
+	require.Equal(t, 12.5, *patched.EstimateHours)
+
+	// Одна неверная задача — ничего не сохраняется.
+	patch = models.TaskPatch{}
+	require.NoError(t, json.Unmarshal([]byte(`{"story_points": 2000}`), &patch))
+	require.Error(t, service.BulkPatch([]uint{t2.ID}, patch, nil))
+	stored, err := taskStorage.GetByID(t2.ID)
+	require.NoError(t, err)
+	require.Equal(t, 5.0, *stored.StoryPoints)
+	projectID := uint(1)
+	require.Error(t, service.BulkPatch([]uint{t2.ID}, models.TaskPatch{ProjectID: &projectID}, nil))
+}
+
 func TestTaskService_PatchTaskAppliesNormalization(t *testing.T) {
 	db := setupTestDB(t)
 	taskStorage := storage.NewTaskStorage(db)
-	service := NewTaskService(taskStorage)
+	service := NewTaskService(taskStorage, storage.NewProjectStorage(db), storage.NewUserStorage(db))
 
 	original := &models.Task{
 		Title:          "Initial   ",
@@ -93,7 +138,7 @@ func TestTaskService_PatchTaskAppliesNormalization(t *testing.T) {
 			Value:   &end,
 			Present: true,
 		},
-	})
+	}, nil)
 	require.NoError(t, err)
 	require.Equal(t, "Updated spec", patched.Title)
 	require.Equal(t, "New scope", patched.Description)
@@ -120,7 +165,7 @@ func TestTaskService_PatchTaskAppliesNormalization(t *testing.T) {
 func TestTaskService_PatchTaskRejectsEmptyTitle(t *testing.T) {
 	db := setupTestDB(t)
 	taskStorage := storage.NewTaskStorage(db)
-	service := NewTaskService(taskStorage)
+	service := NewTaskService(taskStorage, storage.NewProjectStorage(db), storage.NewUserStorage(db))
 
 	task := &models.Task{
 		Title:    "Valid",
@@ -133,10 +178,148 @@ func TestTaskService_PatchTaskRejectsEmptyTitle(t *testing.T) {
 	empty := "   "
 	_, err := service.PatchTask(task.ID, models.TaskPatch{
 		Title: &empty,
-	})
+	}, nil)
 	require.Error(t, err)
 
 	stored, err := taskStorage.GetByID(task.ID)
 	require.NoError(t, err)
 	require.Equal(t, "Valid", stored.Title)
 }
+
+func TestTaskService_ListTasksByQuery(t *testing.T) {
+	db := setupTestDB(t)
+	taskStorage := storage.NewTaskStorage(db)
+	service := NewTaskService(taskStorage, storage.NewProjectStorage(db), storage.NewUserStorage(db))
+
+	now := time.Now().UTC()
+	yesterday := now.Add(-24 * time.Hour)
+	nextWeek := now.Add(6 * 24 * time.Hour)
+	project := models.Project{Title: "Сайт", OwnerID: 1}
+	require.NoError(t, db.Create(&project).Error)
+	tasks := []*models.Task{
+		{Title: "Overdue", Priority: models.PriorityLow, Status: models.StatusTodo, EndAt: &yesterday},
+		{Title: "Urgent in project", Priority: models.PriorityHigh, Status: models.StatusTodo, ProjectID: &project.ID},
+		{Title: "Cancelled in project", Priority: models.PriorityHigh, Status: models.StatusCancelled, ProjectID: &project.ID},
+		{Title: "Late but done", Priority: models.PriorityLow, Status: models.StatusCompleted, EndAt: &yesterday},
+		{Title: "Soon 100%", Priority: models.PriorityMedium, Status: models.StatusTodo, EndAt: &nextWeek},
+	}
+	for _, task := range tasks {
+		require.NoError(t, taskStorage.Create(task))
+	}
+
+	titles := func(query string) []string {
+		condition, err := service.CompileQuery(query, now)
+		require.NoError(t, err, query)
+		list, err := service.ListTasks("asc", TaskFilter{Query: condition})
+		require.NoError(t, err, query)
+		result := make([]string, len(list))
+		for i := range list {
+			result[i] = list[i].Title
+		}
+		return result
+	}
+
+	require.Equal(t, []string{"Overdue", "Urgent in project"}, titles(`(priority:high OR is:overdue) NOT status:cancelled`))
+	require.Equal(t, []string{"Urgent in project", "Cancelled in project"}, titles(`project:"Сайт"`))
+	require.Equal(t, []string{"Soon 100%"}, titles(`end_at < now+7d end_at > now`))
+	require.Equal(t, []string{"Soon 100%"}, titles(`"100%"`))
+	require.Equal(t, []string{"Urgent in project", "Cancelled in project"}, titles(`NOT end_at < now+30d`))
+	require.Equal(t, []string{"Overdue", "Late but done", "Soon 100%"}, titles(`project:none priority<high`))
+
+	_, err := service.CompileQuery(`status:todo AND (`, now)
+	require.Error(t, err)
+}
+
+func TestTaskService_AllDayInUserTimeZone(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.User{ID: 1, Email: "msk@example.com", Username: "msk", Password: "x"}).Error)
+	userStorage := storage.NewUserStorage(db)
+	users := NewUserService(db, userStorage, storage.NewProjectStorage(db), storage.NewTaskStorage(db))
+
+	_, err := users.UpdateSettings(1, "", "", "Mars/Base")
+	require.Error(t, err)
+	_, err = users.UpdateSettings(1, "", "", "Local")
+	require.Error(t, err)
+	updated, err := users.UpdateSettings(1, "", "", " Europe/Moscow ")
+	require.NoError(t, err)
+	require.Equal(t, "Europe/Moscow", updated.TimeZone)
+
+	service := NewTaskService(storage.NewTaskStorage(db), storage.NewProjectStorage(db), userStorage)
+	loc, err := service.Location(1)
+	require.NoError(t, err)
+	require.Equal(t, "Europe/Moscow", loc.String())
+
+	// 12 июня в Москве — с 21:00 UTC 11 июня до конца 20:59 UTC 12 июня.
+	start := time.Date(2025, 6, 12, 10, 0, 0, 0, loc)
+	task := &models.Task{Title: "Holiday", AllDay: true, StartAt: &start}
+	require.NoError(t, service.CreateTask(task, loc))
+	require.Equal(t, time.Date(2025, 6, 11, 21, 0, 0, 0, time.UTC), task.StartAt.UTC())
+	require.Equal(t, time.Date(2025, 6, 12, 20, 59, 59, 999999999, time.UTC), task.EndAt.UTC())
+
+	// Без пояса (nil) день считается в UTC, как раньше.
+	utcTask := &models.Task{Title: "UTC", AllDay: true, StartAt: &start}
+	require.NoError(t, service.CreateTask(utcTask, nil))
+	require.Equal(t, time.Date(2025, 6, 12, 0, 0, 0, 0, time.UTC), utcTask.StartAt.UTC())
+
+	missing, err := service.Location(99)
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, missing)
+}
+
+func TestTaskService_AllDayWestOfUTC(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.User{ID: 1, Email: "ny@example.com", Username: "ny", Password: "x", TimeZone: "America/New_York"}).Error)
+	taskStorage := storage.NewTaskStorage(db)
+	service := NewTaskService(taskStorage, storage.NewProjectStorage(db), storage.NewUserStorage(db))
+	loc, err := service.Location(1)
+	require.NoError(t, err)
+	june12Start := time.Date(2025, 6, 12, 4, 0, 0, 0, time.UTC)
+	june12End := time.Date(2025, 6, 13, 3, 59, 59, 999999999, time.UTC)
+	parse := func(raw string) *time.Time {
+		v, err := time.Parse(time.RFC3339, raw)
+		require.NoError(t, err)
+		return &v
+	}
+
+	// Клиент присылает дату полуночью UTC, полуночью со своим смещением или местной
+	// полуночью в UTC — во всех случаях это 12 июня в Нью-Йорке, а не 11-е.
+	for _, raw := range []string{"2025-06-12T00:00:00Z", "2025-06-12T00:00:00-04:00", "2025-06-12T04:00:00Z"} {
+		task := &models.Task{Title: raw, AllDay: true, StartAt: parse(raw), EndAt: parse(raw)}
+		require.NoError(t, service.CreateTask(task, loc))
+		require.Equal(t, june12Start, task.StartAt.UTC(), raw)
+		require.Equal(t, june12End, task.EndAt.UTC(), raw)
+	}
+
+	// Полночь в записи клиента — это дата, прочий момент — день в поясе пользователя.
+	// Один и тот же момент в двух записях поэтому даёт разные дни (правило из README).
+	sameInstantZ, sameInstantLocal := parse("2025-06-12T00:00:00Z"), parse("2025-06-11T20:00:00-04:00")
+	require.True(t, sameInstantZ.Equal(*sameInstantLocal))
+	asDate := &models.Task{Title: "as date", AllDay: true, StartAt: sameInstantZ}
+	require.NoError(t, service.CreateTask(asDate, loc))
+	require.Equal(t, june12Start, asDate.StartAt.UTC())
+	asInstant := &models.Task{Title: "as instant", AllDay: true, StartAt: sameInstantLocal}
+	require.NoError(t, service.CreateTask(asInstant, loc))
+	require.Equal(t, june12Start.AddDate(0, 0, -1), asInstant.StartAt.UTC())
+
+	// Повторное сохранение той же даты и правки без дат задачу не сдвигают.
+	task := &models.Task{Title: "Holiday", AllDay: true, StartAt: parse("2025-06-12T00:00:00Z")}
+	require.NoError(t, service.CreateTask(task, loc))
+	title := "Renamed"
+	patched, err := service.PatchTask(task.ID, models.TaskPatch{Title: &title}, loc)
+	require.NoError(t, err)
+	require.Equal(t, june12Start, patched.StartAt.UTC())
+	patched, err = service.PatchTask(task.ID, models.TaskPatch{EndAt: models.OptionalTime{Value: parse("2025-06-12T00:00:00Z"), Present: true}}, loc)
+	require.NoError(t, err)
+	require.Equal(t, june12Start, patched.StartAt.UTC())
+	require.Equal(t, june12End, patched.EndAt.UTC())
+
+	// Задача, сохранённая раньше полуночью UTC, при правке остаётся 12 июня.
+	legacyStart := time.Date(2025, 6, 12, 0, 0, 0, 0, time.UTC)
+	legacyEnd := time.Date(2025, 6, 12, 23, 59, 59, 0, time.UTC)
+	legacy := &models.Task{Title: "Legacy", Status: models.StatusTodo, Priority: models.PriorityMedium, Stage: models.StageDefault, AllDay: true, StartAt: &legacyStart, EndAt: &legacyEnd}
+	require.NoError(t, taskStorage.Create(legacy))
+	patched, err = service.PatchTask(legacy.ID, models.TaskPatch{EndAt: models.OptionalTime{Value: parse("2025-06-12T00:00:00Z"), Present: true}}, loc)
+	require.NoError(t, err)
+	require.Equal(t, june12Start, patched.StartAt.UTC())
+	require.Equal(t, june12End, patched.EndAt.UTC())
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	timeEntryNoteMaxLength = 500
+	// Ручная запись не длиннее суток: больше — почти наверняка опечатка в дате.
+	timeEntryMaxDuration = 24 * time.Hour
+	timeEntryListLimit   = 500
+)
+
+// Группировки отчёта по времени.
+const (
+	TimeByProject = storage.TimeByProject
+	TimeByTask    = storage.TimeByTask
+	TimeByUser    = storage.TimeByUser
+	TimeByDay     = storage.TimeByDay
+)
+
+var (
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+	ErrTimeEntryInvalid  = errors.New("invalid time entry")
+	ErrTimerRunning      = errors.New("a timer is already running")
+	ErrNoTimer           = errors.New("no running timer")
+)
+
+// TimeEntryInput — ручная запись или правка. При правке nil-поля не меняются.
+type TimeEntryInput struct {
+	TaskID    *uint      `json:"task_id"`
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      *string    `json:"note"`
+}
+
+// TimerInput — запуск таймера.
+type TimerInput struct {
+	TaskID uint   `json:"task_id"`
+	Note   string `json:"note"`
+}
+
+// TimeEntryListFilter — фильтр своих записей; From/To — YYYY-MM-DD.
+type TimeEntryListFilter struct {
+	TaskID    uint
+	ProjectID uint
+	From, To  string
+}
+
+// TimeReportQuery — параметры отчёта; From/To — YYYY-MM-DD.
+type TimeReportQuery struct {
+	GroupBy   string
+	UserID    uint
+	ProjectID uint
+	From, To  string
+}
+
+// TimeEntryService — учёт времени по задачам. Записи видит и меняет только их автор;
+// владелец проекта видит в отчётах время всех участников по задачам проекта.
+type TimeEntryService struct {
+	entries  *storage.TimeEntryStorage
+	tasks    *storage.TaskStorage
+	projects *storage.ProjectStorage
+	now      func() time.Time
+}
+
+func NewTimeEntryService(e *storage.TimeEntryStorage, t *storage.TaskStorage, p *storage.ProjectStorage) *TimeEntryService {
+	return &TimeEntryService{entries: e, tasks: t, projects: p, now: time.Now}
+}
+
+// Timer возвращает идущий таймер пользователя или nil.
+func (s *TimeEntryService) Timer(userID uint) (*models.TimeEntry, error) {
+	entry, err := s.entries.Running(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// Start запускает таймер по задаче. Если таймер уже идёт — ErrTimerRunning
+// вместе с ним, чтобы клиент мог предложить его остановить.
+func (s *TimeEntryService) Start(userID uint, input TimerInput) (*models.TimeEntry, error) {
+	if _, err := s.task(userID, input.TaskID); err != nil {
+		return nil, err
+	}
+	note, err := normalizeTimeNote(input.Note)
+	if err != nil {
+		return nil, err
+	}
+	if running, err := s.Timer(userID); err != nil {
+		return nil, err
+	} else if running != nil {
+		return running, ErrTimerRunning
+	}
+	entry := &models.TimeEntry{TaskID: input.TaskID, UserID: userID, StartedAt: s.now().UTC(), Note: note}
+	if err := s.entries.Create(entry); err != nil {
+		// Параллельный старт упирается в уникальный индекс по идущим таймерам.
+		if running, _ := s.Timer(userID); running != nil {
+			return running, ErrTimerRunning
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Stop останавливает идущий таймер.
+func (s *TimeEntryService) Stop(userID uint) (*models.TimeEntry, error) {
+	entry, err := s.Timer(userID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrNoTimer
+	}
+	ended := s.now().UTC()
+	if ended.Before(entry.StartedAt) {
+		ended = entry.StartedAt
+	}
+	entry.EndedAt = &ended
+	entry.DurationSeconds = int64(ended.Sub(entry.StartedAt) / time.Second)
+	if err := s.entries.Update(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *TimeEntryService) List(userID uint, filter TimeEntryListFilter) ([]models.TimeEntry, error) {
+	query := storage.TimeEntryFilter{UserID: userID, TaskID: filter.TaskID, ProjectID: filter.ProjectID, Limit: timeEntryListLimit}
+	if filter.From != "" || filter.To != "" {
+		from, to, err := dayRange(filter.From, filter.To, s.now().UTC(), ErrTimeEntryInvalid)
+		if err != nil {
+			return nil, err
+		}
+		query.From, query.To = from, to.AddDate(0, 0, 1)
+	}
+	return s.entries.List(query)
+}
+
+// Create добавляет запись вручную: задача, начало и конец обязательны.
+func (s *TimeEntryService) Create(userID uint, input TimeEntryInput) (*models.TimeEntry, error) {
+	if input.TaskID == nil || input.StartedAt == nil || input.EndedAt == nil {
+		return nil, fmt.Errorf("%w: task_id, started_at and ended_at are required", ErrTimeEntryInvalid)
+	}
+	entry := &models.TimeEntry{UserID: userID}
+	if err := s.apply(userID, entry, input); err != nil {
+		return nil, err
+	}
+	if err := s.entries.Create(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Update правит свою запись. У идущего таймера можно поменять задачу, заметку
+// и начало; ended_at останавливает его.
+func (s *TimeEntryService) Update(userID, id uint, input TimeEntryInput) (*models.TimeEntry, error) {
+	entry, err := s.own(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(userID, entry, input); err != nil {
+		return nil, err
+	}
+	if err := s.entries.Update(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *TimeEntryService) Delete(userID, id uint) error {
+	entry, err := s.own(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.entries.Delete(entry.ID)
+}
+
+// Report суммирует время по проектам, задачам, пользователям или дням.
+// Дни идут по порядку, остальные группы — от большего времени к меньшему.
+func (s *TimeEntryService) Report(userID uint, query TimeReportQuery) (*models.TimeReport, error) {
+	if query.GroupBy == "" {
+		query.GroupBy = TimeByProject
+	}
+	switch query.GroupBy {
+	case TimeByProject, TimeByTask, TimeByUser, TimeByDay:
+	default:
+		return nil, fmt.Errorf("%w: group_by must be project, task, user or day", ErrTimeEntryInvalid)
+	}
+	from, to, err := dayRange(query.From, query.To, s.now().UTC(), ErrTimeEntryInvalid)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.entries.Report(storage.TimeReportFilter{
+		ViewerID:  userID,
+		UserID:    query.UserID,
+		ProjectID: query.ProjectID,
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+	}, query.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if query.GroupBy == TimeByDay || rows[i].Seconds == rows[j].Seconds {
+			return rows[i].Label < rows[j].Label
+		}
+		return rows[i].Seconds > rows[j].Seconds
+	})
+	report := &models.TimeReport{
+		From:    from.Format(dayLayout),
+		To:      to.Format(dayLayout),
+		GroupBy: query.GroupBy,
+		Rows:    rows,
+	}
+	for _, row := range rows {
+		report.TotalSeconds += row.Seconds
+	}
+	return report, nil
+}
+
+func (s *TimeEntryService) apply(userID uint, entry *models.TimeEntry, input TimeEntryInput) error {
+	if input.TaskID != nil && *input.TaskID != entry.TaskID {
+		if _, err := s.task(userID, *input.TaskID); err != nil {
+			return err
+		}
+		entry.TaskID = *input.TaskID
+	}
+	if input.Note != nil {
+		note, err := normalizeTimeNote(*input.Note)
+		if err != nil {
+			return err
+		}
+		entry.Note = note
+	}
+	if input.StartedAt != nil {
+		entry.StartedAt = input.StartedAt.UTC().Truncate(time.Second)
+	}
+	if input.EndedAt != nil {
+		ended := input.EndedAt.UTC().Truncate(time.Second)
+		entry.EndedAt = &ended
+	}
+
+	now := s.now().UTC()
+	if entry.StartedAt.After(now) {
+		return fmt.Errorf("%w: started_at is in the future", ErrTimeEntryInvalid)
+	}
+	if entry.EndedAt == nil {
+		return nil
+	}
+	duration := entry.EndedAt.Sub(entry.StartedAt)
+	if duration <= 0 {
+		return fmt.Errorf("%w: ended_at must be after started_at", ErrTimeEntryInvalid)
+	}
+	if duration > timeEntryMaxDuration {
+		return fmt.Errorf("%w: an entry cannot be longer than 24 hours", ErrTimeEntryInvalid)
+	}
+	entry.DurationSeconds = int64(duration / time.Second)
+	return nil
+}
+
+// task проверяет, что задача видна пользователю: своя задача без проекта или
+// задача проекта, где он владелец или участник.
+func (s *TimeEntryService) task(userID, taskID uint) (*models.Task, error) {
+	task, err := s.tasks.GetByID(taskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	if task.ProjectID != nil {
+		if _, err := s.projects.GetAccessible(userID, *task.ProjectID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrTaskNotFound
+			}
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+func (s *TimeEntryService) own(userID, id uint) (*models.TimeEntry, error) {
+	entry, err := s.entries.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTimeEntryNotFound
+		}
+		return nil, err
+	}
+	if entry.UserID != userID {
+		return nil, ErrTimeEntryNotFound
+	}
+	return entry, nil
+}
+
+func normalizeTimeNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > timeEntryNoteMaxLength {
+		return "", fmt.Errorf("%w: note must be %d characters or fewer", ErrTimeEntryInvalid, timeEntryNoteMaxLength)
+	}
+	return note, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeEntryService_TimersEntriesAndReports(t *testing.T) {
+	db := setupTestDB(t)
+	owner := models.User{ID: 1, Email: "owner@example.com", Username: "owner", Password: "x", MaxProjects: 5}
+	member := models.User{ID: 2, Email: "member@example.com", Username: "member", Password: "x"}
+	stranger := models.User{ID: 3, Email: "stranger@example.com", Username: "stranger", Password: "x"}
+	require.NoError(t, db.Create([]*models.User{&owner, &member, &stranger}).Error)
+
+	taskStorage := storage.NewTaskStorage(db)
+	projectStorage := storage.NewProjectStorage(db)
+	projects := NewProjectService(projectStorage, taskStorage, storage.NewUserStorage(db))
+	service := NewTimeEntryService(storage.NewTimeEntryStorage(db), taskStorage, projectStorage)
+
+	now := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	site, err := projects.Create(owner.ID, &models.ProjectInput{Title: "Site"})
+	require.NoError(t, err)
+	app, err := projects.Create(owner.ID, &models.ProjectInput{Title: "App"})
+	require.NoError(t, err)
+	_, err = projects.AddMember(owner.ID, site.ID, member.Email)
+	require.NoError(t, err)
+
+	task := models.Task{Title: "Landing", Status: models.StatusTodo, ProjectID: &site.ID}
+	require.NoError(t, db.Create(&task).Error)
+
+	// Таймер: один на пользователя, второй старт возвращает идущий.
+	timer, err := service.Start(member.ID, TimerInput{TaskID: task.ID, Note: "  верстка "})
+	require.NoError(t, err)
+	require.True(t, timer.Running())
+	require.Equal(t, "верстка", timer.Note)
+	running, err := service.Start(member.ID, TimerInput{TaskID: task.ID})
+	require.ErrorIs(t, err, ErrTimerRunning)
+	require.Equal(t, timer.ID, running.ID)
+	_, err = service.Start(stranger.ID, TimerInput{TaskID: task.ID})
+	require.ErrorIs(t, err, ErrTaskNotFound)
+
+	now = now.Add(90 * time.Minute)
+	stopped, err := service.Stop(member.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 90*60, stopped.DurationSeconds)
+	_, err = service.Stop(member.ID)
+	require.ErrorIs(t, err, ErrNoTimer)
+
+	// Ручные записи проверяются.
+	at := func(day, hour int) *time.Time {
+		v := time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC)
+		return &v
+	}
+	_, err = service.Create(owner.ID, TimeEntryInput{TaskID: &task.ID, StartedAt: at(11, 10), EndedAt: at(11, 9)})
+	require.ErrorIs(t, err, ErrTimeEntryInvalid)
+	_, err = service.Create(owner.ID, TimeEntryInput{TaskID: &task.ID, StartedAt: at(9, 10), EndedAt: at(11, 10)})
+	require.ErrorIs(t, err, ErrTimeEntryInvalid)
+	_, err = service.Create(owner.ID, TimeEntryInput{TaskID: &task.ID, StartedAt: at(13, 10), EndedAt: at(13, 11)})
+	require.ErrorIs(t, err, ErrTimeEntryInvalid)
+	manual, err := service.Create(owner.ID, TimeEntryInput{TaskID: &task.ID, StartedAt: at(11, 9), EndedAt: at(11, 10)})
+	require.NoError(t, err)
+	require.EqualValues(t, 3600, manual.DurationSeconds)
+
+	// Чужие записи не правятся, свои — правятся с пересчётом длительности.
+	_, err = service.Update(member.ID, manual.ID, TimeEntryInput{EndedAt: at(11, 12)})
+	require.ErrorIs(t, err, ErrTimeEntryNotFound)
+	require.ErrorIs(t, service.Delete(member.ID, manual.ID), ErrTimeEntryNotFound)
+	manual, err = service.Update(owner.ID, manual.ID, TimeEntryInput{EndedAt: at(11, 11)})
+	require.NoError(t, err)
+	require.EqualValues(t, 7200, manual.DurationSeconds)
+
+	report, err := service.Report(owner.ID, TimeReportQuery{GroupBy: TimeByUser, From: "2025-03-01", To: "2025-03-12"})
+	require.NoError(t, err)
+	require.EqualValues(t, 7200+5400, report.TotalSeconds)
+	require.Len(t, report.Rows, 2)
+	require.Equal(t, "owner", report.Rows[0].Label)
+	require.Equal(t, "member", report.Rows[1].Label)
+
+	// Участник видит в отчёте только своё время.
+	report, err = service.Report(member.ID, TimeReportQuery{GroupBy: TimeByUser, From: "2025-03-01", To: "2025-03-12"})
+	require.NoError(t, err)
+	require.EqualValues(t, 5400, report.TotalSeconds)
+
+	report, err = service.Report(owner.ID, TimeReportQuery{GroupBy: TimeByDay, From: "2025-03-01", To: "2025-03-12"})
+	require.NoError(t, err)
+	require.Equal(t, []models.TimeReportRow{
+		{Key: "2025-03-11", Label: "2025-03-11", Seconds: 7200, Entries: 1},
+		{Key: "2025-03-12", Label: "2025-03-12", Seconds: 5400, Entries: 1},
+	}, report.Rows)
+
+	// Перенос задачи в другой проект уносит с собой всё учтённое время.
+	require.NoError(t, projects.AssignTasks(owner.ID, app.ID, []uint{task.ID}, true))
+	report, err = service.Report(owner.ID, TimeReportQuery{GroupBy: TimeByProject, From: "2025-03-01", To: "2025-03-12"})
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 1)
+	require.Equal(t, models.TimeReportRow{Key: strconv.FormatUint(uint64(app.ID), 10), Label: "App", Seconds: 12600, Entries: 2}, report.Rows[0])
+	entries, err := service.List(owner.ID, TimeEntryListFilter{ProjectID: app.ID})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	_, err = service.Report(owner.ID, TimeReportQuery{GroupBy: "week"})
+	require.ErrorIs(t, err, ErrTimeEntryInvalid)
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Секрет из приложения B RFC 6238 ("12345678901234567890") в base32.
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcTOTPSecret, tt.unix/totpPeriod)
+		require.NoError(t, err)
+		require.Equalf(t, tt.want, got, "unix=%d", tt.unix)
+	}
+}
+
+func TestValidateTOTP_SkewAndReplay(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+	code, err := TOTPCode(rfcTOTPSecret, at.Unix()/totpPeriod)
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTP(rfcTOTPSecret, code, at.Add(totpPeriod*time.Second), 0)
+	require.True(t, ok, "previous window must be accepted")
+
+	_, ok = ValidateTOTP(rfcTOTPSecret, code, at, step)
+	require.False(t, ok, "code must not be accepted twice")
+
+	_, ok = ValidateTOTP(rfcTOTPSecret, code, at.Add(5*totpPeriod*time.Second), 0)
+	require.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI(rfcTOTPSecret, "jane@example.com")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/TaskManager:jane@example.com?"))
+	require.Contains(t, uri, "secret="+rfcTOTPSecret)
+	require.Contains(t, uri, "issuer=TaskManager")
+}
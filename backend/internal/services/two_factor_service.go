@@ -0,0 +1,242 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+)
+
+const (
+	recoveryCodesCount = 10
+	challengeTokenTTL  = 5 * time.Minute
+	challengeTokenType = "2fa_challenge"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolling   = errors.New("start two-factor enrollment first")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidChallenge        = errors.New("invalid or expired login challenge")
+)
+
+// TwoFactorEnrollment — данные для настройки приложения-аутентификатора.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TwoFactorService управляет TOTP 2FA и резервными кодами.
+type TwoFactorService struct {
+	users *storage.UserStorage
+	codes *storage.RecoveryCodeStorage
+	now   func() time.Time
+}
+
+func NewTwoFactorService(users *storage.UserStorage, codes *storage.RecoveryCodeStorage) *TwoFactorService {
+	return &TwoFactorService{users: users, codes: codes, now: time.Now}
+}
+
+// BeginEnrollment выпускает новый секрет. До подтверждения кодом 2FA не включена.
+func (s *TwoFactorService) BeginEnrollment(userID uint) (*TwoFactorEnrollment, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	return &TwoFactorEnrollment{Secret: secret, URI: TOTPProvisioningURI(secret, account)}, nil
+}
+
+// ConfirmEnrollment включает 2FA после первого верного кода и возвращает резервные коды.
+// Открытые коды показываются один раз, в БД остаются только хэши.
+func (s *TwoFactorService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+	step, ok := ValidateTOTP(user.TOTPSecret, code, s.now(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.codes.Replace(user.ID, hashes); err != nil {
+		return nil, err
+	}
+	user.TOTPLastStep = step
+	user.TwoFactorEnabled = true
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable выключает 2FA после проверки пароля.
+func (s *TwoFactorService) Disable(userID uint, password string) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled && user.TOTPSecret == "" {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := CheckPassword(user.Password, strings.TrimSpace(password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+	if err := s.codes.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+	user.TwoFactorEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	return s.users.Update(user)
+}
+
+// Verify проверяет второй фактор при входе: TOTP-код или резервный код.
+func (s *TwoFactorService) Verify(user *models.User, code, recoveryCode string) error {
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if strings.TrimSpace(recoveryCode) != "" {
+		used, err := s.codes.Consume(user.ID, HashOpaqueToken(normalizeRecoveryCode(recoveryCode)), s.now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	step, ok := ValidateTOTP(user.TOTPSecret, code, s.now(), user.TOTPLastStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	advanced, err := s.users.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// IsEnabled сообщает, включена ли 2FA у пользователя.
+func (s *TwoFactorService) IsEnabled(userID uint) (bool, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.TwoFactorEnabled, nil
+}
+
+// RemainingRecoveryCodes — сколько резервных кодов ещё не использовано.
+func (s *TwoFactorService) RemainingRecoveryCodes(userID uint) (int64, error) {
+	return s.codes.CountUnused(userID)
+}
+
+// LoginChallenge — содержимое токена второго шага входа.
+type LoginChallenge struct {
+	UserID uint
+	// Reactivate — вход начат через /api/auth/reactivate: деактивированный аккаунт
+	// возвращается в работу только после проверки второго фактора.
+	Reactivate bool
+}
+
+// GenerateChallengeToken выдаёт короткоживущий токен между первым и вторым шагом входа.
+// Middleware его не принимает: тип отличается от access.
+func GenerateChallengeToken(challenge LoginChallenge) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": strconv.Itoa(int(challenge.UserID)),
+		"typ": challengeTokenType,
+		"iat": now.Unix(),
+		"exp": now.Add(challengeTokenTTL).Unix(),
+	}
+	if challenge.Reactivate {
+		claims["reactivate"] = true
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// ParseChallengeToken проверяет токен второго шага и возвращает его содержимое.
+func ParseChallengeToken(raw string) (LoginChallenge, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return LoginChallenge{}, ErrInvalidChallenge
+	}
+	if typ, _ := claims["typ"].(string); typ != challengeTokenType {
+		return LoginChallenge{}, ErrInvalidChallenge
+	}
+	sub, _ := claims["sub"].(string)
+	id, err := strconv.ParseUint(sub, 10, 64)
+	if err != nil || id == 0 {
+		return LoginChallenge{}, ErrInvalidChallenge
+	}
+	reactivate, _ := claims["reactivate"].(bool)
+	return LoginChallenge{UserID: uint(id), Reactivate: reactivate}, nil
+}
+
+// ChallengeTokenTTL — время жизни токена второго шага входа.
+func ChallengeTokenTTL() time.Duration {
+	return challengeTokenTTL
+}
+
+// generateRecoveryCodes выпускает коды вида "abcde-fghij" (50 бит энтропии, base32).
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodesCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(buf))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashOpaqueToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
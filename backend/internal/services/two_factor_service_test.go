@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactorService_EnrollVerifyDisable(t *testing.T) {
+	db := setupTestDB(t)
+	hash, err := HashPassword("secret1")
+	require.NoError(t, err)
+	user := &models.User{Email: "mfa@example.com", Username: "mfa", Password: hash}
+	require.NoError(t, db.Create(user).Error)
+
+	users := storage.NewUserStorage(db)
+	service := NewTwoFactorService(users, storage.NewRecoveryCodeStorage(db))
+	now := time.Unix(1700000000, 0)
+	service.now = func() time.Time { return now }
+
+	enrollment, err := service.BeginEnrollment(user.ID)
+	require.NoError(t, err)
+	require.Contains(t, enrollment.URI, enrollment.Secret)
+
+	_, err = service.ConfirmEnrollment(user.ID, "000000")
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	code, err := TOTPCode(enrollment.Secret, now.Unix()/totpPeriod)
+	require.NoError(t, err)
+	recovery, err := service.ConfirmEnrollment(user.ID, code)
+	require.NoError(t, err)
+	require.Len(t, recovery, recoveryCodesCount)
+
+	reloaded, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	require.True(t, reloaded.TwoFactorEnabled)
+
+	// Код, которым подтвердили подключение, повторно не принимается.
+	require.ErrorIs(t, service.Verify(reloaded, code, ""), ErrInvalidTwoFactorCode)
+
+	now = now.Add(totpPeriod * time.Second)
+	next, err := TOTPCode(enrollment.Secret, now.Unix()/totpPeriod)
+	require.NoError(t, err)
+	require.NoError(t, service.Verify(reloaded, next, ""))
+
+	// Резервный код одноразовый и принимается в любом регистре.
+	require.NoError(t, service.Verify(reloaded, "", " "+recovery[0]+" "))
+	require.ErrorIs(t, service.Verify(reloaded, "", recovery[0]), ErrInvalidTwoFactorCode)
+	remaining, err := service.RemainingRecoveryCodes(user.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, recoveryCodesCount-1, remaining)
+
+	require.Error(t, service.Disable(user.ID, "wrong"))
+	require.NoError(t, service.Disable(user.ID, "secret1"))
+	reloaded, err = users.GetByID(user.ID)
+	require.NoError(t, err)
+	require.False(t, reloaded.TwoFactorEnabled)
+	require.Empty(t, reloaded.TOTPSecret)
+}
+
+func TestChallengeToken_RoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+
+	token, err := GenerateChallengeToken(LoginChallenge{UserID: 42})
+	require.NoError(t, err)
+	challenge, err := ParseChallengeToken(token)
+	require.NoError(t, err)
+	require.EqualValues(t, 42, challenge.UserID)
+	require.False(t, challenge.Reactivate)
+
+	token, err = GenerateChallengeToken(LoginChallenge{UserID: 42, Reactivate: true})
+	require.NoError(t, err)
+	challenge, err = ParseChallengeToken(token)
+	require.NoError(t, err)
+	require.True(t, challenge.Reactivate)
+
+	access, err := GenerateJWT(42, "user")
+	require.NoError(t, err)
+	_, err = ParseChallengeToken(access)
+	require.ErrorIs(t, err, ErrInvalidChallenge)
+}
@@ -1,17 +1,16 @@
 package services
 
 import (
-	"encoding/base64"
 	"errors"
-	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spozitivom/taskmanager/internal/models"
 	"github.com/spozitivom/taskmanager/internal/storage"
 	"gorm.io/gorm"
 )
 
-const maxAvatarBytes = 2 * 1024 * 1024
+var ErrEmailTaken = errors.New("email already registered")
 
 var allowedLanguages = map[string]struct{}{
 	"en": {},
@@ -24,6 +23,32 @@ var allowedThemes = map[string]struct{}{
 	"system": {},
 }
 
+// NormalizeTimeZone проверяет имя часового пояса IANA. Local не принимается: это
+// пояс сервера, а не пользователя.
+func NormalizeTimeZone(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return "", false
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return "", false
+	}
+	return loc.String(), true
+}
+
+// userLocation — часовой пояс пользователя; UTC, если пользователя нет.
+func userLocation(users *storage.UserStorage, userID uint) (*time.Location, error) {
+	user, err := users.GetByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user.Location(), nil
+}
+
 // UserService инкапсулирует операции с профилем пользователя.
 type UserService struct {
 	db       *gorm.DB
@@ -55,14 +80,17 @@ func (s *UserService) UpdateProfile(userID uint, fullName *string, avatar *strin
 		user.FullName = strings.TrimSpace(*fullName)
 	}
 
+	// Загруженный аватар заменяется ссылкой или сбрасывается: его файлы удаляет вызывающий (AvatarService.Discard).
 	if removeAvatar {
 		user.AvatarURL = ""
+		user.AvatarVersion, user.AvatarFormat = "", ""
 	} else if avatar != nil {
 		normalized, err := normalizeAvatar(*avatar)
 		if err != nil {
 			return nil, err
 		}
 		user.AvatarURL = normalized
+		user.AvatarVersion, user.AvatarFormat = "", ""
 	}
 
 	if err := s.users.Update(user); err != nil {
@@ -79,8 +107,8 @@ func (s *UserService) UpdatePassword(userID uint, current, next string) error {
 
 	current = strings.TrimSpace(current)
 	next = strings.TrimSpace(next)
-	if len(next) < 6 {
-		return errors.New("new password must be at least 6 characters")
+	if err := validateNewPassword(next); err != nil {
+		return err
 	}
 	if err := CheckPassword(user.Password, current); err != nil {
 		return errors.New("current password is incorrect")
@@ -97,7 +125,42 @@ func (s *UserService) UpdatePassword(userID uint, current, next string) error {
 	return s.users.Update(user)
 }
 
-func (s *UserService) UpdateSettings(userID uint, language, theme string) (*models.User, error) {
+// ChangeEmail меняет адрес после проверки пароля. Новый адрес считается
+// неподтверждённым, пока пользователь не перейдёт по ссылке из письма.
+func (s *UserService) ChangeEmail(userID uint, password, email string) (*models.User, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckPassword(user.Password, strings.TrimSpace(password)); err != nil {
+		return nil, errors.New("password is incorrect")
+	}
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if normalized == "" {
+		return nil, errors.New("email is required")
+	}
+	if normalized == user.Email {
+		return user, nil
+	}
+	if existing, err := s.users.GetByEmail(normalized); err == nil && existing.ID != user.ID {
+		return nil, ErrEmailTaken
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user.Email = normalized
+	user.EmailVerifiedAt = nil
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateSettings меняет язык, тему и часовой пояс; пустое значение — не менять.
+func (s *UserService) UpdateSettings(userID uint, language, theme, timeZone string) (*models.User, error) {
 	user, err := s.users.GetByID(userID)
 	if err != nil {
 		return nil, err
@@ -117,12 +180,72 @@ func (s *UserService) UpdateSettings(userID uint, language, theme string) (*mode
 		user.Theme = t
 	}
 
+	if strings.TrimSpace(timeZone) != "" {
+		zone, ok := NormalizeTimeZone(timeZone)
+		if !ok {
+			return nil, errors.New("unsupported time zone")
+		}
+		user.TimeZone = zone
+	}
+
 	if err := s.users.Update(user); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
+// AccountStatus возвращает статус учётной записи (для проверки в middleware.Auth).
+func (s *UserService) AccountStatus(userID uint) (string, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if user.IsActive() {
+		return models.AccountStatusActive, nil
+	}
+	return user.Status, nil
+}
+
+// Deactivate отключает аккаунт по желанию пользователя. Данные сохраняются, общие
+// проекты остаются доступны участникам; вернуть аккаунт можно через POST /api/auth/reactivate.
+func (s *UserService) Deactivate(userID uint, password, reason string) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := CheckPassword(user.Password, strings.TrimSpace(password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+	now := time.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+			"status":         models.AccountStatusDeactivated,
+			"status_reason":  truncate(strings.TrimSpace(reason), 255),
+			"deactivated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// Reactivate возвращает аккаунт, отключённый самим пользователем. Заблокированные
+// администратором аккаунты так не разблокировать.
+func (s *UserService) Reactivate(user *models.User) error {
+	if user.IsActive() {
+		return nil
+	}
+	if user.Status != models.AccountStatusDeactivated {
+		return ErrAccountSuspended
+	}
+	user.Status = models.AccountStatusActive
+	user.StatusReason = ""
+	user.DeactivatedAt = nil
+	return s.users.Update(user)
+}
+
 func (s *UserService) DeleteAccount(userID uint) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		var projectIDs []uint
@@ -131,9 +254,22 @@ func (s *UserService) DeleteAccount(userID uint) error {
 		}
 
 		if len(projectIDs) > 0 {
+			taskIDs := tx.Unscoped().Model(&models.Task{}).Select("id").Where("project_id IN ?", projectIDs)
+			if err := tx.Where("task_id IN (?)", taskIDs).Delete(&models.TaskLabel{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("task_id IN (?)", taskIDs).Delete(&models.TimeEntry{}).Error; err != nil {
+				return err
+			}
 			if err := tx.Unscoped().Where("project_id IN ?", projectIDs).Delete(&models.Task{}).Error; err != nil {
 				return err
 			}
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&models.ProjectMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&models.Milestone{}).Error; err != nil {
+				return err
+			}
 			if err := tx.Unscoped().Where("owner_id = ?", userID).Delete(&models.Project{}).Error; err != nil {
 				return err
 			}
@@ -143,6 +279,76 @@ func (s *UserService) DeleteAccount(userID uint) error {
 			}
 		}
 
+		// Личные метки и метки своих проектов вместе со связями с задачами.
+		labelIDs := tx.Model(&models.Label{}).Select("id").Where("owner_id = ?", userID)
+		if len(projectIDs) > 0 {
+			labelIDs = labelIDs.Or("project_id IN ?", projectIDs)
+		}
+		if err := tx.Where("label_id IN (?)", labelIDs).Delete(&models.TaskLabel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&models.Label{}).Error; err != nil {
+			return err
+		}
+		if len(projectIDs) > 0 {
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&models.Label{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("owner_id = ?", userID).Delete(&models.SavedView{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TimeEntry{}).Error; err != nil {
+			return err
+		}
+
+		// Свои шаблоны проектов и доступы к чужим.
+		templateIDs := tx.Model(&models.ProjectTemplate{}).Select("id").Where("owner_id = ?", userID)
+		if err := tx.Where("template_id IN (?) OR user_id = ?", templateIDs, userID).Delete(&models.ProjectTemplateShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&models.ProjectTemplate{}).Error; err != nil {
+			return err
+		}
+
+		if len(projectIDs) > 0 {
+			if err := tx.Model(&models.SavedView{}).Where("shared_project_id IN ?", projectIDs).
+				Update("shared_project_id", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		sessionIDs := tx.Model(&models.Session{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Where("session_id IN (?)", sessionIDs).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.EmailVerificationToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.OIDCIdentity{}).Error; err != nil {
+			return err
+		}
+		// Готовые архивы выгрузок и ссылки на них не должны пережить аккаунт.
+		if err := tx.Where("user_id = ?", userID).Delete(&models.DataExport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.ProjectMember{}).Error; err != nil {
+			return err
+		}
+
 		if err := tx.Unscoped().Delete(&models.User{}, userID).Error; err != nil {
 			return err
 		}
@@ -150,6 +356,15 @@ func (s *UserService) DeleteAccount(userID uint) error {
 	})
 }
 
+func validateNewPassword(next string) error {
+	if len(next) < 6 {
+		return errors.New("new password must be at least 6 characters")
+	}
+	return nil
+}
+
+// normalizeAvatar принимает только внешние ссылки http(s). Картинки загружаются
+// через POST /api/user/avatar и хранятся в blob-хранилище, а не в таблице users.
 func normalizeAvatar(avatar string) (string, error) {
 	avatar = strings.TrimSpace(avatar)
 	if avatar == "" {
@@ -157,35 +372,14 @@ func normalizeAvatar(avatar string) (string, error) {
 	}
 
 	lower := strings.ToLower(avatar)
-	isDataURL := strings.HasPrefix(lower, "data:image/")
-	if !isDataURL && !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
-		return "", errors.New("avatar must be a JPEG/PNG/WebP image")
+	if strings.HasPrefix(lower, "data:") {
+		return "", errors.New("upload avatar images via POST /api/user/avatar")
 	}
-
-	if isDataURL {
-		if !strings.Contains(lower, "jpeg") && !strings.Contains(lower, "jpg") &&
-			!strings.Contains(lower, "png") && !strings.Contains(lower, "webp") {
-			return "", errors.New("avatar must be a JPEG/PNG/WebP image")
-		}
-		dataIdx := strings.Index(avatar, ",")
-		if dataIdx == -1 || dataIdx >= len(avatar)-1 {
-			return "", errors.New("invalid avatar payload")
-		}
-		rawData := avatar[dataIdx+1:]
-		if len(rawData) > base64.StdEncoding.EncodedLen(maxAvatarBytes) {
-			return "", fmt.Errorf("avatar is too large (max %d bytes)", maxAvatarBytes)
-		}
-
-		if decodedLen := len(rawData) * 3 / 4; decodedLen > maxAvatarBytes {
-			return "", fmt.Errorf("avatar is too large (max %d bytes)", maxAvatarBytes)
-		}
-
-		if decoded, err := base64.StdEncoding.DecodeString(rawData); err != nil {
-			return "", errors.New("invalid avatar encoding")
-		} else if len(decoded) > maxAvatarBytes {
-			return "", fmt.Errorf("avatar is too large (max %d bytes)", maxAvatarBytes)
-		}
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return "", errors.New("avatar must be an http(s) URL")
+	}
+	if len(avatar) > 2048 {
+		return "", errors.New("avatar URL is too long")
 	}
-
 	return avatar, nil
 }
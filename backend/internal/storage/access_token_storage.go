@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// AccessTokenStorage хранит персональные токены доступа.
+type AccessTokenStorage struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenStorage(db *gorm.DB) *AccessTokenStorage {
+	return &AccessTokenStorage{db: db}
+}
+
+func (s *AccessTokenStorage) Create(token *models.PersonalAccessToken) error {
+	return s.db.Create(token).Error
+}
+
+func (s *AccessTokenStorage) GetByHash(hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := s.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListByUser возвращает неотозванные токены пользователя, новые сверху.
+func (s *AccessTokenStorage) ListByUser(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (s *AccessTokenStorage) CountActiveByUser(userID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (s *AccessTokenStorage) Revoke(userID, id uint, now time.Time) (bool, error) {
+	res := s.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeAllByUser отзывает все активные токены пользователя.
+func (s *AccessTokenStorage) RevokeAllByUser(userID uint, now time.Time) error {
+	return s.db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+func (s *AccessTokenStorage) TouchLastUsed(id uint, now time.Time) error {
+	return s.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", now).Error
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AdminStorage — выборки для админки и журнал действий администраторов.
+type AdminStorage struct {
+	db *gorm.DB
+}
+
+func NewAdminStorage(db *gorm.DB) *AdminStorage {
+	return &AdminStorage{db: db}
+}
+
+// Transaction выполняет fn в транзакции над той же базой.
+func (s *AdminStorage) Transaction(fn func(tx *gorm.DB) error) error {
+	return s.db.Transaction(fn)
+}
+
+// SearchUsers ищет по подстроке в email, username и имени (без учёта регистра).
+func (s *AdminStorage) SearchUsers(query string, limit, offset int) ([]models.User, int64, error) {
+	q := s.db.Model(&models.User{})
+	if query = strings.ToLower(strings.TrimSpace(query)); query != "" {
+		like := "%" + escapeLike(query) + "%"
+		q = q.Where("LOWER(email) LIKE ? ESCAPE '\\' OR LOWER(username) LIKE ? ESCAPE '\\' OR LOWER(full_name) LIKE ? ESCAPE '\\'", like, like, like)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	if err := q.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// UsageByUsers считает проекты пользователей и задачи в них (удалённые не учитываются).
+func (s *AdminStorage) UsageByUsers(userIDs []uint) (map[uint]models.UserUsage, error) {
+	usage := make(map[uint]models.UserUsage, len(userIDs))
+	if len(userIDs) == 0 {
+		return usage, nil
+	}
+	type row struct {
+		OwnerID uint
+		Count   int64
+	}
+
+	var projects []row
+	if err := s.db.Model(&models.Project{}).
+		Select("owner_id, COUNT(*) AS count").
+		Where("owner_id IN ?", userIDs).
+		Group("owner_id").
+		Scan(&projects).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range projects {
+		u := usage[r.OwnerID]
+		u.Projects = r.Count
+		usage[r.OwnerID] = u
+	}
+
+	var tasks []row
+	if err := s.db.Model(&models.Task{}).
+		Select("projects.owner_id AS owner_id, COUNT(tasks.id) AS count").
+		Joins("JOIN projects ON projects.id = tasks.project_id").
+		Where("projects.owner_id IN ?", userIDs).
+		Group("projects.owner_id").
+		Scan(&tasks).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range tasks {
+		u := usage[r.OwnerID]
+		u.Tasks = r.Count
+		usage[r.OwnerID] = u
+	}
+	return usage, nil
+}
+
+// CountAdmins считает активных администраторов и блокирует их строки до конца
+// транзакции (SELECT … FOR UPDATE): два администратора, снимающие права друг
+// с друга, проверяются по очереди и не оставляют систему без администратора.
+func (s *AdminStorage) CountAdmins() (int64, error) {
+	var ids []uint
+	err := s.db.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND status = ?", models.RoleAdmin, models.AccountStatusActive).
+		Pluck("id", &ids).Error
+	return int64(len(ids)), err
+}
+
+func (s *AdminStorage) CreateAuditEntry(entry *models.AdminAuditEntry) error {
+	return s.db.Create(entry).Error
+}
+
+// ListAuditEntries возвращает журнал от новых к старым; targetUserID = 0 — без фильтра.
+func (s *AdminStorage) ListAuditEntries(targetUserID uint, limit, offset int) ([]models.AdminAuditEntry, error) {
+	q := s.db.Model(&models.AdminAuditEntry{})
+	if targetUserID > 0 {
+		q = q.Where("target_user_id = ?", targetUserID)
+	}
+	var entries []models.AdminAuditEntry
+	err := q.Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, err
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
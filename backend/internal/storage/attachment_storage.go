@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+type AttachmentStorage struct {
+	db *gorm.DB
+}
+
+func NewAttachmentStorage(db *gorm.DB) *AttachmentStorage {
+	return &AttachmentStorage{db: db}
+}
+
+func (s *AttachmentStorage) Create(attachment *models.Attachment) error {
+	return s.db.Create(attachment).Error
+}
+
+func (s *AttachmentStorage) Get(id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := s.db.First(&attachment, id).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (s *AttachmentStorage) ListByTask(taskID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.db.Where("task_id = ?", taskID).Order("id ASC").Find(&attachments).Error
+	return attachments, err
+}
+
+// Delete удаляет запись окончательно: файл из хранилища убирает сервис.
+func (s *AttachmentStorage) Delete(id uint) error {
+	return s.db.Unscoped().Delete(&models.Attachment{}, id).Error
+}
+
+// UsageByUser — объём файлов, загруженных пользователем, включая скрытые вместе с задачами.
+func (s *AttachmentStorage) UsageByUser(userID uint) (int64, error) {
+	var total int64
+	err := s.db.Unscoped().Model(&models.Attachment{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").Scan(&total).Error
+	return total, err
+}
+
+// UsageByProject — объём файлов во всех задачах проекта, включая скрытые.
+func (s *AttachmentStorage) UsageByProject(projectID uint) (int64, error) {
+	var total int64
+	err := s.db.Unscoped().Model(&models.Attachment{}).
+		Joins("JOIN tasks ON tasks.id = attachments.task_id").
+		Where("tasks.project_id = ?", projectID).
+		Select("COALESCE(SUM(attachments.size), 0)").Scan(&total).Error
+	return total, err
+}
+
+// Orphans — вложения, чьи задачи удалены окончательно (например, вместе с аккаунтом)
+// или остались без проекта: окончательное удаление проекта лишь скрывает его задачи,
+// и вернуть их уже некуда.
+func (s *AttachmentStorage) Orphans(limit int) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	projects := s.db.Unscoped().Model(&models.Project{}).Select("id")
+	tasks := s.db.Unscoped().Model(&models.Task{}).Select("id").
+		Where("project_id IS NULL OR project_id IN (?)", projects)
+	err := s.db.Unscoped().Where("task_id NOT IN (?)", tasks).
+		Order("id ASC").Limit(limit).Find(&attachments).Error
+	return attachments, err
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// SharedMember — участник чужого проекта пользователя вместе с email (для выгрузки).
+type SharedMember struct {
+	ProjectID uint      `json:"project_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership — проект другого владельца, в который добавлен пользователь.
+type Membership struct {
+	ProjectID    uint      `json:"project_id"`
+	ProjectTitle string    `json:"project_title"`
+	OwnerEmail   string    `json:"owner_email"`
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DataExportStorage хранит выгрузки и читает данные пользователя для них.
+type DataExportStorage struct {
+	db *gorm.DB
+}
+
+func NewDataExportStorage(db *gorm.DB) *DataExportStorage {
+	return &DataExportStorage{db: db}
+}
+
+func (s *DataExportStorage) Create(export *models.DataExport) error {
+	return s.db.Create(export).Error
+}
+
+func (s *DataExportStorage) Update(export *models.DataExport) error {
+	return s.db.Save(export).Error
+}
+
+// ListByUser возвращает выгрузки пользователя без содержимого архивов.
+func (s *DataExportStorage) ListByUser(userID uint) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := s.db.Omit("archive").Where("user_id = ?", userID).Order("id DESC").Find(&exports).Error
+	return exports, err
+}
+
+func (s *DataExportStorage) Get(userID, id uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := s.db.Omit("archive").Where("user_id = ?", userID).First(&export, id).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (s *DataExportStorage) GetByTokenHash(hash string) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := s.db.Where("token_hash = ? AND token_hash <> ''", hash).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// HasActive сообщает, есть ли у пользователя выгрузка в очереди или в работе.
+func (s *DataExportStorage) HasActive(userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.DataExport{}).
+		Where("user_id = ? AND status IN ?", userID, []string{models.DataExportStatusPending, models.DataExportStatusRunning}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListPending возвращает выгрузки в очереди и зависшие: running, взятые в работу
+// раньше staleBefore (обработчик упал или процесс перезапустили посреди сборки).
+func (s *DataExportStorage) ListPending(staleBefore time.Time, limit int) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := s.claimable(s.db.Omit("archive"), staleBefore).Order("id ASC").Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+// Claim переводит выгрузку в running и запоминает время; false — её уже забрал
+// другой обработчик. Зависшую выгрузку можно забрать повторно.
+func (s *DataExportStorage) Claim(id uint, now, staleBefore time.Time) (bool, error) {
+	res := s.claimable(s.db.Model(&models.DataExport{}).Where("id = ?", id), staleBefore).
+		Updates(map[string]any{"status": models.DataExportStatusRunning, "claimed_at": now})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (s *DataExportStorage) claimable(q *gorm.DB, staleBefore time.Time) *gorm.DB {
+	return q.Where("status = ? OR (status = ? AND (claimed_at IS NULL OR claimed_at < ?))",
+		models.DataExportStatusPending, models.DataExportStatusRunning, staleBefore)
+}
+
+// ExpireBefore удаляет архивы готовых выгрузок, срок хранения которых истёк.
+func (s *DataExportStorage) ExpireBefore(now time.Time) error {
+	return s.db.Model(&models.DataExport{}).
+		Where("status = ? AND expires_at < ?", models.DataExportStatusReady, now).
+		Updates(map[string]any{
+			"status":     models.DataExportStatusExpired,
+			"archive":    nil,
+			"token_hash": "",
+		}).Error
+}
+
+// OwnedProjects возвращает все проекты владельца, включая архивные.
+func (s *DataExportStorage) OwnedProjects(userID uint) ([]models.Project, error) {
+	var projects []models.Project
+	err := s.db.Where("owner_id = ?", userID).Order("id ASC").Find(&projects).Error
+	return projects, err
+}
+
+// TasksByProjects возвращает задачи проектов, включая скрытые архивированием.
+func (s *DataExportStorage) TasksByProjects(projectIDs []uint) ([]models.Task, error) {
+	var tasks []models.Task
+	if len(projectIDs) == 0 {
+		return tasks, nil
+	}
+	err := s.db.Unscoped().Where("project_id IN ?", projectIDs).Order("id ASC").Find(&tasks).Error
+	return tasks, err
+}
+
+func (s *DataExportStorage) SharedMembers(projectIDs []uint) ([]SharedMember, error) {
+	var members []SharedMember
+	if len(projectIDs) == 0 {
+		return members, nil
+	}
+	err := s.db.Table("project_members").
+		Select("project_members.project_id, users.email, project_members.role, project_members.created_at").
+		Joins("JOIN users ON users.id = project_members.user_id").
+		Where("project_members.project_id IN ?", projectIDs).
+		Order("project_members.project_id ASC, users.email ASC").
+		Scan(&members).Error
+	return members, err
+}
+
+func (s *DataExportStorage) Memberships(userID uint) ([]Membership, error) {
+	var memberships []Membership
+	err := s.db.Table("project_members").
+		Select("project_members.project_id, projects.title AS project_title, users.email AS owner_email, project_members.role, project_members.created_at").
+		Joins("JOIN projects ON projects.id = project_members.project_id AND projects.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = projects.owner_id").
+		Where("project_members.user_id = ?", userID).
+		Order("project_members.project_id ASC").
+		Scan(&memberships).Error
+	return memberships, err
+}
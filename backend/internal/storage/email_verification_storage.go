@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationStorage хранит токены подтверждения email.
+type EmailVerificationStorage struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationStorage(db *gorm.DB) *EmailVerificationStorage {
+	return &EmailVerificationStorage{db: db}
+}
+
+func (s *EmailVerificationStorage) Create(token *models.EmailVerificationToken) error {
+	return s.db.Create(token).Error
+}
+
+func (s *EmailVerificationStorage) GetByHash(hash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	if err := s.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *EmailVerificationStorage) CountSince(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.EmailVerificationToken{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// Confirm в одной транзакции гасит токены пользователя и отмечает адрес подтверждённым.
+// Возвращает false, если токен уже был использован.
+func (s *EmailVerificationStorage) Confirm(token *models.EmailVerificationToken, now time.Time) (bool, error) {
+	confirmed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		res = tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", token.UserID, token.Email).
+			Update("email_verified_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		confirmed = res.RowsAffected > 0
+		return nil
+	})
+	return confirmed, err
+}
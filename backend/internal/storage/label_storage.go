@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaskLabelRow — метка вместе с задачей, к которой она прикреплена.
+type TaskLabelRow struct {
+	TaskID uint
+	models.Label
+}
+
+type LabelStorage struct {
+	db *gorm.DB
+}
+
+func NewLabelStorage(db *gorm.DB) *LabelStorage {
+	return &LabelStorage{db: db}
+}
+
+func (s *LabelStorage) Create(label *models.Label) error {
+	return s.db.Create(label).Error
+}
+
+func (s *LabelStorage) Update(label *models.Label) error {
+	return s.db.Save(label).Error
+}
+
+func (s *LabelStorage) Get(id uint) (*models.Label, error) {
+	var label models.Label
+	if err := s.db.First(&label, id).Error; err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+func (s *LabelStorage) GetByIDs(ids []uint) ([]models.Label, error) {
+	var labels []models.Label
+	if len(ids) == 0 {
+		return labels, nil
+	}
+	err := s.db.Where("id IN ?", ids).Find(&labels).Error
+	return labels, err
+}
+
+// FindByName ищет метку с таким же именем (без учёта регистра) в той же области.
+func (s *LabelStorage) FindByName(ownerID, projectID *uint, name string) (*models.Label, error) {
+	var label models.Label
+	if err := s.scope(ownerID, projectID).Where("LOWER(name) = LOWER(?)", name).First(&label).Error; err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+// ListVisible — личные метки пользователя и метки доступных ему проектов.
+// projectID сужает выборку до меток одного проекта (личные метки остаются).
+func (s *LabelStorage) ListVisible(userID uint, projectID *uint) ([]models.Label, error) {
+	projects := s.db.Model(&models.Project{}).Select("id").
+		Where("owner_id = ? OR id IN (?)", userID, s.db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userID))
+	if projectID != nil {
+		projects = projects.Where("id = ?", *projectID)
+	}
+	var labels []models.Label
+	err := s.db.Where("owner_id = ? OR project_id IN (?)", userID, projects).
+		Order("LOWER(name) ASC, id ASC").Find(&labels).Error
+	return labels, err
+}
+
+// CountTasks — число активных задач у каждой метки.
+func (s *LabelStorage) CountTasks(labelIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(labelIDs))
+	if len(labelIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		LabelID uint
+		Count   int64
+	}
+	err := s.db.Model(&models.TaskLabel{}).
+		Select("task_labels.label_id, COUNT(*) AS count").
+		Joins("JOIN tasks ON tasks.id = task_labels.task_id AND tasks.deleted_at IS NULL").
+		Where("task_labels.label_id IN ?", labelIDs).
+		Group("task_labels.label_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.LabelID] = row.Count
+	}
+	return counts, err
+}
+
+// ForTasks возвращает метки задач, видимые пользователю: метки проектов и его личные.
+func (s *LabelStorage) ForTasks(userID uint, taskIDs []uint) ([]TaskLabelRow, error) {
+	var rows []TaskLabelRow
+	if len(taskIDs) == 0 {
+		return rows, nil
+	}
+	err := s.db.Model(&models.Label{}).
+		Select("task_labels.task_id, labels.*").
+		Joins("JOIN task_labels ON task_labels.label_id = labels.id").
+		Where("task_labels.task_id IN ?", taskIDs).
+		Where("labels.project_id IS NOT NULL OR labels.owner_id = ?", userID).
+		Order("LOWER(labels.name) ASC, labels.id ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// AddToTasks прикрепляет метки к задачам; существующие связи не дублируются.
+func (s *LabelStorage) AddToTasks(labelIDs, taskIDs []uint) error {
+	if len(labelIDs) == 0 || len(taskIDs) == 0 {
+		return nil
+	}
+	links := make([]models.TaskLabel, 0, len(labelIDs)*len(taskIDs))
+	for _, taskID := range taskIDs {
+		for _, labelID := range labelIDs {
+			links = append(links, models.TaskLabel{TaskID: taskID, LabelID: labelID})
+		}
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
+
+func (s *LabelStorage) RemoveFromTasks(labelIDs, taskIDs []uint) error {
+	if len(labelIDs) == 0 || len(taskIDs) == 0 {
+		return nil
+	}
+	return s.db.Where("label_id IN ? AND task_id IN ?", labelIDs, taskIDs).Delete(&models.TaskLabel{}).Error
+}
+
+// Merge переносит задачи метки source на target и удаляет source.
+func (s *LabelStorage) Merge(sourceID, targetID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"INSERT INTO task_labels (task_id, label_id, created_at) "+
+				"SELECT task_id, ?, ? FROM task_labels WHERE label_id = ? "+
+				"AND task_id NOT IN (SELECT task_id FROM task_labels WHERE label_id = ?)",
+			targetID, time.Now(), sourceID, targetID,
+		).Error; err != nil {
+			return err
+		}
+		return deleteLabels(tx, []uint{sourceID})
+	})
+}
+
+func (s *LabelStorage) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return deleteLabels(tx, []uint{id})
+	})
+}
+
+func (s *LabelStorage) scope(ownerID, projectID *uint) *gorm.DB {
+	if projectID != nil {
+		return s.db.Where("project_id = ?", *projectID)
+	}
+	return s.db.Where("owner_id = ?", *ownerID)
+}
+
+func deleteLabels(tx *gorm.DB, ids []uint) error {
+	if err := tx.Where("label_id IN ?", ids).Delete(&models.TaskLabel{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(&models.Label{}).Error
+}
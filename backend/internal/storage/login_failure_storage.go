@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// LoginFailureStorage хранит неудачные попытки входа.
+type LoginFailureStorage struct {
+	db *gorm.DB
+}
+
+func NewLoginFailureStorage(db *gorm.DB) *LoginFailureStorage {
+	return &LoginFailureStorage{db: db}
+}
+
+func (s *LoginFailureStorage) Create(failure *models.LoginFailure) error {
+	return s.db.Create(failure).Error
+}
+
+// Stats возвращает число неудач по колонке column = value начиная с since и время последней.
+func (s *LoginFailureStorage) Stats(column, value string, since time.Time) (int64, *time.Time, error) {
+	query := s.db.Model(&models.LoginFailure{}).Where(column+" = ? AND created_at >= ?", value, since)
+	var count int64
+	if err := query.Count(&count).Error; err != nil || count == 0 {
+		return count, nil, err
+	}
+	var last models.LoginFailure
+	if err := s.db.Where(column+" = ?", value).Order("created_at DESC").First(&last).Error; err != nil {
+		return 0, nil, err
+	}
+	return count, &last.CreatedAt, nil
+}
+
+func (s *LoginFailureStorage) DeleteByIdentifier(identifier string) (int64, error) {
+	res := s.db.Where("identifier = ?", identifier).Delete(&models.LoginFailure{})
+	return res.RowsAffected, res.Error
+}
+
+// DetachIdentifier отвязывает неудачи от идентификатора, сохраняя их в счётчике IP.
+func (s *LoginFailureStorage) DetachIdentifier(identifier string) error {
+	return s.db.Model(&models.LoginFailure{}).Where("identifier = ?", identifier).Update("identifier", "").Error
+}
+
+func (s *LoginFailureStorage) DeleteByIP(ip string) (int64, error) {
+	res := s.db.Where("ip = ?", ip).Delete(&models.LoginFailure{})
+	return res.RowsAffected, res.Error
+}
+
+// PurgeBefore удаляет записи старше before — они уже не влияют на блокировки.
+func (s *LoginFailureStorage) PurgeBefore(before time.Time) error {
+	return s.db.Where("created_at < ?", before).Delete(&models.LoginFailure{}).Error
+}
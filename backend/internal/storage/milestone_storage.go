@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// MilestoneTask — то, что нужно для burndown: объём задачи и когда она завершена.
+type MilestoneTask struct {
+	Status      string
+	StoryPoints *float64
+	CompletedAt *time.Time
+}
+
+type MilestoneStorage struct {
+	db *gorm.DB
+}
+
+func NewMilestoneStorage(db *gorm.DB) *MilestoneStorage {
+	return &MilestoneStorage{db: db}
+}
+
+func (s *MilestoneStorage) Create(milestone *models.Milestone) error {
+	return s.db.Create(milestone).Error
+}
+
+func (s *MilestoneStorage) Update(milestone *models.Milestone) error {
+	return s.db.Save(milestone).Error
+}
+
+func (s *MilestoneStorage) Get(id uint) (*models.Milestone, error) {
+	var milestone models.Milestone
+	if err := s.db.First(&milestone, id).Error; err != nil {
+		return nil, err
+	}
+	return &milestone, nil
+}
+
+// ListByProject — спринты проекта по дате начала.
+func (s *MilestoneStorage) ListByProject(projectID uint) ([]models.Milestone, error) {
+	var milestones []models.Milestone
+	err := s.db.Where("project_id = ?", projectID).
+		Order("start_date ASC, id ASC").
+		Find(&milestones).Error
+	return milestones, err
+}
+
+// CountTasks — всего и завершённых задач по спринтам.
+func (s *MilestoneStorage) CountTasks(ids []uint) (map[uint][2]int64, error) {
+	counts := make(map[uint][2]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		MilestoneID uint
+		Total       int64
+		Completed   int64
+	}
+	err := s.db.Model(&models.Task{}).
+		Select("milestone_id, COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS completed", models.StatusCompleted).
+		Where("milestone_id IN ?", ids).
+		Group("milestone_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.MilestoneID] = [2]int64{row.Total, row.Completed}
+	}
+	return counts, err
+}
+
+// NextOpen — ближайший открытый спринт проекта, начинающийся не раньше after.
+func (s *MilestoneStorage) NextOpen(after *models.Milestone) (*models.Milestone, error) {
+	var milestone models.Milestone
+	err := s.db.Where("project_id = ? AND id <> ? AND closed_at IS NULL", after.ProjectID, after.ID).
+		Where("start_date >= ?", after.StartDate).
+		Order("start_date ASC, id ASC").
+		First(&milestone).Error
+	if err != nil {
+		return nil, err
+	}
+	return &milestone, nil
+}
+
+// Close закрывает спринт и переносит его незавершённые задачи в target
+// (nil — в бэклог). Возвращает число перенесённых задач.
+func (s *MilestoneStorage) Close(milestone *models.Milestone, target *uint, closedAt time.Time) (int64, error) {
+	var moved int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Task{}).
+			Where("milestone_id = ? AND status NOT IN ?", milestone.ID, closedTaskStatuses).
+			Update("milestone_id", target)
+		if result.Error != nil {
+			return result.Error
+		}
+		moved = result.RowsAffected
+		milestone.ClosedAt = &closedAt
+		return tx.Save(milestone).Error
+	})
+	return moved, err
+}
+
+// Delete удаляет спринт, его задачи возвращаются в бэклог.
+func (s *MilestoneStorage) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Task{}).Where("milestone_id = ?", id).
+			Update("milestone_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Milestone{}, id).Error
+	})
+}
+
+// AssignTasks ставит задачам спринт (nil — убирает в бэклог).
+func (s *MilestoneStorage) AssignTasks(taskIDs []uint, milestoneID *uint) error {
+	return s.db.Model(&models.Task{}).Where("id IN ?", taskIDs).Update("milestone_id", milestoneID).Error
+}
+
+// Tasks — задачи спринта для burndown; удалённые не учитываются.
+func (s *MilestoneStorage) Tasks(milestoneID uint) ([]MilestoneTask, error) {
+	var tasks []MilestoneTask
+	err := s.db.Model(&models.Task{}).
+		Select("status, story_points, completed_at").
+		Where("milestone_id = ?", milestoneID).
+		Scan(&tasks).Error
+	return tasks, err
+}
+
+// deleteMilestones удаляет спринты проекта и отвязывает от них задачи.
+func deleteMilestones(tx *gorm.DB, projectID uint) error {
+	ids := tx.Model(&models.Milestone{}).Select("id").Where("project_id = ?", projectID)
+	if err := tx.Unscoped().Model(&models.Task{}).Where("milestone_id IN (?)", ids).
+		Update("milestone_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Where("project_id = ?", projectID).Delete(&models.Milestone{}).Error
+}
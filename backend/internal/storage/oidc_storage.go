@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// OIDCStorage хранит привязки к OIDC-провайдеру и незавершённые запросы входа.
+type OIDCStorage struct {
+	db *gorm.DB
+}
+
+func NewOIDCStorage(db *gorm.DB) *OIDCStorage {
+	return &OIDCStorage{db: db}
+}
+
+func (s *OIDCStorage) CreateRequest(req *models.OIDCAuthRequest) error {
+	return s.db.Create(req).Error
+}
+
+// ConsumeRequest атомарно забирает запрос по хэшу state: повторный callback с тем же state не пройдёт.
+func (s *OIDCStorage) ConsumeRequest(stateHash string) (*models.OIDCAuthRequest, error) {
+	var req models.OIDCAuthRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ?", stateHash).First(&req).Error; err != nil {
+			return err
+		}
+		res := tx.Where("id = ?", req.ID).Delete(&models.OIDCAuthRequest{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// PurgeRequests удаляет просроченные запросы входа.
+func (s *OIDCStorage) PurgeRequests(now time.Time) error {
+	return s.db.Where("expires_at < ?", now).Delete(&models.OIDCAuthRequest{}).Error
+}
+
+func (s *OIDCStorage) GetIdentity(issuer, subject string) (*models.OIDCIdentity, error) {
+	var identity models.OIDCIdentity
+	if err := s.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (s *OIDCStorage) CreateIdentity(identity *models.OIDCIdentity) error {
+	return s.db.Create(identity).Error
+}
+
+func (s *OIDCStorage) TouchIdentity(id uint, email string, at time.Time) error {
+	return s.db.Model(&models.OIDCIdentity{}).Where("id = ?", id).
+		Updates(map[string]any{"email": email, "last_login": at}).Error
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// PasswordResetStorage хранит токены восстановления пароля.
+type PasswordResetStorage struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetStorage(db *gorm.DB) *PasswordResetStorage {
+	return &PasswordResetStorage{db: db}
+}
+
+func (s *PasswordResetStorage) Create(token *models.PasswordResetToken) error {
+	return s.db.Create(token).Error
+}
+
+func (s *PasswordResetStorage) GetByHash(hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	if err := s.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CountSince считает, сколько токенов выпущено пользователю начиная с since.
+func (s *PasswordResetStorage) CountSince(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// Consume атомарно помечает токен использованным и гасит остальные токены пользователя.
+// Возвращает false, если токен уже был использован.
+func (s *PasswordResetStorage) Consume(token *models.PasswordResetToken, now time.Time) (bool, error) {
+	consumed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		consumed = true
+		return tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", now).Error
+	})
+	return consumed, err
+}
@@ -15,8 +15,14 @@ func NewProjectStorage(db *gorm.DB) *ProjectStorage {
 	return &ProjectStorage{db: db}
 }
 
+// Transaction выполняет fn в транзакции над той же базой.
+func (s *ProjectStorage) Transaction(fn func(tx *gorm.DB) error) error {
+	return s.db.Transaction(fn)
+}
+
+// List возвращает собственные проекты пользователя и проекты, в которые его добавили участником.
 func (s *ProjectStorage) List(ownerID uint, includeArchived bool) ([]models.Project, error) {
-	query := s.db.Where("owner_id = ?", ownerID)
+	query := s.db.Where("owner_id = ? OR id IN (?)", ownerID, s.memberProjectIDs(ownerID))
 	if !includeArchived {
 		query = query.Where("archived_at IS NULL")
 	}
@@ -37,6 +43,22 @@ func (s *ProjectStorage) Create(p *models.Project) error {
 	return s.db.Create(p).Error
 }
 
+// CreateWithTasks создаёт проект и его задачи одной транзакцией.
+func (s *ProjectStorage) CreateWithTasks(project *models.Project, tasks []models.Task) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return nil
+		}
+		for i := range tasks {
+			tasks[i].ProjectID = &project.ID
+		}
+		return tx.CreateInBatches(tasks, 200).Error
+	})
+}
+
 func (s *ProjectStorage) Get(ownerID, id uint) (*models.Project, error) {
 	var project models.Project
 	if err := s.db.Where("owner_id = ?", ownerID).First(&project, id).Error; err != nil {
@@ -45,6 +67,57 @@ func (s *ProjectStorage) Get(ownerID, id uint) (*models.Project, error) {
 	return &project, nil
 }
 
+// GetByID — проект без проверки владельца, для служебных проверок.
+func (s *ProjectStorage) GetByID(id uint) (*models.Project, error) {
+	var project models.Project
+	if err := s.db.First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetAccessible возвращает проект, если пользователь — владелец или участник.
+// Статус владельца не важен: проекты отключённого пользователя остаются доступны участникам.
+func (s *ProjectStorage) GetAccessible(userID, id uint) (*models.Project, error) {
+	var project models.Project
+	if err := s.db.Where("owner_id = ? OR id IN (?)", userID, s.memberProjectIDs(userID)).First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s *ProjectStorage) memberProjectIDs(userID uint) *gorm.DB {
+	return s.db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userID)
+}
+
+func (s *ProjectStorage) ListMembers(projectID uint) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := s.db.Where("project_id = ?", projectID).Order("created_at").Find(&members).Error
+	return members, err
+}
+
+func (s *ProjectStorage) CountMembers(projectID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.ProjectMember{}).Where("project_id = ?", projectID).Count(&count).Error
+	return count, err
+}
+
+func (s *ProjectStorage) IsMember(projectID, userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", projectID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// AddMember добавляет участника; повторное добавление обновляет роль.
+func (s *ProjectStorage) AddMember(member *models.ProjectMember) error {
+	return s.db.Save(member).Error
+}
+
+func (s *ProjectStorage) RemoveMember(projectID, userID uint) (bool, error) {
+	res := s.db.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&models.ProjectMember{})
+	return res.RowsAffected > 0, res.Error
+}
+
 func (s *ProjectStorage) Update(project *models.Project) error {
 	return s.db.Save(project).Error
 }
@@ -61,5 +134,26 @@ func (s *ProjectStorage) Restore(project *models.Project) error {
 }
 
 func (s *ProjectStorage) HardDelete(project *models.Project) error {
-	return s.db.Unscoped().Delete(project).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", project.ID).Delete(&models.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		var labelIDs []uint
+		if err := tx.Model(&models.Label{}).Where("project_id = ?", project.ID).Pluck("id", &labelIDs).Error; err != nil {
+			return err
+		}
+		if len(labelIDs) > 0 {
+			if err := deleteLabels(tx, labelIDs); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.SavedView{}).Where("shared_project_id = ?", project.ID).
+			Update("shared_project_id", nil).Error; err != nil {
+			return err
+		}
+		if err := deleteMilestones(tx, project.ID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(project).Error
+	})
 }
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectTemplateStorage struct {
+	db *gorm.DB
+}
+
+func NewProjectTemplateStorage(db *gorm.DB) *ProjectTemplateStorage {
+	return &ProjectTemplateStorage{db: db}
+}
+
+func (s *ProjectTemplateStorage) Create(template *models.ProjectTemplate) error {
+	return s.db.Omit("Shares").Create(template).Error
+}
+
+func (s *ProjectTemplateStorage) Update(template *models.ProjectTemplate) error {
+	return s.db.Omit("Shares").Save(template).Error
+}
+
+func (s *ProjectTemplateStorage) Get(id uint) (*models.ProjectTemplate, error) {
+	var template models.ProjectTemplate
+	if err := s.db.First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ListVisible — свои шаблоны и открытые пользователю, по имени.
+func (s *ProjectTemplateStorage) ListVisible(userID uint) ([]models.ProjectTemplate, error) {
+	shared := s.db.Model(&models.ProjectTemplateShare{}).Select("template_id").Where("user_id = ?", userID)
+	var templates []models.ProjectTemplate
+	err := s.db.Where("owner_id = ? OR id IN (?)", userID, shared).
+		Order("name ASC, id ASC").
+		Find(&templates).Error
+	return templates, err
+}
+
+func (s *ProjectTemplateStorage) IsSharedWith(templateID, userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.ProjectTemplateShare{}).
+		Where("template_id = ? AND user_id = ?", templateID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *ProjectTemplateStorage) Shares(templateID uint) ([]models.ProjectTemplateShare, error) {
+	var shares []models.ProjectTemplateShare
+	err := s.db.Where("template_id = ?", templateID).Order("created_at ASC").Find(&shares).Error
+	return shares, err
+}
+
+// AddShare открывает шаблон пользователю; повторное открытие ничего не меняет.
+func (s *ProjectTemplateStorage) AddShare(share *models.ProjectTemplateShare) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(share).Error
+}
+
+func (s *ProjectTemplateStorage) RemoveShare(templateID, userID uint) (bool, error) {
+	result := s.db.Where("template_id = ? AND user_id = ?", templateID, userID).Delete(&models.ProjectTemplateShare{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *ProjectTemplateStorage) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", id).Delete(&models.ProjectTemplateShare{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ProjectTemplate{}, id).Error
+	})
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeStorage хранит резервные коды двухфакторной аутентификации.
+type RecoveryCodeStorage struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeStorage(db *gorm.DB) *RecoveryCodeStorage {
+	return &RecoveryCodeStorage{db: db}
+}
+
+// Replace заменяет все коды пользователя новым набором.
+func (s *RecoveryCodeStorage) Replace(userID uint, hashes []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Consume гасит неиспользованный код. Возвращает false, если такого кода нет.
+func (s *RecoveryCodeStorage) Consume(userID uint, hash string, now time.Time) (bool, error) {
+	res := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (s *RecoveryCodeStorage) CountUnused(userID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (s *RecoveryCodeStorage) DeleteByUser(userID uint) error {
+	return s.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
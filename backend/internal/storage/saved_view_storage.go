@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+type SavedViewStorage struct {
+	db *gorm.DB
+}
+
+func NewSavedViewStorage(db *gorm.DB) *SavedViewStorage {
+	return &SavedViewStorage{db: db}
+}
+
+func (s *SavedViewStorage) Create(view *models.SavedView) error {
+	return s.db.Create(view).Error
+}
+
+func (s *SavedViewStorage) Update(view *models.SavedView) error {
+	return s.db.Save(view).Error
+}
+
+func (s *SavedViewStorage) Delete(id uint) error {
+	return s.db.Delete(&models.SavedView{}, id).Error
+}
+
+func (s *SavedViewStorage) Get(id uint) (*models.SavedView, error) {
+	var view models.SavedView
+	if err := s.db.First(&view, id).Error; err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// ListVisible — свои представления и открытые для проектов, где пользователь
+// владелец или участник.
+func (s *SavedViewStorage) ListVisible(userID uint) ([]models.SavedView, error) {
+	projects := s.db.Model(&models.Project{}).Select("id").
+		Where("owner_id = ? OR id IN (?)", userID, s.db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userID))
+	var views []models.SavedView
+	err := s.db.Where("owner_id = ? OR shared_project_id IN (?)", userID, projects).
+		Order("LOWER(name) ASC, id ASC").Find(&views).Error
+	return views, err
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// SecurityEventFilter — условия выборки журнала; нулевые значения не фильтруют.
+type SecurityEventFilter struct {
+	UserID    uint
+	EventType string
+	Outcome   string
+	IP        string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+// SecurityEventStorage хранит журнал событий безопасности.
+type SecurityEventStorage struct {
+	db *gorm.DB
+}
+
+func NewSecurityEventStorage(db *gorm.DB) *SecurityEventStorage {
+	return &SecurityEventStorage{db: db}
+}
+
+func (s *SecurityEventStorage) Create(event *models.SecurityEvent) error {
+	return s.db.Create(event).Error
+}
+
+// List возвращает события от новых к старым.
+func (s *SecurityEventStorage) List(filter SecurityEventFilter) ([]models.SecurityEvent, error) {
+	q := s.db.Model(&models.SecurityEvent{})
+	if filter.UserID > 0 {
+		q = q.Where("user_id = ?", filter.UserID)
+	}
+	if filter.EventType != "" {
+		q = q.Where("event_type = ?", filter.EventType)
+	}
+	if filter.Outcome != "" {
+		q = q.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.IP != "" {
+		q = q.Where("ip = ?", filter.IP)
+	}
+	if filter.From != nil {
+		q = q.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		q = q.Where("created_at < ?", *filter.To)
+	}
+	var events []models.SecurityEvent
+	err := q.Order("id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&events).Error
+	return events, err
+}
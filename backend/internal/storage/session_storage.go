@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// SessionStorage хранит сессии входа и цепочки refresh-токенов.
+type SessionStorage struct {
+	db *gorm.DB
+}
+
+func NewSessionStorage(db *gorm.DB) *SessionStorage {
+	return &SessionStorage{db: db}
+}
+
+// Create сохраняет новую сессию вместе с первым refresh-токеном.
+func (s *SessionStorage) Create(session *models.Session, token *models.RefreshToken) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		token.SessionID = session.ID
+		return tx.Create(token).Error
+	})
+}
+
+func (s *SessionStorage) GetByID(id uint) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *SessionStorage) GetRefreshToken(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := s.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate атомарно помечает старый токен использованным и выпускает следующий.
+// Возвращает false, если токен уже был использован (параллельный или повторный обмен).
+func (s *SessionStorage) Rotate(old *models.RefreshToken, next *models.RefreshToken, now time.Time) (bool, error) {
+	rotated := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", old.ID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		next.SessionID = old.SessionID
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).Where("id = ?", old.SessionID).
+			Update("last_used_at", now).Error; err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+	return rotated, err
+}
+
+// Revoke отзывает одну сессию пользователя.
+func (s *SessionStorage) Revoke(userID, sessionID uint, now time.Time) (bool, error) {
+	res := s.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeAllByUser отзывает все активные сессии пользователя.
+func (s *SessionStorage) RevokeAllByUser(userID uint, now time.Time) error {
+	return s.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// ListActive возвращает неотозванные и не истёкшие сессии, свежие сверху.
+func (s *SessionStorage) ListActive(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// StatsStorage — агрегаты по задачам. Всё считается в базе через COUNT/SUM,
+// задачи целиком не загружаются. Удалённые задачи и задачи архивных проектов
+// (они скрыты вместе с проектом) не учитываются.
+type StatsStorage struct {
+	db *gorm.DB
+}
+
+func NewStatsStorage(db *gorm.DB) *StatsStorage {
+	return &StatsStorage{db: db}
+}
+
+// TaskTotals — итоговые счётчики сводки.
+type TaskTotals struct {
+	Total       int64
+	Completed   int64
+	Overdue     int64
+	DueThisWeek int64
+}
+
+var closedTaskStatuses = []string{models.StatusCompleted, models.StatusCancelled}
+
+// Totals считает все задачи, завершённые, просроченные на момент now и открытые
+// со сроком в [weekStart, weekEnd).
+func (s *StatsStorage) Totals(projectID *uint, now, weekStart, weekEnd time.Time) (TaskTotals, error) {
+	var totals TaskTotals
+	err := s.tasks(projectID).Select(
+		"COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS completed, "+
+			"COALESCE(SUM(CASE WHEN end_at IS NOT NULL AND end_at < ? AND status NOT IN ? THEN 1 ELSE 0 END), 0) AS overdue, "+
+			"COALESCE(SUM(CASE WHEN end_at IS NOT NULL AND end_at >= ? AND end_at < ? AND status NOT IN ? THEN 1 ELSE 0 END), 0) AS due_this_week",
+		models.StatusCompleted,
+		now.UTC(), closedTaskStatuses,
+		weekStart.UTC(), weekEnd.UTC(), closedTaskStatuses,
+	).Scan(&totals).Error
+	return totals, err
+}
+
+// CountBy группирует задачи по status, priority или stage.
+func (s *StatsStorage) CountBy(column string, projectID *uint) (map[string]int64, error) {
+	switch column {
+	case "status", "priority", "stage":
+	default:
+		return nil, gorm.ErrInvalidField
+	}
+	var rows []struct {
+		Value string
+		Count int64
+	}
+	err := s.tasks(projectID).
+		Select(column + " AS value, COUNT(*) AS count").
+		Group(column).
+		Scan(&rows).Error
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Value] = row.Count
+	}
+	return counts, err
+}
+
+// DailyCounts — число задач по дням по колонке created_at или completed_at
+// в интервале [from, to). Дни считаются в поясе from.
+func (s *StatsStorage) DailyCounts(column string, projectID *uint, from, to time.Time) (map[string]int64, error) {
+	switch column {
+	case "created_at", "completed_at":
+	default:
+		return nil, gorm.ErrInvalidField
+	}
+	day := dayExpr(s.db, column, from)
+	var rows []struct {
+		Day   string
+		Count int64
+	}
+	err := s.tasks(projectID).
+		Select(day+" AS day, COUNT(*) AS count").
+		Where(column+" >= ? AND "+column+" < ?", from.UTC(), to.UTC()).
+		Group(day).
+		Scan(&rows).Error
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Day] = row.Count
+	}
+	return counts, err
+}
+
+// ProjectCompletion — задачи и завершённые задачи по активным проектам пользователя
+// (своим и тем, где он участник).
+func (s *StatsStorage) ProjectCompletion(userID uint) ([]models.ProjectCompletion, error) {
+	var rows []models.ProjectCompletion
+	members := s.db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userID)
+	err := s.db.Model(&models.Project{}).
+		Select("projects.id AS project_id, projects.title, COUNT(tasks.id) AS total, "+
+			"COALESCE(SUM(CASE WHEN tasks.status = ? THEN 1 ELSE 0 END), 0) AS completed", models.StatusCompleted).
+		Joins("LEFT JOIN tasks ON tasks.project_id = projects.id AND tasks.deleted_at IS NULL").
+		Where("projects.owner_id = ? OR projects.id IN (?)", userID, members).
+		Where("projects.archived_at IS NULL").
+		Group("projects.id, projects.title").
+		Order("projects.title ASC, projects.id ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// DayCompletion — оценки задач, завершённых за день.
+type DayCompletion struct {
+	Points float64
+	Hours  float64
+	Tasks  int64
+}
+
+// CompletedEstimates — сумма оценок завершённых задач проекта по дням completed_at
+// (в поясе from) в интервале [from, to).
+func (s *StatsStorage) CompletedEstimates(projectID uint, from, to time.Time) (map[string]DayCompletion, error) {
+	day := dayExpr(s.db, "completed_at", from)
+	var rows []struct {
+		Day    string
+		Points float64
+		Hours  float64
+		Tasks  int64
+	}
+	err := s.tasks(&projectID).
+		Select(day+" AS day, COALESCE(SUM(story_points), 0) AS points, "+
+			"COALESCE(SUM(estimate_hours), 0) AS hours, COUNT(*) AS tasks").
+		Where("status = ? AND completed_at >= ? AND completed_at < ?", models.StatusCompleted, from.UTC(), to.UTC()).
+		Group(day).
+		Scan(&rows).Error
+	days := make(map[string]DayCompletion, len(rows))
+	for _, row := range rows {
+		days[row.Day] = DayCompletion{Points: row.Points, Hours: row.Hours, Tasks: row.Tasks}
+	}
+	return days, err
+}
+
+// RemainingWork — открытые задачи проекта и сумма их оценок.
+type RemainingWork struct {
+	Tasks       int64
+	Unestimated int64
+	Points      float64
+	Hours       float64
+}
+
+// Remaining считает незавершённые и неотменённые задачи проекта.
+func (s *StatsStorage) Remaining(projectID uint) (RemainingWork, error) {
+	var work RemainingWork
+	err := s.tasks(&projectID).
+		Select("COUNT(*) AS tasks, "+
+			"COALESCE(SUM(CASE WHEN story_points IS NULL THEN 1 ELSE 0 END), 0) AS unestimated, "+
+			"COALESCE(SUM(story_points), 0) AS points, COALESCE(SUM(estimate_hours), 0) AS hours").
+		Where("status NOT IN ?", closedTaskStatuses).
+		Scan(&work).Error
+	return work, err
+}
+
+func (s *StatsStorage) tasks(projectID *uint) *gorm.DB {
+	query := s.db.Model(&models.Task{})
+	if projectID != nil {
+		query = query.Where("project_id = ?", *projectID)
+	}
+	return query
+}
+
+// dayExpr — дата (YYYY-MM-DD) из колонки времени в поясе from. В Postgres колонка —
+// timestamptz, пояс передаётся по имени IANA. SQLite поясов не знает: время хранится
+// строкой со смещением, strftime приводит её к UTC и прибавляет смещение пояса на
+// момент from (переход на летнее время внутри диапазона не учитывается).
+func dayExpr(db *gorm.DB, column string, from time.Time) string {
+	if db.Dialector.Name() == "sqlite" {
+		if _, offset := from.Zone(); offset != 0 {
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s, '%+d seconds')", column, offset)
+		}
+		return "strftime('%Y-%m-%d', " + column + ")"
+	}
+	return "to_char(" + column + " AT TIME ZONE '" + zoneName(from.Location()) + "', 'YYYY-MM-DD')"
+}
+
+// zoneName — имя пояса для подстановки в SQL. Имена IANA состоят из букв, цифр и
+// знаков /_+-; всё остальное (в том числе Local) заменяется на UTC.
+func zoneName(loc *time.Location) string {
+	name := loc.String()
+	if name == "" || name == "Local" {
+		return "UTC"
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '/' || r == '_' || r == '+' || r == '-':
+		default:
+			return "UTC"
+		}
+	}
+	return name
+}
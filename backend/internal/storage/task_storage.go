@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/spozitivom/taskmanager/internal/models"
+	"github.com/spozitivom/taskmanager/internal/taskquery"
 	"gorm.io/gorm"
 )
 
@@ -25,34 +28,115 @@ func (s *TaskStorage) GetAllSorted(sortOrder string) ([]models.Task, error) {
 	return tasks, err
 }
 
+// Режимы фильтра по меткам: хотя бы одна из меток или все сразу.
+const (
+	LabelMatchAny = "any"
+	LabelMatchAll = "all"
+)
+
+// TaskFilter — условия выборки задач. ProjectID = 0 — задачи без проекта.
+// Query — скомпилированный запрос на языке taskquery, применяется вместе с остальными условиями.
+type TaskFilter struct {
+	Status    string
+	Priority  string
+	Stage     string
+	ProjectID *uint
+	// Statuses — любой из статусов; применяется вместе со Status.
+	Statuses []string
+	// MilestoneID — спринт; 0 — задачи без спринта (бэклог).
+	MilestoneID *uint
+	LabelIDs    []uint
+	LabelMode   string
+	Query       *taskquery.Condition
+}
+
 // 🔍 GetFiltered — возвращает задачи по фильтрам + сортировке
 func (s *TaskStorage) GetFiltered(sortOrder, status, priority, stage string, projectID *uint) ([]models.Task, error) {
+	return s.List(sortOrder, TaskFilter{Status: status, Priority: priority, Stage: stage, ProjectID: projectID})
+}
+
+// List возвращает задачи, подходящие под фильтр, отсортированные по created_at.
+func (s *TaskStorage) List(sortOrder string, filter TaskFilter) ([]models.Task, error) {
 	if sortOrder != "asc" && sortOrder != "desc" {
 		sortOrder = "desc"
 	}
+	var tasks []models.Task
+	err := s.filtered(filter).Order("created_at " + sortOrder).Find(&tasks).Error
+	return tasks, err
+}
 
+// ListScheduled — задачи, чей интервал [start_at, end_at] пересекается с [from, to):
+// начинаются раньше to и заканчиваются не раньше from. Задачи без дат не попадают.
+// Условие по start_at и end_at опирается на индекс idx_tasks_schedule.
+func (s *TaskStorage) ListScheduled(from, to time.Time, filter TaskFilter) ([]models.Task, error) {
+	var tasks []models.Task
+	err := s.filtered(filter).
+		Where("start_at < ? AND end_at >= ?", to.UTC(), from.UTC()).
+		Order("start_at ASC").Order("id ASC").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// Count — число задач, подходящих под фильтр.
+func (s *TaskStorage) Count(filter TaskFilter) (int64, error) {
+	var count int64
+	err := s.filtered(filter).Count(&count).Error
+	return count, err
+}
+
+func (s *TaskStorage) filtered(filter TaskFilter) *gorm.DB {
 	query := s.db.Model(&models.Task{})
 
-	if status != "" {
-		query = query.Where("status = ?", status)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
 	}
-	if priority != "" {
-		query = query.Where("priority = ?", priority)
+	if filter.Priority != "" {
+		query = query.Where("priority = ?", filter.Priority)
 	}
-	if stage != "" {
-		query = query.Where("stage = ?", stage)
+	if filter.Stage != "" {
+		query = query.Where("stage = ?", filter.Stage)
 	}
-	if projectID != nil {
-		if *projectID == 0 {
+	if filter.ProjectID != nil {
+		if *filter.ProjectID == 0 {
 			query = query.Where("project_id IS NULL")
 		} else {
-			query = query.Where("project_id = ?", *projectID)
+			query = query.Where("project_id = ?", *filter.ProjectID)
+		}
+	}
+	if filter.MilestoneID != nil {
+		if *filter.MilestoneID == 0 {
+			query = query.Where("milestone_id IS NULL")
+		} else {
+			query = query.Where("milestone_id = ?", *filter.MilestoneID)
+		}
+	}
+	if len(filter.LabelIDs) > 0 {
+		labeled := s.db.Model(&models.TaskLabel{}).Select("task_id").Where("label_id IN ?", filter.LabelIDs)
+		if filter.LabelMode == LabelMatchAll {
+			labeled = labeled.Group("task_id").Having("COUNT(DISTINCT label_id) = ?", len(uniqueIDs(filter.LabelIDs)))
 		}
+		query = query.Where("id IN (?)", labeled)
 	}
+	if filter.Query != nil {
+		query = query.Where(filter.Query.SQL, filter.Query.Args...)
+	}
+	return query
+}
 
-	var tasks []models.Task
-	err := query.Order("created_at " + sortOrder).Find(&tasks).Error
-	return tasks, err
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
 }
 
 // GetByID возвращает задачу по ID
@@ -75,16 +159,21 @@ func (s *TaskStorage) Update(task *models.Task) error {
 	return s.db.Save(task).Error
 }
 
-// Delete удаляет задачу по ID
+// Delete удаляет задачу по ID вместе с её вложениями
 func (s *TaskStorage) Delete(id uint) error {
-	return s.db.Delete(&models.Task{}, id).Error
+	return s.BulkDelete([]uint{id})
 }
 
 func (s *TaskStorage) BulkDelete(ids []uint) error {
 	if len(ids) == 0 {
 		return nil
 	}
-	return s.db.Where("id IN ?", ids).Delete(&models.Task{}).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id IN ?", ids).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.Task{}).Error
+	})
 }
 
 func (s *TaskStorage) GetByIDs(ids []uint) ([]models.Task, error) {
@@ -110,6 +199,18 @@ func (s *TaskStorage) SaveAll(tasks []models.Task) error {
 	return nil
 }
 
+// ListByProject — задачи проекта по порядку создания; includeDeleted добавляет
+// удалённые, в том числе скрытые архивированием проекта.
+func (s *TaskStorage) ListByProject(projectID uint, includeDeleted bool) ([]models.Task, error) {
+	query := s.db.Where("project_id = ?", projectID)
+	if includeDeleted {
+		query = query.Unscoped()
+	}
+	var tasks []models.Task
+	err := query.Order("created_at ASC, id ASC").Find(&tasks).Error
+	return tasks, err
+}
+
 func (s *TaskStorage) CountByProject(projectID uint) (int64, error) {
 	var count int64
 	if err := s.db.Model(&models.Task{}).Where("project_id = ?", projectID).Count(&count).Error; err != nil {
@@ -118,12 +219,47 @@ func (s *TaskStorage) CountByProject(projectID uint) (int64, error) {
 	return count, nil
 }
 
+// CountByOwner — живые задачи во всех неудалённых проектах владельца.
+func (s *TaskStorage) CountByOwner(ownerID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.Task{}).
+		Joins("JOIN projects ON projects.id = tasks.project_id AND projects.deleted_at IS NULL").
+		Where("projects.owner_id = ?", ownerID).
+		Count(&count).Error
+	return count, err
+}
+
+// CountDeletedByProject — удалённые задачи проекта, например скрытые его архивированием.
+func (s *TaskStorage) CountDeletedByProject(projectID uint) (int64, error) {
+	var count int64
+	err := s.db.Unscoped().Model(&models.Task{}).
+		Where("project_id = ? AND deleted_at IS NOT NULL", projectID).
+		Count(&count).Error
+	return count, err
+}
+
+// SoftDeleteByProject скрывает задачи проекта и их вложения.
 func (s *TaskStorage) SoftDeleteByProject(projectID uint) error {
-	return s.db.Where("project_id = ?", projectID).Delete(&models.Task{}).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		taskIDs := tx.Model(&models.Task{}).Select("id").Where("project_id = ?", projectID)
+		if err := tx.Where("task_id IN (?)", taskIDs).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("project_id = ?", projectID).Delete(&models.Task{}).Error
+	})
 }
 
+// RestoreByProject возвращает задачи проекта вместе с вложениями.
 func (s *TaskStorage) RestoreByProject(projectID uint) error {
-	return s.db.Unscoped().Model(&models.Task{}).
-		Where("project_id = ?", projectID).
-		Update("deleted_at", nil).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		taskIDs := tx.Unscoped().Model(&models.Task{}).Select("id").Where("project_id = ?", projectID)
+		if err := tx.Unscoped().Model(&models.Attachment{}).
+			Where("task_id IN (?)", taskIDs).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Task{}).
+			Where("project_id = ?", projectID).
+			Update("deleted_at", nil).Error
+	})
 }
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// Группировки отчёта по времени.
+const (
+	TimeByProject = "project"
+	TimeByTask    = "task"
+	TimeByUser    = "user"
+	TimeByDay     = "day"
+)
+
+// TimeEntryFilter — выборка своих записей. Нулевые поля не ограничивают.
+type TimeEntryFilter struct {
+	UserID    uint
+	TaskID    uint
+	ProjectID uint
+	From, To  time.Time // [From, To) по started_at
+	Limit     int
+}
+
+// TimeReportFilter — условия отчёта. В отчёт попадают записи самого ViewerID
+// и все записи по задачам его проектов; идущие таймеры не учитываются.
+type TimeReportFilter struct {
+	ViewerID  uint
+	UserID    uint
+	ProjectID uint
+	From, To  time.Time
+}
+
+type TimeEntryStorage struct {
+	db *gorm.DB
+}
+
+func NewTimeEntryStorage(db *gorm.DB) *TimeEntryStorage {
+	return &TimeEntryStorage{db: db}
+}
+
+func (s *TimeEntryStorage) Create(entry *models.TimeEntry) error {
+	return s.db.Create(entry).Error
+}
+
+func (s *TimeEntryStorage) Update(entry *models.TimeEntry) error {
+	return s.db.Save(entry).Error
+}
+
+func (s *TimeEntryStorage) Delete(id uint) error {
+	return s.db.Delete(&models.TimeEntry{}, id).Error
+}
+
+func (s *TimeEntryStorage) Get(id uint) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	if err := s.db.First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Running — идущий таймер пользователя.
+func (s *TimeEntryStorage) Running(userID uint) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	if err := s.db.Where("user_id = ? AND ended_at IS NULL", userID).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *TimeEntryStorage) List(filter TimeEntryFilter) ([]models.TimeEntry, error) {
+	query := s.db.Model(&models.TimeEntry{}).Where("time_entries.user_id = ?", filter.UserID)
+	if filter.TaskID != 0 {
+		query = query.Where("time_entries.task_id = ?", filter.TaskID)
+	}
+	if filter.ProjectID != 0 {
+		query = query.Where("time_entries.task_id IN (?)",
+			s.db.Unscoped().Model(&models.Task{}).Select("id").Where("project_id = ?", filter.ProjectID))
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("time_entries.started_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("time_entries.started_at < ?", filter.To.UTC())
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var entries []models.TimeEntry
+	err := query.Order("time_entries.started_at DESC, time_entries.id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// Report суммирует завершённые записи по группам. Проект берётся из задачи
+// на момент отчёта, в том числе для удалённых задач и проектов.
+func (s *TimeEntryStorage) Report(filter TimeReportFilter, groupBy string) ([]models.TimeReportRow, error) {
+	ownProjects := s.db.Unscoped().Model(&models.Project{}).Select("id").Where("owner_id = ?", filter.ViewerID)
+	query := s.db.Table("time_entries").
+		Joins("LEFT JOIN tasks ON tasks.id = time_entries.task_id").
+		Where("time_entries.ended_at IS NOT NULL").
+		Where("time_entries.started_at >= ? AND time_entries.started_at < ?", filter.From.UTC(), filter.To.UTC()).
+		Where("time_entries.user_id = ? OR tasks.project_id IN (?)", filter.ViewerID, ownProjects)
+	if filter.UserID != 0 {
+		query = query.Where("time_entries.user_id = ?", filter.UserID)
+	}
+	if filter.ProjectID != 0 {
+		query = query.Where("tasks.project_id = ?", filter.ProjectID)
+	}
+
+	const sums = "COALESCE(SUM(time_entries.duration_seconds), 0) AS seconds, COUNT(*) AS entries"
+	switch groupBy {
+	case TimeByProject:
+		query = query.Joins("LEFT JOIN projects ON projects.id = tasks.project_id").
+			Select("tasks.project_id AS group_id, projects.title AS label, " + sums).
+			Group("tasks.project_id, projects.title")
+	case TimeByTask:
+		query = query.Select("time_entries.task_id AS group_id, tasks.title AS label, " + sums).
+			Group("time_entries.task_id, tasks.title")
+	case TimeByUser:
+		query = query.Joins("LEFT JOIN users ON users.id = time_entries.user_id").
+			Select("time_entries.user_id AS group_id, users.username AS label, " + sums).
+			Group("time_entries.user_id, users.username")
+	case TimeByDay:
+		day := dayExpr(s.db, "time_entries.started_at", filter.From)
+		query = query.Select(day + " AS label, " + sums).Group(day)
+	default:
+		return nil, gorm.ErrInvalidField
+	}
+
+	var rows []struct {
+		GroupID *uint
+		Label   *string
+		Seconds int64
+		Entries int64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make([]models.TimeReportRow, 0, len(rows))
+	for _, row := range rows {
+		item := models.TimeReportRow{Seconds: row.Seconds, Entries: row.Entries}
+		if row.Label != nil {
+			item.Label = *row.Label
+		}
+		switch {
+		case groupBy == TimeByDay:
+			item.Key = item.Label
+		case row.GroupID == nil:
+			item.Key = "none"
+		default:
+			item.Key = strconv.FormatUint(uint64(*row.GroupID), 10)
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"errors"
+
 	"github.com/spozitivom/taskmanager/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserStorage struct {
@@ -21,6 +24,17 @@ func (s *UserStorage) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// LockForUpdate блокирует строку пользователя до конца транзакции (SELECT … FOR UPDATE).
+// SQLite блокировок строк не знает и запросы к нему и так идут по очереди.
+func (s *UserStorage) LockForUpdate(id uint) error {
+	var user models.User
+	err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
+
 func (s *UserStorage) Update(user *models.User) error {
 	return s.db.Save(user).Error
 }
@@ -28,3 +42,30 @@ func (s *UserStorage) Update(user *models.User) error {
 func (s *UserStorage) DeleteByID(id uint) error {
 	return s.db.Unscoped().Delete(&models.User{}, id).Error
 }
+
+func (s *UserStorage) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// AdvanceTOTPStep атомарно запоминает последний принятый шаг TOTP.
+// Возвращает false, если этот шаг (или более поздний) уже был использован.
+func (s *UserStorage) AdvanceTOTPStep(userID uint, step int64) (bool, error) {
+	res := s.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (s *UserStorage) Create(user *models.User) error {
+	return s.db.Create(user).Error
+}
+
+func (s *UserStorage) UsernameExists(username string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,295 @@
+package taskquery
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+)
+
+// Condition — готовое SQL-условие с плейсхолдерами «?» для gorm Where.
+type Condition struct {
+	SQL  string
+	Args []any
+}
+
+var textColumns = map[string]string{
+	"title":       "tasks.title",
+	"description": "tasks.description",
+}
+
+var dateColumns = map[string]string{
+	"start_at":   "tasks.start_at",
+	"end_at":     "tasks.end_at",
+	"created_at": "tasks.created_at",
+	"updated_at": "tasks.updated_at",
+}
+
+// closedStatuses — задачи в этих статусах не бывают просроченными.
+var closedStatuses = []any{models.StatusCompleted, models.StatusCancelled}
+
+// Compile переводит дерево в SQL. Относительные даты отсчитываются от now,
+// границы дней берутся в часовом поясе now. Имена колонок подставляются только
+// из белого списка, значения всегда уходят параметрами.
+//
+// Каждое условие возвращает TRUE или FALSE, но не NULL: сравнения с пустыми
+// колонками обёрнуты в IS NOT NULL, поэтому NOT end_at < now находит и задачи без срока.
+func Compile(node Node, now time.Time) *Condition {
+	if node == nil {
+		return nil
+	}
+	c := &compiler{now: now}
+	c.node(node)
+	return &Condition{SQL: c.sb.String(), Args: c.args}
+}
+
+type compiler struct {
+	sb   strings.Builder
+	args []any
+	now  time.Time
+}
+
+func (c *compiler) write(sql string, args ...any) {
+	c.sb.WriteString(sql)
+	c.args = append(c.args, args...)
+}
+
+func (c *compiler) node(node Node) {
+	switch n := node.(type) {
+	case And:
+		c.write("(")
+		c.node(n.Left)
+		c.write(" AND ")
+		c.node(n.Right)
+		c.write(")")
+	case Or:
+		c.write("(")
+		c.node(n.Left)
+		c.write(" OR ")
+		c.node(n.Right)
+		c.write(")")
+	case Not:
+		c.write("(NOT ")
+		c.node(n.Expr)
+		c.write(")")
+	case *Term:
+		if n.Op == OpNe {
+			// a != b — это NOT (a = b): так же ведут себя списки и пустые колонки.
+			eq := *n
+			eq.Op = OpEq
+			c.write("(NOT ")
+			c.term(&eq)
+			c.write(")")
+			return
+		}
+		c.term(n)
+	}
+}
+
+func (c *compiler) term(t *Term) {
+	switch t.Field {
+	case "text":
+		pattern := likePattern(t.Values[0])
+		c.write("(LOWER(tasks.title) LIKE LOWER(?) ESCAPE '\\' OR LOWER(tasks.description) LIKE LOWER(?) ESCAPE '\\')", pattern, pattern)
+	case "title", "description":
+		column := textColumns[t.Field]
+		if t.Op == OpMatch {
+			c.write("(LOWER("+column+") LIKE LOWER(?) ESCAPE '\\')", likePattern(t.Values[0]))
+		} else {
+			c.write("("+column+" = ?)", t.Values[0])
+		}
+	case "status":
+		c.in("tasks.status", t.Values)
+	case "stage":
+		c.in("tasks.stage", t.Values)
+	case "priority":
+		c.priority(t)
+	case "project":
+		c.project(t.Values)
+	case "project_id":
+		c.projectID(t.Values)
+	case "start_at", "end_at", "created_at", "updated_at":
+		c.date(dateColumns[t.Field], t.Op, t.dates[0])
+	case "all_day":
+		c.write("(tasks.all_day = ?)", t.Values[0] == "true")
+	case "is":
+		c.is(t.Values)
+	case "has":
+		c.has(t.Values)
+	}
+}
+
+func (c *compiler) in(column string, values []string) {
+	if len(values) == 1 {
+		c.write("("+column+" = ?)", values[0])
+		return
+	}
+	c.write("("+column+" IN ?)", toArgs(values))
+}
+
+// priority сравнивает по порядку low < medium < high.
+func (c *compiler) priority(t *Term) {
+	if t.Op == OpMatch || t.Op == OpEq {
+		c.in("tasks.priority", t.Values)
+		return
+	}
+	rank := priorityRank[t.Values[0]]
+	var matched []string
+	for _, p := range []string{models.PriorityLow, models.PriorityMedium, models.PriorityHigh} {
+		r := priorityRank[p]
+		if (t.Op == OpLt && r < rank) || (t.Op == OpLe && r <= rank) ||
+			(t.Op == OpGt && r > rank) || (t.Op == OpGe && r >= rank) {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) == 0 {
+		c.write("(1 = 0)")
+		return
+	}
+	c.in("tasks.priority", matched)
+}
+
+// project ищет проекты по названию без учёта регистра; none — задачи без проекта.
+// Для не-ASCII названий SQLite сравнивает с учётом регистра, Postgres — без.
+func (c *compiler) project(values []string) {
+	var names []any
+	none := false
+	for _, v := range values {
+		if strings.EqualFold(v, "none") {
+			none = true
+			continue
+		}
+		names = append(names, v)
+	}
+	c.write("(")
+	if len(names) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("LOWER(?), ", len(names)), ", ")
+		c.write("(tasks.project_id IS NOT NULL AND tasks.project_id IN "+
+			"(SELECT projects.id FROM projects WHERE LOWER(projects.title) IN ("+placeholders+") AND projects.deleted_at IS NULL))", names...)
+		if none {
+			c.write(" OR ")
+		}
+	}
+	if none {
+		c.write("tasks.project_id IS NULL")
+	}
+	c.write(")")
+}
+
+func (c *compiler) projectID(values []string) {
+	var ids []string
+	none := false
+	for _, v := range values {
+		if v == "none" {
+			none = true
+			continue
+		}
+		ids = append(ids, v)
+	}
+	c.write("(")
+	if len(ids) > 0 {
+		c.write("(tasks.project_id IS NOT NULL AND tasks.project_id IN ?)", toArgs(ids))
+		if none {
+			c.write(" OR ")
+		}
+	}
+	if none {
+		c.write("tasks.project_id IS NULL")
+	}
+	c.write(")")
+}
+
+// date сравнивает колонку с моментом или с календарным днём: для дня «:» и «=»
+// означают «в течение дня», «<» — раньше начала дня, «<=» — не позже его конца.
+func (c *compiler) date(column, op string, value dateValue) {
+	from, day := value.resolve(c.now)
+	to := from
+	if day {
+		to = from.AddDate(0, 0, 1)
+	} else if op == OpMatch {
+		from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+		to = from.AddDate(0, 0, 1)
+		day = true
+	}
+	// В базе время хранится в UTC: так сравнение строк в SQLite совпадает с Postgres.
+	from, to = from.UTC(), to.UTC()
+
+	guard := "(" + column + " IS NOT NULL AND " + column
+	switch {
+	case (op == OpMatch || op == OpEq) && day:
+		c.write(guard+" >= ? AND "+column+" < ?)", from, to)
+	case op == OpEq:
+		c.write(guard+" = ?)", from)
+	case op == OpLt:
+		c.write(guard+" < ?)", from)
+	case op == OpLe && day:
+		c.write(guard+" < ?)", to)
+	case op == OpLe:
+		c.write(guard+" <= ?)", from)
+	case op == OpGt && day:
+		c.write(guard+" >= ?)", to)
+	case op == OpGt:
+		c.write(guard+" > ?)", from)
+	case op == OpGe:
+		c.write(guard+" >= ?)", from)
+	}
+}
+
+func (c *compiler) is(values []string) {
+	c.write("(")
+	for i, v := range values {
+		if i > 0 {
+			c.write(" OR ")
+		}
+		switch v {
+		case "overdue":
+			c.write("(tasks.end_at IS NOT NULL AND tasks.end_at < ? AND tasks.status NOT IN ?)", c.now.UTC(), closedStatuses)
+		case "due_today":
+			start := time.Date(c.now.Year(), c.now.Month(), c.now.Day(), 0, 0, 0, 0, c.now.Location())
+			c.write("(tasks.end_at IS NOT NULL AND tasks.end_at >= ? AND tasks.end_at < ? AND tasks.status NOT IN ?)",
+				start.UTC(), start.AddDate(0, 0, 1).UTC(), closedStatuses)
+		case "open":
+			c.write("(tasks.status NOT IN ?)", closedStatuses)
+		case "done":
+			c.write("(tasks.status = ?)", models.StatusCompleted)
+		case "cancelled":
+			c.write("(tasks.status = ?)", models.StatusCancelled)
+		}
+	}
+	c.write(")")
+}
+
+func (c *compiler) has(values []string) {
+	c.write("(")
+	for i, v := range values {
+		if i > 0 {
+			c.write(" OR ")
+		}
+		switch v {
+		case "project":
+			c.write("tasks.project_id IS NOT NULL")
+		case "start_at":
+			c.write("tasks.start_at IS NOT NULL")
+		case "end_at":
+			c.write("tasks.end_at IS NOT NULL")
+		case "description":
+			c.write("(tasks.description IS NOT NULL AND tasks.description <> '')")
+		}
+	}
+	c.write(")")
+}
+
+// likePattern экранирует спецсимволы LIKE для поиска подстроки. Регистр приводит
+// сама база (LOWER с обеих сторон), чтобы сравнение было одинаковым для колонки и значения.
+func likePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(value) + "%"
+}
+
+func toArgs(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,564 @@
+// Package taskquery — язык запросов к задачам. Строка разбирается в AST,
+// который затем переводится в параметризованное SQL-условие по колонкам tasks.
+//
+// Примеры:
+//
+//	priority:high OR is:overdue
+//	project:"Сайт" AND NOT status:cancelled
+//	(status:todo,in_progress) end_at < now+7d
+//	-has:end_at "отчёт"
+package taskquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spozitivom/taskmanager/internal/models"
+)
+
+const (
+	// MaxLength — предельная длина запроса в символах.
+	MaxLength = 1000
+	maxDepth  = 32
+)
+
+// SyntaxError — ошибка разбора с номером колонки (с 1, в символах).
+type SyntaxError struct {
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+// Node — узел дерева запроса.
+type Node interface {
+	node()
+}
+
+type And struct{ Left, Right Node }
+
+type Or struct{ Left, Right Node }
+
+type Not struct{ Expr Node }
+
+// Term — одно условие «поле оператор значение». Свободный текст без поля
+// ищется в названии и описании (Field = "text").
+type Term struct {
+	Field  string
+	Op     string
+	Values []string
+	Column int
+
+	dates []dateValue
+}
+
+func (And) node()   {}
+func (Or) node()    {}
+func (Not) node()   {}
+func (*Term) node() {}
+
+// Операторы сравнения.
+const (
+	OpMatch = ":"
+	OpEq    = "="
+	OpNe    = "!="
+	OpLt    = "<"
+	OpLe    = "<="
+	OpGt    = ">"
+	OpGe    = ">="
+)
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindStatus
+	kindPriority
+	kindStage
+	kindProject
+	kindProjectID
+	kindDate
+	kindBool
+	kindIs
+	kindHas
+)
+
+var fields = map[string]fieldKind{
+	"title":       kindText,
+	"description": kindText,
+	"status":      kindStatus,
+	"priority":    kindPriority,
+	"stage":       kindStage,
+	"project":     kindProject,
+	"project_id":  kindProjectID,
+	"start_at":    kindDate,
+	"end_at":      kindDate,
+	"created_at":  kindDate,
+	"updated_at":  kindDate,
+	"all_day":     kindBool,
+	"is":          kindIs,
+	"has":         kindHas,
+}
+
+// Синонимы, которые удобнее набирать.
+var fieldAliases = map[string]string{
+	"due":      "end_at",
+	"deadline": "end_at",
+	"start":    "start_at",
+	"created":  "created_at",
+	"updated":  "updated_at",
+}
+
+var isValues = map[string]struct{}{
+	"overdue":   {},
+	"due_today": {},
+	"open":      {},
+	"done":      {},
+	"cancelled": {},
+}
+
+var hasValues = map[string]struct{}{
+	"project":     {},
+	"start_at":    {},
+	"end_at":      {},
+	"description": {},
+}
+
+var priorityRank = map[string]int{
+	models.PriorityLow:    0,
+	models.PriorityMedium: 1,
+	models.PriorityHigh:   2,
+}
+
+// Parse разбирает запрос. Пустой запрос (одни пробелы) даёт nil без ошибки.
+func Parse(input string) (Node, error) {
+	if utf8.RuneCountInString(input) > MaxLength {
+		return nil, &SyntaxError{Column: MaxLength + 1, Msg: fmt.Sprintf("query is longer than %d characters", MaxLength)}
+	}
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+	node, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, &SyntaxError{Column: tok.column, Msg: "unexpected )"}
+		}
+		return nil, &SyntaxError{Column: tok.column, Msg: fmt.Sprintf("unexpected %s", tok.describe())}
+	}
+	return node, nil
+}
+
+// --- лексер ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokMinus
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()":=!<>`, r)
+}
+
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		column := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", column})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", column})
+			i++
+		case r == '"':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					sb.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, &SyntaxError{Column: column, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{tokString, sb.String(), column})
+		case r == ':' || r == '=':
+			tokens = append(tokens, token{tokOp, string(r), column})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{tokOp, string(runes[i : i+2]), column})
+				i += 2
+				continue
+			}
+			if r == '!' {
+				return nil, &SyntaxError{Column: column, Msg: "expected !="}
+			}
+			tokens = append(tokens, token{tokOp, string(r), column})
+			i++
+		case r == '-' && i+1 < len(runes) && (runes[i+1] == '(' || runes[i+1] == '"' || isWordRune(runes[i+1])) &&
+			(i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '('):
+			// «-» в начале слова — отрицание: -status:done.
+			tokens = append(tokens, token{tokMinus, "-", column})
+			i++
+		default:
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokWord, string(runes[start:i]), column})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(runes) + 1}), nil
+}
+
+// --- парсер ---
+//
+//	or    := and { OR and }
+//	and   := unary { [AND] unary }
+//	unary := NOT unary | "-" unary | "(" or ")" | term
+//	term  := word op value | word | string
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(tok token, word string) bool {
+	return tok.kind == tokWord && strings.EqualFold(tok.text, word)
+}
+
+func (p *parser) parseOr(depth int) (Node, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword(p.peek(), "OR") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (Node, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if p.keyword(tok, "AND") {
+			p.next()
+		} else if tok.kind == tokEOF || tok.kind == tokRParen || p.keyword(tok, "OR") {
+			return left, nil
+		}
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary(depth int) (Node, error) {
+	if depth > maxDepth {
+		return nil, &SyntaxError{Column: p.peek().column, Msg: "query is nested too deeply"}
+	}
+	tok := p.peek()
+	switch {
+	case p.keyword(tok, "NOT") || tok.kind == tokMinus:
+		p.next()
+		expr, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	case tok.kind == tokLParen:
+		p.next()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &SyntaxError{Column: closing.column, Msg: fmt.Sprintf("expected ) to close ( at column %d, got %s", tok.column, closing.describe())}
+		}
+		return expr, nil
+	case tok.kind == tokString:
+		p.next()
+		return &Term{Field: "text", Op: OpMatch, Values: []string{tok.text}, Column: tok.column}, nil
+	case tok.kind == tokWord:
+		if p.keyword(tok, "AND") || p.keyword(tok, "OR") {
+			return nil, &SyntaxError{Column: tok.column, Msg: fmt.Sprintf("expected a condition before %s", strings.ToUpper(tok.text))}
+		}
+		p.next()
+		if p.peek().kind != tokOp {
+			return &Term{Field: "text", Op: OpMatch, Values: []string{tok.text}, Column: tok.column}, nil
+		}
+		return p.parseTerm(tok)
+	default:
+		return nil, &SyntaxError{Column: tok.column, Msg: fmt.Sprintf("expected a condition, got %s", tok.describe())}
+	}
+}
+
+func (p *parser) parseTerm(fieldTok token) (Node, error) {
+	name := strings.ToLower(fieldTok.text)
+	if alias, ok := fieldAliases[name]; ok {
+		name = alias
+	}
+	kind, ok := fields[name]
+	if !ok {
+		return nil, &SyntaxError{Column: fieldTok.column, Msg: fmt.Sprintf("unknown field %q", fieldTok.text)}
+	}
+	opTok := p.next()
+	valueTok := p.next()
+	if valueTok.kind != tokWord && valueTok.kind != tokString {
+		return nil, &SyntaxError{Column: valueTok.column, Msg: fmt.Sprintf("expected a value after %s%s, got %s", fieldTok.text, opTok.text, valueTok.describe())}
+	}
+	term := &Term{Field: name, Op: opTok.text, Column: fieldTok.column}
+	if !opAllowed(kind, term.Op) {
+		return nil, &SyntaxError{Column: opTok.column, Msg: fmt.Sprintf("operator %s is not supported for %s", term.Op, name)}
+	}
+
+	// Через запятую можно перечислить несколько значений: status:todo,in_progress.
+	values := []string{valueTok.text}
+	if valueTok.kind == tokWord && kind != kindDate && kind != kindText {
+		values = strings.Split(valueTok.text, ",")
+	}
+	for i, raw := range values {
+		value, err := checkValue(kind, name, raw)
+		if err != nil {
+			return nil, &SyntaxError{Column: valueTok.column, Msg: err.Error()}
+		}
+		values[i] = value
+	}
+	if len(values) > 1 && term.Op != OpMatch && term.Op != OpEq && term.Op != OpNe {
+		return nil, &SyntaxError{Column: valueTok.column, Msg: fmt.Sprintf("operator %s takes a single value", term.Op)}
+	}
+	term.Values = values
+
+	if kind == kindDate {
+		date, err := parseDate(values[0])
+		if err != nil {
+			return nil, &SyntaxError{Column: valueTok.column, Msg: err.Error()}
+		}
+		term.dates = []dateValue{date}
+	}
+	return term, nil
+}
+
+func opAllowed(kind fieldKind, op string) bool {
+	switch kind {
+	case kindDate, kindPriority:
+		return true
+	case kindIs, kindHas:
+		return op == OpMatch
+	default:
+		return op == OpMatch || op == OpEq || op == OpNe
+	}
+}
+
+func checkValue(kind fieldKind, field, raw string) (string, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return "", fmt.Errorf("empty value for %s", field)
+	}
+	switch kind {
+	case kindStatus:
+		status, err := models.NormalizeTaskStatus(strings.ToLower(value))
+		if err != nil {
+			return "", fmt.Errorf("unknown status %q", value)
+		}
+		return status, nil
+	case kindPriority:
+		priority, err := models.NormalizePriority(value)
+		if err != nil {
+			return "", fmt.Errorf("unknown priority %q", value)
+		}
+		return priority, nil
+	case kindProjectID:
+		if strings.EqualFold(value, "none") {
+			return "none", nil
+		}
+		if id, err := strconv.ParseUint(value, 10, 64); err != nil || id == 0 {
+			return "", fmt.Errorf("project_id must be a number or none")
+		}
+	case kindBool:
+		b, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false", field)
+		}
+		return strconv.FormatBool(b), nil
+	case kindIs:
+		value = strings.ToLower(value)
+		if _, ok := isValues[value]; !ok {
+			return "", fmt.Errorf("unknown is:%s, expected overdue, due_today, open, done or cancelled", raw)
+		}
+	case kindHas:
+		value = strings.ToLower(value)
+		if alias, ok := fieldAliases[value]; ok {
+			value = alias
+		}
+		if _, ok := hasValues[value]; !ok {
+			return "", fmt.Errorf("unknown has:%s, expected project, start_at, end_at or description", raw)
+		}
+	}
+	return value, nil
+}
+
+// --- даты ---
+
+// dateValue — момент (now+3h, RFC 3339) или календарный день (today-1d, 2025-03-01).
+// Относительные значения вычисляются при компиляции, в часовом поясе now.
+type dateValue struct {
+	base   string // now, today или пусто для абсолютной даты
+	amount int
+	unit   string
+	abs    time.Time
+	day    bool
+}
+
+var dayBases = map[string]int{"yesterday": -1, "today": 0, "tomorrow": 1}
+
+func parseDate(raw string) (dateValue, error) {
+	value := strings.ToLower(raw)
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return dateValue{abs: t}, nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return dateValue{abs: t, day: true}, nil
+	}
+
+	base, rest := value, ""
+	if i := strings.IndexAny(value, "+-"); i >= 0 {
+		base, rest = value[:i], value[i:]
+	}
+	date := dateValue{base: base, day: true}
+	if shift, ok := dayBases[base]; ok {
+		date.base, date.amount, date.unit = "today", shift, "d"
+	} else if base == "now" {
+		date.day = false
+	} else {
+		return date, fmt.Errorf("invalid date %q, expected now, today, YYYY-MM-DD or RFC 3339", raw)
+	}
+	if rest == "" {
+		return date, nil
+	}
+	if date.amount != 0 {
+		return date, fmt.Errorf("invalid date %q: offsets are only allowed after now or today", raw)
+	}
+
+	i := 1
+	for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+		i++
+	}
+	amount, err := strconv.Atoi(rest[1:i])
+	if err != nil || amount > 100000 {
+		return date, fmt.Errorf("invalid date offset %q", rest)
+	}
+	if rest[0] == '-' {
+		amount = -amount
+	}
+	date.amount = amount
+	date.unit = rest[i:]
+	switch date.unit {
+	case "h":
+		date.day = false
+	case "d", "w", "mo", "y":
+	default:
+		return date, fmt.Errorf("invalid date unit %q, expected h, d, w, mo or y", date.unit)
+	}
+	return date, nil
+}
+
+// resolve возвращает начало интервала и признак «целый день».
+func (d dateValue) resolve(now time.Time) (time.Time, bool) {
+	if d.base == "" {
+		if d.day {
+			return time.Date(d.abs.Year(), d.abs.Month(), d.abs.Day(), 0, 0, 0, 0, now.Location()), true
+		}
+		return d.abs, false
+	}
+	t := now
+	if d.base == "today" {
+		t = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	switch d.unit {
+	case "h":
+		t = t.Add(time.Duration(d.amount) * time.Hour)
+	case "d":
+		t = t.AddDate(0, 0, d.amount)
+	case "w":
+		t = t.AddDate(0, 0, 7*d.amount)
+	case "mo":
+		t = t.AddDate(0, d.amount, 0)
+	case "y":
+		t = t.AddDate(d.amount, 0, 0)
+	}
+	return t, d.day
+}
@@ -0,0 +1,71 @@
+package taskquery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_ReportsErrorColumn(t *testing.T) {
+	cases := []struct {
+		query  string
+		column int
+		msg    string
+	}{
+		{`priority:high OR`, 17, "expected a condition"},
+		{`(status:todo OR is:overdue`, 27, "expected ) to close ( at column 1"},
+		{`status:todo)`, 12, "unexpected )"},
+		{`owner:me`, 1, `unknown field "owner"`},
+		{`priority:urgent`, 10, `unknown priority "urgent"`},
+		{`end_at < next_week`, 10, "invalid date"},
+		{`end_at < now+7x`, 10, "invalid date unit"},
+		{`status < todo`, 8, "operator < is not supported"},
+		{`title:"unterminated`, 7, "unterminated string"},
+		{`is:late`, 4, "unknown is:late"},
+		{`проект status:todo AND`, 23, "expected a condition"},
+	}
+	for _, tc := range cases {
+		_, err := Parse(tc.query)
+		var syntaxErr *SyntaxError
+		require.ErrorAs(t, err, &syntaxErr, tc.query)
+		require.Equal(t, tc.column, syntaxErr.Column, tc.query)
+		require.Contains(t, syntaxErr.Msg, tc.msg, tc.query)
+	}
+
+	node, err := Parse("   ")
+	require.NoError(t, err)
+	require.Nil(t, node)
+}
+
+func TestCompile_BuildsParameterizedSQL(t *testing.T) {
+	now := time.Date(2025, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	node, err := Parse(`(priority:high OR is:overdue) project:"Сайт" AND NOT status:cancelled`)
+	require.NoError(t, err)
+	cond := Compile(node, now)
+	require.Equal(t, "((((tasks.priority = ?) OR ((tasks.end_at IS NOT NULL AND tasks.end_at < ? AND tasks.status NOT IN ?))) AND "+
+		"((tasks.project_id IS NOT NULL AND tasks.project_id IN (SELECT projects.id FROM projects WHERE LOWER(projects.title) IN (LOWER(?)) AND projects.deleted_at IS NULL)))) AND "+
+		"(NOT (tasks.status = ?)))", cond.SQL)
+	require.Equal(t, []any{"high", now, []any{"completed", "cancelled"}, "Сайт", "cancelled"}, cond.Args)
+
+	// Значения никогда не попадают в текст SQL.
+	node, err = Parse(`title:"50%' OR 1=1 --"`)
+	require.NoError(t, err)
+	cond = Compile(node, now)
+	require.Equal(t, `(LOWER(tasks.title) LIKE LOWER(?) ESCAPE '\')`, cond.SQL)
+	require.Equal(t, []any{`%50\%' OR 1=1 --%`}, cond.Args)
+
+	// Календарный день против момента времени.
+	node, err = Parse(`end_at <= today+1d created_at > now-2h`)
+	require.NoError(t, err)
+	cond = Compile(node, now)
+	require.Equal(t, "((tasks.end_at IS NOT NULL AND tasks.end_at < ?) AND (tasks.created_at IS NOT NULL AND tasks.created_at > ?))", cond.SQL)
+	require.Equal(t, []any{time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC), now.Add(-2 * time.Hour)}, cond.Args)
+
+	node, err = Parse(`-priority>=medium status:todo,in_progress`)
+	require.NoError(t, err)
+	cond = Compile(node, now)
+	require.Equal(t, "((NOT (tasks.priority IN ?)) AND (tasks.status IN ?))", cond.SQL)
+	require.Equal(t, []any{[]any{"medium", "high"}, []any{"todo", "in_progress"}}, cond.Args)
+}
@@ -56,6 +56,7 @@ export default function App() {
       setIsLoggedIn(true);
     } catch (err) {
       localStorage.removeItem("token");
+      localStorage.removeItem("refresh_token");
       setIsLoggedIn(false);
     } finally {
       setLoading(false);
@@ -13,11 +13,12 @@ const API = import.meta.env.VITE_API_URL || "/api";
  *  – выбрасывает ошибку, если код ответа НЕ 2xx
  *  – безопасно обрабатывает пустые ответы (204)
  */
-export async function request(url, options = {}) {
+export async function request(url, options = {}, retried = false) {
   const token = localStorage.getItem("token");
 
+  // Для FormData браузер сам выставляет multipart/form-data с boundary
   const headers = {
-    "Content-Type": "application/json",
+    ...(options.body instanceof FormData ? {} : { "Content-Type": "application/json" }),
     ...(options.headers || {}),
   };
 
@@ -35,7 +36,11 @@ export async function request(url, options = {}) {
   if (!res.ok) {
     const isAuthEndpoint = url.startsWith("/auth/");
     if (res.status === 401 && !isAuthEndpoint) {
-      localStorage.removeItem("token");
+      // access-токен короткоживущий — пробуем один раз обновить пару и повторить запрос
+      if (!retried && (await refreshTokens())) {
+        return request(url, options, true);
+      }
+      clearTokens();
       window.location.reload();
     }
     const message = extractErrorMessage(text) || `HTTP ${res.status}`;
@@ -45,6 +50,50 @@ export async function request(url, options = {}) {
   return text ? JSON.parse(text) : null;
 }
 
+let refreshInFlight = null;
+
+// Обменивает refresh-токен на новую пару; параллельные запросы ждут один обмен.
+async function refreshTokens() {
+  const refreshToken = localStorage.getItem("refresh_token");
+  if (!refreshToken) {
+    return false;
+  }
+  if (!refreshInFlight) {
+    refreshInFlight = fetch(`${API}/auth/refresh`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    })
+      .then(async (res) => {
+        if (!res.ok) {
+          return false;
+        }
+        const text = await res.text();
+        storeTokens(text ? JSON.parse(text) : {});
+        return true;
+      })
+      .catch(() => false)
+      .finally(() => {
+        refreshInFlight = null;
+      });
+  }
+  return refreshInFlight;
+}
+
+function storeTokens(payload) {
+  if (payload?.token) {
+    localStorage.setItem("token", payload.token);
+  }
+  if (payload?.refresh_token) {
+    localStorage.setItem("refresh_token", payload.refresh_token);
+  }
+}
+
+function clearTokens() {
+  localStorage.removeItem("token");
+  localStorage.removeItem("refresh_token");
+}
+
 function extractErrorMessage(payload) {
   if (!payload) {
     return "";
@@ -69,6 +118,10 @@ function extractErrorMessage(payload) {
 export const getTasks = (params) =>
   request(`/tasks${params ? `?${params}` : ""}`);
 
+// 📌 Поиск задач выражением: searchTasks('priority:high OR is:overdue')
+export const searchTasks = (query, params = {}) =>
+  getTasks(new URLSearchParams({ ...params, query }).toString());
+
 // 📌 Создать новую задачу
 export const createTask = (data) =>
   request("/tasks", { method: "POST", body: JSON.stringify(data) });
@@ -96,6 +149,194 @@ export const bulkAssignTasks = (payload) =>
     body: JSON.stringify(payload),
   });
 
+// patch — те же поля, что и у updateTask, кроме project_id
+export const bulkPatchTasks = (ids, patch) =>
+  request("/tasks/bulk/patch", {
+    method: "POST",
+    body: JSON.stringify({ ids, patch }),
+  });
+
+// mode: add | remove | replace
+export const bulkLabelTasks = (ids, labelIds, mode = "add") =>
+  request("/tasks/bulk/labels", {
+    method: "POST",
+    body: JSON.stringify({ ids, label_ids: labelIds, mode }),
+  });
+
+export const setTaskLabels = (id, labelIds) =>
+  request(`/tasks/${id}/labels`, { method: "PUT", body: JSON.stringify({ label_ids: labelIds }) });
+
+/* ----------  Labels ---------- */
+
+export const getLabels = (projectId) =>
+  request(`/labels${projectId ? `?project_id=${projectId}` : ""}`);
+
+export const createLabel = (data) =>
+  request("/labels", { method: "POST", body: JSON.stringify(data) });
+
+export const updateLabel = (id, data) =>
+  request(`/labels/${id}`, { method: "PATCH", body: JSON.stringify(data) });
+
+export const mergeLabel = (id, intoId) =>
+  request(`/labels/${id}/merge`, { method: "POST", body: JSON.stringify({ into_id: intoId }) });
+
+export const deleteLabel = (id) =>
+  request(`/labels/${id}`, { method: "DELETE" });
+
+/* ----------  Statistics ---------- */
+
+// from/to — YYYY-MM-DD, по умолчанию последние 30 дней
+const statsQuery = ({ from, to } = {}) => {
+  const params = new URLSearchParams();
+  if (from) params.set("from", from);
+  if (to) params.set("to", to);
+  const query = params.toString();
+  return query ? `?${query}` : "";
+};
+
+export const getStats = (range) => request(`/stats${statsQuery(range)}`);
+
+export const getProjectStats = (id, range) =>
+  request(`/projects/${id}/stats${statsQuery(range)}`);
+
+// weeks — сколько полных недель брать для средней скорости (по умолчанию 8)
+export const getProjectVelocity = (id, weeks) =>
+  request(`/projects/${id}/velocity${weeks ? `?weeks=${weeks}` : ""}`);
+
+/* ----------  Calendar ---------- */
+
+// from/to — YYYY-MM-DD (to включительно) или ISO-время; filters: { project_id, status: ["todo", ...] }
+// Ответ: { from, to, all_day: [...], timed: [...] }
+export const getCalendar = (from, to, filters = {}) => {
+  const params = new URLSearchParams({ from, to });
+  if (filters.project_id) params.set("project_id", filters.project_id);
+  if (filters.status?.length) params.set("status", [].concat(filters.status).join(","));
+  return request(`/calendar?${params}`);
+};
+
+/* ----------  Milestones (sprints) ---------- */
+
+export const getMilestones = (projectId) => request(`/projects/${projectId}/milestones`);
+
+// data: { name, goal, start_date, end_date } — даты YYYY-MM-DD
+export const createMilestone = (projectId, data) =>
+  request(`/projects/${projectId}/milestones`, { method: "POST", body: JSON.stringify(data) });
+
+export const updateMilestone = (id, data) =>
+  request(`/milestones/${id}`, { method: "PATCH", body: JSON.stringify(data) });
+
+export const deleteMilestone = (id) =>
+  request(`/milestones/${id}`, { method: "DELETE" });
+
+// options: {} — в следующий спринт, { backlog: true } или { target_id }
+export const closeMilestone = (id, options = {}) =>
+  request(`/milestones/${id}/close`, { method: "POST", body: JSON.stringify(options) });
+
+export const getMilestoneBurndown = (id) => request(`/milestones/${id}/burndown`);
+
+// milestoneId = null — убрать задачи в бэклог
+export const assignTasksToMilestone = (ids, milestoneId) =>
+  request("/tasks/bulk/milestone", {
+    method: "POST",
+    body: JSON.stringify({ ids, milestone_id: milestoneId }),
+  });
+
+/* ----------  Project templates ---------- */
+
+export const getProjectTemplates = () => request("/project-templates");
+
+export const getProjectTemplate = (id) => request(`/project-templates/${id}`);
+
+// data: { name, project_title, priority, tasks_limit, tags, deadline_offset_days, tasks: [...] }
+export const createProjectTemplate = (data) =>
+  request("/project-templates", { method: "POST", body: JSON.stringify(data) });
+
+export const updateProjectTemplate = (id, data) =>
+  request(`/project-templates/${id}`, { method: "PUT", body: JSON.stringify(data) });
+
+export const deleteProjectTemplate = (id) =>
+  request(`/project-templates/${id}`, { method: "DELETE" });
+
+// data: { name, description, start_date } — start_date YYYY-MM-DD, необязательна
+export const saveProjectAsTemplate = (projectId, data = {}) =>
+  request(`/projects/${projectId}/template`, { method: "POST", body: JSON.stringify(data) });
+
+export const shareProjectTemplate = (id, email) =>
+  request(`/project-templates/${id}/shares`, { method: "POST", body: JSON.stringify({ email }) });
+
+export const unshareProjectTemplate = (id, userId) =>
+  request(`/project-templates/${id}/shares/${userId}`, { method: "DELETE" });
+
+// data: { start_date, title } — по умолчанию сегодня и название из шаблона
+export const createProjectFromTemplate = (id, data = {}) =>
+  request(`/project-templates/${id}/instantiate`, { method: "POST", body: JSON.stringify(data) });
+
+/* ----------  Time tracking ---------- */
+
+export const getTimer = () => request("/time/timer");
+
+export const startTimer = (taskId, note = "") =>
+  request("/time/timer/start", { method: "POST", body: JSON.stringify({ task_id: taskId, note }) });
+
+export const stopTimer = () => request("/time/timer/stop", { method: "POST" });
+
+// filters: { task_id, project_id, from, to }
+export const getTimeEntries = (filters = {}) => {
+  const params = new URLSearchParams();
+  Object.entries(filters).forEach(([key, value]) => {
+    if (value) params.set(key, value);
+  });
+  const query = params.toString();
+  return request(`/time/entries${query ? `?${query}` : ""}`);
+};
+
+export const createTimeEntry = (data) =>
+  request("/time/entries", { method: "POST", body: JSON.stringify(data) });
+
+export const updateTimeEntry = (id, data) =>
+  request(`/time/entries/${id}`, { method: "PATCH", body: JSON.stringify(data) });
+
+export const deleteTimeEntry = (id) =>
+  request(`/time/entries/${id}`, { method: "DELETE" });
+
+// params: { group_by: project|task|user|day, from, to, project_id, user_id }
+const timeReportQuery = (params = {}, format = "json") => {
+  const query = new URLSearchParams({ format });
+  Object.entries(params).forEach(([key, value]) => {
+    if (value) query.set(key, value);
+  });
+  return `/time/report?${query}`;
+};
+
+export const getTimeReport = (params) => request(timeReportQuery(params));
+
+// CSV не проходит через request (он разбирает JSON), поэтому скачиваем Blob напрямую
+export const downloadTimeReport = async (params) => {
+  const token = localStorage.getItem("token");
+  const res = await fetch(`${API}${timeReportQuery(params, "csv")}`, {
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+  });
+  if (!res.ok) {
+    throw new Error(extractErrorMessage(await res.text()) || `HTTP ${res.status}`);
+  }
+  return res.blob();
+};
+
+/* ----------  Saved views ---------- */
+
+export const getViews = () => request("/views");
+
+export const createView = (data) =>
+  request("/views", { method: "POST", body: JSON.stringify(data) });
+
+export const updateView = (id, data) =>
+  request(`/views/${id}`, { method: "PATCH", body: JSON.stringify(data) });
+
+export const deleteView = (id) =>
+  request(`/views/${id}`, { method: "DELETE" });
+
+export const getViewTasks = (id) => request(`/views/${id}/tasks`);
+
 /* ----------  Projects ---------- */
 
 export const getProjects = (includeArchived = false) =>
@@ -119,10 +360,17 @@ export const deleteProject = (id) =>
 export const toggleProjectCompleted = (id, cascade = "cancel_unfinished") =>
   request(`/projects/${id}/toggle-completed?cascade=${cascade}`, { method: "POST" });
 
+// options: { title, reset_statuses, shift_days, include_deleted }
+export const duplicateProject = (id, options = {}) =>
+  request(`/projects/${id}/duplicate`, { method: "POST", body: JSON.stringify(options) });
+
 /* ----------  Профиль / Настройки ---------- */
 
 export const getProfile = () => request("/user/profile");
 
+// Тариф, лимиты и потребление: { plan, limits, projects, tasks, storage, by_project }
+export const getUsage = () => request("/user/usage");
+
 export const updateProfile = (payload) =>
   request("/user/profile", { method: "PATCH", body: JSON.stringify(payload) });
 
@@ -132,6 +380,40 @@ export const updatePassword = (payload) =>
 export const updateSettings = (payload) =>
   request("/user/settings", { method: "PATCH", body: JSON.stringify(payload) });
 
+// Часовой пояс браузера (IANA, например "Europe/Moscow") — по нему сервер считает границы дней
+export const saveBrowserTimeZone = () =>
+  updateSettings({ time_zone: Intl.DateTimeFormat().resolvedOptions().timeZone || "UTC" });
+
+export const deactivateAccount = (password, reason) =>
+  request("/user/deactivate", { method: "POST", body: JSON.stringify({ password, reason }) });
+
+export const fetchSecurityEvents = (limit = 50, offset = 0) =>
+  request(`/user/security-events?limit=${limit}&offset=${offset}`);
+
+// Загрузка аватара файлом (multipart); сервер проверяет тип и готовит миниатюры
+export const uploadAvatar = (file) => {
+  const body = new FormData();
+  body.append("file", file);
+  return request("/user/avatar", { method: "POST", body });
+};
+
+// Вложения задач
+export const fetchAttachments = (taskId) => request(`/tasks/${taskId}/attachments`);
+
+export const uploadAttachment = (taskId, file) => {
+  const body = new FormData();
+  body.append("file", file);
+  return request(`/tasks/${taskId}/attachments`, { method: "POST", body });
+};
+
+export const deleteAttachment = (id) => request(`/attachments/${id}`, { method: "DELETE" });
+
+export const requestDataExport = () => request("/user/exports", { method: "POST" });
+
+export const fetchDataExports = () => request("/user/exports");
+
+export const issueDataExportLink = (id) => request(`/user/exports/${id}/link`, { method: "POST" });
+
 export const deleteAccount = (confirm) =>
   request("/user", { method: "DELETE", body: JSON.stringify({ confirm }) });
 
@@ -156,7 +438,37 @@ export const login = async (identifier, password) => {
     method: "POST",
     body: JSON.stringify(payload),
   });
-  localStorage.setItem("token", res.token);
+  storeTokens(res);
+  return res;
+};
+
+// 📌 Второй шаг входа при включённой 2FA: код из приложения или резервный код
+export const loginTwoFactor = async (challengeToken, { code, recoveryCode } = {}) => {
+  const res = await request("/auth/login/2fa", {
+    method: "POST",
+    body: JSON.stringify({
+      challenge_token: challengeToken,
+      code,
+      recovery_code: recoveryCode,
+    }),
+  });
+  storeTokens(res);
+  return res;
+};
+
+// 📌 Вход через SSO: переходим на страницу провайдера…
+export const startOidcLogin = async () => {
+  const { authorization_url } = await request("/auth/oidc/authorize");
+  window.location.assign(authorization_url);
+};
+
+// …и после возврата на redirect URL обмениваем code/state на токены
+export const completeOidcLogin = async (code, state) => {
+  const res = await request("/auth/oidc/callback", {
+    method: "POST",
+    body: JSON.stringify({ code, state }),
+  });
+  storeTokens(res);
   return res;
 };
 
@@ -178,7 +490,25 @@ export const register = async (payloadOrEmail, username, password) => {
   });
 };
 
-// 📌 Выход (очистка токена)
-export const logout = () => {
-  localStorage.removeItem("token");
+// 📌 Выход: завершаем серверную сессию и очищаем токены
+export const logout = async () => {
+  if (localStorage.getItem("token")) {
+    try {
+      await request("/auth/logout", { method: "POST" });
+    } catch (err) {
+      // сессия уже могла истечь — локальные токены чистим в любом случае
+    }
+  }
+  clearTokens();
 };
+
+// 📌 Выход на всех устройствах
+export const logoutAll = async () => {
+  await request("/auth/logout-all", { method: "POST" });
+  clearTokens();
+};
+
+export const getSessions = () => request("/auth/sessions");
+
+export const revokeSession = (id) =>
+  request(`/auth/sessions/${id}`, { method: "DELETE" });
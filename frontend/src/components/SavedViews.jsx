@@ -0,0 +1,96 @@
+import { useCallback, useEffect, useState } from "react";
+import { Bookmark, Plus, Trash2 } from "lucide-react";
+import * as api from "../api";
+
+/**
+ * Сохранённые представления в боковой панели: имя, число задач,
+ * применение фильтров по клику и сохранение текущих фильтров.
+ */
+export default function SavedViews({ currentFilter, onApply, refreshKey, userId }) {
+  const [views, setViews] = useState([]);
+  const [error, setError] = useState("");
+
+  const load = useCallback(async () => {
+    try {
+      const data = await api.getViews();
+      setViews(Array.isArray(data) ? data : []);
+      setError("");
+    } catch (err) {
+      setError(err.message || "Не удалось загрузить представления");
+    }
+  }, []);
+
+  useEffect(() => {
+    load();
+  }, [load, refreshKey]);
+
+  const handleSave = async () => {
+    const name = window.prompt("Название представления");
+    if (!name?.trim()) return;
+    try {
+      await api.createView({ name, filter: currentFilter });
+      await load();
+    } catch (err) {
+      setError(err.message || "Не удалось сохранить представление");
+    }
+  };
+
+  const handleDelete = async (view) => {
+    if (!window.confirm(`Удалить представление «${view.name}»?`)) return;
+    try {
+      await api.deleteView(view.id);
+      setViews((prev) => prev.filter((v) => v.id !== view.id));
+    } catch (err) {
+      setError(err.message || "Не удалось удалить представление");
+    }
+  };
+
+  return (
+    <div className="mt-4 p-3 rounded-2xl bg-white/90 border border-slate-200 shadow-lg shadow-slate-100">
+      <div className="flex items-center justify-between px-1 pb-2">
+        <span className="text-xs font-semibold uppercase tracking-wide text-slate-500">Представления</span>
+        <button
+          type="button"
+          onClick={handleSave}
+          title="Сохранить текущие фильтры"
+          className="rounded-lg p-1 text-slate-500 hover:bg-slate-100"
+        >
+          <Plus className="h-4 w-4" />
+        </button>
+      </div>
+      {error && <p className="px-1 pb-2 text-xs text-rose-600">{error}</p>}
+      {views.length === 0 && !error && (
+        <p className="px-1 text-xs text-slate-400">Сохраните фильтры, чтобы быстро к ним возвращаться</p>
+      )}
+      <ul className="space-y-1">
+        {views.map((view) => (
+          <li key={view.id} className="group flex items-center gap-1">
+            <button
+              type="button"
+              onClick={() => onApply(view.filter || {})}
+              className="flex-1 min-w-0 text-left flex items-center justify-between gap-2 px-3 py-2 rounded-xl text-sm hover:bg-slate-50"
+            >
+              <span className="flex items-center gap-2 truncate">
+                <Bookmark className="h-3.5 w-3.5 shrink-0 text-indigo-400" />
+                <span className="truncate">{view.name}</span>
+              </span>
+              <span className="min-w-[28px] rounded-full bg-slate-100 px-2 py-0.5 text-center text-xs font-semibold text-slate-600">
+                {view.tasks_count ?? 0}
+              </span>
+            </button>
+            {view.owner_id === userId && (
+              <button
+                type="button"
+                onClick={() => handleDelete(view)}
+                title="Удалить представление"
+                className="hidden group-hover:block rounded-lg p-1 text-slate-400 hover:text-rose-600"
+              >
+                <Trash2 className="h-3.5 w-3.5" />
+              </button>
+            )}
+          </li>
+        ))}
+      </ul>
+    </div>
+  );
+}
@@ -24,6 +24,7 @@ import ProjectsView from "./ProjectsView";
 import TaskEditorModal from "./TaskEditorModal";
 import TaskCalendar from "./calendar/TaskCalendar";
 import UserSettings from "./UserSettings";
+import SavedViews from "./SavedViews";
 import { DragDropContext, Droppable, Draggable } from "@hello-pangea/dnd";
 import { describeProject, formatDeadline } from "../utils/formatters";
 
@@ -277,6 +278,15 @@ export default function TaskDashboard({
     return list;
   }, [tasks, search, statusFilter, priorityFilter, stageFilter, projectFilter, sortKey, sortDir]);
 
+  const applySavedView = (filter) => {
+    setMainView("tasks");
+    setStatusFilter(filter.status || "");
+    setPriorityFilter(filter.priority || "");
+    setStageFilter(filter.stage || "");
+    setProjectFilter(filter.project_id || "");
+    setSortOrder(filter.sort || "desc");
+  };
+
   const resetFilters = () => {
     setSearch("");
     setStatusFilter("");
@@ -523,6 +533,18 @@ export default function TaskDashboard({
               ))}
             </ul>
           </nav>
+          <SavedViews
+            userId={user?.id}
+            refreshKey={tasks.length}
+            currentFilter={{
+              status: statusFilter,
+              priority: priorityFilter,
+              stage: stageFilter,
+              project_id: projectFilter,
+              sort: sortOrder,
+            }}
+            onApply={applySavedView}
+          />
         </aside>
 
         <main className="col-span-12 md:col-span-9 lg:col-span-10 space-y-4">
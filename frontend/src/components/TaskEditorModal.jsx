@@ -124,6 +124,33 @@ export default function TaskEditorModal({
             </Field>
           </div>
 
+          <div className="grid gap-4 md:grid-cols-2">
+            <Field label="Story points">
+              <input
+                type="number"
+                min="0"
+                max="1000"
+                step="0.5"
+                value={form.story_points}
+                onChange={handleChange("story_points")}
+                placeholder="Не оценена"
+                className="w-full rounded-2xl border border-slate-200 px-4 py-2.5 text-sm focus:border-indigo-400 focus:outline-none"
+              />
+            </Field>
+            <Field label="Оценка, часы">
+              <input
+                type="number"
+                min="0"
+                max="10000"
+                step="0.25"
+                value={form.estimate_hours}
+                onChange={handleChange("estimate_hours")}
+                placeholder="Не оценена"
+                className="w-full rounded-2xl border border-slate-200 px-4 py-2.5 text-sm focus:border-indigo-400 focus:outline-none"
+              />
+            </Field>
+          </div>
+
           <Field label="Проект">
             <select
               value={form.project_id}
@@ -194,6 +221,8 @@ function getInitialState(task) {
       stage: "",
       project_id: "",
       deadline: "",
+      story_points: "",
+      estimate_hours: "",
     };
   }
 
@@ -204,10 +233,26 @@ function getInitialState(task) {
     priority: task.priority || "medium",
     stage: task.stage || "",
     project_id: task.project_id ? String(task.project_id) : "",
-    deadline: task.end_at ? task.end_at.slice(0, 10) : "",
+    deadline: task.end_at ? toDateInput(task.end_at) : "",
+    story_points: task.story_points ?? "",
+    estimate_hours: task.estimate_hours ?? "",
   };
 }
 
+// Границы дня на весь день сервер хранит в поясе пользователя, поэтому дату берём
+// по местному времени, а не из UTC-части строки.
+function toDateInput(value) {
+  const date = new Date(value);
+  if (Number.isNaN(date.getTime())) {
+    return "";
+  }
+  const month = String(date.getMonth() + 1).padStart(2, "0");
+  const day = String(date.getDate()).padStart(2, "0");
+  return `${date.getFullYear()}-${month}-${day}`;
+}
+
+// Полночь с Z сервер понимает как календарную дату и сам отсчитывает этот день
+// в поясе пользователя.
 function transformForm(form) {
   const dateISO = form.deadline ? new Date(`${form.deadline}T00:00:00Z`).toISOString() : null;
   return {
@@ -219,5 +264,8 @@ function transformForm(form) {
     project_id: form.project_id ? Number(form.project_id) : null,
     end_at: dateISO,
     all_day: Boolean(dateISO),
+    // пустое поле сбрасывает оценку
+    story_points: form.story_points === "" ? null : Number(form.story_points),
+    estimate_hours: form.estimate_hours === "" ? null : Number(form.estimate_hours),
   };
 }
@@ -2,7 +2,7 @@ import { useEffect, useMemo, useRef, useState } from "react";
 import { Globe2, Loader2, LogOut, Moon, ShieldCheck, Sun, Trash2, Upload, X } from "lucide-react";
 import * as api from "../api";
 
-const AVATAR_LIMIT = 2 * 1024 * 1024; // 2 МБ
+const AVATAR_LIMIT = 5 * 1024 * 1024; // 5 МБ, как на сервере
 
 export default function UserSettings({ user, onUserChange, onLogout }) {
   const [profileForm, setProfileForm] = useState({ fullName: "", email: "" });
@@ -41,10 +41,10 @@ export default function UserSettings({ user, onUserChange, onLogout }) {
     setProfileStatus(null);
     setProfileLoading(true);
     try {
-      const payload = { full_name: profileForm.fullName };
-      if (avatar.payload !== undefined) {
-        payload.avatar = avatar.payload;
+      if (avatar.file) {
+        await api.uploadAvatar(avatar.file);
       }
+      const payload = { full_name: profileForm.fullName };
       if (avatar.remove) {
         payload.remove_avatar = true;
       }
@@ -143,7 +143,7 @@ export default function UserSettings({ user, onUserChange, onLogout }) {
               ref={fileInputRef}
               type="file"
               className="hidden"
-              accept="image/png,image/jpeg,image/webp"
+              accept="image/png,image/jpeg,image/gif"
               onChange={avatar.handleSelect(setProfileStatus)}
             />
           </div>
@@ -420,26 +420,26 @@ function PasswordField({ label, value, onChange }) {
 
 function useAvatarUpload({ initialUrl = "" } = {}) {
   const [preview, setPreview] = useState(initialUrl || "");
-  const [payload, setPayload] = useState(undefined);
+  const [file, setFile] = useState(null);
   const [remove, setRemove] = useState(false);
 
   const handleSelect = (setStatus) => (event) => {
     const file = event.target.files?.[0];
     if (!file) return;
-    if (!["image/jpeg", "image/png", "image/webp"].includes(file.type)) {
-      setStatus?.({ type: "error", message: "Поддерживаются jpg, png или webp" });
+    if (!["image/jpeg", "image/png", "image/gif"].includes(file.type)) {
+      setStatus?.({ type: "error", message: "Поддерживаются jpg, png или gif" });
       return;
     }
     if (file.size > AVATAR_LIMIT) {
-      setStatus?.({ type: "error", message: "Аватар должен быть не больше 2 МБ" });
+      setStatus?.({ type: "error", message: "Аватар должен быть не больше 5 МБ" });
       return;
     }
 
+    // Превью — локально; сам файл уходит multipart-запросом при сохранении
     const reader = new FileReader();
     reader.onload = () => {
-      const data = reader.result?.toString() || "";
-      setPreview(data);
-      setPayload(data);
+      setPreview(reader.result?.toString() || "");
+      setFile(file);
       setRemove(false);
       setStatus?.(null);
     };
@@ -448,14 +448,14 @@ function useAvatarUpload({ initialUrl = "" } = {}) {
 
   const reset = (nextUrl = "") => {
     setPreview(nextUrl);
-    setPayload(undefined);
+    setFile(null);
     setRemove(!nextUrl);
   };
 
   const markSaved = () => {
-    setPayload(undefined);
+    setFile(null);
     setRemove(false);
   };
 
-  return { preview, payload, remove, handleSelect, reset, markSaved };
+  return { preview, file, remove, handleSelect, reset, markSaved };
 }